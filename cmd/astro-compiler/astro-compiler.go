@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/withastro/compiler/compiler"
+)
+
+const usage = `Usage: astro-compiler <command> [options] <file|directory>...
+
+Commands:
+  parse      Print the AST of each file (ParseResult)
+  transform  Compile each file to JavaScript (TransformResult)
+  tsx        Convert each file to TSX (TSXResult)
+  tokenize   Print the tokens of each file (TokenizeResult)
+  props      Print the JSON Schema of the Props of each file (PropsResult)
+  serve      Compile files on request, speaking JSON-RPC over stdio
+
+Directories are searched recursively for .astro files. Use "-" to read from stdin.
+Results are written to stdout as newline-delimited JSON, one object per file,
+unless -out-dir is set: the result of a file is then written to <file>.json in
+the out dir, under its path relative to the directory it was found in. Run
+"astro-compiler <command> -h" to list its options.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var cmd *command
+	switch args[0] {
+	case "parse":
+		cmd = newParseCommand()
+	case "transform":
+		cmd = newTransformCommand()
+	case "tsx":
+		cmd = newTSXCommand()
+	case "tokenize":
+		cmd = newTokenizeCommand()
+	case "props":
+		cmd = newPropsCommand()
+	case "serve":
+		return serve(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "astro-compiler: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	cmd.flags.SetOutput(stderr)
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if cmd.flags.NArg() == 0 {
+		fmt.Fprintf(stderr, "astro-compiler %s: no input files\n", cmd.flags.Name())
+		return 2
+	}
+	// stdin can only be read once
+	stdinArgs := 0
+	for _, arg := range cmd.flags.Args() {
+		if arg == "-" {
+			stdinArgs++
+		}
+	}
+	if stdinArgs > 1 {
+		fmt.Fprintf(stderr, "astro-compiler %s: \"-\" is listed more than once, stdin can only be read once\n", cmd.flags.Name())
+		return 2
+	}
+
+	files, err := collectFiles(cmd.flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "astro-compiler: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	written := make(map[string]string, len(files))
+	for _, input := range files {
+		file := input.path
+		source, err := readSource(file, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "astro-compiler: %v\n", err)
+			status = 1
+			continue
+		}
+		filename := file
+		if filename == "-" {
+			filename = "<stdin>"
+		}
+		result, diagnostics := cmd.run(source, filename)
+		if hasErrors(diagnostics) {
+			status = 1
+		}
+		if *cmd.outDir == "" || file == "-" {
+			err = writeResult(stdout, result)
+		} else {
+			err = writeResultFile(*cmd.outDir, input, written, result)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "astro-compiler: %v\n", err)
+			return 1
+		}
+	}
+	return status
+}
+
+type command struct {
+	flags  *flag.FlagSet
+	outDir *string
+	// run compiles a single file, returning the JSON-serializable result and its diagnostics
+	run func(source string, filename string) (any, []compiler.DiagnosticMessage)
+}
+
+func newCommand(name string) *command {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	return &command{
+		flags:  flags,
+		outDir: flags.String("out-dir", "", "write one JSON file per input into `dir` instead of stdout"),
+	}
+}
+
+type transformFlags struct {
+	internalURL             *string
+	sourcemap               *string
+	astroGlobalArgs         *string
+	compact                 *bool
+	resultScopedSlot        *bool
+	scopedStyleStrategy     *string
+	transitionsAnimationURL *string
+	annotateSourceFile      *bool
+	renderScript            *bool
+	contentModelWarnings    *bool
+	foreignContentWarnings  *bool
+	validateExpressions     *bool
+	slotWarnings            *bool
+	accessibilityWarnings   *bool
+}
+
+func addTransformFlags(flags *flag.FlagSet) *transformFlags {
+	return &transformFlags{
+		internalURL:             flags.String("internal-url", "astro/runtime/server/index.js", "import specifier of the Astro runtime"),
+		sourcemap:               flags.String("sourcemap", "", "emit a source map: inline, external or both"),
+		astroGlobalArgs:         flags.String("astro-global-args", "", "arguments passed to $$createAstro"),
+		compact:                 flags.Bool("compact", false, "collapse whitespace in the output"),
+		resultScopedSlot:        flags.Bool("result-scoped-slot", false, "pass $$result to slot functions"),
+		scopedStyleStrategy:     flags.String("scoped-style-strategy", "where", "how styles are scoped: where, class or attribute"),
+		transitionsAnimationURL: flags.String("transitions-animation-url", "astro/components/viewtransitions.css", "import specifier of the view transitions stylesheet"),
+		annotateSourceFile:      flags.Bool("annotate-source-file", false, "annotate elements with data-astro-source-file"),
+		renderScript:            flags.Bool("render-script", false, "render processed scripts with renderScript instead of hoisting them"),
+		contentModelWarnings:    flags.Bool("content-model-warnings", false, "warn about the markup restructured by the HTML parsing rules"),
+		foreignContentWarnings:  flags.Bool("foreign-content-warnings", false, "warn about the mistakes in inline SVG and MathML"),
+		validateExpressions:     flags.Bool("validate-expressions", false, "report the syntax errors of the expressions"),
+		slotWarnings:            flags.Bool("slot-warnings", false, "warn about the content passed to the slots which the imported .astro components don't define"),
+		accessibilityWarnings:   flags.Bool("accessibility-warnings", false, "report the accessibility mistakes of the template"),
+	}
+}
+
+func (f *transformFlags) options(filename string) compiler.TransformOptions {
+	var loadComponent func(string) (string, bool)
+	if *f.slotWarnings {
+		// Relative specifiers are resolved against the filename, so the components are read from disk
+		loadComponent = func(path string) (string, bool) {
+			b, err := os.ReadFile(path)
+			return string(b), err == nil
+		}
+	}
+	return compiler.TransformOptions{
+		Filename:                filename,
+		InternalURL:             *f.internalURL,
+		SourceMap:               *f.sourcemap,
+		AstroGlobalArgs:         *f.astroGlobalArgs,
+		Compact:                 *f.compact,
+		ResultScopedSlot:        *f.resultScopedSlot,
+		ScopedStyleStrategy:     *f.scopedStyleStrategy,
+		TransitionsAnimationURL: *f.transitionsAnimationURL,
+		AnnotateSourceFile:      *f.annotateSourceFile,
+		RenderScript:            *f.renderScript,
+		ContentModelWarnings:    *f.contentModelWarnings,
+		ForeignContentWarnings:  *f.foreignContentWarnings,
+		ValidateExpressions:     *f.validateExpressions,
+		AccessibilityWarnings:   *f.accessibilityWarnings,
+		LoadComponent:           loadComponent,
+	}
+}
+
+// The JS API returns the AST and the TSX source map as parsed objects, so they are embedded as raw JSON here
+
+type ParseResult struct {
+	Filename    string                       `json:"filename"`
+	AST         json.RawMessage              `json:"ast"`
+	Diagnostics []compiler.DiagnosticMessage `json:"diagnostics"`
+	Slots       []compiler.Slot              `json:"slots"`
+	Components  []compiler.ComponentUsage    `json:"components"`
+}
+
+func newParseCommand() *command {
+	cmd := newCommand("parse")
+	position := cmd.flags.Bool("position", true, "include node positions in the AST")
+	recoverErrors := cmd.flags.Bool("recover", false, "recover from unterminated expressions and unbalanced tags")
+	frontmatterAST := cmd.flags.Bool("frontmatter-ast", false, "include the ESTree AST of the frontmatter")
+	validateExpressions := cmd.flags.Bool("validate-expressions", false, "report the syntax errors of the expressions")
+	cmd.run = func(source string, filename string) (any, []compiler.DiagnosticMessage) {
+		result := compiler.Parse(source, compiler.ParseOptions{
+			Filename:            filename,
+			Position:            *position,
+			Recover:             *recoverErrors,
+			FrontmatterAST:      *frontmatterAST,
+			ValidateExpressions: *validateExpressions,
+		})
+		return ParseResult{
+			Filename:    filename,
+			AST:         json.RawMessage(result.AST),
+			Diagnostics: result.Diagnostics,
+			Slots:       result.Slots,
+			Components:  result.Components,
+		}, result.Diagnostics
+	}
+	return cmd
+}
+
+type TSXResult struct {
+	Filename    string                       `json:"filename"`
+	Code        string                       `json:"code"`
+	Map         json.RawMessage              `json:"map"`
+	Diagnostics []compiler.DiagnosticMessage `json:"diagnostics"`
+	Ranges      compiler.TSXRanges           `json:"metaRanges"`
+}
+
+func newTSXCommand() *command {
+	cmd := newCommand("tsx")
+	sourcemapOption := cmd.flags.String("sourcemap", "", "set to external to omit the inline source map")
+	includeScripts := cmd.flags.Bool("include-scripts", true, "include the content of script tags in the TSX output")
+	includeStyles := cmd.flags.Bool("include-styles", true, "include the content of style tags in the TSX output")
+	recoverErrors := cmd.flags.Bool("recover", false, "recover from unterminated expressions and unbalanced tags")
+	cmd.run = func(source string, filename string) (any, []compiler.DiagnosticMessage) {
+		result := compiler.ConvertToTSX(source, compiler.TSXOptions{
+			Filename:       filename,
+			SourceMap:      *sourcemapOption,
+			IncludeScripts: *includeScripts,
+			IncludeStyles:  *includeStyles,
+			Recover:        *recoverErrors,
+		})
+		return TSXResult{
+			Filename:    filename,
+			Code:        result.Code,
+			Map:         json.RawMessage(result.Map),
+			Diagnostics: result.Diagnostics,
+			Ranges:      result.Ranges,
+		}, result.Diagnostics
+	}
+	return cmd
+}
+
+type TokenizeResult struct {
+	Filename string `json:"filename"`
+	compiler.TokenizeResult
+}
+
+func newTokenizeCommand() *command {
+	cmd := newCommand("tokenize")
+	cmd.run = func(source string, filename string) (any, []compiler.DiagnosticMessage) {
+		result := compiler.Tokenize(source, compiler.TokenizeOptions{Filename: filename})
+		return TokenizeResult{Filename: filename, TokenizeResult: result}, result.Diagnostics
+	}
+	return cmd
+}
+
+type PropsResult struct {
+	Filename string `json:"filename"`
+	// Schema is null when the component doesn't declare Props
+	Schema      json.RawMessage              `json:"schema"`
+	Diagnostics []compiler.DiagnosticMessage `json:"diagnostics"`
+}
+
+func newPropsResult(filename string, result compiler.PropsResult) PropsResult {
+	props := PropsResult{Filename: filename, Diagnostics: result.Diagnostics}
+	if result.Schema != "" {
+		props.Schema = json.RawMessage(result.Schema)
+	}
+	return props
+}
+
+func newPropsCommand() *command {
+	cmd := newCommand("props")
+	cmd.run = func(source string, filename string) (any, []compiler.DiagnosticMessage) {
+		result := compiler.ExtractProps(source, compiler.PropsOptions{Filename: filename})
+		return newPropsResult(filename, result), result.Diagnostics
+	}
+	return cmd
+}
+
+type TransformResult struct {
+	Filename string `json:"filename"`
+	compiler.TransformResult
+}
+
+func newTransformCommand() *command {
+	cmd := newCommand("transform")
+	tf := addTransformFlags(cmd.flags)
+	cmd.run = func(source string, filename string) (any, []compiler.DiagnosticMessage) {
+		result, err := compiler.Transform(source, tf.options(filename))
+		if err != nil {
+			diagnostics := []compiler.DiagnosticMessage{{
+				Severity: int(compiler.ErrorType),
+				Text:     err.Error(),
+			}}
+			return TransformResult{Filename: filename, TransformResult: compiler.TransformResult{Diagnostics: diagnostics}}, diagnostics
+		}
+		return TransformResult{Filename: filename, TransformResult: result}, result.Diagnostics
+	}
+	return cmd
+}
+
+func hasErrors(diagnostics []compiler.DiagnosticMessage) bool {
+	for _, d := range diagnostics {
+		if d.Severity == int(compiler.ErrorType) {
+			return true
+		}
+	}
+	return false
+}
+
+// inputFile is a file to compile. name is its path relative to the input root, the directory
+// argument it was found in, or its base name when it was listed explicitly.
+type inputFile struct {
+	path string
+	name string
+}
+
+// collectFiles expands directories into the .astro files they contain, in lexical order.
+// Explicitly listed files are kept regardless of their extension.
+func collectFiles(args []string) ([]inputFile, error) {
+	files := make([]inputFile, 0, len(args))
+	for _, arg := range args {
+		if arg == "-" {
+			files = append(files, inputFile{path: arg, name: arg})
+			continue
+		}
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, inputFile{path: arg, name: filepath.Base(arg)})
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != arg && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) == ".astro" {
+				name, err := filepath.Rel(arg, path)
+				if err != nil {
+					return err
+				}
+				files = append(files, inputFile{path: path, name: name})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func readSource(file string, stdin io.Reader) (string, error) {
+	var b []byte
+	var err error
+	if file == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(file)
+	}
+	return string(b), err
+}
+
+func encodeResult(result any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(result); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeResult(stdout io.Writer, result any) error {
+	b, err := encodeResult(result)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(b)
+	return err
+}
+
+// writeResultFile writes the result of input to outDir, under the name of input. written maps the
+// files already written to their input, so that two inputs with the same name don't overwrite
+// each other.
+func writeResultFile(outDir string, input inputFile, written map[string]string, result any) error {
+	name := input.name + ".json"
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("%s: the result would be written outside of the out dir", input.path)
+	}
+	out := filepath.Join(outDir, name)
+	if previous, ok := written[out]; ok {
+		return fmt.Errorf("%s: the result would overwrite the result of %s in %s", input.path, previous, out)
+	}
+	written[out] = input.path
+	b, err := encodeResult(result)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(out, b, 0o644)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFixture(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "index.astro", "---\nimport Counter from './Counter.jsx';\n---\n<style>h1 { color: red; }</style>\n<h1>Hello</h1>\n<Counter client:visible />")
+	writeFixture(t, dir, "nested/about.astro", "<p>About</p>")
+	writeFixture(t, dir, "nested/notes.md", "# Not a component")
+	writeFixture(t, dir, "node_modules/pkg/Skipped.astro", "<p>Skipped</p>")
+
+	tests := []struct {
+		name  string
+		args  []string
+		files []string
+		keys  []string
+	}{
+		{
+			name:  "parse",
+			args:  []string{"parse", dir},
+			files: []string{"index.astro", "nested/about.astro"},
+			keys:  []string{"filename", "ast", "diagnostics", "slots", "components"},
+		},
+		{
+			name:  "transform",
+			args:  []string{"transform", "-sourcemap", "external", dir},
+			files: []string{"index.astro", "nested/about.astro"},
+			keys:  []string{"filename", "code", "map", "css", "scripts", "hydratedComponents", "diagnostics"},
+		},
+		{
+			name:  "tsx",
+			args:  []string{"tsx", filepath.Join(dir, "nested/about.astro")},
+			files: []string{"nested/about.astro"},
+			keys:  []string{"filename", "code", "map", "metaRanges", "diagnostics"},
+		},
+		{
+			name:  "tokenize",
+			args:  []string{"tokenize", filepath.Join(dir, "nested/about.astro")},
+			files: []string{"nested/about.astro"},
+			keys:  []string{"filename", "tokens", "diagnostics"},
+		},
+		{
+			name:  "props",
+			args:  []string{"props", filepath.Join(dir, "nested/about.astro")},
+			files: []string{"nested/about.astro"},
+			keys:  []string{"filename", "schema", "diagnostics"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if status := run(tt.args, strings.NewReader(""), &stdout, &stderr); status != 0 {
+				t.Fatalf("exit status %d: %s", status, stderr.String())
+			}
+			lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+			if len(lines) != len(tt.files) {
+				t.Fatalf("expected %d results, got %d:\n%s", len(tt.files), len(lines), stdout.String())
+			}
+			for i, line := range lines {
+				result := map[string]json.RawMessage{}
+				if err := json.Unmarshal([]byte(line), &result); err != nil {
+					t.Fatalf("invalid JSON output: %v\n%s", err, line)
+				}
+				var filename string
+				json.Unmarshal(result["filename"], &filename)
+				if filename != filepath.Join(dir, tt.files[i]) {
+					t.Errorf("expected result for %s, got %s", tt.files[i], filename)
+				}
+				for _, key := range tt.keys {
+					if _, ok := result[key]; !ok {
+						t.Errorf("missing %q in %s result", key, tt.name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"transform", "-"}, strings.NewReader("<div>{value}</div>"), &stdout, &stderr)
+	if status != 0 {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	result := struct {
+		Filename string `json:"filename"`
+		Code     string `json:"code"`
+	}{}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Filename != "<stdin>" {
+		t.Errorf("expected <stdin>, got %s", result.Filename)
+	}
+	if !strings.Contains(result.Code, "<div>${value}</div>") {
+		t.Errorf("unexpected code:\n%s", result.Code)
+	}
+}
+
+func TestRunOutDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "src/index.astro", "<h1>Hello</h1>")
+	writeFixture(t, dir, "src/pages/about.astro", "<h1>About</h1>")
+	other := writeFixture(t, dir, "other/page.astro", "<h1>Other</h1>")
+	out := filepath.Join(dir, "out")
+
+	// The results are written relative to the input root, even for a path going through ".."
+	var stdout, stderr bytes.Buffer
+	args := []string{"tsx", "-out-dir", out, filepath.Join(dir, "src"), filepath.Join(dir, "src", "..", "other", "page.astro")}
+	if status := run(args, strings.NewReader(""), &stdout, &stderr); status != 0 {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected no output on stdout, got %q", stdout.String())
+	}
+	for _, name := range []string{"index.astro.json", "pages/about.astro.json", "page.astro.json"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if status := run([]string{"tsx", "-out-dir", out, other, filepath.Join(dir, "src", "index.astro"), filepath.Join(dir, "src", "pages", "..", "index.astro")}, strings.NewReader(""), &stdout, &stderr); status != 1 {
+		t.Errorf("expected exit status 1 for two inputs with the same name, got %d", status)
+	}
+	if !strings.Contains(stderr.String(), "would overwrite the result of") {
+		t.Errorf("unexpected error: %s", stderr.String())
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{}, strings.NewReader(""), &stdout, &stderr); status != 2 {
+		t.Errorf("expected exit status 2 without a command, got %d", status)
+	}
+	if status := run([]string{"compile", "index.astro"}, strings.NewReader(""), &stdout, &stderr); status != 2 {
+		t.Errorf("expected exit status 2 for an unknown command, got %d", status)
+	}
+	if status := run([]string{"parse"}, strings.NewReader(""), &stdout, &stderr); status != 2 {
+		t.Errorf("expected exit status 2 without input files, got %d", status)
+	}
+	if status := run([]string{"parse", "-", "-"}, strings.NewReader("<div />"), &stdout, &stderr); status != 2 || stdout.Len() != 0 {
+		t.Errorf("expected exit status 2 and no output when stdin is listed twice, got %d", status)
+	}
+}
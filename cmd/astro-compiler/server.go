@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/withastro/compiler/compiler"
+	"github.com/withastro/compiler/internal/jsonrpc"
+)
+
+const serveUsage = `Usage: astro-compiler serve
+
+Keeps the compiler running, reading JSON-RPC 2.0 requests from stdin and writing
+the responses to stdout, one message per line. The methods "parse", "tokenize",
+"extractProps", "transform" and "convertToTSX" take {"source": string, "options":
+object}, with the options of the JS API. Requests are cancelled with the
+"$/cancelRequest" notification.
+
+With "resolvePath": true, "preprocessStyle": true or "loadComponent": true in the
+options of "transform", the server sends requests of the same name back to the client:
+
+  resolvePath      {"specifier", "filename"} -> string | null
+  loadComponent    {"path", "filename"} -> string | null
+  preprocessStyle  {"content", "attrs", "filename"} -> {"code", "map"?} | {"error"} | null
+
+When stdin is closed, the server answers the requests in flight and exits. A
+"transform" still waiting for the client to answer one of its requests fails, as
+the answer can no longer be read.
+`
+
+func serve(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, serveUsage) }
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "astro-compiler serve: unexpected argument %q\n", flags.Arg(0))
+		return 2
+	}
+
+	s := &server{}
+	s.conn = jsonrpc.NewConn(jsonrpc.NewLineStream(stdin, stdout), s.handle)
+	if err := s.conn.Run(context.Background()); err != nil {
+		fmt.Fprintf(stderr, "astro-compiler serve: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+type server struct {
+	conn *jsonrpc.Conn
+}
+
+type serverParams[T any] struct {
+	Source  string `json:"source"`
+	Options T      `json:"options"`
+}
+
+type serverParseOptions struct {
+	Filename string `json:"filename"`
+	// Position defaults to true, as in the JS API
+	Position            *bool `json:"position"`
+	Recover             bool  `json:"recover"`
+	FrontmatterAST      bool  `json:"frontmatterAST"`
+	ValidateExpressions bool  `json:"validateExpressions"`
+}
+
+type serverTokenizeOptions struct {
+	Filename string `json:"filename"`
+}
+
+type serverPropsOptions struct {
+	Filename string `json:"filename"`
+}
+
+type serverTransformOptions struct {
+	Filename                string          `json:"filename"`
+	NormalizedFilename      string          `json:"normalizedFilename"`
+	InternalURL             string          `json:"internalURL"`
+	SourceMap               sourceMapOption `json:"sourcemap"`
+	AstroGlobalArgs         string          `json:"astroGlobalArgs"`
+	Compact                 bool            `json:"compact"`
+	ResultScopedSlot        bool            `json:"resultScopedSlot"`
+	ScopedStyleStrategy     string          `json:"scopedStyleStrategy"`
+	TransitionsAnimationURL string          `json:"transitionsAnimationURL"`
+	AnnotateSourceFile      bool            `json:"annotateSourceFile"`
+	RenderScript            bool            `json:"renderScript"`
+	ContentModelWarnings    bool            `json:"contentModelWarnings"`
+	ForeignContentWarnings  bool            `json:"foreignContentWarnings"`
+	ValidateExpressions     bool            `json:"validateExpressions"`
+	AccessibilityWarnings   bool            `json:"accessibilityWarnings"`
+	// ResolvePath, PreprocessStyle and LoadComponent are set when the client answers the requests
+	// of the same name
+	ResolvePath     bool `json:"resolvePath"`
+	PreprocessStyle bool `json:"preprocessStyle"`
+	LoadComponent   bool `json:"loadComponent"`
+}
+
+type serverTSXOptions struct {
+	Filename           string          `json:"filename"`
+	NormalizedFilename string          `json:"normalizedFilename"`
+	SourceMap          sourceMapOption `json:"sourcemap"`
+	// IncludeScripts and IncludeStyles default to true, as in the JS API
+	IncludeScripts *bool `json:"includeScripts"`
+	IncludeStyles  *bool `json:"includeStyles"`
+	Recover        bool  `json:"recover"`
+}
+
+// sourceMapOption accepts a string, or `true` for "both" like the JS API
+type sourceMapOption string
+
+func (o *sourceMapOption) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*o = ""
+		if enabled {
+			*o = "both"
+		}
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(o))
+}
+
+type resolvePathParams struct {
+	Specifier string `json:"specifier"`
+	Filename  string `json:"filename"`
+}
+
+type loadComponentParams struct {
+	Path     string `json:"path"`
+	Filename string `json:"filename"`
+}
+
+type preprocessStyleParams struct {
+	Content string `json:"content"`
+	// Attrs maps the attributes of the style to their value, or to true when they have none, like
+	// with the JS API
+	Attrs    map[string]any `json:"attrs"`
+	Filename string         `json:"filename"`
+}
+
+type preprocessStyleResult struct {
+	Code  string          `json:"code"`
+	Map   json.RawMessage `json:"map,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+func (s *server) handle(ctx context.Context, req *jsonrpc.Request) (any, error) {
+	if req.IsNotification() {
+		return nil, nil
+	}
+	switch req.Method {
+	case "parse":
+		var params serverParams[serverParseOptions]
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		opts := compiler.ParseOptions{
+			Filename:            params.Options.Filename,
+			Position:            boolOption(params.Options.Position, true),
+			Recover:             params.Options.Recover,
+			FrontmatterAST:      params.Options.FrontmatterAST,
+			ValidateExpressions: params.Options.ValidateExpressions,
+		}
+		return cancellable(ctx, func() any {
+			result := compiler.Parse(params.Source, opts)
+			return ParseResult{
+				Filename:    filenameOrStdin(opts.Filename),
+				AST:         json.RawMessage(result.AST),
+				Diagnostics: result.Diagnostics,
+				Slots:       result.Slots,
+				Components:  result.Components,
+			}
+		})
+	case "tokenize":
+		var params serverParams[serverTokenizeOptions]
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		opts := compiler.TokenizeOptions{Filename: params.Options.Filename}
+		return cancellable(ctx, func() any {
+			result := compiler.Tokenize(params.Source, opts)
+			return TokenizeResult{Filename: filenameOrStdin(opts.Filename), TokenizeResult: result}
+		})
+	case "extractProps":
+		var params serverParams[serverPropsOptions]
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		opts := compiler.PropsOptions{Filename: params.Options.Filename}
+		return cancellable(ctx, func() any {
+			return newPropsResult(filenameOrStdin(opts.Filename), compiler.ExtractProps(params.Source, opts))
+		})
+	case "transform":
+		var params serverParams[serverTransformOptions]
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		// A request sent back to the client fails when stdin is closed before its response. The
+		// transform is then failed as well, rather than completed without the answers of the client.
+		var unanswered atomic.Bool
+		call := func(method string, params any, result any) error {
+			err := s.conn.Call(ctx, method, params, result)
+			if errors.Is(err, jsonrpc.ErrClosed) {
+				unanswered.Store(true)
+			}
+			return err
+		}
+		opts := s.transformOptions(call, params.Options)
+		result, err := cancellable(ctx, func() any {
+			result, err := compiler.TransformContext(ctx, params.Source, opts)
+			if err != nil {
+				// Like the CLI, a component that cannot be parsed is reported as a diagnostic
+				result = compiler.TransformResult{Diagnostics: []compiler.DiagnosticMessage{{
+					Severity: int(compiler.ErrorType),
+					Text:     err.Error(),
+				}}}
+			}
+			return TransformResult{Filename: filenameOrStdin(opts.Filename), TransformResult: result}
+		})
+		if err == nil && unanswered.Load() {
+			return nil, jsonrpc.Errorf(jsonrpc.InternalError, "stdin was closed before the client answered the requests of the transform")
+		}
+		return result, err
+	case "convertToTSX":
+		var params serverParams[serverTSXOptions]
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		opts := compiler.TSXOptions{
+			Filename:           params.Options.Filename,
+			NormalizedFilename: params.Options.NormalizedFilename,
+			SourceMap:          string(params.Options.SourceMap),
+			IncludeScripts:     boolOption(params.Options.IncludeScripts, true),
+			IncludeStyles:      boolOption(params.Options.IncludeStyles, true),
+			Recover:            params.Options.Recover,
+		}
+		return cancellable(ctx, func() any {
+			result := compiler.ConvertToTSX(params.Source, opts)
+			return TSXResult{
+				Filename:    filenameOrStdin(opts.Filename),
+				Code:        result.Code,
+				Map:         json.RawMessage(result.Map),
+				Diagnostics: result.Diagnostics,
+				Ranges:      result.Ranges,
+			}
+		})
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.MethodNotFound, "method not found: %s", req.Method)
+}
+
+// clientCall sends a request back to the client, for as long as the request being handled is not
+// cancelled
+type clientCall func(method string, params any, result any) error
+
+// transformOptions turns the callbacks enabled by the client into requests sent back to it with call
+func (s *server) transformOptions(call clientCall, options serverTransformOptions) compiler.TransformOptions {
+	opts := compiler.TransformOptions{
+		Filename:                options.Filename,
+		NormalizedFilename:      options.NormalizedFilename,
+		InternalURL:             options.InternalURL,
+		SourceMap:               string(options.SourceMap),
+		AstroGlobalArgs:         options.AstroGlobalArgs,
+		Compact:                 options.Compact,
+		ResultScopedSlot:        options.ResultScopedSlot,
+		ScopedStyleStrategy:     options.ScopedStyleStrategy,
+		TransitionsAnimationURL: options.TransitionsAnimationURL,
+		AnnotateSourceFile:      options.AnnotateSourceFile,
+		RenderScript:            options.RenderScript,
+		ContentModelWarnings:    options.ContentModelWarnings,
+		ForeignContentWarnings:  options.ForeignContentWarnings,
+		ValidateExpressions:     options.ValidateExpressions,
+		AccessibilityWarnings:   options.AccessibilityWarnings,
+	}
+	if options.ResolvePath {
+		opts.ResolvePath = func(specifier string) string {
+			var resolved *string
+			err := call("resolvePath", resolvePathParams{Specifier: specifier, Filename: options.Filename}, &resolved)
+			// As with the JS API, the specifier is kept when it isn't resolved
+			if err != nil || resolved == nil {
+				return specifier
+			}
+			return *resolved
+		}
+	}
+	if options.LoadComponent {
+		opts.LoadComponent = func(path string) (string, bool) {
+			var source *string
+			if err := call("loadComponent", loadComponentParams{Path: path, Filename: options.Filename}, &source); err != nil || source == nil {
+				return "", false
+			}
+			return *source, true
+		}
+	}
+	if options.PreprocessStyle {
+		opts.PreprocessStyle = &clientStylePreprocessor{call: call, filename: options.Filename}
+	}
+	return opts
+}
+
+// clientStylePreprocessor preprocesses styles with `preprocessStyle` requests to the client
+type clientStylePreprocessor struct {
+	call     clientCall
+	filename string
+}
+
+func (p *clientStylePreprocessor) PreprocessStyle(content string, attrs map[string]*string) (compiler.PreprocessorResult, error) {
+	params := preprocessStyleParams{Content: content, Attrs: make(map[string]any, len(attrs)), Filename: p.filename}
+	for key, value := range attrs {
+		if value == nil {
+			params.Attrs[key] = true
+		} else {
+			params.Attrs[key] = *value
+		}
+	}
+	var result *preprocessStyleResult
+	if err := p.call("preprocessStyle", params, &result); err != nil {
+		return compiler.PreprocessorResult{}, err
+	}
+	// A null result skips the style, as Rollup allows for transforms
+	if result == nil {
+		return compiler.PreprocessorResult{}, nil
+	}
+	if result.Error != "" {
+		return compiler.PreprocessorResult{}, errors.New(result.Error)
+	}
+	return compiler.PreprocessorResult{Code: result.Code, Map: sourceMapString(result.Map)}, nil
+}
+
+// sourceMapString accepts a source map either as a JSON string or as an object
+func sourceMapString(data json.RawMessage) string {
+	var m string
+	if len(data) == 0 || json.Unmarshal(data, &m) == nil {
+		return m
+	}
+	return string(data)
+}
+
+// cancellable runs compile, returning early when ctx is cancelled. Only transforms stop at the
+// next step of the pipeline; other requests keep running in the background and their result is dropped.
+func cancellable(ctx context.Context, compile func() any) (any, error) {
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		// A panic would otherwise bring the whole server down, as it escapes the handler of the request
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: jsonrpc.Errorf(jsonrpc.InternalError, "%v", r)}
+			}
+		}()
+		done <- outcome{result: compile()}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func boolOption(value *bool, defaultValue bool) bool {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+func filenameOrStdin(filename string) string {
+	if filename == "" {
+		return "<stdin>"
+	}
+	return filename
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/withastro/compiler/internal/jsonrpc"
+)
+
+// serveClient runs `astro-compiler serve` connected to a JSON-RPC client answering the
+// reverse requests with handler
+func serveClient(t *testing.T, handler jsonrpc.Handler) *jsonrpc.Conn {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	done := make(chan int, 1)
+	go func() {
+		done <- serve(nil, serverIn, serverOut, io.Discard)
+		serverIn.Close()
+		serverOut.Close()
+	}()
+
+	if handler == nil {
+		handler = func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+			return nil, jsonrpc.Errorf(jsonrpc.MethodNotFound, "method not found: %s", req.Method)
+		}
+	}
+	client := jsonrpc.NewConn(jsonrpc.NewLineStream(clientIn, clientOut), handler)
+	go client.Run(context.Background())
+	t.Cleanup(func() {
+		clientOut.Close()
+		if status := <-done; status != 0 {
+			t.Errorf("expected the server to exit cleanly, got status %d", status)
+		}
+	})
+	return client
+}
+
+func call(t *testing.T, client *jsonrpc.Conn, method string, params any, result any) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Call(ctx, method, params, result); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	client := serveClient(t, nil)
+	source := "---\nimport Counter from './Counter.jsx';\n---\n<h1>Hello</h1>\n<Counter client:load />"
+
+	var parsed ParseResult
+	call(t, client, "parse", map[string]any{"source": source, "options": map[string]any{"filename": "/src/index.astro"}}, &parsed)
+	if parsed.Filename != "/src/index.astro" || !bytes.Contains(parsed.AST, []byte(`"type":"root"`)) || !bytes.Contains(parsed.AST, []byte(`"position"`)) {
+		t.Errorf("unexpected parse result: %+v", parsed)
+	}
+
+	var transformed TransformResult
+	call(t, client, "transform", map[string]any{"source": source, "options": map[string]any{"filename": "/src/index.astro", "sourcemap": true}}, &transformed)
+	if !strings.Contains(transformed.Code, "<h1>Hello</h1>") || transformed.Map == "" || len(transformed.HydratedComponents) != 1 {
+		t.Errorf("unexpected transform result: %+v", transformed)
+	}
+	if resolved := transformed.HydratedComponents[0].ResolvedPath; resolved != "/src/Counter.jsx" {
+		t.Errorf("expected the specifier to be resolved against the filename, got %s", resolved)
+	}
+
+	var tsx TSXResult
+	call(t, client, "convertToTSX", map[string]any{"source": source, "options": map[string]any{"sourcemap": "external"}}, &tsx)
+	if tsx.Filename != "<stdin>" || !strings.Contains(tsx.Code, "<h1>Hello</h1>") || strings.Contains(tsx.Code, "sourceMappingURL") || !json.Valid(tsx.Map) {
+		t.Errorf("unexpected TSX result: %+v", tsx)
+	}
+
+	var tokenized TokenizeResult
+	call(t, client, "tokenize", map[string]any{"source": source}, &tokenized)
+	if len(tokenized.Tokens) == 0 || tokenized.Tokens[0].Type != "frontmatter-fence" {
+		t.Errorf("unexpected tokenize result: %+v", tokenized)
+	}
+
+	var props PropsResult
+	call(t, client, "extractProps", map[string]any{"source": "---\ninterface Props { title: string }\n---", "options": map[string]any{"filename": "Card.astro"}}, &props)
+	if props.Filename != "Card.astro" || !strings.Contains(string(props.Schema), `"title":{"type":"string"}`) {
+		t.Errorf("unexpected props result: %+v", props)
+	}
+
+	err := client.Call(context.Background(), "compile", nil, nil)
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.MethodNotFound {
+		t.Errorf("expected MethodNotFound, got %v", err)
+	}
+	err = client.Call(context.Background(), "transform", map[string]any{"source": 1}, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.InvalidParams {
+		t.Errorf("expected InvalidParams, got %v", err)
+	}
+}
+
+func TestServeReverseRequests(t *testing.T) {
+	client := serveClient(t, func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		switch req.Method {
+		case "resolvePath":
+			var params resolvePathParams
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, err
+			}
+			if params.Filename != "/src/index.astro" {
+				return nil, errors.New("unexpected filename")
+			}
+			return "/resolved/" + strings.TrimPrefix(params.Specifier, "./"), nil
+		case "loadComponent":
+			var params loadComponentParams
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, err
+			}
+			if params.Path != "/resolved/Card.astro" {
+				return nil, nil
+			}
+			return `<div><slot name="title" /><slot /></div>`, nil
+		case "preprocessStyle":
+			var params preprocessStyleParams
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, err
+			}
+			switch params.Attrs["lang"] {
+			case "scss":
+				return map[string]any{
+					"code": strings.ReplaceAll(params.Content, "$color", "red"),
+					"map":  map[string]any{"version": 3, "mappings": "AAAA"},
+				}, nil
+			case "less":
+				return preprocessStyleResult{Error: "less is not supported"}, nil
+			}
+			if params.Attrs["is:global"] != true {
+				return nil, fmt.Errorf("expected is:global to be sent as true, got %v", params.Attrs)
+			}
+			return nil, nil
+		}
+		return nil, jsonrpc.Errorf(jsonrpc.MethodNotFound, "method not found: %s", req.Method)
+	})
+
+	source := strings.Join([]string{
+		"---",
+		"import Counter from './Counter.jsx';",
+		"import Card from './Card.astro';",
+		"---",
+		`<style lang="scss">h1 { color: $color; }</style>`,
+		`<style lang="less">p { color: @color; }</style>`,
+		`<style is:global>div { color: blue; }</style>`,
+		`<Counter client:visible />`,
+		`<Card><h2 slot="title">Title</h2><p slot="footer">Footer</p></Card>`,
+	}, "\n")
+	var result TransformResult
+	call(t, client, "transform", map[string]any{"source": source, "options": map[string]any{
+		"filename":        "/src/index.astro",
+		"resolvePath":     true,
+		"preprocessStyle": true,
+		"loadComponent":   true,
+	}}, &result)
+
+	if len(result.HydratedComponents) != 1 || result.HydratedComponents[0].ResolvedPath != "/resolved/Counter.jsx" {
+		t.Errorf("expected the component to be resolved by the client, got %+v", result.HydratedComponents)
+	}
+	css := strings.Join(result.CSS, "\n")
+	if !strings.Contains(css, "color:red") || !strings.Contains(css, "color: blue") {
+		t.Errorf("expected the styles to be preprocessed by the client, got %q", css)
+	}
+	for i, css := range result.CSS {
+		if strings.Contains(css, "color:red") && (len(result.CSSMaps) != len(result.CSS) || result.CSSMaps[i] != `{"mappings":"AAAA","version":3}`) {
+			t.Errorf("expected the source map of the preprocessed style, got %q", result.CSSMaps)
+		}
+	}
+	if len(result.StyleError) != 1 || result.StyleError[0] != "less is not supported" {
+		t.Errorf("expected a style error, got %v", result.StyleError)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Text != "`<Card>` has no slot named `footer`, so this content is not rendered" {
+		t.Errorf("expected the slots of the component loaded by the client, got %+v", result.Diagnostics)
+	}
+}
+
+func TestServeCancel(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	client := serveClient(t, func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	params := map[string]any{"source": "<style>h1 { color: red; }</style>", "options": map[string]any{"preprocessStyle": true}}
+	if err := client.Call(ctx, "transform", params, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the call to be cancelled, got %v", err)
+	}
+
+	// Cancelling the transform cancels the pending request to the client
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reverse request to be cancelled")
+	}
+}
+
+func TestServeStdin(t *testing.T) {
+	stdin, input := io.Pipe()
+	output, stdout := io.Pipe()
+	done := make(chan int, 1)
+	go func() {
+		done <- run([]string{"serve"}, stdin, stdout, io.Discard)
+		stdout.Close()
+	}()
+
+	go io.WriteString(input, strings.Join([]string{
+		`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":2}}`,
+		``,
+		`not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"transform","params":{"source":"<div>{value}</div>"}}`,
+		``,
+	}, "\n"))
+
+	responses := bufio.NewScanner(output)
+	var lines []string
+	for len(lines) < 2 && responses.Scan() {
+		lines = append(lines, responses.Text())
+	}
+	input.Close()
+	if status := <-done; status != 0 {
+		t.Fatalf("exit status %d", status)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 responses, got %d: %s", len(lines), lines)
+	}
+
+	var parseError, transform struct {
+		ID     json.RawMessage  `json:"id"`
+		Result *TransformResult `json:"result"`
+		Error  *jsonrpc.Error   `json:"error"`
+	}
+	json.Unmarshal([]byte(lines[0]), &parseError)
+	json.Unmarshal([]byte(lines[1]), &transform)
+	if string(parseError.ID) != "null" || parseError.Error == nil || parseError.Error.Code != jsonrpc.ParseError {
+		t.Errorf("expected a parse error for the invalid message, got %s", lines[0])
+	}
+	if string(transform.ID) != "1" || transform.Result == nil || !strings.Contains(transform.Result.Code, "<div>${value}</div>") {
+		t.Errorf("unexpected transform response: %s", lines[1])
+	}
+}
+
+func TestServeStdinClosed(t *testing.T) {
+	// stdin is closed right after the requests, before the server answers them
+	stdin := strings.NewReader(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"transform","params":{"source":"<div>{value}</div>"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"transform","params":{"source":"<style>h1 { color: red; }</style>","options":{"preprocessStyle":true}}}`,
+		``,
+	}, "\n"))
+	var stdout bytes.Buffer
+	if status := run([]string{"serve"}, stdin, &stdout, io.Discard); status != 0 {
+		t.Fatalf("exit status %d", status)
+	}
+
+	var answered, failed bool
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var response struct {
+			ID     json.RawMessage  `json:"id"`
+			Method string           `json:"method"`
+			Result *TransformResult `json:"result"`
+			Error  *jsonrpc.Error   `json:"error"`
+		}
+		json.Unmarshal([]byte(line), &response)
+		switch {
+		case response.Method != "":
+			// The request sent back to the client, which can no longer answer it
+		case string(response.ID) == "1":
+			answered = response.Result != nil && strings.Contains(response.Result.Code, "<div>${value}</div>")
+		case string(response.ID) == "2":
+			failed = response.Error != nil
+		}
+	}
+	if !answered {
+		t.Errorf("expected the transform to be answered after stdin was closed, got %s", stdout.String())
+	}
+	if !failed {
+		t.Errorf("expected the transform waiting for the client to fail, got %s", stdout.String())
+	}
+}
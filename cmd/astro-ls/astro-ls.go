@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/withastro/compiler/internal/jsonrpc"
+	"github.com/withastro/compiler/internal/lsp"
+)
+
+// astro-ls is a language server for Astro components, speaking LSP over stdio
+func main() {
+	server := lsp.NewServer(jsonrpc.NewHeaderStream(os.Stdin, os.Stdout))
+	if err := server.Run(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "astro-ls: %v\n", err)
+		os.Exit(1)
+	}
+}
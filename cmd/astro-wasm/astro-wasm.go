@@ -1,22 +1,15 @@
 package main
 
 import (
-	"encoding/base64"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"strings"
-	"sync"
+	"sync/atomic"
 	"syscall/js"
-	"unicode"
+	"time"
 
 	"github.com/norunners/vert"
-	astro "github.com/withastro/compiler/internal"
-	"github.com/withastro/compiler/internal/handler"
-	"github.com/withastro/compiler/internal/loc"
-	"github.com/withastro/compiler/internal/printer"
-	"github.com/withastro/compiler/internal/sourcemap"
-	t "github.com/withastro/compiler/internal/t"
-	"github.com/withastro/compiler/internal/transform"
+	"github.com/withastro/compiler/compiler"
 	wasm_utils "github.com/withastro/compiler/internal_wasm/utils"
 )
 
@@ -24,8 +17,13 @@ func main() {
 	js.Global().Set("@astrojs/compiler", js.ValueOf(make(map[string]interface{})))
 	module := js.Global().Get("@astrojs/compiler")
 	module.Set("transform", Transform())
+	module.Set("transformSync", TransformSync())
+	module.Set("transformBatch", TransformBatch())
 	module.Set("parse", Parse())
+	module.Set("parseFragment", ParseFragment())
 	module.Set("convertToTSX", ConvertToTSX())
+	module.Set("tokenize", Tokenize())
+	module.Set("extractProps", ExtractProps())
 
 	<-make(chan struct{})
 }
@@ -51,479 +49,439 @@ func jsBool(j js.Value) bool {
 	return j.Bool()
 }
 
-func makeParseOptions(options js.Value) t.ParseOptions {
-	position := true
-
-	pos := options.Get("position")
-	if !pos.IsNull() && !pos.IsUndefined() {
-		position = pos.Bool()
+// jsErrorMessage returns the message of a thrown value, which is usually an Error
+func jsErrorMessage(j js.Value) string {
+	if j.Type() == js.TypeObject && j.Get("message").Type() == js.TypeString {
+		return j.Get("message").String()
 	}
+	return jsString(j)
+}
 
-	filename := jsString(options.Get("filename"))
-	if filename == "" {
-		filename = "<stdin>"
+func makeParseOptions(options js.Value) compiler.ParseOptions {
+	return compiler.ParseOptions{
+		Filename:            jsString(options.Get("filename")),
+		Position:            jsBoolOptional(options.Get("position"), true),
+		Recover:             jsBool(options.Get("recover")),
+		FrontmatterAST:      jsBool(options.Get("frontmatterAST")),
+		ValidateExpressions: jsBool(options.Get("validateExpressions")),
 	}
+}
 
-	return t.ParseOptions{
-		Filename: filename,
-		Position: position,
+func makeParseFragmentOptions(options js.Value) compiler.ParseFragmentOptions {
+	return compiler.ParseFragmentOptions{
+		ParseOptions: makeParseOptions(options),
+		Context:      jsString(options.Get("context")),
 	}
 }
 
-func makeTransformOptions(options js.Value) transform.TransformOptions {
-	filename := jsString(options.Get("filename"))
-	if filename == "" {
-		filename = "<stdin>"
+// makeContext returns a context cancelled by the `signal` (an AbortSignal) and `timeout` (in
+// milliseconds) options. stop must be called once the context isn't used anymore.
+func makeContext(options js.Value) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	stop = func() { cancel(nil) }
+	if timeout := options.Get("timeout"); timeout.Type() == js.TypeNumber {
+		ms := timeout.Int()
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, time.Duration(ms)*time.Millisecond, fmt.Errorf("timed out after %dms", ms))
+		stop = func() {
+			cancelTimeout()
+			cancel(nil)
+		}
 	}
 
-	normalizedFilename := jsString(options.Get("normalizedFilename"))
-	if normalizedFilename == "" {
-		normalizedFilename = filename
+	signal := options.Get("signal")
+	if signal.Type() != js.TypeObject {
+		return ctx, stop
 	}
+	if signal.Get("aborted").Bool() {
+		cancel(abortReason(signal))
+		return ctx, stop
+	}
+	onAbort := js.FuncOf(func(this js.Value, args []js.Value) any {
+		cancel(abortReason(signal))
+		return nil
+	})
+	signal.Call("addEventListener", "abort", onAbort)
+	stopTimeout := stop
+	stop = func() {
+		signal.Call("removeEventListener", "abort", onAbort)
+		onAbort.Release()
+		stopTimeout()
+	}
+	return ctx, stop
+}
 
-	internalURL := jsString(options.Get("internalURL"))
-	if internalURL == "" {
-		internalURL = "astro/runtime/server/index.js"
+// abortReason returns the reason of an aborted AbortSignal as an error
+func abortReason(signal js.Value) error {
+	if reason := signal.Get("reason"); !reason.IsUndefined() {
+		return errors.New(jsErrorMessage(reason))
 	}
+	return errors.New("aborted")
+}
 
-	sourcemap := jsString(options.Get("sourcemap"))
-	if sourcemap == "<boolean: true>" {
-		sourcemap = "both"
+// transformError converts an error of a transform to the JS error rejecting its promise.
+// A cancelled transform rejects with an AbortError, with the ERROR_CANCELLED diagnostic.
+func transformError(err error) js.Value {
+	jsError := wasm_utils.ErrorToJSError(err)
+	var cancelled *compiler.CancelledError
+	if errors.As(err, &cancelled) {
+		jsError.Set("name", "AbortError")
+		jsError.Set("diagnostic", vert.ValueOf(cancelled.Diagnostic()).Value)
 	}
+	return jsError
+}
 
-	astroGlobalArgs := jsString(options.Get("astroGlobalArgs"))
+// jsCallbacks calls the functions passed in the options of the JS API
+type jsCallbacks struct {
+	ctx context.Context
+	// sync is set by transformSync, which can't wait for a Promise as the event loop is blocked
+	// until it returns. A callback returning a Promise fails the transform.
+	sync bool
+	// async holds the name of the first callback which returned a Promise in sync mode
+	async atomic.Value
+}
 
-	compact := false
-	if jsBool(options.Get("compact")) {
-		compact = true
+// call invokes the callback fn and returns its result. Unless c.sync is set, the result is
+// awaited until c.ctx is done, and a rejection is returned as an error.
+func (c *jsCallbacks) call(name string, fn js.Value, args ...any) (js.Value, error) {
+	value := fn.Invoke(args...)
+	if c.sync {
+		if value.Type() == js.TypeObject && value.Get("then").Type() == js.TypeFunction {
+			c.async.CompareAndSwap(nil, name)
+			return js.Undefined(), fmt.Errorf("%s returned a Promise", name)
+		}
+		return value, nil
 	}
-
-	scopedSlot := false
-	if jsBool(options.Get("resultScopedSlot")) {
-		scopedSlot = true
+	result, reason, err := wasm_utils.AwaitContext(c.ctx, value)
+	if err != nil {
+		return js.Undefined(), err
 	}
+	if reason != nil {
+		return js.Undefined(), errors.New(jsErrorMessage(reason[0]))
+	}
+	return result[0], nil
+}
 
-	transitionsAnimationURL := jsString(options.Get("transitionsAnimationURL"))
-	if transitionsAnimationURL == "" {
-		transitionsAnimationURL = "astro/components/viewtransitions.css"
+// err returns the error failing a sync transform, if a callback returned a Promise
+func (c *jsCallbacks) err() error {
+	if name, ok := c.async.Load().(string); ok {
+		return fmt.Errorf("transformSync: %s returned a Promise, use transform for async callbacks", name)
 	}
+	return nil
+}
 
-	annotateSourceFile := false
-	if jsBool(options.Get("annotateSourceFile")) {
-		annotateSourceFile = true
+func makeTransformOptions(callbacks *jsCallbacks, options js.Value) compiler.TransformOptions {
+	sourcemap := jsString(options.Get("sourcemap"))
+	if sourcemap == "<boolean: true>" {
+		sourcemap = "both"
 	}
 
-	var resolvePath any = options.Get("resolvePath")
 	var resolvePathFn func(string) string
-	if resolvePath.(js.Value).Type() == js.TypeFunction {
+	if resolvePath := options.Get("resolvePath"); resolvePath.Type() == js.TypeFunction {
 		resolvePathFn = func(id string) string {
-			result, _ := wasm_utils.Await(resolvePath.(js.Value).Invoke(id))
-			if result[0].Equal(js.Undefined()) || result[0].Equal(js.Null()) {
+			result, err := callbacks.call("resolvePath", resolvePath, id)
+			if err != nil || result.Equal(js.Undefined()) || result.Equal(js.Null()) {
 				return id
 			} else {
-				return result[0].String()
+				return result.String()
 			}
 		}
 	}
 
-	preprocessStyle := options.Get("preprocessStyle")
-
-	scopedStyleStrategy := jsString(options.Get("scopedStyleStrategy"))
-	if scopedStyleStrategy == "" {
-		scopedStyleStrategy = "where"
+	var loadComponentFn func(string) (string, bool)
+	if loadComponent := options.Get("loadComponent"); loadComponent.Type() == js.TypeFunction {
+		loadComponentFn = func(path string) (string, bool) {
+			result, err := callbacks.call("loadComponent", loadComponent, path)
+			if err != nil || result.Type() != js.TypeString {
+				return "", false
+			}
+			return result.String(), true
+		}
 	}
 
-	renderScript := false
-	if jsBool(options.Get("renderScript")) {
-		renderScript = true
+	var preprocessor compiler.StylePreprocessor
+	if preprocessStyle := options.Get("preprocessStyle"); preprocessStyle.Type() == js.TypeFunction {
+		preprocessor = jsStylePreprocessor{callbacks: callbacks, fn: preprocessStyle}
 	}
 
-	return transform.TransformOptions{
-		Filename:                filename,
-		NormalizedFilename:      normalizedFilename,
-		InternalURL:             internalURL,
+	return compiler.TransformOptions{
+		Filename:                jsString(options.Get("filename")),
+		NormalizedFilename:      jsString(options.Get("normalizedFilename")),
+		InternalURL:             jsString(options.Get("internalURL")),
 		SourceMap:               sourcemap,
-		AstroGlobalArgs:         astroGlobalArgs,
-		Compact:                 compact,
+		AstroGlobalArgs:         jsString(options.Get("astroGlobalArgs")),
+		Compact:                 jsBool(options.Get("compact")),
 		ResolvePath:             resolvePathFn,
-		PreprocessStyle:         preprocessStyle,
-		ResultScopedSlot:        scopedSlot,
-		ScopedStyleStrategy:     scopedStyleStrategy,
-		TransitionsAnimationURL: transitionsAnimationURL,
-		AnnotateSourceFile:      annotateSourceFile,
-		RenderScript:            renderScript,
+		LoadComponent:           loadComponentFn,
+		PreprocessStyle:         preprocessor,
+		ResultScopedSlot:        jsBool(options.Get("resultScopedSlot")),
+		ScopedStyleStrategy:     jsString(options.Get("scopedStyleStrategy")),
+		TransitionsAnimationURL: jsString(options.Get("transitionsAnimationURL")),
+		AnnotateSourceFile:      jsBool(options.Get("annotateSourceFile")),
+		RenderScript:            jsBool(options.Get("renderScript")),
+		ContentModelWarnings:    jsBool(options.Get("contentModelWarnings")),
+		ForeignContentWarnings:  jsBool(options.Get("foreignContentWarnings")),
+		ValidateExpressions:     jsBool(options.Get("validateExpressions")),
+		AccessibilityWarnings:   jsBool(options.Get("accessibilityWarnings")),
+		Directives:              makeDirectives(callbacks, options.Get("directives")),
 	}
 }
 
-func makeTSXOptions(options js.Value) printer.TSXOptions {
-	includeScripts := jsBoolOptional(options.Get("includeScripts"), true)
-	includeStyles := jsBoolOptional(options.Get("includeStyles"), true)
-
-	return printer.TSXOptions{
-		IncludeScripts: includeScripts,
-		IncludeStyles:  includeStyles,
+// makeDirectives converts the `directives` option of the JS API, keeping nil when it is unset
+func makeDirectives(callbacks *jsCallbacks, directives js.Value) []compiler.Directive {
+	if directives.Type() != js.TypeObject {
+		return nil
+	}
+	result := make([]compiler.Directive, directives.Length())
+	for i := range result {
+		d := directives.Index(i)
+		result[i] = compiler.Directive{
+			Name:          jsString(d.Get("name")),
+			RequiresValue: jsBool(d.Get("requiresValue")),
+		}
+		if elements := d.Get("elements"); elements.Type() == js.TypeObject {
+			for j := 0; j < elements.Length(); j++ {
+				result[i].Elements = append(result[i].Elements, jsString(elements.Index(j)))
+			}
+		}
+		if validate := d.Get("validate"); validate.Type() == js.TypeFunction {
+			result[i].Validate = func(value string) error {
+				message, err := callbacks.call("validate", validate, value)
+				if err != nil {
+					return err
+				}
+				if message := jsString(message); message != "" {
+					return errors.New(message)
+				}
+				return nil
+			}
+		}
 	}
+	return result
 }
 
-type RawSourceMap struct {
-	File           string   `js:"file"`
-	Mappings       string   `js:"mappings"`
-	Names          []string `js:"names"`
-	Sources        []string `js:"sources"`
-	SourcesContent []string `js:"sourcesContent"`
-	Version        int      `js:"version"`
+// jsStylePreprocessor calls the `preprocessStyle` option of the JS API
+type jsStylePreprocessor struct {
+	callbacks *jsCallbacks
+	fn        js.Value
 }
 
-type HoistedScript struct {
-	Code string `js:"code"`
-	Src  string `js:"src"`
-	Type string `js:"type"`
-	Map  string `js:"map"`
+func (p jsStylePreprocessor) PreprocessStyle(content string, attrs map[string]*string) (compiler.PreprocessorResult, error) {
+	data, err := p.callbacks.call("preprocessStyle", p.fn, content, wasm_utils.GetAttrs(attrs))
+	if err != nil {
+		return compiler.PreprocessorResult{}, err
+	}
+	// note: Rollup (and by extension our Astro Vite plugin) allows for "undefined" and "null" responses if a transform wishes to skip this occurrence
+	if data.Equal(js.Undefined()) || data.Equal(js.Null()) {
+		return compiler.PreprocessorResult{}, nil
+	}
+	if err := jsString(data.Get("error")); err != "" {
+		return compiler.PreprocessorResult{}, errors.New(err)
+	}
+	// Source maps are usually strings, but may also be objects
+	sourcemap := data.Get("map")
+	if sourcemap.Type() == js.TypeObject {
+		sourcemap = js.Global().Get("JSON").Call("stringify", sourcemap)
+	}
+	return compiler.PreprocessorResult{
+		Code: jsString(data.Get("code")),
+		Map:  jsString(sourcemap),
+	}, nil
 }
 
-type HydratedComponent struct {
-	ExportName   string `js:"exportName"`
-	LocalName    string `js:"localName"`
-	Specifier    string `js:"specifier"`
-	ResolvedPath string `js:"resolvedPath"`
-}
+func makeBatchOptions(callbacks *jsCallbacks, options js.Value) compiler.BatchOptions {
+	var resolvePathFn func(string, string) string
+	if resolvePath := options.Get("resolvePath"); resolvePath.Type() == js.TypeFunction {
+		resolvePathFn = func(id string, importer string) string {
+			result, err := callbacks.call("resolvePath", resolvePath, id, importer)
+			if err != nil || result.Equal(js.Undefined()) || result.Equal(js.Null()) {
+				return id
+			} else {
+				return result.String()
+			}
+		}
+	}
 
-type ParseResult struct {
-	AST         string                  `js:"ast"`
-	Diagnostics []loc.DiagnosticMessage `js:"diagnostics"`
+	concurrency := 0
+	if value := options.Get("concurrency"); value.Type() == js.TypeNumber {
+		concurrency = value.Int()
+	}
+
+	return compiler.BatchOptions{
+		Concurrency: concurrency,
+		ResolvePath: resolvePathFn,
+	}
 }
 
-type TSXResult struct {
-	Code        string                  `js:"code"`
-	Map         string                  `js:"map"`
-	Diagnostics []loc.DiagnosticMessage `js:"diagnostics"`
-	Ranges      printer.TSXRanges       `js:"metaRanges"`
+func makeTSXOptions(options js.Value) compiler.TSXOptions {
+	return compiler.TSXOptions{
+		Filename:           jsString(options.Get("filename")),
+		NormalizedFilename: jsString(options.Get("normalizedFilename")),
+		SourceMap:          jsString(options.Get("sourcemap")),
+		IncludeScripts:     jsBoolOptional(options.Get("includeScripts"), true),
+		IncludeStyles:      jsBoolOptional(options.Get("includeStyles"), true),
+		Recover:            jsBool(options.Get("recover")),
+	}
 }
 
-type TransformResult struct {
-	Code                 string                  `js:"code"`
-	Diagnostics          []loc.DiagnosticMessage `js:"diagnostics"`
-	Map                  string                  `js:"map"`
-	Scope                string                  `js:"scope"`
-	CSS                  []string                `js:"css"`
-	Scripts              []HoistedScript         `js:"scripts"`
-	HydratedComponents   []HydratedComponent     `js:"hydratedComponents"`
-	ClientOnlyComponents []HydratedComponent     `js:"clientOnlyComponents"`
-	ServerComponents     []HydratedComponent     `js:"serverComponents"`
-	ContainsHead         bool                    `js:"containsHead"`
-	StyleError           []string                `js:"styleError"`
-	Propagation          bool                    `js:"propagation"`
+func makeTokenizeOptions(options js.Value) compiler.TokenizeOptions {
+	return compiler.TokenizeOptions{
+		Filename: jsString(options.Get("filename")),
+	}
 }
 
-// This is spawned as a goroutine to preprocess style nodes using an async function passed from JS
-func preprocessStyle(i int, style *astro.Node, transformOptions transform.TransformOptions, styleError *[]string, cb func()) {
-	defer cb()
-	if style.FirstChild == nil {
-		return
+func makePropsOptions(options js.Value) compiler.PropsOptions {
+	return compiler.PropsOptions{
+		Filename: jsString(options.Get("filename")),
 	}
-	attrs := wasm_utils.GetAttrs(style)
-	data, _ := wasm_utils.Await(transformOptions.PreprocessStyle.(js.Value).Invoke(style.FirstChild.Data, attrs))
-	// note: Rollup (and by extension our Astro Vite plugin) allows for "undefined" and "null" responses if a transform wishes to skip this occurrence
-	if data[0].Equal(js.Undefined()) || data[0].Equal(js.Null()) {
-		return
-	}
-	// If an error return, override the style's CSS so the compiler doesn't hang
-	// And return a styleError. The caller will use this to know that style processing failed.
-	if err := jsString(data[0].Get("error")); err != "" {
-		style.FirstChild.Data = ""
-		//*styleError = err
-		*styleError = append(*styleError, err)
-		return
-	}
-	str := jsString(data[0].Get("code"))
-	if str == "" {
-		return
-	}
-	style.FirstChild.Data = str
 }
 
 func Parse() any {
 	return js.FuncOf(func(this js.Value, args []js.Value) any {
 		source := jsString(args[0])
 		parseOptions := makeParseOptions(js.Value(args[1]))
-		transformOptions := makeTransformOptions(js.Value(args[1]))
-		transformOptions.Scope = "xxxxxx"
-		h := handler.NewHandler(source, parseOptions.Filename)
 
-		var doc *astro.Node
-		doc, err := astro.ParseWithOptions(strings.NewReader(source), astro.ParseOptionWithHandler(h), astro.ParseOptionEnableLiteral(true))
-		if err != nil {
-			h.AppendError(err)
-		}
-		result := printer.PrintToJSON(source, doc, parseOptions)
+		return vert.ValueOf(compiler.Parse(source, parseOptions)).Value
+	})
+}
 
-		// AFTER printing, exec transformations to pickup any errors/warnings
-		transform.Transform(doc, transformOptions, h)
+func ParseFragment() any {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		source := jsString(args[0])
+		parseFragmentOptions := makeParseFragmentOptions(js.Value(args[1]))
 
-		return vert.ValueOf(ParseResult{
-			AST:         string(result.Output),
-			Diagnostics: h.Diagnostics(),
-		}).Value
+		return vert.ValueOf(compiler.ParseFragment(source, parseFragmentOptions)).Value
 	})
 }
 
 func ConvertToTSX() any {
 	return js.FuncOf(func(this js.Value, args []js.Value) any {
 		source := jsString(args[0])
-		transformOptions := makeTransformOptions(js.Value(args[1]))
-		transformOptions.Scope = "xxxxxx"
-		h := handler.NewHandler(source, transformOptions.Filename)
-
-		var doc *astro.Node
-		doc, err := astro.ParseWithOptions(strings.NewReader(source), astro.ParseOptionWithHandler(h), astro.ParseOptionEnableLiteral(true))
-		if err != nil {
-			h.AppendError(err)
-		}
-
 		tsxOptions := makeTSXOptions(js.Value(args[1]))
 
-		result := printer.PrintToTSX(source, doc, tsxOptions, transformOptions, h)
+		return vert.ValueOf(compiler.ConvertToTSX(source, tsxOptions)).Value
+	})
+}
+
+func Tokenize() any {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		source := jsString(args[0])
+		tokenizeOptions := makeTokenizeOptions(js.Value(args[1]))
 
-		// AFTER printing, exec transformations to pickup any errors/warnings
-		transform.Transform(doc, transformOptions, h)
+		return vert.ValueOf(compiler.Tokenize(source, tokenizeOptions)).Value
+	})
+}
 
-		sourcemapString := createSourceMapString(source, result, transformOptions)
-		code := string(result.Output)
-		if transformOptions.SourceMap != "external" {
-			inlineSourcemap := `//# sourceMappingURL=data:application/json;charset=utf-8;base64,` + base64.StdEncoding.EncodeToString([]byte(sourcemapString))
-			code += "\n" + inlineSourcemap
-		}
+func ExtractProps() any {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		source := jsString(args[0])
+		propsOptions := makePropsOptions(js.Value(args[1]))
 
-		return vert.ValueOf(TSXResult{
-			Code:        code,
-			Map:         sourcemapString,
-			Diagnostics: h.Diagnostics(),
-			Ranges:      result.TSXRanges,
-		}).Value
+		return vert.ValueOf(compiler.ExtractProps(source, propsOptions)).Value
 	})
 }
 
 func Transform() any {
 	return js.FuncOf(func(this js.Value, args []js.Value) any {
-		source := strings.TrimRightFunc(jsString(args[0]), unicode.IsSpace)
-
-		transformOptions := makeTransformOptions(js.Value(args[1]))
-		scopeStr := transformOptions.NormalizedFilename
-		if scopeStr == "<stdin>" {
-			scopeStr = source
-		}
-		transformOptions.Scope = astro.HashString(scopeStr)
-		h := handler.NewHandler(source, transformOptions.Filename)
+		source := jsString(args[0])
+		ctx, stop := makeContext(js.Value(args[1]))
+		transformOptions := makeTransformOptions(&jsCallbacks{ctx: ctx}, js.Value(args[1]))
 
-		styleError := []string{}
 		promiseHandle := js.FuncOf(func(this js.Value, args []js.Value) any {
 			resolve := args[0]
 			reject := args[1]
 
+			// Callbacks passed from JS may be async, so the pipeline can't block the event loop
 			go func() {
-				var doc *astro.Node
+				defer stop()
 				defer func() {
 					if err := recover(); err != nil {
-						reject.Invoke(wasm_utils.ErrorToJSError(h, err.(error)))
+						reject.Invoke(wasm_utils.ErrorToJSError(wasm_utils.RecoveredError(err)))
 						return
 					}
 				}()
 
-				doc, err := astro.ParseWithOptions(strings.NewReader(source), astro.ParseOptionWithHandler(h))
+				result, err := compiler.TransformContext(ctx, source, transformOptions)
 				if err != nil {
-					reject.Invoke(wasm_utils.ErrorToJSError(h, err))
+					reject.Invoke(transformError(err))
 					return
 				}
+				resolve.Invoke(vert.ValueOf(result).Value)
+			}()
 
-				// Hoist styles and scripts to the top-level
-				transform.ExtractStyles(doc)
-
-				// Pre-process styles
-				// Important! These goroutines need to be spawned from this file or they don't work
-				var wg sync.WaitGroup
-				if len(doc.Styles) > 0 {
-					if transformOptions.PreprocessStyle.(js.Value).Type() == js.TypeFunction {
-						for i, style := range doc.Styles {
-							wg.Add(1)
-							i := i
-							go preprocessStyle(i, style, transformOptions, &styleError, wg.Done)
-						}
-					}
-				}
-				// Wait for all the style goroutines to finish
-				wg.Wait()
-
-				// Perform CSS and element scoping as needed
-				transform.Transform(doc, transformOptions, h)
-
-				css := []string{}
-				scripts := []HoistedScript{}
-				hydratedComponents := []HydratedComponent{}
-				clientOnlyComponents := []HydratedComponent{}
-				serverComponents := []HydratedComponent{}
-				css_result := printer.PrintCSS(source, doc, transformOptions)
-				for _, bytes := range css_result.Output {
-					css = append(css, string(bytes))
-				}
+			return nil
+		})
+		defer promiseHandle.Release()
 
-				// Append hoisted scripts
-				for _, node := range doc.Scripts {
-					src := astro.GetAttribute(node, "src")
-					script := HoistedScript{
-						Src:  "",
-						Code: "",
-						Type: "",
-						Map:  "",
-					}
+		// Create and return the Promise object
+		promiseConstructor := js.Global().Get("Promise")
+		return promiseConstructor.New(promiseHandle)
+	})
+}
 
-					if src != nil {
-						script.Type = "external"
-						script.Src = src.Val
-					} else if node.FirstChild != nil {
-						script.Type = "inline"
-
-						if transformOptions.SourceMap != "" {
-							isLine := func(r rune) bool { return r == '\r' || r == '\n' }
-							isNotLine := func(r rune) bool { return !(r == '\r' || r == '\n') }
-							output := make([]byte, 0)
-							builder := sourcemap.MakeChunkBuilder(nil, sourcemap.GenerateLineOffsetTables(source, len(strings.Split(source, "\n"))))
-							sourcesContent, _ := json.Marshal(source)
-							if len(node.FirstChild.Loc) > 0 {
-								i := node.FirstChild.Loc[0].Start
-								nonWS := strings.IndexFunc(node.FirstChild.Data, isNotLine)
-								i += nonWS
-								for _, ln := range strings.Split(strings.TrimFunc(node.FirstChild.Data, isLine), "\n") {
-									content := []byte(ln)
-									content = append(content, '\n')
-									for j, b := range content {
-										if j == 0 || !unicode.IsSpace(rune(b)) {
-											builder.AddSourceMapping(loc.Loc{Start: i}, output)
-										}
-										output = append(output, b)
-										i += 1
-									}
-								}
-								output = append(output, '\n')
-							} else {
-								output = append(output, []byte(strings.TrimSpace(node.FirstChild.Data))...)
-							}
-							sourcemap := fmt.Sprintf(
-								`{ "version": 3, "sources": ["%s"], "sourcesContent": [%s], "mappings": "%s", "names": [] }`,
-								transformOptions.Filename,
-								string(sourcesContent),
-								string(builder.GenerateChunk(output).Buffer),
-							)
-							script.Map = sourcemap
-							script.Code = string(output)
-						} else {
-							script.Code = node.FirstChild.Data
-						}
-					}
+// TransformSync runs the pipeline of Transform without returning to the event loop, so its
+// callbacks must be synchronous. It returns an Error instead of the result on failure, for the
+// JS wrapper to throw. The signal and timeout options are ignored.
+func TransformSync() any {
+	return js.FuncOf(func(this js.Value, args []js.Value) (result any) {
+		defer func() {
+			if err := recover(); err != nil {
+				result = wasm_utils.ErrorToJSErrorInstance(wasm_utils.RecoveredError(err))
+			}
+		}()
 
-					// sourcemapString := createSourceMapString(source, result, transformOptions)
-					// inlineSourcemap := `//# sourceMappingURL=data:application/json;charset=utf-8;base64,` + base64.StdEncoding.EncodeToString([]byte(sourcemapString))
-					scripts = append(scripts, script)
-				}
+		source := jsString(args[0])
+		callbacks := &jsCallbacks{ctx: context.Background(), sync: true}
+		transformOptions := makeTransformOptions(callbacks, js.Value(args[1]))
 
-				for _, c := range doc.HydratedComponents {
-					hydratedComponents = append(hydratedComponents, HydratedComponent{
-						ExportName:   c.ExportName,
-						Specifier:    c.Specifier,
-						ResolvedPath: c.ResolvedPath,
-					})
-				}
+		transformResult, err := compiler.Transform(source, transformOptions)
+		if err == nil {
+			err = callbacks.err()
+		}
+		if err != nil {
+			return wasm_utils.ErrorToJSErrorInstance(err)
+		}
+		return vert.ValueOf(transformResult).Value
+	})
+}
 
-				for _, c := range doc.ClientOnlyComponents {
-					clientOnlyComponents = append(clientOnlyComponents, HydratedComponent{
-						ExportName:   c.ExportName,
-						Specifier:    c.Specifier,
-						ResolvedPath: c.ResolvedPath,
-					})
-				}
+func TransformBatch() any {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		inputs := args[0]
+		// The signal and timeout of the batch apply to every file
+		ctx, stop := makeContext(js.Value(args[1]))
+		callbacks := &jsCallbacks{ctx: ctx}
+		files := make([]compiler.BatchFile, inputs.Length())
+		for i := range files {
+			input := inputs.Index(i)
+			files[i] = compiler.BatchFile{
+				Source:  jsString(input.Get("source")),
+				Options: makeTransformOptions(callbacks, input.Get("options")),
+			}
+		}
+		batchOptions := makeBatchOptions(callbacks, js.Value(args[1]))
 
-				for _, c := range doc.ServerComponents {
-					serverComponents = append(serverComponents, HydratedComponent{
-						ExportName:   c.ExportName,
-						LocalName:    c.LocalName,
-						Specifier:    c.Specifier,
-						ResolvedPath: c.ResolvedPath,
-					})
-				}
+		promiseHandle := js.FuncOf(func(this js.Value, args []js.Value) any {
+			resolve := args[0]
+			reject := args[1]
 
-				var value vert.Value
-				result := printer.PrintToJS(source, doc, len(css), transformOptions, h)
-				transformResult := &TransformResult{
-					CSS:                  css,
-					Scope:                transformOptions.Scope,
-					Scripts:              scripts,
-					HydratedComponents:   hydratedComponents,
-					ClientOnlyComponents: clientOnlyComponents,
-					ServerComponents:     serverComponents,
-					ContainsHead:         doc.ContainsHead,
-					StyleError:           styleError,
-					Propagation:          doc.HeadPropagation,
-				}
-				switch transformOptions.SourceMap {
-				case "external":
-					value = createExternalSourceMap(source, transformResult, result, transformOptions)
-				case "both":
-					value = createBothSourceMap(source, transformResult, result, transformOptions)
-				case "inline":
-					value = createInlineSourceMap(source, transformResult, result, transformOptions)
-				default:
-					transformResult.Code = string(result.Output)
-					transformResult.Map = ""
-					value = vert.ValueOf(transformResult)
-				}
-				value.Set("diagnostics", vert.ValueOf(h.Diagnostics()).Value)
-				resolve.Invoke(value.Value)
+			go func() {
+				defer stop()
+				defer func() {
+					if err := recover(); err != nil {
+						reject.Invoke(wasm_utils.ErrorToJSError(wasm_utils.RecoveredError(err)))
+						return
+					}
+				}()
+
+				resolve.Invoke(vert.ValueOf(compiler.TransformBatchContext(ctx, files, batchOptions)).Value)
 			}()
 
 			return nil
 		})
 		defer promiseHandle.Release()
 
-		// Create and return the Promise object
 		promiseConstructor := js.Global().Get("Promise")
 		return promiseConstructor.New(promiseHandle)
 	})
 }
-
-func createSourceMapString(source string, result printer.PrintResult, transformOptions transform.TransformOptions) string {
-	sourcesContent, _ := json.Marshal(source)
-	sourcemap := RawSourceMap{
-		Version:        3,
-		Sources:        []string{transformOptions.Filename},
-		SourcesContent: []string{string(sourcesContent)},
-		Mappings:       string(result.SourceMapChunk.Buffer),
-	}
-	return fmt.Sprintf(`{
-  "version": 3,
-  "sources": ["%s"],
-  "sourcesContent": [%s],
-  "mappings": "%s",
-  "names": []
-}`, sourcemap.Sources[0], sourcemap.SourcesContent[0], sourcemap.Mappings)
-}
-
-func createExternalSourceMap(source string, transformResult *TransformResult, result printer.PrintResult, transformOptions transform.TransformOptions) vert.Value {
-	transformResult.Code = string(result.Output)
-	transformResult.Map = createSourceMapString(source, result, transformOptions)
-	return vert.ValueOf(transformResult)
-}
-
-func createInlineSourceMap(source string, transformResult *TransformResult, result printer.PrintResult, transformOptions transform.TransformOptions) vert.Value {
-	sourcemapString := createSourceMapString(source, result, transformOptions)
-	inlineSourcemap := `//# sourceMappingURL=data:application/json;charset=utf-8;base64,` + base64.StdEncoding.EncodeToString([]byte(sourcemapString))
-	transformResult.Code = string(result.Output) + "\n" + inlineSourcemap
-	transformResult.Map = ""
-	return vert.ValueOf(transformResult)
-}
-
-func createBothSourceMap(source string, transformResult *TransformResult, result printer.PrintResult, transformOptions transform.TransformOptions) vert.Value {
-	sourcemapString := createSourceMapString(source, result, transformOptions)
-	inlineSourcemap := `//# sourceMappingURL=data:application/json;charset=utf-8;base64,` + base64.StdEncoding.EncodeToString([]byte(sourcemapString))
-	transformResult.Code = string(result.Output) + "\n" + inlineSourcemap
-	transformResult.Map = sourcemapString
-	return vert.ValueOf(transformResult)
-}
@@ -0,0 +1,197 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/withastro/compiler/internal/transform"
+)
+
+// BatchFile is a single component compiled by TransformBatch
+type BatchFile struct {
+	Source  string
+	Options TransformOptions
+}
+
+type BatchOptions struct {
+	// Concurrency is the maximum number of files compiled at the same time.
+	// Defaults to runtime.GOMAXPROCS(0).
+	Concurrency int
+	// ResolvePath resolves the specifier of a hydrated or server component imported by importer.
+	// When set, it replaces the ResolvePath of every file in the batch.
+	//
+	// Results are memoized for the whole batch, keyed by the specifier and the directory
+	// of the importer, so ResolvePath is called once per import shared between files.
+	ResolvePath func(specifier string, importer string) string
+}
+
+type BatchFileResult struct {
+	Filename string          `js:"filename" json:"filename"`
+	Result   TransformResult `js:"result" json:"result"`
+	// Error is set when the file could not be compiled at all. It is also reported in Result.Diagnostics.
+	Error string `js:"error" json:"error,omitempty"`
+}
+
+type BatchResult struct {
+	// Files holds the result of each file, in the order they were passed to TransformBatch
+	Files []BatchFileResult `js:"files" json:"files"`
+	// Diagnostics aggregates the diagnostics of every file, in the same order
+	Diagnostics []DiagnosticMessage `js:"diagnostics" json:"diagnostics"`
+}
+
+// HasErrors reports whether any file of the batch has an error diagnostic
+func (r BatchResult) HasErrors() bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity == int(ErrorType) {
+			return true
+		}
+	}
+	return false
+}
+
+// TransformBatch compiles many components concurrently with a bounded pool of goroutines.
+// Component paths are resolved once per batch, see BatchOptions.ResolvePath.
+func TransformBatch(files []BatchFile, opts BatchOptions) BatchResult {
+	return TransformBatchContext(context.Background(), files, opts)
+}
+
+// TransformBatchContext is like TransformBatch, but stops compiling when ctx is done. The files
+// which aren't compiled by then fail with a *CancelledError, see TransformContext.
+func TransformBatchContext(ctx context.Context, files []BatchFile, opts BatchOptions) BatchResult {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	resolver := newResolveCache(opts.ResolvePath)
+
+	results := make([]BatchFileResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = transformBatchFile(ctx, files[i], resolver)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	batch := BatchResult{Files: results, Diagnostics: []DiagnosticMessage{}}
+	for _, result := range results {
+		batch.Diagnostics = append(batch.Diagnostics, result.Result.Diagnostics...)
+	}
+	return batch
+}
+
+func transformBatchFile(ctx context.Context, file BatchFile, resolver *resolveCache) (result BatchFileResult) {
+	options := file.Options
+	transformOptions := options.toTransformOptions()
+	result.Filename = transformOptions.Filename
+	if resolver.resolve != nil || options.ResolvePath == nil {
+		options.ResolvePath = func(specifier string) string {
+			return resolver.get(specifier, transformOptions.Filename)
+		}
+	}
+
+	// A panic must not take down the other files of the batch
+	defer func() {
+		if r := recover(); r != nil {
+			result.fail(fmt.Errorf("%v", r))
+		}
+	}()
+
+	transformResult, err := TransformContext(ctx, file.Source, options)
+	if err != nil {
+		result.fail(err)
+		return result
+	}
+	result.Result = transformResult
+	return result
+}
+
+func (r *BatchFileResult) fail(err error) {
+	r.Error = err.Error()
+	diagnostic := DiagnosticMessage{
+		Severity: int(ErrorType),
+		Text:     r.Error,
+		Location: &DiagnosticLocation{File: r.Filename},
+	}
+	var cancelled *CancelledError
+	if errors.As(err, &cancelled) {
+		diagnostic = cancelled.Diagnostic()
+	}
+	r.Result = TransformResult{
+		Diagnostics:          []DiagnosticMessage{diagnostic},
+		CSS:                  []string{},
+		CSSMaps:              []string{},
+		Scripts:              []HoistedScript{},
+		HydratedComponents:   []HydratedComponent{},
+		ClientOnlyComponents: []HydratedComponent{},
+		ServerComponents:     []HydratedComponent{},
+		Components:           []ComponentUsage{},
+		UnusedImports:        []ComponentImport{},
+		Slots:                []Slot{},
+		StyleError:           []string{},
+	}
+}
+
+// resolveCache memoizes ResolveIdForMatch across the files of a batch
+type resolveCache struct {
+	resolve func(specifier string, importer string) string
+
+	mu      sync.Mutex
+	entries map[resolveKey]*resolveEntry
+}
+
+type resolveKey struct {
+	specifier string
+	dir       string
+}
+
+type resolveEntry struct {
+	once sync.Once
+	path string
+}
+
+func newResolveCache(resolve func(specifier string, importer string) string) *resolveCache {
+	return &resolveCache{
+		resolve: resolve,
+		entries: make(map[resolveKey]*resolveEntry),
+	}
+}
+
+func (c *resolveCache) get(specifier string, importer string) string {
+	key := resolveKey{specifier: specifier, dir: filepath.Dir(importer)}
+	if importer == "<stdin>" {
+		// Components without a filename don't share a directory with the other files
+		key.dir = importer
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &resolveEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	// Concurrent lookups of the same key wait for the first one instead of resolving again
+	entry.once.Do(func() {
+		if c.resolve != nil {
+			entry.path = c.resolve(specifier, importer)
+			return
+		}
+		entry.path = transform.ResolveIdForMatch(specifier, &transform.TransformOptions{Filename: importer})
+	})
+	return entry.path
+}
@@ -0,0 +1,135 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/withastro/compiler/internal/loc"
+	"github.com/withastro/compiler/internal/test_utils"
+)
+
+func TestTransformBatch(t *testing.T) {
+	source := test_utils.Dedent(`
+		---
+		import Counter from '../components/Counter.jsx';
+		import Island from '../components/Island.astro';
+		---
+		<Counter client:load />
+		<Island server:defer />
+		<script async src="/analytics.js"></script>
+	`)
+
+	files := []BatchFile{}
+	for i := 0; i < 20; i++ {
+		files = append(files, BatchFile{
+			Source:  source,
+			Options: TransformOptions{Filename: fmt.Sprintf("/src/pages/page-%d.astro", i)},
+		})
+	}
+
+	var calls atomic.Int32
+	result := TransformBatch(files, BatchOptions{
+		Concurrency: 4,
+		ResolvePath: func(specifier string, importer string) string {
+			calls.Add(1)
+			return "/resolved/" + strings.TrimPrefix(specifier, "../")
+		},
+	})
+
+	if len(result.Files) != len(files) {
+		t.Fatalf("expected %d results, got %d", len(files), len(result.Files))
+	}
+	for i, file := range result.Files {
+		if file.Filename != files[i].Options.Filename {
+			t.Errorf("result %d is for %s, want %s", i, file.Filename, files[i].Options.Filename)
+		}
+		wantHydrated := []HydratedComponent{{ExportName: "default", Specifier: "../components/Counter.jsx", ResolvedPath: "/resolved/components/Counter.jsx"}}
+		if diff := test_utils.ANSIDiff(wantHydrated, file.Result.HydratedComponents); diff != "" {
+			t.Errorf("hydrated components mismatch (-want +got):\n%s", diff)
+		}
+		if len(file.Result.Diagnostics) != 1 || file.Result.Diagnostics[0].Location.File != file.Filename {
+			t.Errorf("expected the is:inline hint for %s, got %v", file.Filename, file.Result.Diagnostics)
+		}
+	}
+
+	// Counter and Island are resolved once for the whole batch
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls to ResolvePath, got %d", calls.Load())
+	}
+	if len(result.Diagnostics) != len(files) {
+		t.Errorf("expected %d aggregated diagnostics, got %d", len(files), len(result.Diagnostics))
+	}
+	if result.HasErrors() {
+		t.Errorf("expected no errors, got %v", result.Diagnostics)
+	}
+}
+
+func TestTransformBatchResolveByDirectory(t *testing.T) {
+	source := "---\nimport Counter from './Counter.jsx';\n---\n<Counter client:idle />"
+	result := TransformBatch([]BatchFile{
+		{Source: source, Options: TransformOptions{Filename: "/src/a/One.astro"}},
+		{Source: source, Options: TransformOptions{Filename: "/src/a/Two.astro"}},
+		{Source: source, Options: TransformOptions{Filename: "/src/b/Three.astro"}},
+	}, BatchOptions{})
+
+	want := []string{"/src/a/Counter.jsx", "/src/a/Counter.jsx", "/src/b/Counter.jsx"}
+	for i, file := range result.Files {
+		if len(file.Result.HydratedComponents) != 1 || file.Result.HydratedComponents[0].ResolvedPath != want[i] {
+			t.Errorf("%s: expected Counter to resolve to %s, got %v", file.Filename, want[i], file.Result.HydratedComponents)
+		}
+	}
+}
+
+func TestTransformBatchFileResolvePath(t *testing.T) {
+	result := TransformBatch([]BatchFile{{
+		Source: "---\nimport Counter from './Counter.jsx';\n---\n<Counter client:idle />",
+		Options: TransformOptions{
+			Filename:    "/src/One.astro",
+			ResolvePath: func(specifier string) string { return "/custom/" + specifier },
+		},
+	}}, BatchOptions{})
+
+	if got := result.Files[0].Result.HydratedComponents[0].ResolvedPath; got != "/custom/./Counter.jsx" {
+		t.Errorf("expected the ResolvePath of the file to be used, got %s", got)
+	}
+}
+
+func TestTransformBatchPanic(t *testing.T) {
+	result := TransformBatch([]BatchFile{
+		{Source: "<h1>Hello</h1>", Options: TransformOptions{Filename: "/src/Ok.astro"}},
+		{Source: "---\nimport Counter from './Counter.jsx';\n---\n<Counter client:idle />", Options: TransformOptions{Filename: "/src/Panic.astro"}},
+	}, BatchOptions{
+		ResolvePath: func(specifier string, importer string) string { panic("cannot resolve " + specifier) },
+	})
+
+	if result.Files[0].Error != "" || !strings.Contains(result.Files[0].Result.Code, "<h1>Hello</h1>") {
+		t.Errorf("expected the first file to compile, got %+v", result.Files[0])
+	}
+	if result.Files[1].Error != "cannot resolve ./Counter.jsx" {
+		t.Errorf("expected the panic to be reported, got %q", result.Files[1].Error)
+	}
+	if !result.HasErrors() {
+		t.Error("expected the batch to have errors")
+	}
+}
+
+func TestTransformBatchContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := TransformBatchContext(ctx, []BatchFile{
+		{Source: "<h1>Hello</h1>", Options: TransformOptions{Filename: "/src/One.astro"}},
+		{Source: "<h1>World</h1>", Options: TransformOptions{Filename: "/src/Two.astro"}},
+	}, BatchOptions{})
+
+	for _, file := range result.Files {
+		if file.Error != fmt.Sprintf("transform of %s was cancelled: context canceled", file.Filename) {
+			t.Errorf("expected %s to be cancelled, got %q", file.Filename, file.Error)
+		}
+		if d := file.Result.Diagnostics; len(d) != 1 || d[0].Code != int(loc.ERROR_CANCELLED) || d[0].Location.File != file.Filename {
+			t.Errorf("expected an ERROR_CANCELLED diagnostic for %s, got %v", file.Filename, d)
+		}
+	}
+}
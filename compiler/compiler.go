@@ -0,0 +1,488 @@
+// Package compiler is the public Go API of the Astro compiler. It runs the same
+// pipeline as the `@astrojs/compiler` JS package: Parse, Transform and ConvertToTSX
+// return the same data as their JS counterparts.
+package compiler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+	"github.com/withastro/compiler/internal/printer"
+	"github.com/withastro/compiler/internal/sourcemap"
+	"github.com/withastro/compiler/internal/t"
+	"github.com/withastro/compiler/internal/transform"
+	"golang.org/x/net/html/atom"
+)
+
+// Parse returns the JSON AST of an Astro component. Parsing never fails: problems are
+// reported in ParseResult.Diagnostics.
+func Parse(source string, opts ParseOptions) ParseResult {
+	return parse(source, opts, func(h *handler.Handler) (*astro.Node, error) {
+		return astro.ParseWithOptions(strings.NewReader(source), opts.parseOptions(h)...)
+	})
+}
+
+// ParseFragment returns the JSON AST of a fragment of a component, like a snippet or an editor
+// selection, parsed as the content of the element named by ParseFragmentOptions.Context. Unlike
+// Parse, no <html>, <head> or <body> element is implied around the fragment.
+func ParseFragment(source string, opts ParseFragmentOptions) ParseResult {
+	return parse(source, opts.ParseOptions, func(h *handler.Handler) (*astro.Node, error) {
+		nodes, err := astro.ParseFragmentWithOptions(strings.NewReader(source), fragmentContext(opts.Context), opts.parseOptions(h)...)
+		// The fragment is transformed as a document, which is built like the one of ParseWithOptions
+		doc := &astro.Node{Type: astro.DocumentNode, HydrationDirectives: make(map[string]bool)}
+		for _, n := range nodes {
+			doc.AppendChild(n)
+		}
+		return doc, err
+	})
+}
+
+// parse prints the document returned by parseDocument to JSON, then transforms it for the
+// diagnostics of the transform
+func parse(source string, opts ParseOptions, parseDocument func(h *handler.Handler) (*astro.Node, error)) ParseResult {
+	filename := opts.filename()
+	transformOptions := transform.TransformOptions{
+		Filename:            filename,
+		NormalizedFilename:  filename,
+		ScopedStyleStrategy: "where",
+		Scope:               "xxxxxx",
+	}
+	h := handler.NewHandler(source, filename)
+
+	doc, err := parseDocument(h)
+	if err != nil {
+		h.AppendError(err)
+	}
+	result := printer.PrintToJSON(source, doc, t.ParseOptions{Filename: filename, Position: opts.Position, FrontmatterAST: opts.FrontmatterAST})
+
+	// AFTER printing, exec transformations to pickup any errors/warnings
+	transform.Transform(doc, transformOptions, h)
+
+	return ParseResult{
+		AST:         string(result.Output),
+		Diagnostics: h.Diagnostics(),
+		Slots:       toSlots(h, doc.Slots),
+		Components:  toComponentUsages(h, doc.ComponentUsages),
+	}
+}
+
+func (opts ParseOptions) filename() string {
+	if opts.Filename == "" {
+		return "<stdin>"
+	}
+	return opts.Filename
+}
+
+func (opts ParseOptions) parseOptions(h *handler.Handler) []astro.ParseOption {
+	return []astro.ParseOption{
+		astro.ParseOptionWithHandler(h),
+		astro.ParseOptionEnableLiteral(true),
+		astro.ParseOptionEnableRecovery(opts.Recover),
+		astro.ParseOptionEnableFrontmatterAST(opts.FrontmatterAST),
+		astro.ParseOptionEnableExpressionValidation(opts.ValidateExpressions),
+	}
+}
+
+// fragmentContext returns the element named name for ParseFragment. <svg> and <math> are in
+// their own namespace, so that their content is parsed as foreign content.
+func fragmentContext(name string) *astro.Node {
+	if name == "" {
+		name = "body"
+	}
+	context := &astro.Node{Type: astro.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name))}
+	if context.DataAtom == atom.Svg || context.DataAtom == atom.Math {
+		context.Namespace = name
+	}
+	return context
+}
+
+// ConvertToTSX converts an Astro component to TSX, for use by type checkers and editor tooling.
+func ConvertToTSX(source string, opts TSXOptions) TSXResult {
+	h := handler.NewHandler(source, opts.filename())
+	doc, err := astro.ParseWithOptions(strings.NewReader(source), opts.parseOptions(h)...)
+	if err != nil {
+		h.AppendError(err)
+	}
+	return convertToTSX(source, doc, opts, h)
+}
+
+func (opts TSXOptions) filename() string {
+	if opts.Filename == "" {
+		return "<stdin>"
+	}
+	return opts.Filename
+}
+
+func (opts TSXOptions) parseOptions(h *handler.Handler) []astro.ParseOption {
+	return []astro.ParseOption{astro.ParseOptionWithHandler(h), astro.ParseOptionEnableLiteral(true), astro.ParseOptionEnableRecovery(opts.Recover)}
+}
+
+// convertToTSX prints doc, the tree parsed from source, to TSX. h holds the diagnostics of the parser.
+func convertToTSX(source string, doc *astro.Node, opts TSXOptions, h *handler.Handler) TSXResult {
+	filename := opts.filename()
+	normalizedFilename := opts.NormalizedFilename
+	if normalizedFilename == "" {
+		normalizedFilename = filename
+	}
+	transformOptions := transform.TransformOptions{
+		Filename:            filename,
+		NormalizedFilename:  normalizedFilename,
+		SourceMap:           opts.SourceMap,
+		ScopedStyleStrategy: "where",
+		Scope:               "xxxxxx",
+	}
+
+	tsxOptions := printer.TSXOptions{
+		IncludeScripts: opts.IncludeScripts,
+		IncludeStyles:  opts.IncludeStyles,
+	}
+	result := printer.PrintToTSX(source, doc, tsxOptions, transformOptions, h)
+
+	// AFTER printing, exec transformations to pickup any errors/warnings
+	transform.Transform(doc, transformOptions, h)
+
+	sourcemapString := createSourceMapString(source, result, transformOptions)
+	code := string(result.Output)
+	if transformOptions.SourceMap != "external" {
+		code += "\n" + inlineSourceMapComment(sourcemapString)
+	}
+
+	return TSXResult{
+		Code:        code,
+		Map:         sourcemapString,
+		Diagnostics: h.Diagnostics(),
+		Ranges:      result.TSXRanges,
+	}
+}
+
+// Transform compiles an Astro component to a JavaScript module. An error is only returned
+// when the component cannot be parsed; other problems are reported in TransformResult.Diagnostics.
+func Transform(source string, opts TransformOptions) (TransformResult, error) {
+	return TransformContext(context.Background(), source, opts)
+}
+
+// CancelledError is returned by TransformContext when its context is done before the
+// transform completes. It matches context.Canceled or context.DeadlineExceeded with errors.Is.
+type CancelledError struct {
+	Filename string
+	// Cause is the cause of the context, see context.Cause
+	Cause error
+	err   error
+}
+
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("transform of %s was cancelled: %v", e.Filename, e.Cause)
+}
+
+func (e *CancelledError) Unwrap() []error {
+	return []error{e.err, e.Cause}
+}
+
+// Diagnostic returns the error as an ERROR_CANCELLED diagnostic
+func (e *CancelledError) Diagnostic() DiagnosticMessage {
+	return DiagnosticMessage{
+		Severity: int(ErrorType),
+		Code:     int(loc.ERROR_CANCELLED),
+		Location: &DiagnosticLocation{File: e.Filename},
+		Text:     e.Error(),
+	}
+}
+
+// TransformContext is like Transform, but returns a *CancelledError as soon as ctx is done.
+// The styles being preprocessed are abandoned, and ResolvePath isn't called anymore.
+//
+// Parsing and printing can't be interrupted; ctx is checked between the steps of the transform.
+func TransformContext(ctx context.Context, source string, opts TransformOptions) (TransformResult, error) {
+	source = strings.TrimRightFunc(source, unicode.IsSpace)
+
+	transformOptions := opts.toTransformOptions()
+	cancelled := func() error {
+		if ctx.Err() == nil {
+			return nil
+		}
+		return &CancelledError{Filename: transformOptions.Filename, Cause: context.Cause(ctx), err: ctx.Err()}
+	}
+	if err := cancelled(); err != nil {
+		return TransformResult{}, err
+	}
+	if resolvePath := transformOptions.ResolvePath; resolvePath != nil {
+		transformOptions.ResolvePath = func(specifier string) string {
+			// The specifier is kept, the result is dropped anyway
+			if ctx.Err() != nil {
+				return specifier
+			}
+			return resolvePath(specifier)
+		}
+	}
+	if loadComponent := transformOptions.LoadComponent; loadComponent != nil {
+		transformOptions.LoadComponent = func(path string) (string, bool) {
+			if ctx.Err() != nil {
+				return "", false
+			}
+			return loadComponent(path)
+		}
+	}
+
+	scopeStr := transformOptions.NormalizedFilename
+	if scopeStr == "<stdin>" {
+		scopeStr = source
+	}
+	transformOptions.Scope = astro.HashString(scopeStr)
+	h := handler.NewHandler(source, transformOptions.Filename)
+
+	doc, err := astro.ParseWithOptions(strings.NewReader(source), astro.ParseOptionWithHandler(h), astro.ParseOptionEnableContentModelWarnings(opts.ContentModelWarnings), astro.ParseOptionEnableForeignContentWarnings(opts.ForeignContentWarnings), astro.ParseOptionEnableExpressionValidation(opts.ValidateExpressions))
+	if err != nil {
+		return TransformResult{}, err
+	}
+
+	// Hoist styles and scripts to the top-level
+	transform.ExtractStyles(doc)
+
+	styleErrors, styleMaps, err := transform.PreprocessStyles(ctx, doc, transformOptions)
+	if err != nil {
+		return TransformResult{}, cancelled()
+	}
+	styleError := []string{}
+	for _, err := range styleErrors {
+		styleError = append(styleError, err.Error())
+	}
+
+	// Perform CSS and element scoping as needed
+	transform.Transform(doc, transformOptions, h)
+	if err := cancelled(); err != nil {
+		return TransformResult{}, err
+	}
+
+	css := []string{}
+	for _, bytes := range printer.PrintCSS(source, doc, transformOptions).Output {
+		css = append(css, string(bytes))
+	}
+	// Only the styles which aren't empty are printed by PrintCSS
+	cssMaps := []string{}
+	for i, style := range doc.Styles {
+		if style.FirstChild != nil && strings.TrimSpace(style.FirstChild.Data) != "" {
+			cssMaps = append(cssMaps, styleMaps[i])
+		}
+	}
+
+	scripts := []HoistedScript{}
+	for _, node := range doc.Scripts {
+		scripts = append(scripts, hoistScript(source, node, transformOptions))
+	}
+
+	result := printer.PrintToJS(source, doc, len(css), transformOptions, h)
+	transformResult := TransformResult{
+		Code:                 string(result.Output),
+		CSS:                  css,
+		CSSMaps:              cssMaps,
+		Scope:                transformOptions.Scope,
+		Scripts:              scripts,
+		HydratedComponents:   toHydratedComponents(doc.HydratedComponents),
+		ClientOnlyComponents: toHydratedComponents(doc.ClientOnlyComponents),
+		ServerComponents:     toHydratedComponents(doc.ServerComponents),
+		Components:           toComponentUsages(h, doc.ComponentUsages),
+		UnusedImports:        toComponentImports(h, doc.UnusedComponentImports),
+		Slots:                toSlots(h, doc.Slots),
+		ContainsHead:         doc.ContainsHead,
+		StyleError:           styleError,
+		Propagation:          doc.HeadPropagation,
+	}
+	switch transformOptions.SourceMap {
+	case "external":
+		transformResult.Map = createSourceMapString(source, result, transformOptions)
+	case "both":
+		transformResult.Map = createSourceMapString(source, result, transformOptions)
+		transformResult.Code += "\n" + inlineSourceMapComment(transformResult.Map)
+	case "inline":
+		transformResult.Code += "\n" + inlineSourceMapComment(createSourceMapString(source, result, transformOptions))
+	}
+	if err := cancelled(); err != nil {
+		return TransformResult{}, err
+	}
+	transformResult.Diagnostics = h.Diagnostics()
+	return transformResult, nil
+}
+
+func (opts TransformOptions) toTransformOptions() transform.TransformOptions {
+	filename := opts.Filename
+	if filename == "" {
+		filename = "<stdin>"
+	}
+	normalizedFilename := opts.NormalizedFilename
+	if normalizedFilename == "" {
+		normalizedFilename = filename
+	}
+	internalURL := opts.InternalURL
+	if internalURL == "" {
+		internalURL = "astro/runtime/server/index.js"
+	}
+	scopedStyleStrategy := opts.ScopedStyleStrategy
+	if scopedStyleStrategy == "" {
+		scopedStyleStrategy = "where"
+	}
+	transitionsAnimationURL := opts.TransitionsAnimationURL
+	if transitionsAnimationURL == "" {
+		transitionsAnimationURL = "astro/components/viewtransitions.css"
+	}
+
+	return transform.TransformOptions{
+		Filename:                filename,
+		NormalizedFilename:      normalizedFilename,
+		InternalURL:             internalURL,
+		SourceMap:               opts.SourceMap,
+		AstroGlobalArgs:         opts.AstroGlobalArgs,
+		Compact:                 opts.Compact,
+		ResolvePath:             opts.ResolvePath,
+		LoadComponent:           opts.LoadComponent,
+		ResultScopedSlot:        opts.ResultScopedSlot,
+		ScopedStyleStrategy:     scopedStyleStrategy,
+		TransitionsAnimationURL: transitionsAnimationURL,
+		AnnotateSourceFile:      opts.AnnotateSourceFile,
+		RenderScript:            opts.RenderScript,
+		AccessibilityWarnings:   opts.AccessibilityWarnings,
+		PreprocessStyle:         opts.PreprocessStyle,
+		Directives:              opts.Directives,
+		Passes:                  opts.Passes,
+	}
+}
+
+func toHydratedComponents(components []*astro.HydratedComponentMetadata) []HydratedComponent {
+	result := []HydratedComponent{}
+	for _, c := range components {
+		result = append(result, HydratedComponent{
+			ExportName:   c.ExportName,
+			LocalName:    c.LocalName,
+			Specifier:    c.Specifier,
+			ResolvedPath: c.ResolvedPath,
+		})
+	}
+	return result
+}
+
+func toComponentUsages(h *handler.Handler, components []*astro.ComponentUsageMetadata) []ComponentUsage {
+	result := []ComponentUsage{}
+	for _, c := range components {
+		result = append(result, ComponentUsage{
+			ExportName:   c.ExportName,
+			LocalName:    c.LocalName,
+			Specifier:    c.Specifier,
+			ResolvedPath: c.ResolvedPath,
+			Attributes:   c.Attributes,
+			Slots:        c.Slots,
+			SlotContents: toSlotContents(h, c.SlotContents),
+			Location:     h.Location(c.Range),
+		})
+	}
+	return result
+}
+
+func toSlots(h *handler.Handler, slots []*astro.SlotMetadata) []Slot {
+	result := []Slot{}
+	for _, s := range slots {
+		result = append(result, Slot{
+			Name:        s.Name,
+			Dynamic:     s.Dynamic,
+			HasFallback: s.HasFallback,
+			Location:    h.Location(s.Range),
+		})
+	}
+	return result
+}
+
+func toSlotContents(h *handler.Handler, contents []*astro.SlotMetadata) []SlotContent {
+	result := []SlotContent{}
+	for _, c := range contents {
+		result = append(result, SlotContent{
+			Name:     c.Name,
+			Dynamic:  c.Dynamic,
+			Location: h.Location(c.Range),
+		})
+	}
+	return result
+}
+
+func toComponentImports(h *handler.Handler, imports []*astro.ComponentUsageMetadata) []ComponentImport {
+	result := []ComponentImport{}
+	for _, i := range imports {
+		result = append(result, ComponentImport{
+			ExportName:   i.ExportName,
+			LocalName:    i.LocalName,
+			Specifier:    i.Specifier,
+			ResolvedPath: i.ResolvedPath,
+			Location:     h.Location(i.Range),
+		})
+	}
+	return result
+}
+
+func hoistScript(source string, node *astro.Node, transformOptions transform.TransformOptions) HoistedScript {
+	script := HoistedScript{}
+	if src := astro.GetAttribute(node, "src"); src != nil {
+		script.Type = "external"
+		script.Src = src.Val
+		return script
+	}
+	if node.FirstChild == nil {
+		return script
+	}
+	script.Type = "inline"
+	if transformOptions.SourceMap == "" {
+		script.Code = node.FirstChild.Data
+		return script
+	}
+
+	isLine := func(r rune) bool { return r == '\r' || r == '\n' }
+	isNotLine := func(r rune) bool { return !(r == '\r' || r == '\n') }
+	output := make([]byte, 0)
+	builder := sourcemap.MakeChunkBuilder(nil, sourcemap.GenerateLineOffsetTables(source, len(strings.Split(source, "\n"))))
+	sourcesContent, _ := json.Marshal(source)
+	if len(node.FirstChild.Loc) > 0 {
+		i := node.FirstChild.Loc[0].Start
+		nonWS := strings.IndexFunc(node.FirstChild.Data, isNotLine)
+		i += nonWS
+		for _, ln := range strings.Split(strings.TrimFunc(node.FirstChild.Data, isLine), "\n") {
+			content := []byte(ln)
+			content = append(content, '\n')
+			for j, b := range content {
+				if j == 0 || !unicode.IsSpace(rune(b)) {
+					builder.AddSourceMapping(loc.Loc{Start: i}, output)
+				}
+				output = append(output, b)
+				i += 1
+			}
+		}
+		output = append(output, '\n')
+	} else {
+		output = append(output, []byte(strings.TrimSpace(node.FirstChild.Data))...)
+	}
+	script.Map = fmt.Sprintf(
+		`{ "version": 3, "sources": ["%s"], "sourcesContent": [%s], "mappings": "%s", "names": [] }`,
+		transformOptions.Filename,
+		string(sourcesContent),
+		string(builder.GenerateChunk(output).Buffer),
+	)
+	script.Code = string(output)
+	return script
+}
+
+func createSourceMapString(source string, result printer.PrintResult, transformOptions transform.TransformOptions) string {
+	sourcesContent, _ := json.Marshal(source)
+	return fmt.Sprintf(`{
+  "version": 3,
+  "sources": ["%s"],
+  "sourcesContent": [%s],
+  "mappings": "%s",
+  "names": []
+}`, transformOptions.Filename, string(sourcesContent), string(result.SourceMapChunk.Buffer))
+}
+
+func inlineSourceMapComment(sourcemapString string) string {
+	return `//# sourceMappingURL=data:application/json;charset=utf-8;base64,` + base64.StdEncoding.EncodeToString([]byte(sourcemapString))
+}
@@ -0,0 +1,737 @@
+package compiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/withastro/compiler/internal/loc"
+	"github.com/withastro/compiler/internal/test_utils"
+)
+
+func TestParse(t *testing.T) {
+	result := Parse(`<h1 class="title">Hello</h1>`, ParseOptions{Position: true})
+
+	var ast struct {
+		Type     string `json:"type"`
+		Children []struct {
+			Type     string          `json:"type"`
+			Name     string          `json:"name"`
+			Position json.RawMessage `json:"position"`
+		} `json:"children"`
+	}
+	if err := json.Unmarshal([]byte(result.AST), &ast); err != nil {
+		t.Fatalf("invalid AST: %v\n%s", err, result.AST)
+	}
+	if ast.Type != "root" || len(ast.Children) != 1 || ast.Children[0].Name != "h1" {
+		t.Errorf("unexpected AST: %s", result.AST)
+	}
+	if ast.Children[0].Position == nil {
+		t.Errorf("expected a position with Position: true")
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %v", result.Diagnostics)
+	}
+}
+
+func TestParseEndPositions(t *testing.T) {
+	source := `<h1 class="title">Hello</h1>`
+	result := Parse(source, ParseOptions{Position: true})
+
+	type point struct {
+		Offset int `json:"offset"`
+	}
+	type position struct {
+		Start point     `json:"start"`
+		End   point     `json:"end"`
+		Close *position `json:"close"`
+		Value *position `json:"value"`
+	}
+	var ast struct {
+		Children []struct {
+			Position   position `json:"position"`
+			Attributes []struct {
+				Position position `json:"position"`
+			} `json:"attributes"`
+		} `json:"children"`
+	}
+	if err := json.Unmarshal([]byte(result.AST), &ast); err != nil {
+		t.Fatalf("invalid AST: %v\n%s", err, result.AST)
+	}
+	h1 := ast.Children[0]
+	if h1.Position.End.Offset != len(source) || h1.Position.Close == nil || h1.Position.Close.Start.Offset != len(source)-len("</h1>") {
+		t.Errorf("expected the element to end with its end tag, got %+v", h1.Position)
+	}
+	attr := h1.Attributes[0].Position
+	if got := source[attr.Start.Offset:attr.End.Offset]; got != `class="title"` {
+		t.Errorf("expected the range of the whole attribute, got %q", got)
+	}
+	if attr.Value == nil || source[attr.Value.Start.Offset:attr.Value.End.Offset] != "title" {
+		t.Errorf("expected the range of the attribute value, got %+v", attr.Value)
+	}
+}
+
+func TestParseRecover(t *testing.T) {
+	source := "<div>{value</div>\n<p>after</p>"
+
+	result := Parse(source, ParseOptions{Recover: true})
+	if !strings.Contains(result.AST, `"type":"error"`) || !strings.Contains(result.AST, `"value":"after"`) {
+		t.Errorf("expected an error node and the rest of the component, got %s", result.AST)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != int(loc.ERROR_UNTERMINATED_EXPRESSION) {
+		t.Errorf("expected an unterminated expression, got %v", result.Diagnostics)
+	}
+
+	tsx := ConvertToTSX(source, TSXOptions{Recover: true})
+	if !strings.Contains(tsx.Code, "<div>{value}</div>") || !strings.Contains(tsx.Code, "<p>after</p>") {
+		t.Errorf("expected the expression to be closed before </div>, got\n%s", tsx.Code)
+	}
+
+	// An unclosed shorthand fragment is located at its "<"
+	fragment := "<>}\n="
+	for _, diagnostics := range [][]DiagnosticMessage{
+		Parse(fragment, ParseOptions{Recover: true}).Diagnostics,
+		ConvertToTSX(fragment, TSXOptions{Recover: true}).Diagnostics,
+	} {
+		for _, d := range diagnostics {
+			if d.Location.Line < 1 || d.Location.Column < 1 {
+				t.Errorf("expected a location in the source, got %+v", d.Location)
+			}
+		}
+	}
+}
+
+func TestParseFrontmatterAST(t *testing.T) {
+	source := "---\nimport Card from './Card.astro';\nconst { title } = Astro.props as Props;\n---\n<Card />"
+
+	result := Parse(source, ParseOptions{Position: true, FrontmatterAST: true})
+	var ast struct {
+		Children []struct {
+			Type    string `json:"type"`
+			Program *struct {
+				Type string `json:"type"`
+				Body []struct {
+					Type  string `json:"type"`
+					Start int    `json:"start"`
+					Loc   struct {
+						Start struct {
+							Line   int `json:"line"`
+							Column int `json:"column"`
+						} `json:"start"`
+					} `json:"loc"`
+				} `json:"body"`
+			} `json:"program"`
+		} `json:"children"`
+	}
+	if err := json.Unmarshal([]byte(result.AST), &ast); err != nil {
+		t.Fatalf("invalid AST: %v\n%s", err, result.AST)
+	}
+	program := ast.Children[0].Program
+	if program == nil || program.Type != "Program" || len(program.Body) != 2 {
+		t.Fatalf("expected a program with two statements, got %s", result.AST)
+	}
+	if program.Body[0].Type != "ImportDeclaration" || program.Body[0].Start != 4 {
+		t.Errorf("expected an import at offset 4, got %+v", program.Body[0])
+	}
+	if second := program.Body[1]; second.Type != "VariableDeclaration" || second.Loc.Start.Line != 3 || second.Loc.Start.Column != 0 {
+		t.Errorf("expected a declaration at 3:0, got %+v", second)
+	}
+
+	if plain := Parse(source, ParseOptions{}); strings.Contains(plain.AST, `"program"`) {
+		t.Errorf("expected no program without FrontmatterAST, got %s", plain.AST)
+	}
+
+	invalid := Parse("---\nconst = 1;\n---\n", ParseOptions{FrontmatterAST: true})
+	if strings.Contains(invalid.AST, `"program"`) {
+		t.Errorf("expected no program for an invalid frontmatter, got %s", invalid.AST)
+	}
+	if len(invalid.Diagnostics) != 1 || invalid.Diagnostics[0].Code != int(loc.ERROR_FRONTMATTER_SYNTAX) {
+		t.Errorf("expected a frontmatter syntax error, got %v", invalid.Diagnostics)
+	}
+}
+
+func TestParseFragment(t *testing.T) {
+	tests := []struct {
+		name    string
+		context string
+		source  string
+		// want is the type and name of each node, depth first
+		want []string
+	}{
+		{"default", "", "<p>a</p>", []string{"element p", "text"}},
+		{"tbody", "tbody", "<tr><td>a</td></tr>", []string{"element tr", "element td", "text"}},
+		{"svg", "svg", "<circle r='1' />", []string{"element circle"}},
+		{"frontmatter", "", "---\nconst a = 1;\n---\n<title>{a}</title>", []string{"frontmatter", "element title", "expression", "text"}},
+	}
+	type node struct {
+		Type     string `json:"type"`
+		Name     string `json:"name"`
+		Children []node `json:"children"`
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseFragment(tt.source, ParseFragmentOptions{Context: tt.context})
+			var ast node
+			if err := json.Unmarshal([]byte(result.AST), &ast); err != nil {
+				t.Fatalf("invalid AST: %v\n%s", err, result.AST)
+			}
+			got := make([]string, 0)
+			var walk func(n node)
+			walk = func(n node) {
+				for _, c := range n.Children {
+					got = append(got, strings.TrimSpace(c.Type+" "+c.Name))
+					walk(c)
+				}
+			}
+			walk(ast)
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("nodes = %q, expected %q\n%s", got, tt.want, result.AST)
+			}
+			if len(result.Diagnostics) != 0 {
+				t.Errorf("expected no diagnostics, got %v", result.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestParseFragmentFrontmatterAST(t *testing.T) {
+	// The options are applied as for Parse
+	result := ParseFragment("---\nconst a = 1;\n---\n<p>{a}</p>", ParseFragmentOptions{ParseOptions: ParseOptions{FrontmatterAST: true}})
+	if !strings.Contains(result.AST, `"program":{"type":"Program"`) {
+		t.Errorf("expected the program of the frontmatter, got %s", result.AST)
+	}
+}
+
+func TestParseFragmentHydratedComponent(t *testing.T) {
+	for _, context := range []string{"", "tbody"} {
+		source := "<Counter client:load />"
+		if context == "tbody" {
+			source = "<tr><td><Counter client:only=\"react\" /></td></tr>"
+		}
+		result := ParseFragment(source, ParseFragmentOptions{Context: context})
+		if !strings.Contains(result.AST, `"name":"Counter"`) {
+			t.Errorf("expected the component in the AST of context %q, got %s", context, result.AST)
+		}
+		if len(result.Components) != 1 || result.Components[0].LocalName != "Counter" {
+			t.Errorf("expected the component usage in context %q, got %+v", context, result.Components)
+		}
+	}
+}
+
+func TestConvertToTSXEmptyComment(t *testing.T) {
+	// "</{" starts a bogus comment, empty until the following ">"
+	for _, source := range []string{"<!---->", "<em>world</{x}em>"} {
+		result := ConvertToTSX(source, TSXOptions{})
+		if !strings.Contains(result.Code, "{/** */}") {
+			t.Errorf("expected an empty comment in the code of %q, got:\n%s", source, result.Code)
+		}
+	}
+}
+
+func TestConvertToTSX(t *testing.T) {
+	source := "---\nconst name = 'world';\n---\n<h1>Hello {name}</h1>"
+
+	result := ConvertToTSX(source, TSXOptions{Filename: "/src/Hello.astro"})
+	if !strings.Contains(result.Code, "<h1>Hello {name}</h1>") {
+		t.Errorf("unexpected code:\n%s", result.Code)
+	}
+	if !strings.Contains(result.Code, "//# sourceMappingURL=data:application/json") {
+		t.Errorf("expected an inline source map")
+	}
+	if !json.Valid([]byte(result.Map)) {
+		t.Errorf("expected a JSON source map, got %s", result.Map)
+	}
+	if result.Ranges.Frontmatter.End <= result.Ranges.Frontmatter.Start {
+		t.Errorf("expected a frontmatter range, got %+v", result.Ranges.Frontmatter)
+	}
+
+	external := ConvertToTSX(source, TSXOptions{Filename: "/src/Hello.astro", SourceMap: "external"})
+	if strings.Contains(external.Code, "sourceMappingURL") {
+		t.Errorf("expected no inline source map with SourceMap: external")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	source := test_utils.Dedent(`
+		---
+		import Counter from './Counter.jsx';
+		import Island from './Island.astro';
+		---
+		<html>
+			<head><title>Test</title></head>
+			<body>
+				<Counter client:load />
+				<Island server:defer />
+				<script src="/external.js"></script>
+				<script>console.log("inline")</script>
+			</body>
+		</html>
+		<style>h1 { color: red; }</style>
+	`)
+
+	result, err := Transform(source, TransformOptions{
+		Filename: "/src/pages/index.astro",
+		ResolvePath: func(specifier string) string {
+			return "/resolved/" + strings.TrimPrefix(specifier, "./")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Scope == "" {
+		t.Error("expected a scope")
+	}
+	if len(result.CSS) != 1 || !strings.Contains(result.CSS[0], "h1:where(.astro-"+result.Scope+")") {
+		t.Errorf("unexpected css: %v", result.CSS)
+	}
+	if !result.ContainsHead {
+		t.Error("expected containsHead to be true")
+	}
+	if result.Map != "" || strings.Contains(result.Code, "sourceMappingURL") {
+		t.Error("expected no source map by default")
+	}
+
+	// Hoisted scripts are listed last to first
+	wantScripts := []HoistedScript{
+		{Type: "inline", Code: `console.log("inline")`},
+		{Type: "external", Src: "/external.js"},
+	}
+	if diff := test_utils.ANSIDiff(wantScripts, result.Scripts); diff != "" {
+		t.Errorf("scripts mismatch (-want +got):\n%s", diff)
+	}
+
+	wantHydrated := []HydratedComponent{{ExportName: "default", Specifier: "./Counter.jsx", ResolvedPath: "/resolved/Counter.jsx"}}
+	if diff := test_utils.ANSIDiff(wantHydrated, result.HydratedComponents); diff != "" {
+		t.Errorf("hydrated components mismatch (-want +got):\n%s", diff)
+	}
+	wantServer := []HydratedComponent{{ExportName: "default", LocalName: "Island", Specifier: "./Island.astro", ResolvedPath: "/resolved/Island.astro"}}
+	if diff := test_utils.ANSIDiff(wantServer, result.ServerComponents); diff != "" {
+		t.Errorf("server components mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTransformContentModelWarnings(t *testing.T) {
+	source := "<p><div>text</div></p>"
+
+	result, err := Transform(source, TransformOptions{ContentModelWarnings: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Code, "<p></p><div>text</div>") {
+		t.Errorf("expected the <p> to be closed before the <div>, got\n%s", result.Code)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != int(loc.WARNING_IMPLIED_END_TAG) || result.Diagnostics[0].Location.Column != 4 {
+		t.Fatalf("expected a warning at the <div>, got %v", result.Diagnostics)
+	}
+	if text := result.Diagnostics[0].Text; !strings.Contains(text, "written as `<p><div>…</p>`, parsed as `<p></p><div>…`") {
+		t.Errorf("expected the structure as written and as parsed, got %q", text)
+	}
+
+	result, err = Transform(source, TransformOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("expected no warning by default, got %v", result.Diagnostics)
+	}
+}
+
+func TestTransformValidateExpressions(t *testing.T) {
+	source := "---\nconst items = [];\n---\n<ul>\n\t{items.map((item) => <li>{item.name}</li>))}\n</ul>\n<Card render={() => <b>ok</b>} />"
+
+	result, err := Transform(source, TransformOptions{ValidateExpressions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("expected a single warning, got %v", result.Diagnostics)
+	}
+	d := result.Diagnostics[0]
+	if d.Code != int(loc.WARNING_EXPRESSION_SYNTAX) || d.Severity != int(WarningType) || d.Text != "Invalid expression: Unexpected token ')'" || d.Location.Line != 5 || d.Location.Column != 44 {
+		t.Errorf("expected a warning at the extra parenthesis, got %+v at %+v", d, *d.Location)
+	}
+
+	result, err = Transform(source, TransformOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("expected no warning by default, got %v", result.Diagnostics)
+	}
+}
+
+func TestTransformDirectives(t *testing.T) {
+	source := "---\nimport Counter from '../components/Counter.jsx';\n---\n<Counter client:hover />\n<Counter client:laod />\n<div client:load data:theme=\"blue\" />\n<img data:src=\"\" />"
+	directives := []Directive{
+		{Name: "client:hover", Elements: []string{DirectiveOnComponent}},
+		{Name: "data:*", Elements: []string{DirectiveOnElement}, Validate: func(value string) error {
+			if value == "" {
+				return errors.New("expected a non-empty value")
+			}
+			return nil
+		}},
+	}
+
+	result, err := Transform(source, TransformOptions{Directives: directives})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range result.Diagnostics {
+		got = append(got, fmt.Sprintf("%d %d:%d", d.Code, d.Location.Line, d.Location.Column))
+	}
+	if want := "2017 5:10, 2018 6:6, 2018 7:16"; strings.Join(got, ", ") != want {
+		t.Fatalf("expected %s, got %v", want, result.Diagnostics)
+	}
+	if !strings.Contains(result.Code, `"client:hover":true`) {
+		t.Errorf("expected the custom directive to be kept, got\n%s", result.Code)
+	}
+
+	// Only the built-in client directives are validated by default
+	result, err = Transform(source, TransformOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != int(loc.WARNING_MISUSED_DIRECTIVE) || result.Diagnostics[0].Location.Line != 6 {
+		t.Errorf("expected a warning at `client:load` on <div> by default, got %v", result.Diagnostics)
+	}
+}
+
+func TestTransformForeignContentWarnings(t *testing.T) {
+	source := `<svg viewBox="0 0 24 24"><path stroke_width="2" d="M0 0" /></svg>`
+
+	result, err := Transform(source, TransformOptions{ForeignContentWarnings: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != int(loc.WARNING_UNKNOWN_FOREIGN_ATTRIBUTE) || result.Diagnostics[0].Location.Column != 32 {
+		t.Fatalf("expected a warning at `stroke_width`, got %v", result.Diagnostics)
+	}
+	if hint := result.Diagnostics[0].Hint; hint != "Did you mean `stroke-width`?" {
+		t.Errorf("expected the closest attribute as hint, got %q", hint)
+	}
+
+	result, err = Transform(source, TransformOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("expected no warning by default, got %v", result.Diagnostics)
+	}
+}
+
+func TestTransformAccessibilityWarnings(t *testing.T) {
+	source := "<html>\n<body>\n<img src=\"logo.png\" />\n<div role=\"buton\" tabindex=\"2\"></div>\n</body>\n</html>"
+
+	result, err := Transform(source, TransformOptions{AccessibilityWarnings: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range result.Diagnostics {
+		got = append(got, fmt.Sprintf("%d %d:%d %d", d.Code, d.Location.Line, d.Location.Column, d.Severity))
+	}
+	want := []string{"2027 1:2 2", "2023 3:2 2", "2026 4:12 2", "4001 4:29 4"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	result, err = Transform(source, TransformOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("expected no warning by default, got %v", result.Diagnostics)
+	}
+}
+
+func TestTransformSlots(t *testing.T) {
+	source := "---\nimport Card from './Card.astro';\n---\n<slot name=\"header\">Header</slot>\n<Card><p slot=\"footer\" /></Card>"
+
+	parsed := Parse(source, ParseOptions{})
+	if len(parsed.Slots) != 1 || parsed.Slots[0].Name != "header" || !parsed.Slots[0].HasFallback || parsed.Slots[0].Location.Line != 4 {
+		t.Errorf("unexpected slots: %+v", parsed.Slots)
+	}
+	if len(parsed.Components) != 1 || len(parsed.Components[0].SlotContents) != 1 || parsed.Components[0].SlotContents[0].Name != "footer" || parsed.Components[0].SlotContents[0].Location.Column != 8 {
+		t.Errorf("unexpected components: %+v", parsed.Components)
+	}
+
+	var loaded []string
+	result, err := Transform(source, TransformOptions{Filename: "/src/index.astro", LoadComponent: func(path string) (string, bool) {
+		loaded = append(loaded, path)
+		return "<slot />", true
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Slots) != 1 || result.Slots[0].Name != "header" {
+		t.Errorf("unexpected slots: %+v", result.Slots)
+	}
+	if len(loaded) != 1 || loaded[0] != "/src/Card.astro" {
+		t.Errorf("expected the component to be loaded by its resolved path, got %v", loaded)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != int(loc.WARNING_UNKNOWN_SLOT) || result.Diagnostics[0].Location.Line != 5 {
+		t.Errorf("expected a warning at the content of the footer slot, got %v", result.Diagnostics)
+	}
+}
+
+func TestTransformSourceMap(t *testing.T) {
+	tests := []struct {
+		sourcemap string
+		inline    bool
+		external  bool
+	}{
+		{sourcemap: "", inline: false, external: false},
+		{sourcemap: "inline", inline: true, external: false},
+		{sourcemap: "external", inline: false, external: true},
+		{sourcemap: "both", inline: true, external: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sourcemap, func(t *testing.T) {
+			result, err := Transform(`<div>{value}</div>`, TransformOptions{Filename: "index.astro", SourceMap: tt.sourcemap})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inline := strings.Contains(result.Code, "//# sourceMappingURL="); inline != tt.inline {
+				t.Errorf("inline source map = %v, want %v", inline, tt.inline)
+			}
+			if external := result.Map != ""; external != tt.external {
+				t.Errorf("external source map = %v, want %v", external, tt.external)
+			}
+			if tt.external && !json.Valid([]byte(result.Map)) {
+				t.Errorf("invalid source map: %s", result.Map)
+			}
+		})
+	}
+}
+
+func TestTransformPreprocessStyle(t *testing.T) {
+	source := test_utils.Dedent(`
+		<style lang="scss">$color: red; h1 { color: $color; }</style>
+		<style lang="less">h2 { color: blue; }</style>
+		<style is:global>h3 { color: green; }</style>
+		<style lang="">h4 { color: black; }</style>
+		<h1>Hello</h1>
+	`)
+
+	var calls atomic.Int32
+	result, err := Transform(source, TransformOptions{
+		Filename: "index.astro",
+		PreprocessStyle: PreprocessStyleFunc(func(content string, attrs map[string]*string) (PreprocessorResult, error) {
+			calls.Add(1)
+			lang, ok := attrs["lang"]
+			if !ok {
+				if value, ok := attrs["is:global"]; !ok || value != nil {
+					t.Errorf("expected is:global to be passed without a value, got %v", attrs)
+				}
+				return PreprocessorResult{}, nil
+			}
+			if lang == nil {
+				t.Fatalf("expected lang to be passed with a value, got %v", attrs)
+			}
+			switch *lang {
+			case "scss":
+				return PreprocessorResult{Code: "h1 { color: red; }", Map: `{"version":3,"mappings":"AAAA"}`}, nil
+			case "less":
+				return PreprocessorResult{}, errors.New("less is not installed")
+			case "":
+				return PreprocessorResult{}, nil
+			}
+			t.Errorf("unexpected lang %q", *lang)
+			return PreprocessorResult{}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("expected 4 calls to PreprocessStyle, got %d", calls.Load())
+	}
+	if diff := test_utils.ANSIDiff([]string{"less is not installed"}, result.StyleError); diff != "" {
+		t.Errorf("styleError mismatch (-want +got):\n%s", diff)
+	}
+	css := strings.Join(result.CSS, "\n")
+	if strings.Contains(css, "$color") || !strings.Contains(css, "h1:where(") {
+		t.Errorf("expected the preprocessed style in the output, got %v", result.CSS)
+	}
+	if strings.Contains(css, "h2") {
+		t.Errorf("expected the failing style to be emptied, got %v", result.CSS)
+	}
+	if !strings.Contains(css, "h3") || !strings.Contains(css, "h4") {
+		t.Errorf("expected the skipped styles to be kept, got %v", result.CSS)
+	}
+	// The emptied style isn't printed, so there is a map for each of the other styles
+	if len(result.CSSMaps) != len(result.CSS) {
+		t.Fatalf("expected a map for each style, got %d maps for %d styles", len(result.CSSMaps), len(result.CSS))
+	}
+	for i, css := range result.CSS {
+		want := ""
+		if strings.Contains(css, "h1") {
+			want = `{"version":3,"mappings":"AAAA"}`
+		}
+		if result.CSSMaps[i] != want {
+			t.Errorf("expected map %q for %q, got %q", want, css, result.CSSMaps[i])
+		}
+	}
+}
+
+// sassPreprocessor is a StylePreprocessor implemented by a type, as Go callers may do
+type sassPreprocessor struct {
+	variables map[string]string
+}
+
+func (p sassPreprocessor) PreprocessStyle(content string, attrs map[string]*string) (PreprocessorResult, error) {
+	if lang := attrs["lang"]; lang == nil || *lang != "scss" {
+		return PreprocessorResult{}, nil
+	}
+	for name, value := range p.variables {
+		content = strings.ReplaceAll(content, "$"+name, value)
+	}
+	return PreprocessorResult{Code: content}, nil
+}
+
+func TestTransformStylePreprocessor(t *testing.T) {
+	result, err := Transform(`<style lang="scss">h1 { color: $primary; }</style><h1>Hello</h1>`, TransformOptions{
+		Filename:        "index.astro",
+		PreprocessStyle: sassPreprocessor{variables: map[string]string{"primary": "rebeccapurple"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.CSS) != 1 || !strings.Contains(result.CSS[0], "color:rebeccapurple") {
+		t.Errorf("expected the preprocessed style, got %v", result.CSS)
+	}
+	if diff := test_utils.ANSIDiff([]string{""}, result.CSSMaps); diff != "" {
+		t.Errorf("cssMaps mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTransformPreprocessStylePanic(t *testing.T) {
+	source := `<style lang="scss">h1 { color: red; }</style><style>h2 { color: blue; }</style><h1>Hello</h1>`
+	result, err := Transform(source, TransformOptions{
+		Filename: "index.astro",
+		PreprocessStyle: PreprocessStyleFunc(func(content string, attrs map[string]*string) (PreprocessorResult, error) {
+			if _, ok := attrs["lang"]; ok {
+				panic("sass is broken")
+			}
+			return PreprocessorResult{}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := test_utils.ANSIDiff([]string{"style preprocessor panicked: sass is broken"}, result.StyleError); diff != "" {
+		t.Errorf("styleError mismatch (-want +got):\n%s", diff)
+	}
+	css := strings.Join(result.CSS, "\n")
+	if strings.Contains(css, "h1") || !strings.Contains(css, "h2") {
+		t.Errorf("expected only the style of the panic to be emptied, got %v", result.CSS)
+	}
+}
+
+func TestTransformContext(t *testing.T) {
+	source := test_utils.Dedent(`
+		---
+		import Counter from './Counter.jsx';
+		---
+		<style lang="scss">h1 { color: red; }</style>
+		<Counter client:load />
+	`)
+
+	t.Run("timeout", func(t *testing.T) {
+		// The preprocessor never settles until the test ends
+		release := make(chan struct{})
+		defer close(release)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := TransformContext(ctx, source, TransformOptions{
+			Filename: "/src/index.astro",
+			PreprocessStyle: PreprocessStyleFunc(func(content string, attrs map[string]*string) (PreprocessorResult, error) {
+				<-release
+				return PreprocessorResult{}, nil
+			}),
+		})
+		var cancelled *CancelledError
+		if !errors.As(err, &cancelled) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the transform to time out, got %v", err)
+		}
+		if want := "transform of /src/index.astro was cancelled: context deadline exceeded"; err.Error() != want {
+			t.Errorf("want: %q\n got: %q", want, err.Error())
+		}
+		diagnostic := cancelled.Diagnostic()
+		if diagnostic.Code != int(loc.ERROR_CANCELLED) || diagnostic.Severity != int(ErrorType) || diagnostic.Location.File != "/src/index.astro" {
+			t.Errorf("unexpected diagnostic %+v", diagnostic)
+		}
+	})
+
+	t.Run("cause", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		resolved := false
+		_, err := TransformContext(ctx, source, TransformOptions{
+			Filename: "/src/index.astro",
+			PreprocessStyle: PreprocessStyleFunc(func(content string, attrs map[string]*string) (PreprocessorResult, error) {
+				cancel(errors.New("the file changed"))
+				return PreprocessorResult{}, nil
+			}),
+			ResolvePath: func(specifier string) string {
+				resolved = true
+				return specifier
+			},
+		})
+		if !errors.Is(err, context.Canceled) || !strings.HasSuffix(err.Error(), "was cancelled: the file changed") {
+			t.Errorf("expected the transform to be cancelled with its cause, got %v", err)
+		}
+		if resolved {
+			t.Error("expected ResolvePath not to be called once the transform is cancelled")
+		}
+	})
+
+	t.Run("not cancelled", func(t *testing.T) {
+		result, err := TransformContext(context.Background(), source, TransformOptions{Filename: "/src/index.astro"})
+		if err != nil || len(result.CSS) != 1 || len(result.HydratedComponents) != 1 {
+			t.Errorf("unexpected result %+v, %v", result, err)
+		}
+	})
+}
+
+func TestTransformPasses(t *testing.T) {
+	source := `<a href="/about" data-track="nav">About</a><button data-track>Buy</button>`
+
+	result, err := Transform(source, TransformOptions{
+		Filename: "index.astro",
+		Passes: []TransformPass{{
+			Name:   "track",
+			Before: []string{PassAddComponentProps},
+			Enter: func(ctx *TransformPassContext, n *Node) {
+				for i, attr := range n.Attr {
+					if attr.Key != "data-track" {
+						continue
+					}
+					if attr.Val == "" {
+						ctx.Handler.AppendWarning(&ErrorWithRange{
+							Text:  "data-track needs an event name",
+							Range: Range{Loc: attr.KeyLoc, Len: len(attr.Key)},
+						})
+						continue
+					}
+					n.Attr[i].Key = "data-event"
+				}
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Code, `data-event="nav"`) {
+		t.Errorf("expected the custom pass to rewrite the attribute, got:\n%s", result.Code)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Text != "data-track needs an event name" || result.Diagnostics[0].Location.Column != 52 {
+		t.Errorf("expected a warning from the custom pass, got %+v", result.Diagnostics)
+	}
+}
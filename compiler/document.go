@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+)
+
+// An Edit replaces the bytes of a source between Start and End with Text
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// A TSXDocument is a component which is converted to TSX again after each edit, like a document
+// open in an editor. Only the part of the source around an edit is parsed again, with the same
+// result as ConvertToTSX for the whole source. A TSXDocument is not safe for concurrent use.
+type TSXDocument struct {
+	source string
+	opts   TSXOptions
+	doc    *astro.Node
+	// parsed holds the diagnostics of the parser, which are kept for the parts of the source that
+	// are not parsed again
+	parsed *handler.Handler
+}
+
+func NewTSXDocument(source string, opts TSXOptions) *TSXDocument {
+	d := &TSXDocument{opts: opts}
+	d.parse(source)
+	return d
+}
+
+func (d *TSXDocument) parse(source string) {
+	d.source = source
+	d.parsed = handler.NewHandler(source, d.opts.filename())
+	doc, err := astro.ParseWithOptions(strings.NewReader(source), append(d.opts.parseOptions(d.parsed), astro.ParseOptionEnableIncremental(true))...)
+	if err != nil {
+		d.parsed.AppendError(err)
+	}
+	d.doc = doc
+}
+
+// Source returns the source of the document, with the edits applied
+func (d *TSXDocument) Source() string {
+	return d.source
+}
+
+// Edit applies edit to the source of the document. An error is only returned when the edit is out
+// of the range of the source, which is then left unchanged.
+func (d *TSXDocument) Edit(edit Edit) error {
+	if edit.Start < 0 || edit.Start > edit.End || edit.End > len(d.source) {
+		return fmt.Errorf("edit [%d, %d) is out of range for a source of length %d", edit.Start, edit.End, len(d.source))
+	}
+	e := astro.Edit{Start: edit.Start, End: edit.End, Text: edit.Text}
+	text := e.Apply(d.source)
+	h := handler.NewHandler(text, d.opts.filename())
+	doc, reparsed, err := astro.Reparse(d.doc, d.source, e, d.opts.parseOptions(h)...)
+	if err != nil {
+		// The error of the parser is reported like ConvertToTSX does
+		d.parse(text)
+		return nil
+	}
+	if reparsed.Start == 0 && reparsed.End == len(text) {
+		d.parsed = h
+	} else {
+		delta := len(text) - len(d.source)
+		d.parsed = d.parsed.Replace(text, reparsed.Start, reparsed.End-delta, reparsed.End, h)
+	}
+	d.source, d.doc = text, doc
+	return nil
+}
+
+// ConvertToTSX returns the TSX of the current source, like ConvertToTSX
+func (d *TSXDocument) ConvertToTSX() TSXResult {
+	h := handler.NewHandler(d.source, d.opts.filename())
+	h.AppendAll(d.parsed)
+	// The tree is transformed after printing, so the one kept for the next edit is copied
+	return convertToTSX(d.source, astro.CloneTree(d.doc), d.opts, h)
+}
@@ -0,0 +1,58 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTSXDocument(t *testing.T) {
+	sources := []string{
+		"---\nconst items = ['a', 'b'];\n---\n<main>\n\t<h1>Hello <em>world</em></h1>\n\t<ul>{items.map(item => <li>{item}</li>)}</ul>\n</main>\n<style>main { color: red; }</style>",
+		"<div>\n\t<p>One</p>\n\t<p>Two {unclosed</p>\n</div>\n<section><span>}</span></section>",
+		"<Layout title={title}>\n\t<table><tr><td>Cell</td></tr></table>\n\t<img src={src}>\n</Layout>",
+	}
+	edits := []string{"a", "<b>", "</p>", "{x}", "}", "{"}
+
+	for _, recover := range []bool{false, true} {
+		opts := TSXOptions{Filename: "Component.astro", IncludeScripts: true, IncludeStyles: true, Recover: recover}
+		for _, source := range sources {
+			// Each edit is checked on a document which is kept between the edits
+			d := NewTSXDocument(source, opts)
+			for i := 0; i <= len(source); i++ {
+				edit := Edit{Start: i, End: i, Text: edits[i%len(edits)]}
+				if i%3 == 2 && i < len(source) {
+					edit = Edit{Start: i, End: i + 1}
+				}
+				text := source[:edit.Start] + edit.Text + source[edit.End:]
+				if err := d.Edit(edit); err != nil {
+					t.Fatal(err)
+				}
+				if got, want := d.ConvertToTSX(), ConvertToTSX(text, opts); !reflect.DeepEqual(got, want) {
+					t.Fatalf("after %+v in %q (recover: %v):\ngot  %+v\nwant %+v", edit, source, recover, got, want)
+				}
+				// The edit is undone, which is another edit
+				if err := d.Edit(Edit{Start: edit.Start, End: edit.Start + len(edit.Text), Text: source[edit.Start:edit.End]}); err != nil {
+					t.Fatal(err)
+				}
+				if d.Source() != source {
+					t.Fatalf("expected %q after undoing %+v, got %q", source, edit, d.Source())
+				}
+			}
+			if got, want := d.ConvertToTSX(), ConvertToTSX(source, opts); !reflect.DeepEqual(got, want) {
+				t.Fatalf("after undoing the edits of %q:\ngot  %+v\nwant %+v", source, got, want)
+			}
+		}
+	}
+}
+
+func TestTSXDocumentEditOutOfRange(t *testing.T) {
+	source := "<div></div>"
+	d := NewTSXDocument(source, TSXOptions{})
+	if err := d.Edit(Edit{Start: 4, End: 20}); err == nil {
+		t.Error("expected an error for an edit out of range")
+	}
+	if d.Source() != source || !strings.Contains(d.ConvertToTSX().Code, "<div></div>") {
+		t.Errorf("expected the document to be unchanged, got %q", d.Source())
+	}
+}
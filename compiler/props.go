@@ -0,0 +1,55 @@
+package compiler
+
+import (
+	"encoding/json"
+	"strings"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/props"
+)
+
+type PropsOptions struct {
+	Filename string
+}
+
+type PropsResult struct {
+	// Schema is the JSON encoded JSON Schema of the Props, or empty when the component doesn't
+	// declare Props
+	Schema      string              `js:"schema" json:"schema"`
+	Diagnostics []DiagnosticMessage `js:"diagnostics" json:"diagnostics"`
+}
+
+// ExtractProps returns the JSON Schema of the `Props` interface or type declared in the frontmatter
+// of an Astro component, e.g. for documentation or editors. Each property has its type, its JSDoc
+// description and the default value given to it by the destructuring of `Astro.props`. Types which
+// have no JSON Schema equivalent are described by their TypeScript source in a "tsType" keyword.
+// Extracting never fails: problems are reported in PropsResult.Diagnostics.
+func ExtractProps(source string, opts PropsOptions) PropsResult {
+	filename := opts.Filename
+	if filename == "" {
+		filename = "<stdin>"
+	}
+	h := handler.NewHandler(source, filename)
+
+	doc, err := astro.ParseWithOptions(strings.NewReader(source), astro.ParseOptionWithHandler(h), astro.ParseOptionEnableLiteral(true), astro.ParseOptionEnableFrontmatterAST(true))
+	if err != nil {
+		h.AppendError(err)
+	}
+	result := PropsResult{}
+	if doc != nil && doc.FirstChild != nil && doc.FirstChild.Type == astro.FrontmatterNode && doc.FirstChild.Program != nil {
+		if schema := props.Extract(doc.FirstChild.Program, source); schema != nil {
+			var b strings.Builder
+			encoder := json.NewEncoder(&b)
+			// The TypeScript types of tsType, like "() => void", are written as is
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(schema); err != nil {
+				h.AppendError(err)
+			} else {
+				result.Schema = strings.TrimSuffix(b.String(), "\n")
+			}
+		}
+	}
+	result.Diagnostics = h.Diagnostics()
+	return result
+}
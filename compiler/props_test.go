@@ -0,0 +1,32 @@
+package compiler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractProps(t *testing.T) {
+	source := "---\n/** A card */\ninterface Props {\n  /** The title */\n  title: string;\n  size?: 'sm' | 'lg';\n  onClick?: () => void;\n}\nconst { size = 'sm' } = Astro.props;\n---\n<h1>{Astro.props.title}</h1>"
+	result := ExtractProps(source, PropsOptions{})
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %v", result.Diagnostics)
+	}
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","description":"A card","type":"object","properties":{"title":{"description":"The title","type":"string"},"size":{"type":"string","enum":["sm","lg"],"default":"sm"},"onClick":{"tsType":"() => void"}},"required":["title"]}`
+	if result.Schema != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, result.Schema)
+	}
+	if !json.Valid([]byte(result.Schema)) {
+		t.Errorf("invalid JSON: %s", result.Schema)
+	}
+
+	for _, source := range []string{"<p>No frontmatter</p>", "---\nconst a = 1;\n---\n<p>{a}</p>"} {
+		if result := ExtractProps(source, PropsOptions{}); result.Schema != "" || len(result.Diagnostics) != 0 {
+			t.Errorf("expected no schema for %q, got %+v", source, result)
+		}
+	}
+
+	result = ExtractProps("---\ninterface Props { title: }\n---", PropsOptions{Filename: "Card.astro"})
+	if result.Schema != "" || len(result.Diagnostics) != 1 || result.Diagnostics[0].Location.File != "Card.astro" {
+		t.Errorf("expected a syntax error of the frontmatter, got %+v", result)
+	}
+}
@@ -0,0 +1,131 @@
+package compiler
+
+import (
+	"unicode/utf8"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+type TokenizeOptions struct {
+	Filename string
+}
+
+type TokenizeResult struct {
+	// Tokens cover the whole source, in order
+	Tokens      []Token             `js:"tokens" json:"tokens"`
+	Diagnostics []DiagnosticMessage `js:"diagnostics" json:"diagnostics"`
+}
+
+// Token is a token of an Astro component, as written in the source
+type Token struct {
+	// Type is one of "text", "start-tag", "end-tag", "self-closing-tag", "comment", "doctype",
+	// "frontmatter-fence", "start-expression" or "end-expression". The content of the frontmatter,
+	// of expressions, scripts and styles is text.
+	Type  string     `js:"type" json:"type"`
+	Raw   string     `js:"raw" json:"raw"`
+	Range TokenRange `js:"range" json:"range"`
+	// Name is the name of a tag, and Attributes its attributes
+	Name       string           `js:"name" json:"name,omitempty"`
+	Attributes []TokenAttribute `js:"attributes" json:"attributes,omitempty"`
+}
+
+type TokenAttribute struct {
+	// Kind is one of "quoted", "empty", "expression", "spread", "shorthand" or "template-literal",
+	// as the kind of an attribute of the AST
+	Kind string `js:"kind" json:"kind"`
+	Name string `js:"name" json:"name"`
+	// NameRange excludes the braces of a shorthand attribute and the "..." of a spread attribute
+	NameRange TokenRange `js:"nameRange" json:"nameRange"`
+	Value     string     `js:"value" json:"value"`
+	// ValueRange excludes quotes and braces. It is empty, at the end of the name, for the attributes without a value.
+	ValueRange TokenRange `js:"valueRange" json:"valueRange"`
+	// Quote is the quote around the value: `"`, `'` or "`", or empty when the value isn't quoted
+	Quote string `js:"quote" json:"quote"`
+}
+
+// TokenRange is a range of the source, as offsets in bytes and in UTF-16 code units. The latter
+// index the source as a JS string.
+type TokenRange struct {
+	Start      int `js:"start" json:"start"`
+	End        int `js:"end" json:"end"`
+	UTF16Start int `js:"utf16Start" json:"utf16Start"`
+	UTF16End   int `js:"utf16End" json:"utf16End"`
+}
+
+// Tokenize splits an Astro component into tokens, e.g. for syntax highlighting. Unlike Parse,
+// it keeps the source as written: no tag is implied or closed. Tokenizing never fails: problems
+// are reported in TokenizeResult.Diagnostics.
+func Tokenize(source string, opts TokenizeOptions) TokenizeResult {
+	filename := opts.Filename
+	if filename == "" {
+		filename = "<stdin>"
+	}
+	h := handler.NewHandler(source, filename)
+	offsets := utf16Offsets(source)
+	span := func(s loc.Span) TokenRange {
+		return TokenRange{Start: s.Start, End: s.End, UTF16Start: offsets[s.Start], UTF16End: offsets[s.End]}
+	}
+
+	tokens := []Token{}
+	for _, t := range astro.Tokenize([]byte(source), h) {
+		token := Token{
+			Type:       tokenTypes[t.Type],
+			Raw:        source[t.Raw.Start:t.Raw.End],
+			Range:      span(t.Raw),
+			Name:       t.Data,
+			Attributes: []TokenAttribute{},
+		}
+		for _, attr := range t.Attr {
+			attribute := TokenAttribute{
+				Kind:       attr.Type.String(),
+				Name:       source[attr.Key.Start:attr.Key.End],
+				NameRange:  span(attr.Key),
+				Value:      source[attr.Val.Start:attr.Val.End],
+				ValueRange: span(attr.Val),
+			}
+			if attr.Quote != 0 {
+				attribute.Quote = string(attr.Quote)
+			}
+			token.Attributes = append(token.Attributes, attribute)
+		}
+		tokens = append(tokens, token)
+	}
+	return TokenizeResult{
+		Tokens:      tokens,
+		Diagnostics: h.Diagnostics(),
+	}
+}
+
+var tokenTypes = map[astro.TokenType]string{
+	astro.TextToken:             "text",
+	astro.StartTagToken:         "start-tag",
+	astro.EndTagToken:           "end-tag",
+	astro.SelfClosingTagToken:   "self-closing-tag",
+	astro.CommentToken:          "comment",
+	astro.DoctypeToken:          "doctype",
+	astro.FrontmatterFenceToken: "frontmatter-fence",
+	astro.StartExpressionToken:  "start-expression",
+	astro.EndExpressionToken:    "end-expression",
+}
+
+// utf16Offsets maps each byte offset of source, up to len(source), to an offset in UTF-16 code units
+func utf16Offsets(source string) []int {
+	offsets := make([]int, len(source)+1)
+	n := 0
+	for i := 0; i < len(source); {
+		// Invalid bytes are decoded one by one, as U+FFFD
+		r, size := utf8.DecodeRuneInString(source[i:])
+		for j := i; j < i+size; j++ {
+			offsets[j] = n
+		}
+		i += size
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	offsets[len(source)] = n
+	return offsets
+}
@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestTokenize(t *testing.T) {
+	source := "---\nconst title = 'Héllo';\n---\n<h1 class=\"big\" id='a' data-x=`b` {title} {...props} hidden style=c>🚀 {title}</h1>\n<!-- done -->"
+	result := Tokenize(source, TokenizeOptions{})
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %v", result.Diagnostics)
+	}
+
+	var raw strings.Builder
+	types := make([]string, 0)
+	for _, token := range result.Tokens {
+		raw.WriteString(token.Raw)
+		types = append(types, token.Type)
+		if source[token.Range.Start:token.Range.End] != token.Raw {
+			t.Errorf("expected the range of %q to match its raw text", token.Raw)
+		}
+	}
+	if raw.String() != source {
+		t.Errorf("expected the tokens to cover the source, got %q", raw.String())
+	}
+	expected := "frontmatter-fence text text frontmatter-fence start-tag text start-expression text end-expression end-tag text comment"
+	if strings.Join(types, " ") != expected {
+		t.Errorf("expected tokens\n%s\ngot\n%s", expected, strings.Join(types, " "))
+	}
+
+	h1 := result.Tokens[4]
+	if h1.Name != "h1" || len(h1.Attributes) != 7 {
+		t.Fatalf("unexpected tag: %+v", h1)
+	}
+	attributes := []struct{ kind, name, value, quote string }{
+		{"quoted", "class", "big", `"`},
+		{"quoted", "id", "a", "'"},
+		{"template-literal", "data-x", "b", "`"},
+		{"shorthand", "title", "", ""},
+		{"spread", "props", "", ""},
+		{"empty", "hidden", "", ""},
+		{"quoted", "style", "c", ""},
+	}
+	for i, want := range attributes {
+		got := h1.Attributes[i]
+		if got.Kind != want.kind || got.Name != want.name || got.Value != want.value || got.Quote != want.quote {
+			t.Errorf("expected attribute %+v, got %+v", want, got)
+		}
+		if source[got.NameRange.Start:got.NameRange.End] != got.Name || source[got.ValueRange.Start:got.ValueRange.End] != got.Value {
+			t.Errorf("expected the ranges of %s to match its name and value", got.Name)
+		}
+	}
+
+	// UTF-16 offsets index the source as a JS string
+	js := utf16.Encode([]rune(source))
+	for _, token := range result.Tokens {
+		if string(utf16.Decode(js[token.Range.UTF16Start:token.Range.UTF16End])) != token.Raw {
+			t.Errorf("expected the UTF-16 range of %q to match its raw text", token.Raw)
+		}
+	}
+}
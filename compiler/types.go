@@ -0,0 +1,283 @@
+package compiler
+
+import (
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+	"github.com/withastro/compiler/internal/printer"
+	"github.com/withastro/compiler/internal/transform"
+)
+
+type (
+	DiagnosticMessage  = loc.DiagnosticMessage
+	DiagnosticLocation = loc.DiagnosticLocation
+	DiagnosticSeverity = loc.DiagnosticSeverity
+	TSXRange           = loc.TSXRange
+	TSXRanges          = printer.TSXRanges
+	TSXExtractedTag    = printer.TSXExtractedTag
+
+	Node                 = astro.Node
+	Attribute            = astro.Attribute
+	Handler              = handler.Handler
+	DiagnosticCode       = loc.DiagnosticCode
+	ErrorWithRange       = loc.ErrorWithRange
+	Range                = loc.Range
+	Loc                  = loc.Loc
+	TransformPass        = transform.Pass
+	TransformPassContext = transform.PassContext
+	Directive            = transform.Directive
+)
+
+const (
+	ErrorType       = loc.ErrorType
+	WarningType     = loc.WarningType
+	InformationType = loc.InformationType
+	HintType        = loc.HintType
+)
+
+// Names of the built-in transform passes, in the order they run, for the Before and After
+// constraints of a TransformPass
+const (
+	PassWarnRerunOnExternalESMs     = transform.PassWarnRerunOnExternalESMs
+	PassWarnMisplacedReload         = transform.PassWarnMisplacedReload
+	PassHintImplicitInlineDirective = transform.PassHintImplicitInlineDirective
+	PassValidateDirectives          = transform.PassValidateDirectives
+	PassExtractScript               = transform.PassExtractScript
+	PassRecordComponents            = transform.PassRecordComponents
+	PassLintAccessibility           = transform.PassLintAccessibility
+	PassAddComponentProps           = transform.PassAddComponentProps
+	PassScopeElement                = transform.PassScopeElement
+	PassTransitions                 = transform.PassTransitions
+	PassDefineVars                  = transform.PassDefineVars
+	PassMergeClassList              = transform.PassMergeClassList
+	PassContainsHead                = transform.PassContainsHead
+	PassAnnotateSourceFile          = transform.PassAnnotateSourceFile
+)
+
+// Kinds of elements for Directive.Elements, besides the name of an element like "script"
+const (
+	DirectiveOnComponent     = transform.DirectiveOnComponent
+	DirectiveOnCustomElement = transform.DirectiveOnCustomElement
+	DirectiveOnFragment      = transform.DirectiveOnFragment
+	DirectiveOnElement       = transform.DirectiveOnElement
+)
+
+// StylePreprocessor preprocesses the content of a <style> tag, e.g. to compile Sass to CSS.
+// attrs holds the static attributes of the tag; the value of an attribute written without one
+// (like `is:global`) is nil, unlike an empty quoted value (like `lang=""`). The styles of a
+// component are preprocessed concurrently.
+//
+// Returning an empty Code leaves the style untouched. Returning an error empties the style, so
+// that its source is never emitted as CSS, and reports the error in TransformResult.StyleError.
+// A panic is reported the same way.
+type StylePreprocessor = transform.StylePreprocessor
+
+// PreprocessorResult is the preprocessed code of a style, with an optional JSON source map
+// from Code to the original content of the style, reported in TransformResult.CSSMaps.
+type PreprocessorResult = transform.PreprocessorResult
+
+// PreprocessStyleFunc is a function implementing StylePreprocessor
+type PreprocessStyleFunc = transform.StylePreprocessorFunc
+
+type ParseOptions struct {
+	Filename string
+	// Position adds the source position of each node to the AST
+	Position bool
+	// Recover parses an incomplete component, e.g. while it is being edited: an unterminated
+	// frontmatter or expression, a stray "}" and unbalanced tags are closed at the nearest
+	// boundary. Each recovery adds an "error" node to the AST and a diagnostic.
+	Recover bool
+	// FrontmatterAST parses the frontmatter as TypeScript and adds its ESTree AST to the
+	// "program" of the frontmatter node. A syntax error is reported as a diagnostic.
+	FrontmatterAST bool
+	// ValidateExpressions parses the expressions of the template and of the attribute values as
+	// JavaScript with JSX, and reports their syntax errors as warnings
+	ValidateExpressions bool
+}
+
+type ParseFragmentOptions struct {
+	ParseOptions
+	// Context is the name of the element the fragment is parsed in, like "tbody" or "svg".
+	// Defaults to "body".
+	Context string
+}
+
+type TransformOptions struct {
+	Filename string
+	// NormalizedFilename is used to compute the scope hash. Defaults to Filename.
+	NormalizedFilename string
+	// InternalURL is the import specifier of the Astro runtime. Defaults to "astro/runtime/server/index.js".
+	InternalURL string
+	// SourceMap is one of "inline", "external" or "both". Source maps are omitted when empty.
+	SourceMap       string
+	AstroGlobalArgs string
+	Compact         bool
+	// ResultScopedSlot passes $$result to slot functions
+	ResultScopedSlot bool
+	// ScopedStyleStrategy is one of "where", "class" or "attribute". Defaults to "where".
+	ScopedStyleStrategy string
+	// TransitionsAnimationURL defaults to "astro/components/viewtransitions.css".
+	TransitionsAnimationURL string
+	AnnotateSourceFile      bool
+	// RenderScript renders processed scripts using `renderScript` from InternalURL instead of hoisting them.
+	RenderScript bool
+	// ResolvePath resolves the specifier of a hydrated or server component.
+	// When nil, relative specifiers are resolved against Filename.
+	ResolvePath     func(specifier string) string
+	PreprocessStyle StylePreprocessor
+	// LoadComponent returns the source of the .astro component at path, a specifier resolved like
+	// with ResolvePath, or false when it can't be loaded. When set, the content passed to a slot
+	// which the .astro component doesn't define, and which is never rendered, is reported as a warning.
+	LoadComponent func(path string) (source string, ok bool)
+	// ContentModelWarnings warns about the markup restructured by the HTML parsing rules, like a
+	// <div> closing the <p> it is written in or a <div> moved out of a <table>, with its structure as
+	// written and as rendered. An element whose end tag is omitted, like the <p> of
+	// `<p>a<div>b</div>`, and the content which isn't restructured, like a <div> in a <ul>, are not
+	// warned about.
+	ContentModelWarnings bool
+	// ForeignContentWarnings warns about the mistakes in inline SVG and MathML: elements and
+	// attributes written with the wrong casing, like `viewbox`, unknown attributes, like
+	// `stroke_width`, and HTML elements outside of a <foreignObject>.
+	ForeignContentWarnings bool
+	// ValidateExpressions parses the expressions of the template and of the attribute values as
+	// JavaScript with JSX, and reports their syntax errors as warnings
+	ValidateExpressions bool
+	// AccessibilityWarnings reports the accessibility mistakes of the template: images without
+	// `alt`, links, buttons and form controls without an accessible name or label, unknown or
+	// invalid ARIA attributes and roles, positive `tabindex` values, an <html> without `lang` and
+	// duplicate ids. Each rule has its own diagnostic code, and the attributes written as
+	// expressions or spread are not checked.
+	AccessibilityWarnings bool
+	// Directives declares the custom directives of integrations, like `client:hover` or `data:*`.
+	// When not nil, the attributes in the namespace of a directive are validated against these and
+	// the built-in directives: unknown directives, directives on the wrong kind of element and
+	// missing or invalid values are reported as warnings. When nil, only the built-in client
+	// directives are validated, like a `client:media` without a media query or a `client:load` on
+	// an HTML element.
+	Directives []Directive
+	// Passes visit the document along with the built-in transform passes, e.g. to implement
+	// custom directives or lint rules. They run in the order given by their Before and After
+	// constraints, or after the built-in passes. Diagnostics appended to the Handler of the
+	// TransformPassContext are reported in TransformResult.Diagnostics.
+	Passes []TransformPass
+}
+
+type TSXOptions struct {
+	Filename           string
+	NormalizedFilename string
+	// SourceMap set to "external" omits the inline source map comment from the code.
+	SourceMap string
+	// IncludeScripts includes the content of script tags in the generated TSX
+	IncludeScripts bool
+	// IncludeStyles includes the content of style tags in the generated TSX
+	IncludeStyles bool
+	// Recover parses an incomplete component with recovery, as ParseOptions.Recover does
+	Recover bool
+}
+
+type HoistedScript struct {
+	Code string `js:"code" json:"code,omitempty"`
+	Src  string `js:"src" json:"src,omitempty"`
+	Type string `js:"type" json:"type"`
+	Map  string `js:"map" json:"map,omitempty"`
+}
+
+type HydratedComponent struct {
+	ExportName   string `js:"exportName" json:"exportName"`
+	LocalName    string `js:"localName" json:"localName"`
+	Specifier    string `js:"specifier" json:"specifier"`
+	ResolvedPath string `js:"resolvedPath" json:"resolvedPath"`
+}
+
+// ComponentUsage is a component rendered in the template
+type ComponentUsage struct {
+	ExportName   string `js:"exportName" json:"exportName"`
+	LocalName    string `js:"localName" json:"localName"`
+	Specifier    string `js:"specifier" json:"specifier"`
+	ResolvedPath string `js:"resolvedPath" json:"resolvedPath"`
+	// Attributes are the names of the attributes passed to the component, like "...props" for a
+	// spread attribute
+	Attributes []string `js:"attributes" json:"attributes"`
+	// Slots are the names of the slots filled by the children of the component, "default" for the
+	// children without a slot attribute
+	Slots []string `js:"slots" json:"slots"`
+	// SlotContents are the children of the component filling a slot, in order
+	SlotContents []SlotContent      `js:"slotContents" json:"slotContents"`
+	Location     DiagnosticLocation `js:"location" json:"location"`
+}
+
+// Slot is a slot defined by a <slot> element of the template
+type Slot struct {
+	// Name is the name of the slot, "default" when it isn't named, or the code of the expression
+	// naming a dynamic slot
+	Name    string `js:"name" json:"name"`
+	Dynamic bool   `js:"dynamic" json:"dynamic"`
+	// HasFallback reports whether the slot has fallback content, rendered when no content is passed to it
+	HasFallback bool               `js:"hasFallback" json:"hasFallback"`
+	Location    DiagnosticLocation `js:"location" json:"location"`
+}
+
+// SlotContent is a child of a component filling a slot
+type SlotContent struct {
+	// Name is the name of the slot, "default" for a child without a slot attribute, or the code of
+	// the expression naming a dynamic slot, like `slot={tab}`
+	Name    string `js:"name" json:"name"`
+	Dynamic bool   `js:"dynamic" json:"dynamic"`
+	// Location is the location of the name of the element, or of the start of the text
+	Location DiagnosticLocation `js:"location" json:"location"`
+}
+
+// ComponentImport is an import of a component which the component never uses
+type ComponentImport struct {
+	ExportName   string `js:"exportName" json:"exportName"`
+	LocalName    string `js:"localName" json:"localName"`
+	Specifier    string `js:"specifier" json:"specifier"`
+	ResolvedPath string `js:"resolvedPath" json:"resolvedPath"`
+	// Location is the location of the import statement
+	Location DiagnosticLocation `js:"location" json:"location"`
+}
+
+type ParseResult struct {
+	// AST is the JSON encoded syntax tree
+	AST         string              `js:"ast" json:"ast"`
+	Diagnostics []DiagnosticMessage `js:"diagnostics" json:"diagnostics"`
+	// Slots are the slots defined by the <slot> elements of the template, in order
+	Slots []Slot `js:"slots" json:"slots"`
+	// Components are the components rendered in the template, with the slots they fill, as in
+	// TransformResult.Components
+	Components []ComponentUsage `js:"components" json:"components"`
+}
+
+type TSXResult struct {
+	Code string `js:"code" json:"code"`
+	// Map is the JSON encoded source map
+	Map         string              `js:"map" json:"map"`
+	Diagnostics []DiagnosticMessage `js:"diagnostics" json:"diagnostics"`
+	Ranges      TSXRanges           `js:"metaRanges" json:"metaRanges"`
+}
+
+type TransformResult struct {
+	Code        string              `js:"code" json:"code"`
+	Diagnostics []DiagnosticMessage `js:"diagnostics" json:"diagnostics"`
+	Map         string              `js:"map" json:"map"`
+	Scope       string              `js:"scope" json:"scope"`
+	CSS         []string            `js:"css" json:"css"`
+	// CSSMaps holds the source map returned by the StylePreprocessor for each entry of CSS, or
+	// an empty string. It maps the preprocessed style, before scoping, to its original content.
+	CSSMaps              []string            `js:"cssMaps" json:"cssMaps"`
+	Scripts              []HoistedScript     `js:"scripts" json:"scripts"`
+	HydratedComponents   []HydratedComponent `js:"hydratedComponents" json:"hydratedComponents"`
+	ClientOnlyComponents []HydratedComponent `js:"clientOnlyComponents" json:"clientOnlyComponents"`
+	ServerComponents     []HydratedComponent `js:"serverComponents" json:"serverComponents"`
+	// Components are the components rendered in the template, in order, with the imports they
+	// match. The components which aren't imported, like `Astro.self`, have no specifier.
+	Components []ComponentUsage `js:"components" json:"components"`
+	// UnusedImports are the imports of components, like `import Card from "./Card.astro"`, which
+	// are neither rendered nor referenced in the frontmatter or the expressions of the template
+	UnusedImports []ComponentImport `js:"unusedImports" json:"unusedImports"`
+	// Slots are the slots defined by the <slot> elements of the template, in order
+	Slots        []Slot   `js:"slots" json:"slots"`
+	ContainsHead bool     `js:"containsHead" json:"containsHead"`
+	StyleError   []string `js:"styleError" json:"styleError"`
+	Propagation  bool     `js:"propagation" json:"propagation"`
+}
@@ -0,0 +1,229 @@
+package astro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/withastro/compiler/internal/loc"
+	a "golang.org/x/net/html/atom"
+)
+
+// impliedEndTag is a start tag closing the elements it is written in, like the <div> of
+// "<p><div>". It is only reported once one of the elements is closed by its own end tag,
+// see warnImpliedEndTag: otherwise the end tag was omitted, which is allowed.
+type impliedEndTag struct {
+	tag Token
+	// closed are the elements closed by tag, outermost first
+	closed []*Node
+}
+
+func (p *parser) appendContentModelWarning(err *loc.ErrorWithRange) {
+	if p.handler != nil {
+		p.handler.AppendWarning(err)
+	}
+}
+
+// warnFosterParented warns about n, written in a table, which the foster parenting rules move
+// before the table (section 12.2.6.1)
+func (p *parser) warnFosterParented(n *Node) {
+	if !p.contentModel {
+		return
+	}
+	table, template := -1, -1
+	for i := len(p.oe) - 1; i >= 0; i-- {
+		if table == -1 && p.oe[i].DataAtom == a.Table {
+			table = i
+		}
+		if template == -1 && p.oe[i].DataAtom == a.Template {
+			template = i
+		}
+	}
+	// The node is added to the template instead
+	if table == -1 || template > table {
+		return
+	}
+
+	var what, name string
+	var r loc.Range
+	switch {
+	case n.Type == ElementNode && n.Open != (loc.Span{}):
+		what, name = "element", fmt.Sprintf("`<%s>`", n.Data)
+		r = loc.Range{Loc: loc.Loc{Start: n.Open.Start}, Len: len("<") + len(n.Data)}
+	case n.Type == TextNode && strings.Trim(n.Data, whitespace) != "":
+		what, name = "text", "Text"
+		r = n.Span.Range()
+	default:
+		return
+	}
+	content := "text"
+	if n.Type == ElementNode {
+		content = startTags([]*Node{n})
+	}
+	tables := startTags(p.oe[table:])
+	p.appendContentModelWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_FOSTER_PARENTED,
+		Text:  fmt.Sprintf("%s is moved before the `<table>` it is written in: written as `%s`, parsed as `%s`", name, tables+content, content+tables),
+		Hint:  fmt.Sprintf("`<%s>` can only contain table elements, move the %s into a cell or out of the table", p.top().Data, what),
+		Range: r,
+	})
+}
+
+// recordImpliedEndTags records the elements closed by the current start tag, given open, the
+// stack of open elements before the tag. An element which can't contain the start tag, like the
+// <a> of "<a><a>", is warned about right away.
+func (p *parser) recordImpliedEndTags(open nodeStack) {
+	common := 0
+	for common < len(open) && common < len(p.oe) && open[common] == p.oe[common] {
+		common++
+	}
+	var closed []*Node
+	for _, n := range open[common:] {
+		if n.Type == ElementNode && !n.Expression && n.Open != (loc.Span{}) {
+			closed = append(closed, n)
+		}
+	}
+	for i, n := range closed {
+		if cannotContain(n, p.tok) {
+			p.warnClosedByStartTag(p.tok, closed[i:], "")
+			return
+		}
+	}
+	if len(closed) > 0 {
+		p.impliedEndTags = append(p.impliedEndTags, impliedEndTag{tag: p.tok, closed: closed})
+	}
+}
+
+// cannotContain reports whether the element n closed by the start tag is a parse error even
+// when n has no end tag: n is of the same kind as the tag, like nested <a> or headings. The end
+// tags of other elements can be omitted, like the </p> of "<p>a<div>b</div>".
+func cannotContain(n *Node, tag Token) bool {
+	switch tag.DataAtom {
+	case a.A, a.Button, a.Nobr:
+		return n.DataAtom == tag.DataAtom
+	case a.H1, a.H2, a.H3, a.H4, a.H5, a.H6:
+		switch n.DataAtom {
+		case a.H1, a.H2, a.H3, a.H4, a.H5, a.H6:
+			return true
+		}
+	}
+	return false
+}
+
+// warnImpliedEndTag warns about the start tag which closed the element of the current end tag,
+// when that end tag closed no open element, given open, the stack of open elements before it: the
+// markup was written with the element around the start tag, like "<p><div></div></p>".
+func (p *parser) warnImpliedEndTag(open nodeStack) {
+	for _, n := range open {
+		if n.Type == ElementNode && !n.Expression && strings.EqualFold(n.Data, p.tok.Data) {
+			return
+		}
+	}
+	for i := len(p.impliedEndTags) - 1; i >= 0; i-- {
+		e := p.impliedEndTags[i]
+		for j, n := range e.closed {
+			if strings.EqualFold(n.Data, p.tok.Data) {
+				p.warnClosedByStartTag(e.tag, e.closed[j:], p.tok.Data)
+				p.impliedEndTags = append(p.impliedEndTags[:i], p.impliedEndTags[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// warnClosedByStartTag warns about the start tag closing the elements it is written in, outermost
+// first, given the name of the end tag of the first element, written after the start tag, if any
+func (p *parser) warnClosedByStartTag(tag Token, closed []*Node, end string) {
+	n := closed[0]
+	written := startTags(closed) + fmt.Sprintf("<%s>…", tag.Data)
+	if end != "" {
+		written += fmt.Sprintf("</%s>", end)
+	}
+	// The end tag closes nothing: it is dropped
+	parsed := startTags(closed) + endTags(closed) + fmt.Sprintf("<%s>…", tag.Data)
+	p.appendContentModelWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_IMPLIED_END_TAG,
+		Text:  fmt.Sprintf("`<%s>` closes the `<%s>` it is written in: written as `%s`, parsed as `%s`", tag.Data, n.Data, written, parsed),
+		Hint:  fmt.Sprintf("Close `<%s>` before `<%s>`", n.Data, tag.Data),
+		Range: loc.Range{Loc: loc.Loc{Start: tag.Span.Start}, Len: len("<") + len(tag.Data)},
+	})
+}
+
+// warnNestedForm warns about the current <form> start tag, which is ignored while another form
+// is open (section 12.2.6.4.7)
+func (p *parser) warnNestedForm() {
+	if !p.contentModel || p.tok.Span == (loc.Span{}) {
+		return
+	}
+	p.appendContentModelWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_NESTED_FORM,
+		Text:  "`<form>` is ignored in another `<form>`: written as `<form><form>…</form>`, parsed as `<form>…</form>`",
+		Hint:  "Forms can't be nested, close the other `<form>` first",
+		Range: loc.Range{Loc: loc.Loc{Start: p.tok.Span.Start}, Len: len("<form")},
+	})
+}
+
+// warnMisnestedFormatting warns about the current end tag of a formatting element closing the
+// elements opened in it, which the adoption agency algorithm restructures (section 12.2.6.4.7).
+// open is the formatting element and the elements opened in it, and parent the parent of the
+// formatting element, once the algorithm ran.
+func (p *parser) warnMisnestedFormatting(open []*Node, parent *Node) {
+	relevant := func(n *Node) bool {
+		for _, o := range open {
+			// The algorithm clones the formatting elements, without their source
+			if n == o || n.Open == (loc.Span{}) && n.Type == ElementNode && n.Data == o.Data {
+				return true
+			}
+		}
+		return false
+	}
+	var parsed strings.Builder
+	var render func(n *Node)
+	render = func(n *Node) {
+		if !relevant(n) {
+			return
+		}
+		if !n.Expression {
+			parsed.WriteString("<" + n.Data + ">")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			render(c)
+		}
+		if !n.Expression {
+			parsed.WriteString("</" + n.Data + ">")
+		}
+	}
+	for c := open[0]; c != nil && c.Parent == parent; c = c.NextSibling {
+		render(c)
+	}
+
+	inner := open[1]
+	p.appendContentModelWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_MISNESTED_FORMATTING,
+		Text:  fmt.Sprintf("`</%s>` closes the `<%s>` opened in it: written as `%s`, parsed as `%s`", p.tok.Data, inner.Data, startTags(open)+"</"+p.tok.Data+">", parsed.String()),
+		Hint:  fmt.Sprintf("Close `<%s>` before `</%s>`", inner.Data, p.tok.Data),
+		Range: p.tok.Span.Range(),
+	})
+}
+
+// startTags returns the start tags of the elements, skipping expressions and implied elements
+func startTags(nodes []*Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.Type == ElementNode && !n.Expression && !isImplicitNode(n) {
+			b.WriteString("<" + n.Data + ">")
+		}
+	}
+	return b.String()
+}
+
+// endTags returns the end tags of the elements, in reverse order, skipping expressions and
+// implied elements
+func endTags(nodes []*Node) string {
+	var b strings.Builder
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if n := nodes[i]; n.Type == ElementNode && !n.Expression && !isImplicitNode(n) {
+			b.WriteString("</" + n.Data + ">")
+		}
+	}
+	return b.String()
+}
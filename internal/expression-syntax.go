@@ -0,0 +1,151 @@
+package astro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/withastro/compiler/internal/js_parser"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+// validateExpressions reports the syntax errors of the expressions of the template and of the
+// attribute values as warnings, given the source as it was before parsing. They are not errors, as
+// the expressions are still compiled as written.
+func (p *parser) validateExpressions(n *Node, source string) {
+	if n.Type == ElementNode && n.Expression {
+		p.validateTemplateExpression(n, source)
+	} else if n.Type == ElementNode {
+		for _, attr := range n.Attr {
+			p.validateAttributeExpression(attr, source)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		p.validateExpressions(c, source)
+	}
+}
+
+// validateTemplateExpression parses the content of the expression n as JavaScript. The markup in
+// it, parsed by the Astro parser, is replaced by placeholders of the same length, so that the
+// offsets of the errors are offsets in the source.
+func (p *parser) validateTemplateExpression(n *Node, source string) {
+	if n.Open == (loc.Span{}) || n.Close == (loc.Span{}) {
+		return
+	}
+	start, end := n.Open.End, n.Close.Start
+	// The text children hold the code; anything else in the braces is markup
+	isCode := make([]bool, end-start)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != TextNode {
+			continue
+		}
+		if c.Span == (loc.Span{}) || c.Span.Start < start || c.Span.End > end {
+			// The text was moved by the parser, its code is unknown
+			return
+		}
+		for i := c.Span.Start; i < c.Span.End; i++ {
+			isCode[i-start] = true
+		}
+	}
+	// Consecutive elements only separated by whitespace are a single value, like "<li /><li />"
+	var markup []loc.Span
+	for i := 0; i < len(isCode); {
+		if isCode[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(isCode) && !isCode[j] {
+			j++
+		}
+		if last := len(markup) - 1; last >= 0 && strings.TrimSpace(source[markup[last].End:start+i]) == "" {
+			markup[last].End = start + j
+		} else {
+			markup = append(markup, loc.Span{Start: start + i, End: start + j})
+		}
+		i = j
+	}
+
+	code := source
+	if len(markup) > 0 {
+		code = blankMarkup(source[:end], markup)
+	}
+	// The code is parsed in place of the source, for the offsets of the errors
+	_, err := js_parser.ParseJSXExpression(code, start, end)
+	if err == nil {
+		return
+	}
+	e := err.(*js_parser.SyntaxError)
+	message := e.Message
+	for _, m := range markup {
+		if e.Pos >= m.Start && e.Pos < m.End {
+			tag := firstTag(source[m.Start:m.End])
+			message = fmt.Sprintf("Unexpected markup '%s'", tag)
+			e.Pos, e.End = m.Start, m.Start+len(tag)
+		}
+	}
+	p.appendExpressionWarning(e, message)
+}
+
+// blankMarkup replaces each range of markup in source by a placeholder identifier of the same length
+func blankMarkup(source string, markup []loc.Span) string {
+	code := []byte(source)
+	for _, m := range markup {
+		code[m.Start] = '_'
+		for i := m.Start + 1; i < m.End; i++ {
+			// Line terminators are kept, for the automatic insertion of semicolons
+			if code[i] != '\n' && code[i] != '\r' {
+				code[i] = ' '
+			}
+		}
+	}
+	return string(code)
+}
+
+// firstTag returns the first tag of markup, like "<li>"
+func firstTag(markup string) string {
+	if i := strings.IndexByte(markup, '>'); i != -1 {
+		return markup[:i+1]
+	}
+	return markup
+}
+
+// validateAttributeExpression parses the value of an expression, spread, shorthand or template
+// literal attribute as JavaScript
+func (p *parser) validateAttributeExpression(attr Attribute, source string) {
+	var start, end int
+	switch attr.Type {
+	case ExpressionAttribute:
+		start, end = attr.ValSpan.Start, attr.ValSpan.End
+	case SpreadAttribute, ShorthandAttribute:
+		start, end = attr.KeySpan.Start, attr.KeySpan.End
+	case TemplateLiteralAttribute:
+		// The backticks are part of the code
+		start, end = attr.ValSpan.Start-1, attr.ValSpan.End+1
+	default:
+		return
+	}
+	if attr.Span == (loc.Span{}) || end > len(source) {
+		return
+	}
+	expression, err := js_parser.ParseJSXExpression(source, start, end)
+	if err != nil {
+		e := err.(*js_parser.SyntaxError)
+		p.appendExpressionWarning(e, e.Message)
+		return
+	}
+	// A comment, like {/* comment */}, is an empty expression or shorthand attribute, which is dropped
+	if expression == nil && attr.Type == SpreadAttribute {
+		p.appendExpressionWarning(&js_parser.SyntaxError{Pos: start, End: end}, "Expected an expression")
+	}
+}
+
+func (p *parser) appendExpressionWarning(e *js_parser.SyntaxError, message string) {
+	if p.handler == nil {
+		return
+	}
+	p.handler.AppendWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_EXPRESSION_SYNTAX,
+		Text:  fmt.Sprintf("Invalid expression: %s", message),
+		Range: loc.Range{Loc: loc.Loc{Start: e.Pos}, Len: e.End - e.Pos},
+	})
+}
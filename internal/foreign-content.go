@@ -0,0 +1,230 @@
+package astro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/withastro/compiler/internal/helpers"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+// foreignNamespace describes the elements and attributes of the SVG or MathML namespace, for the
+// warnings of ParseOptionEnableForeignContentWarnings
+type foreignNamespace struct {
+	// name is the name of the namespace in the warnings, like "SVG"
+	name string
+	// elements and attributes map the lowercase names to the names as they must be written
+	elements   map[string]string
+	attributes map[string]string
+	// htmlHint tells where HTML content can be written in the namespace
+	htmlHint string
+}
+
+var foreignNamespaces = map[string]*foreignNamespace{
+	"svg": {
+		name:       "SVG",
+		elements:   foreignNames(svgElements, svgTagNameAdjustments),
+		attributes: foreignNames(svgAttributes, svgAttributeAdjustments),
+		htmlHint:   "HTML content must be wrapped in a `<foreignObject>`",
+	},
+	"math": {
+		name:       "MathML",
+		elements:   foreignNames(mathMLElements, nil),
+		attributes: foreignNames(mathMLAttributes, mathMLAttributeAdjustments),
+		htmlHint:   "HTML content must be wrapped in a token element like `<mtext>`",
+	},
+}
+
+func foreignNames(names []string, adjustments map[string]string) map[string]string {
+	m := make(map[string]string, len(names)+len(adjustments))
+	for _, name := range names {
+		m[strings.ToLower(name)] = name
+	}
+	for lower, name := range adjustments {
+		m[lower] = name
+	}
+	return m
+}
+
+func (p *parser) appendForeignContentWarning(err *loc.ErrorWithRange) {
+	if p.handler != nil {
+		p.handler.AppendWarning(err)
+	}
+}
+
+// warnForeignStartTag warns about the current start tag of an element of namespace, like "svg",
+// written with the wrong casing, which isn't an element of the namespace, or with unknown or
+// mis-cased attributes. It runs before the names are adjusted, to check them as written.
+func (p *parser) warnForeignStartTag(namespace string) {
+	ns := foreignNamespaces[namespace]
+	if !p.foreignContent || ns == nil || p.tok.Span == (loc.Span{}) {
+		return
+	}
+	tag := p.tok.Data
+	tagRange := loc.Range{Loc: loc.Loc{Start: p.tok.Span.Start}, Len: len("<") + len(tag)}
+	if isComponent(tag) || isCustomElement(tag) || isFragment(tag) || isSlot(tag) {
+		return
+	}
+	if name, ok := ns.elements[strings.ToLower(tag)]; !ok {
+		p.appendForeignContentWarning(&loc.ErrorWithRange{
+			Code:  loc.WARNING_HTML_IN_FOREIGN_CONTENT,
+			Text:  fmt.Sprintf("`<%s>` is not an %s element", tag, ns.name),
+			Hint:  ns.htmlHint,
+			Range: tagRange,
+		})
+	} else if name != tag {
+		p.appendForeignContentWarning(&loc.ErrorWithRange{
+			Code:  loc.WARNING_FOREIGN_CASING,
+			Text:  fmt.Sprintf("`<%s>` must be written `<%s>` in %s", tag, name, ns.name),
+			Hint:  fmt.Sprintf("The names of the %s elements are case-sensitive", ns.name),
+			Range: tagRange,
+		})
+	}
+
+	for _, attr := range p.tok.Attr {
+		key := attr.Key
+		if attr.Type == SpreadAttribute || attr.Type == ShorthandAttribute || !isForeignAttributeChecked(key) {
+			continue
+		}
+		keyRange := loc.Range{Loc: attr.KeyLoc, Len: len(key)}
+		name, ok := ns.attributes[strings.ToLower(key)]
+		switch {
+		case !ok:
+			p.appendForeignContentWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_UNKNOWN_FOREIGN_ATTRIBUTE,
+				Text:  fmt.Sprintf("Unknown attribute `%s` on the %s element `<%s>`", key, ns.name, tag),
+				Hint:  suggestForeignAttribute(ns, key),
+				Range: keyRange,
+			})
+		case name != key:
+			p.appendForeignContentWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_FOREIGN_CASING,
+				Text:  fmt.Sprintf("`%s` must be written `%s` on %s elements", key, name, ns.name),
+				Hint:  fmt.Sprintf("The names of the %s attributes are case-sensitive", ns.name),
+				Range: keyRange,
+			})
+		}
+	}
+}
+
+// isForeignAttributeChecked reports whether the attribute key is checked against the attributes of
+// a namespace: directives, namespaced attributes like "xlink:href", event handlers and the data-*
+// and aria-* attributes are not
+func isForeignAttributeChecked(key string) bool {
+	if key == "" || strings.Contains(key, ":") {
+		return false
+	}
+	lower := strings.ToLower(key)
+	return !strings.HasPrefix(lower, "on") && !strings.HasPrefix(lower, "data-") && !strings.HasPrefix(lower, "aria-")
+}
+
+// suggestForeignAttribute returns a hint with the attribute of ns closest to the unknown key, like
+// "stroke-width" for "stroke_width" or "strokeWidth", when it looks like a typo
+func suggestForeignAttribute(ns *foreignNamespace, key string) string {
+	lower := strings.ToLower(key)
+	best, bestDistance := "", 3
+	for l, name := range ns.attributes {
+		if d := helpers.EditDistance(lower, l); d < bestDistance || d == bestDistance && best != "" && name < best {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("Did you mean `%s`?", best)
+}
+
+// warnForeignBreakout warns about the current start tag of an HTML element, like <div>, which
+// closes the foreign elements it is written in, given i, the index in the stack of open elements
+// of the HTML element or integration point the elements are closed up to (section 12.2.6.5)
+func (p *parser) warnForeignBreakout(i int) {
+	if !p.foreignContent || i+1 >= len(p.oe) || p.tok.Span == (loc.Span{}) {
+		return
+	}
+	root := p.oe[i+1]
+	ns := foreignNamespaces[root.Namespace]
+	if ns == nil {
+		return
+	}
+	p.appendForeignContentWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_HTML_IN_FOREIGN_CONTENT,
+		Text:  fmt.Sprintf("`<%s>` closes the `<%s>` it is written in: HTML elements can't be used in %s", p.tok.Data, root.Data, ns.name),
+		Hint:  ns.htmlHint,
+		Range: loc.Range{Loc: loc.Loc{Start: p.tok.Span.Start}, Len: len("<") + len(p.tok.Data)},
+	})
+}
+
+// svgElements are the SVG elements, besides the ones of svgTagNameAdjustments. The elements whose
+// name contains a dash, like <font-face>, are custom elements for the parser.
+var svgElements = []string{
+	"a", "animate", "circle", "cursor", "defs", "desc", "discard", "ellipse", "feDropShadow",
+	"filter", "font", "g", "glyph", "hatch", "hatchpath", "hkern", "image", "line", "marker", "mask",
+	"metadata", "mpath", "path", "pattern", "polygon", "polyline", "rect", "script", "set", "stop",
+	"style", "svg", "switch", "symbol", "text", "title", "tref", "tspan", "use", "view", "vkern",
+}
+
+// svgAttributes are the SVG attributes, including the presentation attributes, besides the ones
+// of svgAttributeAdjustments
+var svgAttributes = []string{
+	// Core and conditional processing attributes
+	"id", "class", "style", "lang", "tabindex", "autofocus", "nonce", "part", "exportparts", "slot",
+	"role", "is", "xmlns", "href", "requiredExtensions", "requiredFeatures", "systemLanguage",
+	"externalResourcesRequired",
+	// Presentation attributes
+	"alignment-baseline", "baseline-shift", "clip", "clip-path", "clip-rule", "color",
+	"color-interpolation", "color-interpolation-filters", "color-profile", "color-rendering", "cursor",
+	"d", "direction", "display", "dominant-baseline", "enable-background", "fill", "fill-opacity",
+	"fill-rule", "filter", "flood-color", "flood-opacity", "font-family", "font-size",
+	"font-size-adjust", "font-stretch", "font-style", "font-variant", "font-weight",
+	"glyph-orientation-horizontal", "glyph-orientation-vertical", "image-rendering", "kerning",
+	"letter-spacing", "lighting-color", "marker-end", "marker-mid", "marker-start", "mask", "mask-type",
+	"opacity", "overflow", "paint-order", "pointer-events", "shape-rendering", "stop-color",
+	"stop-opacity", "stroke", "stroke-dasharray", "stroke-dashoffset", "stroke-linecap",
+	"stroke-linejoin", "stroke-miterlimit", "stroke-opacity", "stroke-width", "text-anchor",
+	"text-decoration", "text-overflow", "text-rendering", "transform", "transform-origin",
+	"unicode-bidi", "vector-effect", "visibility", "white-space", "word-spacing", "writing-mode",
+	// Element attributes
+	"accent-height", "accumulate", "additive", "alphabetic", "amplitude", "arabic-form", "ascent",
+	"azimuth", "bbox", "begin", "bias", "by", "cap-height", "contentScriptType", "contentStyleType",
+	"crossorigin", "cx", "cy", "decoding", "descent", "divisor", "download", "dur", "dx", "dy",
+	"elevation", "end", "exponent", "fetchpriority", "filterRes", "format", "fr", "from", "fx", "fy",
+	"g1", "g2", "glyph-name", "hanging", "height", "horiz-adv-x", "horiz-origin-x", "horiz-origin-y",
+	"hreflang", "ideographic", "in", "in2", "intercept", "k", "k1", "k2", "k3", "k4", "local",
+	"mathematical", "max", "media", "method", "min", "mode", "name", "offset", "operator", "order",
+	"orient", "orientation", "origin", "overline-position", "overline-thickness", "panose-1", "path",
+	"ping", "points", "r", "radius", "referrerpolicy", "rel", "rendering-intent", "restart", "result",
+	"rotate", "rx", "ry", "scale", "seed", "side", "slope", "spacing", "stemh", "stemv",
+	"strikethrough-position", "strikethrough-thickness", "string", "target", "to", "type", "u1", "u2",
+	"underline-position", "underline-thickness", "unicode", "unicode-range", "units-per-em",
+	"v-alphabetic", "v-hanging", "v-ideographic", "v-mathematical", "values", "version", "vert-adv-y",
+	"vert-origin-x", "vert-origin-y", "width", "widths", "x", "x-height", "x1", "x2", "y", "y1", "y2",
+	"z",
+}
+
+// mathMLElements are the presentation MathML elements
+var mathMLElements = []string{
+	"math", "maction", "maligngroup", "malignmark", "annotation", "annotation-xml", "menclose",
+	"merror", "mfenced", "mfrac", "mglyph", "mi", "mlabeledtr", "mlongdiv", "mmultiscripts", "mn",
+	"mo", "mover", "mpadded", "mphantom", "mprescripts", "mroot", "mrow", "ms", "mscarries",
+	"mscarry", "msgroup", "msline", "mspace", "msqrt", "msrow", "mstack", "mstyle", "msub",
+	"msubsup", "msup", "mtable", "mtd", "mtext", "mtr", "munder", "munderover", "none", "semantics",
+}
+
+// mathMLAttributes are the MathML attributes, besides the ones of mathMLAttributeAdjustments
+var mathMLAttributes = []string{
+	"id", "class", "style", "tabindex", "autofocus", "nonce", "slot", "role", "xmlns", "href", "dir",
+	"display", "displaystyle", "scriptlevel", "mathbackground", "mathcolor", "mathsize", "mathvariant",
+	"intent", "arg", "accent", "accentunder", "actiontype", "align", "alignmentscope", "altimg",
+	"altimg-height", "altimg-valign", "altimg-width", "alttext", "bevelled", "cd", "charalign",
+	"close", "columnalign", "columnlines", "columnspacing", "columnspan", "columnwidth", "crossout",
+	"decimalpoint", "denomalign", "depth", "edge", "encoding", "equalcolumns", "equalrows", "fence",
+	"form", "frame", "framespacing", "groupalign", "height", "indentalign", "indentalignfirst",
+	"indentalignlast", "indentshift", "indentshiftfirst", "indentshiftlast", "indenttarget",
+	"infixlinebreakstyle", "largeop", "length", "linebreak", "linebreakmultchar", "linebreakstyle",
+	"lineleading", "linethickness", "location", "longdivstyle", "lquote", "lspace", "maxsize",
+	"maxwidth", "minlabelspacing", "minsize", "movablelimits", "name", "notation", "numalign", "open",
+	"overflow", "position", "rowalign", "rowlines", "rowspacing", "rowspan", "rquote", "rspace",
+	"scriptminsize", "scriptsizemultiplier", "selection", "separator", "separators", "shift", "side",
+	"src", "stackalign", "stretchy", "subscriptshift", "superscriptshift", "symmetric", "valign",
+	"voffset", "width",
+}
@@ -3,12 +3,16 @@ package handler
 import (
 	"errors"
 	"strings"
+	"sync"
 
 	"github.com/withastro/compiler/internal/loc"
 	"github.com/withastro/compiler/internal/sourcemap"
 )
 
+// Handler collects the diagnostics of a single compilation. It is safe for concurrent use,
+// e.g. by the goroutines preprocessing styles or resolving paths.
 type Handler struct {
+	mu         sync.Mutex
 	sourcetext string
 	filename   string
 	builder    sourcemap.ChunkBuilder
@@ -31,82 +35,172 @@ func NewHandler(sourcetext string, filename string) *Handler {
 }
 
 func (h *Handler) HasErrors() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	return len(h.errors) > 0
 }
 
 func (h *Handler) AppendError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.errors = append(h.errors, err)
 }
 
 func (h *Handler) AppendWarning(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.warnings = append(h.warnings, err)
 }
 
 func (h *Handler) AppendInfo(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.infos = append(h.infos, err)
 }
 
 func (h *Handler) AppendHint(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.hints = append(h.hints, err)
 }
 
+// AppendAll appends the diagnostics collected by another handler for the same source,
+// e.g. while parsing part of it again
+func (h *Handler) AppendAll(other *Handler) {
+	other.mu.Lock()
+	errs, warnings, infos, hints := other.errors, other.warnings, other.infos, other.hints
+	other.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errors = append(h.errors, errs...)
+	h.warnings = append(h.warnings, warnings...)
+	h.infos = append(h.infos, infos...)
+	h.hints = append(h.hints, hints...)
+}
+
+// Replace returns a handler for text, the source of h after the part from start to end was parsed
+// again as the part of text from start to newEnd, whose diagnostics were collected by other. The
+// diagnostics of h in that part are replaced by the ones of other, and the ones following it are
+// moved to their offset in text.
+func (h *Handler) Replace(text string, start int, end int, newEnd int, other *Handler) *Handler {
+	other.mu.Lock()
+	replacements := [][]error{other.errors, other.warnings, other.infos, other.hints}
+	other.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := NewHandler(text, h.filename)
+	lists := []*[]error{&result.errors, &result.warnings, &result.infos, &result.hints}
+	for i, errs := range [][]error{h.errors, h.warnings, h.infos, h.hints} {
+		inserted := false
+		for _, err := range errs {
+			var rangedError *loc.ErrorWithRange
+			if !errors.As(err, &rangedError) || rangedError.Range.Loc.Start < start {
+				*lists[i] = append(*lists[i], err)
+				continue
+			}
+			if rangedError.Range.Loc.Start < end {
+				continue
+			}
+			// The diagnostics of the part come before the ones following it
+			if !inserted {
+				*lists[i] = append(*lists[i], replacements[i]...)
+				inserted = true
+			}
+			moved := *rangedError
+			moved.Range.Loc.Start += newEnd - end
+			*lists[i] = append(*lists[i], &moved)
+		}
+		if !inserted {
+			*lists[i] = append(*lists[i], replacements[i]...)
+		}
+	}
+	return result
+}
+
 func (h *Handler) Errors() []loc.DiagnosticMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	msgs := make([]loc.DiagnosticMessage, 0)
 	for _, err := range h.errors {
 		if err != nil {
-			msgs = append(msgs, ErrorToMessage(h, loc.ErrorType, err))
+			msgs = append(msgs, h.errorToMessage(loc.ErrorType, err))
 		}
 	}
 	return msgs
 }
 
 func (h *Handler) Warnings() []loc.DiagnosticMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	msgs := make([]loc.DiagnosticMessage, 0)
 	for _, err := range h.warnings {
 		if err != nil {
-			msgs = append(msgs, ErrorToMessage(h, loc.WarningType, err))
+			msgs = append(msgs, h.errorToMessage(loc.WarningType, err))
 		}
 	}
 	return msgs
 }
 
 func (h *Handler) Diagnostics() []loc.DiagnosticMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	msgs := make([]loc.DiagnosticMessage, 0)
 	for _, err := range h.errors {
 		if err != nil {
-			msgs = append(msgs, ErrorToMessage(h, loc.ErrorType, err))
+			msgs = append(msgs, h.errorToMessage(loc.ErrorType, err))
 		}
 	}
 	for _, err := range h.warnings {
 		if err != nil {
-			msgs = append(msgs, ErrorToMessage(h, loc.WarningType, err))
+			msgs = append(msgs, h.errorToMessage(loc.WarningType, err))
 		}
 	}
 	for _, err := range h.infos {
 		if err != nil {
-			msgs = append(msgs, ErrorToMessage(h, loc.InformationType, err))
+			msgs = append(msgs, h.errorToMessage(loc.InformationType, err))
 		}
 	}
 	for _, err := range h.hints {
 		if err != nil {
-			msgs = append(msgs, ErrorToMessage(h, loc.HintType, err))
+			msgs = append(msgs, h.errorToMessage(loc.HintType, err))
 		}
 	}
 	return msgs
 }
 
+// Location returns the location of the range r of the source
+func (h *Handler) Location(r loc.Range) loc.DiagnosticLocation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.location(r)
+}
+
+// location must be called with h.mu held, see errorToMessage
+func (h *Handler) location(r loc.Range) loc.DiagnosticLocation {
+	pos := h.builder.GetLineAndColumnForLocation(r.Loc)
+	return loc.DiagnosticLocation{
+		File:   h.filename,
+		Line:   pos[0],
+		Column: pos[1],
+		Length: r.Len,
+	}
+}
+
 func ErrorToMessage(h *Handler, severity loc.DiagnosticSeverity, err error) loc.DiagnosticMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.errorToMessage(severity, err)
+}
+
+// errorToMessage must be called with h.mu held, as looking up a location mutates the builder
+func (h *Handler) errorToMessage(severity loc.DiagnosticSeverity, err error) loc.DiagnosticMessage {
 	var rangedError *loc.ErrorWithRange
 	switch {
 	case errors.As(err, &rangedError):
-		pos := h.builder.GetLineAndColumnForLocation(rangedError.Range.Loc)
-		location := &loc.DiagnosticLocation{
-			File:   h.filename,
-			Line:   pos[0],
-			Column: pos[1],
-			Length: rangedError.Range.Len,
-		}
-		message := rangedError.ToMessage(location)
+		location := h.location(rangedError.Range)
+		message := rangedError.ToMessage(&location)
 		message.Severity = int(severity)
 		return message
 	default:
@@ -0,0 +1,419 @@
+package astro
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+	a "golang.org/x/net/html/atom"
+)
+
+// An Edit replaces the bytes of a source between Start and End with Text.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Apply returns source with the edit applied.
+func (e Edit) Apply(source string) string {
+	return source[:e.Start] + e.Text + source[e.End:]
+}
+
+// reparseState is the state of the parser when it starts and finishes the content of an element.
+type reparseState struct {
+	// contentStart is the offset right after the start tag of the element
+	contentStart int
+	start        *parserState
+	// end is only set when the element is closed by its own end tag
+	end *parserState
+}
+
+// parserState holds everything the parser of the content of an element depends on,
+// besides the stack of open elements.
+type parserState struct {
+	// isolated is set when the stack of open elements is the list of ancestors of the element,
+	// and no active formatting element, form or template can be affected by its content.
+	isolated bool
+
+	im, originalIM   insertionMode
+	framesetOK       bool
+	quirks           bool
+	hasHead          bool
+	frontmatterState FrontmatterState
+
+	fm                         FrontmatterState
+	rawTag                     string
+	noExpressionTag            string
+	openBraceIsExpressionStart bool
+}
+
+// recordReparseState records the state of the parser after the start tag of an element,
+// and after its end tag. top and depth are the top and length of the stack before the token.
+func (p *parser) recordReparseState(top *Node, depth int) {
+	switch p.tok.Type {
+	case StartTagToken:
+		n := p.oe.top()
+		if n == nil || n == top || n.reparse != nil || len(n.Loc) != 1 || n.Loc[0] != p.tok.Loc {
+			return
+		}
+		n.reparse = &reparseState{
+			contentStart: p.tokenizer.raw.End,
+			start:        p.parserState(n, len(p.oe)-1),
+		}
+	case EndTagToken:
+		n := top
+		if n == nil || n.reparse == nil || n.reparse.end != nil || len(n.Loc) != 2 || n.Loc[1] != p.tok.Loc {
+			return
+		}
+		if len(p.oe) != depth-1 || p.oe.index(n) != -1 {
+			return
+		}
+		n.reparse.end = p.parserState(n, len(p.oe))
+	}
+}
+
+// parserState returns the current state of the parser, for the content of n.
+// The ancestors of n are expected at the bottom of the stack of open elements, up to depth.
+func (p *parser) parserState(n *Node, depth int) *parserState {
+	z := p.tokenizer
+	return &parserState{
+		isolated:                   p.isIsolated(n, depth),
+		im:                         p.im,
+		originalIM:                 p.originalIM,
+		framesetOK:                 p.framesetOK,
+		quirks:                     p.quirks,
+		hasHead:                    p.head != nil,
+		frontmatterState:           p.frontmatterState,
+		fm:                         z.fm,
+		rawTag:                     z.rawTag,
+		noExpressionTag:            z.noExpressionTag,
+		openBraceIsExpressionStart: z.openBraceIsExpressionStart,
+	}
+}
+
+func (p *parser) isIsolated(n *Node, depth int) bool {
+	if p.fragment || p.form != nil || p.fosterParenting || len(p.templateStack) > 0 {
+		return false
+	}
+	if len(p.tokenizer.expressionStack) > 0 || !sameFunc(p.exitLiteralIM, neverExitLiteralIM) {
+		return false
+	}
+	switch {
+	case sameFunc(p.im, inBodyIM), sameFunc(p.im, textIM):
+	case sameFunc(p.im, inLiteralIM) && p.literal:
+	default:
+		return false
+	}
+	// Formatting elements after the last marker would be reconstructed in the content, or closed by it
+	if top := p.afe.top(); top != nil && top.Type != scopeMarkerNode {
+		return false
+	}
+	parent := n.Parent
+	for i := depth - 1; i >= 0; i-- {
+		if p.oe[i] != parent || parent.DataAtom == a.Head {
+			return false
+		}
+		parent = parent.Parent
+	}
+	return parent == nil || parent.Type == DocumentNode
+}
+
+func sameFunc(f, g any) bool {
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(g).Pointer()
+}
+
+func (s *parserState) equal(other *parserState) bool {
+	return s.isolated == other.isolated &&
+		sameFunc(s.im, other.im) &&
+		sameFunc(s.originalIM, other.originalIM) &&
+		s.framesetOK == other.framesetOK &&
+		s.quirks == other.quirks &&
+		s.hasHead == other.hasHead &&
+		s.frontmatterState == other.frontmatterState &&
+		s.fm == other.fm &&
+		s.rawTag == other.rawTag &&
+		s.noExpressionTag == other.noExpressionTag &&
+		s.openBraceIsExpressionStart == other.openBraceIsExpressionStart
+}
+
+// Reparse updates doc, the tree parsed from source with ParseOptionEnableIncremental, after edit
+// is applied to source. Only the content of the innermost element around the edit is tokenized
+// and parsed again, resuming the parser in the state it had after the start tag. The children of
+// the element are replaced in place, and the locations that follow the edit are shifted.
+//
+// When the edit could change the tree outside of that element (e.g. closing it early, or leaving
+// a formatting element open), or when doc was not parsed incrementally, the edited source is
+// parsed from scratch and a new tree is returned. Either way, the result is identical to a full
+// parse of the edited source. doc must not have been transformed, and opts must be the options it
+// was parsed with.
+//
+// Only the diagnostics of the part that is parsed again are reported to the handler. That part is
+// returned as a range of the edited source: the content and the end tag of the element, or the
+// whole source.
+func Reparse(doc *Node, source string, edit Edit, opts ...ParseOption) (*Node, loc.Span, error) {
+	doc, element, err := reparse(doc, source, edit, opts...)
+	switch {
+	case err != nil:
+		return doc, loc.Span{}, err
+	case element == nil:
+		return doc, loc.Span{Start: 0, End: len(edit.Apply(source))}, nil
+	}
+	return doc, loc.Span{Start: element.reparse.contentStart, End: element.Close.End}, nil
+}
+
+// CloneTree returns a copy of the tree rooted at n, which can be transformed while n is kept for
+// Reparse. n must not have been transformed. The copy is not updated in place by Reparse.
+func CloneTree(n *Node) *Node {
+	m := &Node{}
+	*m = *n
+	m.Parent, m.FirstChild, m.LastChild, m.PrevSibling, m.NextSibling = nil, nil, nil, nil, nil
+	m.reparse, m.source = nil, nil
+	m.Attr = slices.Clone(n.Attr)
+	m.Loc = slices.Clone(n.Loc)
+	m.HydrationDirectives = maps.Clone(n.HydrationDirectives)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		m.AppendChild(CloneTree(c))
+	}
+	return m
+}
+
+// reparse is Reparse, also returning the element whose content was parsed again, or nil when the
+// tree was parsed from scratch.
+func reparse(doc *Node, source string, edit Edit, opts ...ParseOption) (*Node, *Node, error) {
+	if edit.Start < 0 || edit.Start > edit.End || edit.End > len(source) {
+		return nil, nil, fmt.Errorf("html: edit [%d, %d) is out of range for a source of length %d", edit.Start, edit.End, len(source))
+	}
+	text := edit.Apply(source)
+
+	candidates := make([]*Node, 0)
+	walkReparseCandidates(doc, source, edit, &candidates)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if reparseElement(candidates[i], text, edit, opts) {
+			if doc.source != nil {
+				recordSource(doc, text)
+			}
+			return doc, candidates[i], nil
+		}
+	}
+
+	opts = append(opts[:len(opts):len(opts)], ParseOptionEnableIncremental(true))
+	doc, err := ParseWithOptions(strings.NewReader(text), opts...)
+	return doc, nil, err
+}
+
+// walkReparseCandidates collects the elements whose content contains the edit, outermost first
+func walkReparseCandidates(n *Node, source string, edit Edit, candidates *[]*Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if s := c.reparse; s != nil && s.end != nil && s.start.isolated && s.end.isolated {
+			contentEnd := c.Loc[1].Start - len("</")
+			if s.contentStart <= edit.Start && edit.End <= contentEnd && strings.HasPrefix(source[contentEnd:], "</") && c.Close != (loc.Span{}) {
+				*candidates = append(*candidates, c)
+			}
+		}
+		walkReparseCandidates(c, source, edit, candidates)
+	}
+}
+
+// reparseElement parses the content of n again from text, the edited source. When the result is
+// the same as a full parse would give, the children of n are replaced and the locations shifted.
+func reparseElement(n *Node, text string, edit Edit, opts []ParseOption) bool {
+	state := n.reparse
+	delta := len(edit.Text) - (edit.End - edit.Start)
+	start := n.Loc[0].Start - len("<")
+	if start < 0 || text[start] != '<' {
+		return false
+	}
+
+	// The ancestors of n are cloned, for the parser to find them on the stack without modifying them
+	var oe nodeStack
+	for parent := n.Parent; parent != nil && parent.Type != DocumentNode; parent = parent.Parent {
+		oe = append(nodeStack{cloneAncestor(parent)}, oe...)
+	}
+	for i := 1; i < len(oe); i++ {
+		oe[i-1].AppendChild(oe[i])
+	}
+	element := cloneAncestor(n)
+	element.Loc = []loc.Loc{n.Loc[0]}
+	element.reparse = &reparseState{contentStart: state.contentStart, start: state.start}
+	if len(oe) > 0 {
+		oe.top().AppendChild(element)
+	}
+	oe = append(oe, element)
+	depth := len(oe) - 1
+
+	z := NewTokenizer(strings.NewReader(text))
+	z.fm = FrontmatterClosed
+	z.raw = loc.Span{Start: start, End: start}
+	z.data = z.raw
+	p := &parser{
+		tokenizer:        z,
+		doc:              &Node{Type: DocumentNode},
+		oe:               oe,
+		exitLiteralIM:    neverExitLiteralIM,
+		im:               state.start.im,
+		originalIM:       state.start.originalIM,
+		framesetOK:       state.start.framesetOK,
+		quirks:           state.start.quirks,
+		frontmatterState: state.start.frontmatterState,
+	}
+	var head *Node
+	if state.start.hasHead {
+		head = &Node{Type: ElementNode, DataAtom: a.Head, Data: a.Head.String()}
+		p.head = head
+	}
+	for _, f := range opts {
+		f(p)
+	}
+	// Diagnostics are only kept when the content could be parsed again
+	h := p.handler
+	p.handler = &handler.Handler{}
+	p.incremental = true
+
+	// The start tag is tokenized again, for the tokenizer to be in the same state as before. Its
+	// diagnostics were already reported.
+	z.handler = &handler.Handler{}
+	if z.Next() != StartTagToken || z.raw.End != state.contentStart {
+		return false
+	}
+	z.handler = p.handler
+	p.tok = z.Token()
+	if p.tok.Data != n.Data {
+		return false
+	}
+	z.fm = state.start.fm
+	z.rawTag = state.start.rawTag
+	z.noExpressionTag = state.start.noExpressionTag
+	z.openBraceIsExpressionStart = state.start.openBraceIsExpressionStart
+
+	for len(p.oe) > depth && p.oe[depth] == element {
+		top, depth := p.oe.top(), len(p.oe)
+		z.AllowCDATA(top.Namespace != "")
+		p.ltok = p.tok
+		z.Next()
+		p.tok = z.Token()
+		switch p.tok.Type {
+		case ErrorToken:
+			return false
+		case StartTagToken, SelfClosingTagToken, EndTagToken:
+			switch p.tok.DataAtom {
+			case a.Html, a.Head, a.Body, a.Frameset:
+				return false
+			}
+		}
+		if p.recover {
+			p.parseCurrentTokenWithRecovery()
+		} else {
+			p.parseCurrentToken()
+		}
+		p.recordReparseState(top, depth)
+	}
+
+	// n must have been closed by its own end tag, leaving the parser in the same state as before
+	if len(element.Loc) != 2 || element.Loc[1].Start != n.Loc[1].Start+delta || element.reparse.end == nil {
+		return false
+	}
+	if !element.reparse.end.equal(state.end) || len(p.oe) != depth || p.doc.FirstChild != nil {
+		return false
+	}
+	if head != nil && head.FirstChild != nil {
+		return false
+	}
+	for i, ancestor := range oe[:depth] {
+		if p.oe[i] != ancestor || ancestor.FirstChild != ancestor.LastChild {
+			return false
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = n.FirstChild {
+		n.RemoveChild(c)
+	}
+	for c := element.FirstChild; c != nil; c = element.FirstChild {
+		element.RemoveChild(c)
+		n.AppendChild(c)
+	}
+	n.Loc[1] = element.Loc[1]
+	n.Close = element.Close
+	n.reparse = element.reparse
+	if h != nil {
+		h.AppendAll(p.handler)
+	}
+
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	shiftLocations(root, n, edit.End, delta)
+	// The spans of n and its ancestors are set again, as their implied ends depend on the new content
+	for m := n; m != nil; m = m.Parent {
+		if m.Open != (loc.Span{}) {
+			m.Span = loc.Span{}
+		}
+	}
+	finishSpans(root)
+	return true
+}
+
+// cloneAncestor returns a copy of n without its relatives
+func cloneAncestor(n *Node) *Node {
+	return &Node{
+		Type:          n.Type,
+		DataAtom:      n.DataAtom,
+		Data:          n.Data,
+		Namespace:     n.Namespace,
+		Attr:          append([]Attribute(nil), n.Attr...),
+		Fragment:      n.Fragment,
+		CustomElement: n.CustomElement,
+		Component:     n.Component,
+		Expression:    n.Expression,
+		Loc:           append([]loc.Loc(nil), n.Loc...),
+	}
+}
+
+// shiftLocations moves the locations from offset on by delta, except in the content of skip
+func shiftLocations(n *Node, skip *Node, offset int, delta int) {
+	shift := func(l *loc.Loc) {
+		if l.Start >= offset {
+			l.Start += delta
+		}
+	}
+	shiftSpan := func(s *loc.Span) {
+		if *s == (loc.Span{}) {
+			return
+		}
+		if s.Start >= offset {
+			s.Start += delta
+		}
+		if s.End >= offset {
+			s.End += delta
+		}
+	}
+	if n != skip {
+		for i := range n.Loc {
+			shift(&n.Loc[i])
+		}
+		shiftSpan(&n.Span)
+		shiftSpan(&n.Open)
+		shiftSpan(&n.Close)
+	}
+	for i := range n.Attr {
+		shift(&n.Attr[i].KeyLoc)
+		shift(&n.Attr[i].ValLoc)
+		shiftSpan(&n.Attr[i].Span)
+		shiftSpan(&n.Attr[i].KeySpan)
+		shiftSpan(&n.Attr[i].ValSpan)
+	}
+	if n.reparse != nil && n.reparse.contentStart >= offset {
+		n.reparse.contentStart += delta
+	}
+	if n == skip {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		shiftLocations(c, skip, offset, delta)
+	}
+}
@@ -0,0 +1,358 @@
+package astro
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/test_utils"
+)
+
+// reparseEditTexts are inserted at the offsets of the sources checked by testReparse
+var reparseEditTexts = []string{"a", "<b>x</b>", "{y}", "}", "<", "</div>"}
+
+// reparseMode is a combination of the options checked by testReparse
+type reparseMode struct {
+	literal bool
+	recover bool
+}
+
+var reparseModes = []reparseMode{{}, {literal: true}, {literal: true, recover: true}}
+
+func (m reparseMode) options() []ParseOption {
+	return []ParseOption{ParseOptionEnableLiteral(m.literal), ParseOptionEnableRecovery(m.recover)}
+}
+
+// reparseEdit is an edit checked by testReparse, in one of the modes
+type reparseEdit struct {
+	edit Edit
+	mode reparseMode
+}
+
+// testReparse checks that reparsing source after an edit at every offset gives the same tree as a
+// full parse. The edits and the modes take turns from one offset to the next, which keeps
+// TestReparseFixtures fast. TestReparseEveryEdit checks all of them.
+func testReparse(t *testing.T, source string) {
+	t.Helper()
+	edits := make([]reparseEdit, 0, len(source)+1)
+	turn := 0
+	for i := 0; i <= len(source); i++ {
+		if i < len(source) && !utf8.RuneStart(source[i]) {
+			continue
+		}
+		mode := reparseModes[turn/(len(reparseEditTexts)+1)%len(reparseModes)]
+		if k := turn % (len(reparseEditTexts) + 1); k < len(reparseEditTexts) {
+			edits = append(edits, reparseEdit{Edit{Start: i, End: i, Text: reparseEditTexts[k]}, mode})
+		} else if _, size := utf8.DecodeRuneInString(source[i:]); size > 0 {
+			edits = append(edits, reparseEdit{Edit{Start: i, End: i + size}, mode})
+		}
+		turn++
+	}
+	checkReparse(t, source, edits)
+}
+
+// testReparseEveryEdit checks every edit at every offset of source, in every mode
+func testReparseEveryEdit(t *testing.T, source string) {
+	t.Helper()
+	edits := make([]reparseEdit, 0)
+	for _, mode := range reparseModes {
+		for i := 0; i <= len(source); i++ {
+			if i < len(source) && !utf8.RuneStart(source[i]) {
+				continue
+			}
+			for _, text := range reparseEditTexts {
+				edits = append(edits, reparseEdit{Edit{Start: i, End: i, Text: text}, mode})
+			}
+			if _, size := utf8.DecodeRuneInString(source[i:]); size > 0 {
+				edits = append(edits, reparseEdit{Edit{Start: i, End: i + size}, mode})
+			}
+		}
+	}
+	checkReparse(t, source, edits)
+}
+
+func checkReparse(t *testing.T, source string, edits []reparseEdit) {
+	t.Helper()
+	for _, e := range edits {
+		edit, mode := e.edit, e.mode
+		got, incremental, panicked := tryReparse(t, parseIncremental(t, source, mode), source, edit, mode)
+		if panicked != nil {
+			// The parser cannot handle every malformed input yet. The reparse may only panic when the
+			// full parse is expected to, in the same way.
+			if expected := tryParse(edit.Apply(source), mode); fmt.Sprint(expected) != fmt.Sprint(panicked) {
+				t.Fatalf("reparse of %q (%+v) after %+v panicked: %v", source, mode, edit, panicked)
+			}
+			continue
+		}
+		if !incremental {
+			continue
+		}
+		if diff := diffReparse(got, parseIncremental(t, edit.Apply(source), mode)); diff != "" {
+			t.Fatalf("reparse of %q (%+v) after %+v does not match a full parse: %s", source, mode, edit, diff)
+		}
+	}
+}
+
+// tryReparse reparses source after edit, returning the value of the panic of the reparse, if any
+func tryReparse(t *testing.T, doc *Node, source string, edit Edit, mode reparseMode) (got *Node, incremental bool, panicked any) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = r
+		}
+	}()
+	opts := append(mode.options(), ParseOptionWithHandler(handler.NewHandler(edit.Apply(source), "")))
+	got, element, err := reparse(doc, source, edit, opts...)
+	if err != nil {
+		t.Fatalf("reparse of %q (%+v) after %+v failed: %v", source, mode, edit, err)
+	}
+	return got, element != nil, nil
+}
+
+// tryParse returns the value of the panic of a full parse of source, or nil
+func tryParse(source string, mode reparseMode) (panicked any) {
+	defer func() {
+		panicked = recover()
+	}()
+	opts := append(mode.options(), ParseOptionWithHandler(handler.NewHandler(source, "")), ParseOptionEnableIncremental(true))
+	ParseWithOptions(strings.NewReader(source), opts...)
+	return nil
+}
+
+func parseIncremental(t *testing.T, source string, mode reparseMode) *Node {
+	t.Helper()
+	h := handler.NewHandler(source, "")
+	opts := append(mode.options(), ParseOptionWithHandler(h), ParseOptionEnableIncremental(true))
+	doc, err := ParseWithOptions(strings.NewReader(source), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// diffReparse describes the first difference between two trees
+func diffReparse(got *Node, want *Node) string {
+	var diff func(path string, x *Node, y *Node) string
+	diff = func(path string, x *Node, y *Node) string {
+		path = fmt.Sprintf("%s/%s(%s)", path, y.Type, y.Data)
+		if x.Type != y.Type || x.DataAtom != y.DataAtom || x.Data != y.Data || x.Namespace != y.Namespace {
+			return fmt.Sprintf("%s: got %s(%q)", path, x.Type, x.Data)
+		}
+		if x.Fragment != y.Fragment || x.Component != y.Component || x.CustomElement != y.CustomElement || x.Expression != y.Expression {
+			return fmt.Sprintf("%s: flags differ", path)
+		}
+		if fmt.Sprint(x.Loc) != fmt.Sprint(y.Loc) {
+			return fmt.Sprintf("%s: got Loc %v, want %v", path, x.Loc, y.Loc)
+		}
+		if x.Span != y.Span || x.Open != y.Open || x.Close != y.Close {
+			return fmt.Sprintf("%s: got spans %v %v %v, want %v %v %v", path, x.Span, x.Open, x.Close, y.Span, y.Open, y.Close)
+		}
+		if len(x.Attr) != len(y.Attr) {
+			return fmt.Sprintf("%s: got %d attributes, want %d", path, len(x.Attr), len(y.Attr))
+		}
+		for i := range x.Attr {
+			ax, ay := x.Attr[i], y.Attr[i]
+			if ax.Namespace != ay.Namespace || ax.Key != ay.Key || ax.KeyLoc != ay.KeyLoc || ax.Val != ay.Val || ax.ValLoc != ay.ValLoc || ax.Type != ay.Type || ax.Span != ay.Span || ax.KeySpan != ay.KeySpan || ax.ValSpan != ay.ValSpan {
+				return fmt.Sprintf("%s: got attribute %+v, want %+v", path, ax, ay)
+			}
+		}
+		cx, cy := x.FirstChild, y.FirstChild
+		for ; cx != nil && cy != nil; cx, cy = cx.NextSibling, cy.NextSibling {
+			if cx.Parent != x {
+				return fmt.Sprintf("%s: broken parent link", path)
+			}
+			if d := diff(path, cx, cy); d != "" {
+				return d
+			}
+		}
+		if cx != nil || cy != nil {
+			return fmt.Sprintf("%s: different number of children", path)
+		}
+		return ""
+	}
+	return diff("", got, want)
+}
+
+func TestReparse(t *testing.T) {
+	source := strings.Join([]string{
+		"---",
+		"import Layout from '../layouts/Layout.astro';",
+		"const items = ['a', 'b'];",
+		"---",
+		`<Layout title="Home">`,
+		`	<main class="content">`,
+		`		<h1>Hello <em>world</em></h1>`,
+		`		<p>Some text</p>`,
+		`		<ul>{items.map(item => <li>{item}</li>)}</ul>`,
+		`	</main>`,
+		`</Layout>`,
+		`<style>main { color: red; }</style>`,
+		`<script>console.log("hi")</script>`,
+	}, "\n")
+
+	tests := []struct {
+		name        string
+		find        string
+		offset      int
+		remove      int
+		text        string
+		incremental bool
+	}{
+		{name: "text", find: "Some text", offset: 5, text: "more ", incremental: true},
+		{name: "element", find: "Some text", offset: 0, text: "<strong>Bold</strong> ", incremental: true},
+		{name: "delete", find: "Some text", offset: 4, remove: 5, incremental: true},
+		{name: "expression in text", find: "Some text", offset: 0, text: "{items.length} ", incremental: true},
+		{name: "style", find: "red", offset: 0, remove: 3, text: "blue", incremental: true},
+		{name: "script", find: `"hi"`, offset: 1, remove: 2, text: "hello", incremental: true},
+		{name: "component children", find: "\t<main", offset: 0, text: "<Header />\n", incremental: true},
+		{name: "end tag", find: "Some text", offset: 4, text: "</p>", incremental: true},
+		{name: "closing the parent", find: "Some text", offset: 4, text: "</main>", incremental: false},
+		{name: "unclosed formatting element", find: "Some text", offset: 4, text: "<b>", incremental: true},
+		{name: "unclosed formatting element at the root", find: "<style>", offset: 0, text: "<b>", incremental: false},
+		{name: "unclosed expression", find: "Some text", offset: 4, text: "{", incremental: false},
+		{name: "attribute", find: `"content"`, offset: 1, text: "main ", incremental: true},
+		{name: "attribute at the root", find: `"Home"`, offset: 1, text: "My ", incremental: false},
+		{name: "frontmatter", find: "'a'", offset: 0, text: "'z', ", incremental: false},
+		{name: "expression", find: "<li>", offset: 4, text: "- ", incremental: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := strings.Index(source, tt.find) + tt.offset
+			edit := Edit{Start: start, End: start + tt.remove, Text: tt.text}
+			doc := parseIncremental(t, source, reparseMode{})
+			h := handler.NewHandler(edit.Apply(source), "")
+			got, element, err := reparse(doc, source, edit, ParseOptionWithHandler(h))
+			if err != nil {
+				t.Fatal(err)
+			}
+			incremental := element != nil
+			if incremental != tt.incremental {
+				t.Errorf("expected an incremental reparse to be %v", tt.incremental)
+			}
+			if incremental && got != doc {
+				t.Error("expected the tree to be updated in place")
+			}
+			if diff := diffReparse(got, parseIncremental(t, edit.Apply(source), reparseMode{})); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// TestReparseFixtures checks the edits of testReparse on the sources of the tokenizer and the parser suites
+func TestReparseFixtures(t *testing.T) {
+	inputs := func(n int, input func(int) string) []string {
+		s := make([]string, n)
+		for i := range s {
+			s[i] = input(i)
+		}
+		return s
+	}
+	suites := []struct {
+		name   string
+		inputs []string
+	}{
+		{"parser location", inputs(len(parserLocTests), func(i int) string { return parserLocTests[i].input })},
+		{"basic", inputs(len(basicTests), func(i int) string { return basicTests[i].input })},
+		{"frontmatter", inputs(len(frontmatterTests), func(i int) string { return frontmatterTests[i].input })},
+		{"expressions", inputs(len(expressionTests), func(i int) string { return expressionTests[i].input })},
+		{"attributes", inputs(len(attributeTests), func(i int) string { return attributeTests[i].input })},
+		{"loc", inputs(len(locTests), func(i int) string { return locTests[i].input })},
+	}
+
+	for _, suite := range suites {
+		t.Run(suite.name, func(t *testing.T) {
+			for _, input := range suite.inputs {
+				testReparse(t, test_utils.Dedent(input))
+			}
+		})
+	}
+}
+
+func TestReparseEveryEdit(t *testing.T) {
+	for _, source := range []string{
+		"---\nconst a = 1;\n---\n<div class=\"a\">{a}</div>",
+		"<ul><li>One<li><b>Two</b></ul><p>Text {items.map(i => <i>{i}</i>)}</p>",
+		"<Layout title={title}><table><tr><td>Cell</td></tr></table></Layout>",
+		"<style>a { color: red; }</style><script>let a = '<b>';</script>",
+	} {
+		testReparseEveryEdit(t, source)
+	}
+}
+
+func TestReparseSequence(t *testing.T) {
+	source := "<div class=\"card\">\n\t<p>Hello</p>\n</div>\n<footer>{year}</footer>\n"
+	doc := parseIncremental(t, source, reparseMode{})
+	offset := strings.Index(source, "Hello") + len("Hello")
+
+	// Type a word, one character at a time, then remove it
+	typed := 0
+	for _, text := range []string{" ", "<", "b", ">", "w", "<", "/", "b", ">"} {
+		edit := Edit{Start: offset + typed, End: offset + typed, Text: text}
+		var err error
+		doc, _, err = reparse(doc, source, edit, ParseOptionWithHandler(handler.NewHandler(edit.Apply(source), "")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		source = edit.Apply(source)
+		typed += len(text)
+		if diff := diffReparse(doc, parseIncremental(t, source, reparseMode{})); diff != "" {
+			t.Fatalf("after typing %q: %s", source, diff)
+		}
+	}
+	edit := Edit{Start: offset, End: offset + typed}
+	doc, element, err := reparse(doc, source, edit, ParseOptionWithHandler(handler.NewHandler(edit.Apply(source), "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source = edit.Apply(source)
+	if element == nil {
+		t.Error("expected the removal to be reparsed incrementally")
+	}
+	if diff := diffReparse(doc, parseIncremental(t, source, reparseMode{})); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestReparseImpliedEnd(t *testing.T) {
+	// The <li> are closed by their parent, and end with their content
+	source := "<ul><li>One<li><b>Two</b></ul>"
+	mode := reparseMode{literal: true}
+	for _, edit := range []Edit{
+		{Start: strings.Index(source, "<b>") + 1, End: strings.Index(source, "<b>") + 1, Text: "a"},
+		{Start: strings.Index(source, "Two"), End: strings.Index(source, "Two") + 1},
+	} {
+		got, _, panicked := tryReparse(t, parseIncremental(t, source, mode), source, edit, mode)
+		if panicked != nil {
+			t.Fatalf("reparse after %+v panicked: %v", edit, panicked)
+		}
+		if diff := diffReparse(got, parseIncremental(t, edit.Apply(source), mode)); diff != "" {
+			t.Errorf("after %+v: %s", edit, diff)
+		}
+	}
+}
+
+func TestReparseDiagnostics(t *testing.T) {
+	source := "<div><p>Text</p></div>"
+	edit := Edit{Start: strings.Index(source, "Text"), End: strings.Index(source, "Text"), Text: "<a"}
+	h := handler.NewHandler(edit.Apply(source), "")
+	_, element, err := reparse(parseIncremental(t, source, reparseMode{}), source, edit, ParseOptionWithHandler(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := handler.NewHandler(edit.Apply(source), "")
+	ParseWithOptions(strings.NewReader(edit.Apply(source)), ParseOptionWithHandler(full))
+	if fmt.Sprint(h.Diagnostics()) != fmt.Sprint(full.Diagnostics()) {
+		t.Errorf("expected the diagnostics of a full parse (incremental: %v), got %v, want %v", element != nil, h.Diagnostics(), full.Diagnostics())
+	}
+}
+
+func TestReparseOutOfRange(t *testing.T) {
+	source := "<div></div>"
+	if _, _, err := Reparse(parseIncremental(t, source, reparseMode{}), source, Edit{Start: 4, End: 20}); err == nil {
+		t.Error("expected an error for an edit out of range")
+	}
+}
@@ -0,0 +1,184 @@
+package js_parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Node is a node of an ESTree AST, with the TypeScript nodes of typescript-estree. Start and
+// End are byte offsets in the whole source given to the parser.
+type Node struct {
+	Type   string
+	Start  int
+	End    int
+	Fields []Field
+}
+
+// Field is a property of a Node. Value is a *Node, a []*Node (with nil for the holes of an
+// array), an Object, a string, a float64, a bool or nil.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Object is a plain object value, like the value of a TemplateElement
+type Object []Field
+
+// SyntaxError is an error of the source at byte offset Pos. End is the end of the unexpected
+// token, or Pos when the error is at a single position.
+type SyntaxError struct {
+	Pos     int
+	End     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+// Get returns the value of the field key, or nil
+func (n *Node) Get(key string) any {
+	for _, f := range n.Fields {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// Child returns the node of the field key, or nil
+func (n *Node) Child(key string) *Node {
+	child, _ := n.Get(key).(*Node)
+	return child
+}
+
+// Children returns the nodes of the field key
+func (n *Node) Children(key string) []*Node {
+	children, _ := n.Get(key).([]*Node)
+	return children
+}
+
+func (n *Node) set(key string, value any) {
+	for i, f := range n.Fields {
+		if f.Key == key {
+			n.Fields[i].Value = value
+			return
+		}
+	}
+	n.Fields = append(n.Fields, Field{key, value})
+}
+
+// Walk calls fn for n and its descendants, depth first, as long as fn returns true
+func Walk(n *Node, fn func(*Node) bool) {
+	if n == nil || !fn(n) {
+		return
+	}
+	for _, f := range n.Fields {
+		switch value := f.Value.(type) {
+		case *Node:
+			Walk(value, fn)
+		case []*Node:
+			for _, child := range value {
+				Walk(child, fn)
+			}
+		}
+	}
+}
+
+// Position maps a byte offset to a line, starting at 1, and a column, starting at 0
+type Position func(offset int) (line int, column int)
+
+// WriteJSON writes n as ESTree JSON to b, adding a "loc" to each node when position is not nil
+func (n *Node) WriteJSON(b *strings.Builder, position Position) {
+	if n == nil {
+		b.WriteString("null")
+		return
+	}
+	b.WriteString(`{"type":`)
+	writeString(b, n.Type)
+	for _, f := range n.Fields {
+		b.WriteByte(',')
+		writeString(b, f.Key)
+		b.WriteByte(':')
+		writeValue(b, f.Value, position)
+	}
+	fmt.Fprintf(b, `,"start":%d,"end":%d`, n.Start, n.End)
+	if position != nil {
+		startLine, startColumn := position(n.Start)
+		endLine, endColumn := position(n.End)
+		fmt.Fprintf(b, `,"loc":{"start":{"line":%d,"column":%d},"end":{"line":%d,"column":%d}}`, startLine, startColumn, endLine, endColumn)
+	}
+	b.WriteByte('}')
+}
+
+func writeValue(b *strings.Builder, value any, position Position) {
+	switch value := value.(type) {
+	case nil:
+		b.WriteString("null")
+	case *Node:
+		value.WriteJSON(b, position)
+	case []*Node:
+		b.WriteByte('[')
+		for i, child := range value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			child.WriteJSON(b, position)
+		}
+		b.WriteByte(']')
+	case Object:
+		b.WriteByte('{')
+		for i, f := range value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeString(b, f.Key)
+			b.WriteByte(':')
+			writeValue(b, f.Value, position)
+		}
+		b.WriteByte('}')
+	case string:
+		writeString(b, value)
+	case bool:
+		b.WriteString(strconv.FormatBool(value))
+	case float64:
+		switch {
+		case math.IsInf(value, 0) || math.IsNaN(value):
+			// JSON has no Infinity, as with JSON.stringify
+			b.WriteString("null")
+		case value == math.Trunc(value) && math.Abs(value) < 1e21:
+			b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+		default:
+			b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+		}
+	default:
+		panic(fmt.Sprintf("js_parser: unexpected field value %T", value))
+	}
+}
+
+func writeString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == '\u2028' || r == '\u2029':
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	b.WriteByte('"')
+}
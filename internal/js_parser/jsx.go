@@ -0,0 +1,317 @@
+package js_parser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokens read by readJSX, besides tName, tString and tPunct
+const (
+	// tJSXText is the text between the tags and the expressions of the children of an element
+	tJSXText tokenKind = iota + 100
+)
+
+// jsxMode is the context readJSX reads a token in
+type jsxMode int
+
+const (
+	// jsxTag reads the names, strings and punctuators of a tag
+	jsxTag jsxMode = iota
+	// jsxChildren reads text until a tag or an expression
+	jsxChildren
+)
+
+// readJSX reads the next token of a JSX element. In a tag, names may contain dashes, and strings
+// have no escapes; children are read as text until a "<" or a "{".
+func (l *lexer) readJSX(mode jsxMode) token {
+	nl := false
+	if mode == jsxTag {
+		nl = l.skipSpace()
+	}
+	t := token{start: l.pos, nl: nl}
+	if l.pos >= l.end {
+		t.kind = tEOF
+		t.end = l.pos
+		return t
+	}
+	c := l.source[l.pos]
+	switch {
+	case mode == jsxChildren && c != '<' && c != '{':
+		t.kind = tJSXText
+		for l.pos < l.end && l.source[l.pos] != '<' && l.source[l.pos] != '{' {
+			l.pos++
+		}
+		t.value = l.source[t.start:l.pos]
+	case mode == jsxTag && (c == '"' || c == '\''):
+		end := strings.IndexByte(l.source[l.pos+1:l.end], c)
+		if end == -1 {
+			l.fail(t.start, "Unterminated string literal")
+		}
+		t.kind = tString
+		t.value = l.source[l.pos+1 : l.pos+1+end]
+		l.pos += 1 + end + 1
+	case mode == jsxTag && isJSXNameStart(l.source, l.pos):
+		t.kind = tName
+		for l.pos < l.end {
+			r, size := utf8.DecodeRuneInString(l.source[l.pos:l.end])
+			if r != '-' && !isIdentifierPart(r) {
+				break
+			}
+			l.pos += size
+		}
+		t.value = l.source[t.start:l.pos]
+	default:
+		t.kind = tPunct
+		t.value = string(c)
+		l.pos++
+	}
+	t.end = l.pos
+	return t
+}
+
+func isJSXNameStart(source string, pos int) bool {
+	r, _ := utf8.DecodeRuneInString(source[pos:])
+	return isIdentifierStart(r)
+}
+
+// nextJSX reads the token after the current one in mode
+func (p *parser) nextJSX(mode jsxMode) {
+	p.prevEnd = p.tok.end
+	p.pos = p.tok.end
+	p.tok = p.readJSX(mode)
+}
+
+// isJSXTypeParameters reports whether the "<" of an arrow function with type parameters can't
+// start a JSX element, like "<T,>" or "<T extends U>", which is how they are written in a .tsx file
+func (p *parser) isJSXTypeParameters(typeParameters *Node) bool {
+	params := typeParameters.Children("params")
+	if len(params) != 1 || params[0].Child("constraint") != nil {
+		return true
+	}
+	return strings.HasSuffix(strings.TrimRight(p.source[typeParameters.Start:typeParameters.End-1], " \t\r\n"), ",")
+}
+
+// parseJSXElement parses a JSX element or fragment starting at the current "<" token. The
+// current token is left on the ">" ending the element, to be followed in the mode of the caller.
+func (p *parser) parseJSXElement() *Node {
+	start := p.tok.start
+	p.nextJSX(jsxTag)
+	if p.is(">") {
+		opening := p.make("JSXOpeningFragment", start)
+		opening.End = p.tok.end
+		children := p.parseJSXChildren()
+		closingStart := p.tok.start
+		p.nextJSX(jsxTag)
+		p.expectJSX("/")
+		if !p.is(">") {
+			p.failRange(closingStart, p.tok.end, "Expected corresponding JSX closing tag for <>")
+		}
+		closing := p.make("JSXClosingFragment", closingStart)
+		closing.End = p.tok.end
+		n := p.make("JSXFragment", start, "openingFragment", opening, "children", children, "closingFragment", closing)
+		n.End = p.tok.end
+		return n
+	}
+
+	name := p.parseJSXElementName()
+	var typeArguments *Node
+	if p.is("<") {
+		// <Select<Option> /> passes type arguments to a generic component
+		p.pos = p.tok.start
+		p.tok = p.lexer.next()
+		typeArguments = p.parseTypeArguments()
+		p.pos = p.tok.start
+		p.tok = p.readJSX(jsxTag)
+	}
+	attributes := []*Node{}
+	for !p.is(">") && !p.is("/") {
+		attributes = append(attributes, p.parseJSXAttribute())
+	}
+	selfClosing := p.is("/")
+	if selfClosing {
+		p.nextJSX(jsxTag)
+		if !p.is(">") {
+			p.expected(">")
+		}
+	}
+	opening := p.make("JSXOpeningElement", start, "name", name, "attributes", attributes, "selfClosing", selfClosing)
+	opening.End = p.tok.end
+	if typeArguments != nil {
+		opening.set("typeArguments", typeArguments)
+	}
+	if selfClosing {
+		n := p.make("JSXElement", start, "openingElement", opening, "children", []*Node{}, "closingElement", nil)
+		n.End = p.tok.end
+		return n
+	}
+
+	children := p.parseJSXChildren()
+	closingStart := p.tok.start
+	p.nextJSX(jsxTag)
+	p.expectJSX("/")
+	closingName := p.parseJSXElementName()
+	if !p.is(">") || jsxName(closingName) != jsxName(name) {
+		p.failRange(closingStart, p.tok.end, "Expected corresponding JSX closing tag for <%s>", jsxName(name))
+	}
+	closing := p.make("JSXClosingElement", closingStart, "name", closingName)
+	closing.End = p.tok.end
+	n := p.make("JSXElement", start, "openingElement", opening, "children", children, "closingElement", closing)
+	n.End = p.tok.end
+	return n
+}
+
+// parseJSXElementName parses a name like "div", "svg:rect" or "Foo.Bar" in a tag
+func (p *parser) parseJSXElementName() *Node {
+	start := p.tok.start
+	name := p.parseJSXIdentifier()
+	if p.is(":") {
+		p.nextJSX(jsxTag)
+		local := p.parseJSXIdentifier()
+		return p.make("JSXNamespacedName", start, "namespace", name, "name", local)
+	}
+	for p.is(".") {
+		p.nextJSX(jsxTag)
+		property := p.parseJSXIdentifier()
+		name = p.make("JSXMemberExpression", start, "object", name, "property", property)
+	}
+	return name
+}
+
+func (p *parser) parseJSXIdentifier() *Node {
+	if p.tok.kind != tName {
+		p.unexpected()
+	}
+	start, name := p.tok.start, p.tok.value
+	p.nextJSX(jsxTag)
+	return p.make("JSXIdentifier", start, "name", name)
+}
+
+// jsxName returns the source of the name of an element, to match its closing tag
+func jsxName(n *Node) string {
+	switch n.Type {
+	case "JSXNamespacedName":
+		return jsxName(n.Child("namespace")) + ":" + jsxName(n.Child("name"))
+	case "JSXMemberExpression":
+		return jsxName(n.Child("object")) + "." + jsxName(n.Child("property"))
+	}
+	name, _ := n.Get("name").(string)
+	return name
+}
+
+func (p *parser) parseJSXAttribute() *Node {
+	start := p.tok.start
+	if p.is("{") {
+		p.next()
+		p.expect("...")
+		argument := p.parseMaybeAssign()
+		if !p.is("}") {
+			p.expected("}")
+		}
+		p.nextJSX(jsxTag)
+		return p.make("JSXSpreadAttribute", start, "argument", argument)
+	}
+
+	name := p.parseJSXIdentifier()
+	if p.is(":") {
+		p.nextJSX(jsxTag)
+		local := p.parseJSXIdentifier()
+		name = p.make("JSXNamespacedName", start, "namespace", name, "name", local)
+	}
+	if !p.is("=") {
+		return p.make("JSXAttribute", start, "name", name, "value", nil)
+	}
+	p.nextJSX(jsxTag)
+	var value *Node
+	switch {
+	case p.tok.kind == tString:
+		valueStart, raw := p.tok.start, p.source[p.tok.start:p.tok.end]
+		value = &Node{Type: "Literal", Start: valueStart, End: p.tok.end, Fields: []Field{{"value", p.tok.value}, {"raw", raw}}}
+		p.nextJSX(jsxTag)
+	case p.is("{"):
+		value = p.parseJSXExpressionContainer(jsxTag)
+		if value.Child("expression").Type == "JSXEmptyExpression" {
+			p.fail(value.Start, "JSX attributes must only be assigned a non-empty expression")
+		}
+	case p.is("<"):
+		value = p.parseJSXElement()
+		p.nextJSX(jsxTag)
+	default:
+		p.unexpected()
+	}
+	return p.make("JSXAttribute", start, "name", name, "value", value)
+}
+
+// parseJSXExpressionContainer parses the expression at the current "{", then reads the token after
+// its "}" in mode
+func (p *parser) parseJSXExpressionContainer(mode jsxMode) *Node {
+	start := p.tok.start
+	p.next()
+	var n *Node
+	switch {
+	case p.is("}"):
+		empty := &Node{Type: "JSXEmptyExpression", Start: start + 1, End: p.tok.start}
+		n = &Node{Type: "JSXExpressionContainer", Start: start, End: p.tok.end, Fields: []Field{{"expression", empty}}}
+	case p.is("...") && mode == jsxChildren:
+		p.next()
+		expression := p.parseExpression()
+		if !p.is("}") {
+			p.expected("}")
+		}
+		n = &Node{Type: "JSXSpreadChild", Start: start, End: p.tok.end, Fields: []Field{{"expression", expression}}}
+	default:
+		expression := p.parseExpression()
+		if !p.is("}") {
+			p.expected("}")
+		}
+		n = &Node{Type: "JSXExpressionContainer", Start: start, End: p.tok.end, Fields: []Field{{"expression", expression}}}
+	}
+	p.nextJSX(mode)
+	return n
+}
+
+// parseJSXChildren parses the children of an element after the ">" of its opening tag, up to the
+// "<" of its closing tag
+func (p *parser) parseJSXChildren() []*Node {
+	open := p.tok.end
+	children := []*Node{}
+	p.nextJSX(jsxChildren)
+	for {
+		switch {
+		case p.tok.kind == tEOF:
+			p.fail(open, "Unterminated JSX contents")
+		case p.tok.kind == tJSXText:
+			if i := strings.IndexAny(p.tok.value, ">}"); i != -1 {
+				c := p.tok.value[i : i+1]
+				p.failRange(p.tok.start+i, p.tok.start+i+1, "Unexpected token '%s' in JSX text, use {'%s'} instead", c, c)
+			}
+			// The value is the raw text: HTML entities are not decoded
+			children = append(children, &Node{Type: "JSXText", Start: p.tok.start, End: p.tok.end, Fields: []Field{{"value", p.tok.value}, {"raw", p.tok.value}}})
+			p.nextJSX(jsxChildren)
+		case p.is("{"):
+			children = append(children, p.parseJSXExpressionContainer(jsxChildren))
+		case p.is("<"):
+			if next := p.peekJSX(); next.kind == tPunct && next.value == "/" {
+				return children
+			}
+			children = append(children, p.parseJSXElement())
+			p.nextJSX(jsxChildren)
+		}
+	}
+}
+
+// expectJSX consumes the punctuator value of a tag
+func (p *parser) expectJSX(value string) {
+	if !p.is(value) {
+		p.expected(value)
+	}
+	p.nextJSX(jsxTag)
+}
+
+// peekJSX returns the token of a tag after the current one
+func (p *parser) peekJSX() token {
+	s := p.save()
+	p.nextJSX(jsxTag)
+	t := p.tok
+	p.restore(s)
+	return t
+}
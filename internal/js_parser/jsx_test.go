@@ -0,0 +1,99 @@
+package js_parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJSXExpression(t *testing.T) {
+	tests := []testcase{
+		{
+			name:   "element",
+			source: `<div class="a" data-id='1'>Hello {name}!</div>`,
+			want:   "JSXElement JSXOpeningElement JSXIdentifier JSXAttribute JSXIdentifier Literal JSXAttribute JSXIdentifier Literal JSXText JSXExpressionContainer Identifier JSXText JSXClosingElement JSXIdentifier",
+		},
+		{
+			name:   "self-closing and nested",
+			source: `items.map((item) => <li><Icon.Check size={2} {...rest} />{item}</li>)`,
+			want:   "CallExpression MemberExpression Identifier Identifier ArrowFunctionExpression Identifier JSXElement JSXOpeningElement JSXIdentifier JSXElement JSXOpeningElement JSXMemberExpression JSXIdentifier JSXIdentifier JSXAttribute JSXIdentifier JSXExpressionContainer Literal JSXSpreadAttribute Identifier JSXExpressionContainer Identifier JSXClosingElement JSXIdentifier",
+		},
+		{
+			name:   "fragment",
+			source: `ok ? <><b /></> : null`,
+			want:   "ConditionalExpression Identifier JSXFragment JSXOpeningFragment JSXElement JSXOpeningElement JSXIdentifier JSXClosingFragment Literal",
+		},
+		{
+			name:   "namespaced names",
+			source: `<svg:rect xlink:href="#a" client:load />`,
+			want:   "JSXElement JSXOpeningElement JSXNamespacedName JSXIdentifier JSXIdentifier JSXAttribute JSXNamespacedName JSXIdentifier JSXIdentifier Literal JSXAttribute JSXNamespacedName JSXIdentifier JSXIdentifier",
+		},
+		{
+			name:   "element attribute and empty expression",
+			source: `<Card icon=<Star />>{/* comment */}</Card>`,
+			want:   "JSXElement JSXOpeningElement JSXIdentifier JSXAttribute JSXIdentifier JSXElement JSXOpeningElement JSXIdentifier JSXExpressionContainer JSXEmptyExpression JSXClosingElement JSXIdentifier",
+		},
+		{
+			name:   "generic arrow",
+			source: `<T,>(value: T) => value`,
+			want:   "ArrowFunctionExpression Identifier TSTypeAnnotation TSTypeReference Identifier Identifier TSTypeParameterDeclaration TSTypeParameter Identifier",
+		},
+		{
+			name:   "comparison",
+			source: `a < b && c > d`,
+			want:   "LogicalExpression BinaryExpression Identifier Identifier BinaryExpression Identifier Identifier",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := ParseJSXExpression(tt.source, 0, len(tt.source))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			types := []string{}
+			Walk(expression, func(n *Node) bool {
+				types = append(types, n.Type)
+				return true
+			})
+			if got := strings.Join(types, " "); got != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+			if expression.Start != 0 || expression.End != len(tt.source) {
+				t.Errorf("expected the expression to span 0-%d, got %d-%d", len(tt.source), expression.Start, expression.End)
+			}
+			checkRanges(t, expression)
+		})
+	}
+
+	if expression, err := ParseJSXExpression("{/* empty */}", 1, 12); expression != nil || err != nil {
+		t.Errorf("expected no expression for a comment, got %v, %v", expression, err)
+	}
+}
+
+func TestParseJSXExpressionErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		pos     int
+		message string
+	}{
+		{"<div></span>", 5, "Expected corresponding JSX closing tag for <div>"},
+		{"<div><p></div>", 8, "Expected corresponding JSX closing tag for <p>"},
+		{"<div>", 5, "Unterminated JSX contents"},
+		{"<a>1 > 0</a>", 5, "Unexpected token '>' in JSX text, use {'>'} instead"},
+		{"<a b={} />", 5, "JSX attributes must only be assigned a non-empty expression"},
+		{"<a>{a b}</a>", 6, "Expected '}' but found 'b'"},
+		{"<T>(x) => x", 8, "Unexpected token '>' in JSX text, use {'>'} instead"},
+		{"<a /><b />", 9, "Unexpected token '>'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := ParseJSXExpression(tt.source, 0, len(tt.source))
+			e, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if e.Pos != tt.pos || e.Message != tt.message {
+				t.Errorf("expected %q at %d, got %q at %d", tt.message, tt.pos, e.Message, e.Pos)
+			}
+		})
+	}
+}
@@ -0,0 +1,525 @@
+package js_parser
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	// tName is an identifier or a keyword; value holds the name with its escapes decoded
+	tName
+	tPrivateName
+	tPunct
+	tNumber
+	tBigInt
+	tString
+	// tTemplate is a part of a template literal: value is its cooked text, and tail is set
+	// when it ends the template
+	tTemplate
+	tRegExp
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	start int
+	end   int
+	// nl is set when a line terminator precedes the token
+	nl bool
+	// escaped is set when a name contains escapes, so that it can't be a keyword
+	escaped bool
+	// number is the value of a tNumber
+	number float64
+	// tail is set on the last part of a template literal, and invalid on a part with an invalid escape
+	tail    bool
+	invalid bool
+}
+
+// lexer splits a range of source into tokens. Regular expressions and the continuations of
+// template literals depend on the syntax, so the parser asks for them with rescanSlash and
+// rescanTemplate.
+type lexer struct {
+	source   string
+	pos      int
+	end      int
+	comments []*Node
+}
+
+func (l *lexer) fail(pos int, format string, args ...any) {
+	l.failRange(pos, pos, format, args...)
+}
+
+func (l *lexer) failRange(start int, end int, format string, args ...any) {
+	panic(&SyntaxError{Pos: start, End: end, Message: fmt.Sprintf(format, args...)})
+}
+
+var punctuators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=", "*=", "/=", "%=",
+	"&=", "|=", "^=", "<<", ">>", "**",
+	"{", "}", "(", ")", "[", "]", ";", ",", "<", ">", "+", "-", "*", "/", "%", "&", "|", "^",
+	"!", "~", "?", ":", "=", ".", "@",
+}
+
+func (l *lexer) next() token {
+	nl := l.skipSpace()
+	t := token{start: l.pos, nl: nl}
+	if l.pos >= l.end {
+		t.kind = tEOF
+		t.end = l.pos
+		return t
+	}
+	c := l.source[l.pos]
+	switch {
+	case c == '"' || c == '\'':
+		t.kind = tString
+		t.value = l.readString(c)
+	case c == '`':
+		l.pos++
+		l.readTemplate(&t)
+	case c == '#':
+		l.pos++
+		if name, _ := l.readName(); name != "" {
+			t.kind = tPrivateName
+			t.value = name
+		} else {
+			l.fail(t.start, "Unexpected character '#'")
+		}
+	case isDigit(c) || (c == '.' && l.pos+1 < l.end && isDigit(l.source[l.pos+1])):
+		l.readNumber(&t)
+	default:
+		if name, escaped := l.readName(); name != "" {
+			t.kind = tName
+			t.value = name
+			t.escaped = escaped
+			break
+		}
+		for _, p := range punctuators {
+			if strings.HasPrefix(l.source[l.pos:l.end], p) {
+				// "?." followed by a digit is a conditional, as in `a?.5:b`
+				if p == "?." && l.pos+2 < l.end && isDigit(l.source[l.pos+2]) {
+					continue
+				}
+				t.kind = tPunct
+				t.value = p
+				l.pos += len(p)
+				break
+			}
+		}
+		if t.kind != tPunct {
+			r, _ := utf8.DecodeRuneInString(l.source[l.pos:])
+			l.fail(l.pos, "Unexpected character %q", r)
+		}
+	}
+	t.end = l.pos
+	return t
+}
+
+// skipSpace skips whitespace and comments, returning whether a line terminator was skipped
+func (l *lexer) skipSpace() bool {
+	nl := false
+	for l.pos < l.end {
+		c := l.source[l.pos]
+		switch {
+		case c == '\n' || c == '\r':
+			nl = true
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			l.pos++
+		case c == '/' && l.pos+1 < l.end && l.source[l.pos+1] == '/':
+			start := l.pos
+			for l.pos < l.end && !isLineTerminator(l.source, l.pos) {
+				l.pos++
+			}
+			l.comment("Line", start, start+2, l.pos)
+		case c == '/' && l.pos+1 < l.end && l.source[l.pos+1] == '*':
+			start := l.pos
+			end := strings.Index(l.source[l.pos+2:l.end], "*/")
+			if end == -1 {
+				l.fail(start, "Unterminated comment")
+			}
+			l.pos += 2 + end + 2
+			if strings.ContainsAny(l.source[start:l.pos], "\n\r\u2028\u2029") {
+				nl = true
+			}
+			l.comment("Block", start, start+2, l.pos-2)
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(l.source[l.pos:])
+			if r == '\u2028' || r == '\u2029' {
+				nl = true
+			} else if !unicode.Is(unicode.Zs, r) && r != '\ufeff' {
+				return nl
+			}
+			l.pos += size
+		default:
+			return nl
+		}
+	}
+	return nl
+}
+
+func (l *lexer) comment(kind string, start int, valueStart int, valueEnd int) {
+	l.comments = append(l.comments, &Node{
+		Type:   kind,
+		Start:  start,
+		End:    l.pos,
+		Fields: []Field{{"value", l.source[valueStart:valueEnd]}},
+	})
+}
+
+// readName reads an identifier name, returning an empty name when there is none
+func (l *lexer) readName() (name string, escaped bool) {
+	var b strings.Builder
+	start := l.pos
+	for l.pos < l.end {
+		r, size := utf8.DecodeRuneInString(l.source[l.pos:l.end])
+		if r == '\\' {
+			if l.pos+1 >= l.end || l.source[l.pos+1] != 'u' {
+				l.fail(l.pos, "Invalid escape in identifier")
+			}
+			l.pos += 2
+			r = l.readUnicodeEscape()
+			escaped = true
+		} else {
+			if !isIdentifierPart(r) || (l.pos == start && !isIdentifierStart(r)) {
+				break
+			}
+			l.pos += size
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), escaped
+}
+
+// readUnicodeEscape reads the XXXX or {X...} of a \u escape
+func (l *lexer) readUnicodeEscape() rune {
+	if l.pos < l.end && l.source[l.pos] == '{' {
+		end := strings.IndexByte(l.source[l.pos:l.end], '}')
+		if end == -1 {
+			l.fail(l.pos, "Invalid Unicode escape")
+		}
+		n, err := strconv.ParseUint(l.source[l.pos+1:l.pos+end], 16, 32)
+		if err != nil || n > unicode.MaxRune {
+			l.fail(l.pos, "Invalid Unicode escape")
+		}
+		l.pos += end + 1
+		return rune(n)
+	}
+	if l.pos+4 > l.end {
+		l.fail(l.pos, "Invalid Unicode escape")
+	}
+	n, err := strconv.ParseUint(l.source[l.pos:l.pos+4], 16, 32)
+	if err != nil {
+		l.fail(l.pos, "Invalid Unicode escape")
+	}
+	l.pos += 4
+	return rune(n)
+}
+
+func (l *lexer) readNumber(t *token) {
+	start := l.pos
+	t.kind = tNumber
+	if l.source[l.pos] == '0' && l.pos+1 < l.end && strings.IndexByte("xXoObB", l.source[l.pos+1]) != -1 {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[l.source[l.pos+1]|0x20]
+		l.pos += 2
+		digits := l.readDigits(base)
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			l.fail(start, "Invalid number")
+		}
+		if l.pos < l.end && l.source[l.pos] == 'n' {
+			l.pos++
+			t.kind = tBigInt
+			t.value = n.String()
+		} else {
+			t.number, _ = new(big.Float).SetInt(n).Float64()
+		}
+	} else {
+		digits := l.readDigits(10)
+		integer := true
+		if l.pos < l.end && l.source[l.pos] == '.' {
+			l.pos++
+			digits += "." + l.readDigits(10)
+			integer = false
+		}
+		if l.pos < l.end && (l.source[l.pos] == 'e' || l.source[l.pos] == 'E') {
+			l.pos++
+			digits += "e"
+			if l.pos < l.end && (l.source[l.pos] == '+' || l.source[l.pos] == '-') {
+				digits += string(l.source[l.pos])
+				l.pos++
+			}
+			exponent := l.readDigits(10)
+			if exponent == "" {
+				l.fail(start, "Invalid number")
+			}
+			digits += exponent
+			integer = false
+		}
+		if integer && l.pos < l.end && l.source[l.pos] == 'n' {
+			l.pos++
+			t.kind = tBigInt
+			n, _ := new(big.Int).SetString(digits, 10)
+			t.value = n.String()
+		} else {
+			n, err := strconv.ParseFloat(digits, 64)
+			if err != nil && !math.IsInf(n, 0) {
+				l.fail(start, "Invalid number")
+			}
+			t.number = n
+		}
+	}
+	if l.pos < l.end {
+		if r, _ := utf8.DecodeRuneInString(l.source[l.pos:l.end]); isIdentifierStart(r) || isDigit(l.source[l.pos]) {
+			l.fail(l.pos, "Identifier directly after number")
+		}
+	}
+}
+
+// readDigits reads digits in base, without the numeric separators
+func (l *lexer) readDigits(base int) string {
+	var b strings.Builder
+	for l.pos < l.end {
+		c := l.source[l.pos]
+		if c == '_' {
+			l.pos++
+			continue
+		}
+		d := digitValue(c)
+		if d < 0 || d >= base {
+			break
+		}
+		b.WriteByte(c)
+		l.pos++
+	}
+	return b.String()
+}
+
+func (l *lexer) readString(quote byte) string {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for {
+		if l.pos >= l.end || l.source[l.pos] == '\n' || l.source[l.pos] == '\r' {
+			l.fail(start, "Unterminated string")
+		}
+		c := l.source[l.pos]
+		if c == quote {
+			l.pos++
+			return b.String()
+		}
+		if c == '\\' {
+			if !l.readEscape(&b, false) {
+				l.fail(l.pos, "Invalid escape sequence")
+			}
+			continue
+		}
+		b.WriteByte(c)
+		l.pos++
+	}
+}
+
+// readEscape reads an escape sequence at the backslash of l.pos into b. Escapes which are only
+// valid in strings, like legacy octal escapes, are invalid in templates.
+func (l *lexer) readEscape(b *strings.Builder, template bool) bool {
+	l.pos++
+	if l.pos >= l.end {
+		return false
+	}
+	c := l.source[l.pos]
+	l.pos++
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'v':
+		b.WriteByte('\v')
+	case '\r':
+		// Line continuation
+		if l.pos < l.end && l.source[l.pos] == '\n' {
+			l.pos++
+		}
+	case '\n':
+	case 'x':
+		if l.pos+2 > l.end {
+			return false
+		}
+		n, err := strconv.ParseUint(l.source[l.pos:l.pos+2], 16, 8)
+		if err != nil {
+			return false
+		}
+		l.pos += 2
+		b.WriteRune(rune(n))
+	case 'u':
+		ok := true
+		func() {
+			defer func() {
+				if recover() != nil {
+					ok = false
+				}
+			}()
+			b.WriteRune(l.readUnicodeEscape())
+		}()
+		return ok
+	default:
+		if c >= '0' && c <= '7' {
+			if c == '0' && (l.pos >= l.end || !isDigit(l.source[l.pos])) {
+				b.WriteByte(0)
+				return true
+			}
+			if template {
+				return false
+			}
+			// Legacy octal escape
+			n := int(c - '0')
+			for i := 0; i < 2 && l.pos < l.end && l.source[l.pos] >= '0' && l.source[l.pos] <= '7' && n*8+int(l.source[l.pos]-'0') <= 0xff; i++ {
+				n = n*8 + int(l.source[l.pos]-'0')
+				l.pos++
+			}
+			b.WriteRune(rune(n))
+			return true
+		}
+		if template && (c == '8' || c == '9') {
+			return false
+		}
+		// Any other character, including a multibyte one, escapes itself
+		l.pos--
+		r, size := utf8.DecodeRuneInString(l.source[l.pos:l.end])
+		l.pos += size
+		if r != '\u2028' && r != '\u2029' {
+			b.WriteRune(r)
+		}
+	}
+	return true
+}
+
+// readTemplate reads a part of a template literal, after its opening "`" or "}"
+func (l *lexer) readTemplate(t *token) {
+	t.kind = tTemplate
+	var b strings.Builder
+	for {
+		if l.pos >= l.end {
+			l.fail(t.start, "Unterminated template literal")
+		}
+		c := l.source[l.pos]
+		switch {
+		case c == '`':
+			l.pos++
+			t.tail = true
+			t.value = b.String()
+			return
+		case c == '$' && l.pos+1 < l.end && l.source[l.pos+1] == '{':
+			l.pos += 2
+			t.value = b.String()
+			return
+		case c == '\\':
+			if !l.readEscape(&b, true) {
+				t.invalid = true
+			}
+		case c == '\r':
+			// Line terminators are normalized to "\n" in cooked strings
+			l.pos++
+			if l.pos < l.end && l.source[l.pos] == '\n' {
+				l.pos++
+			}
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+// rescanTemplate reads the part of a template literal following the "}" of t
+func (l *lexer) rescanTemplate(t token) token {
+	l.pos = t.start + 1
+	next := token{start: t.start, nl: t.nl}
+	l.readTemplate(&next)
+	next.end = l.pos
+	return next
+}
+
+// rescanSlash reads the regular expression starting at the "/" or "/=" of t
+func (l *lexer) rescanSlash(t token) token {
+	l.pos = t.start + 1
+	inClass := false
+	for {
+		if l.pos >= l.end || isLineTerminator(l.source, l.pos) {
+			l.fail(t.start, "Unterminated regular expression")
+		}
+		c := l.source[l.pos]
+		l.pos++
+		if c == '\\' {
+			if l.pos < l.end && !isLineTerminator(l.source, l.pos) {
+				_, size := utf8.DecodeRuneInString(l.source[l.pos:l.end])
+				l.pos += size
+			}
+			continue
+		}
+		if c == '[' {
+			inClass = true
+		} else if c == ']' {
+			inClass = false
+		} else if c == '/' && !inClass {
+			break
+		}
+	}
+	pattern := l.source[t.start+1 : l.pos-1]
+	flags, _ := l.readName()
+	return token{kind: tRegExp, start: t.start, end: l.pos, nl: t.nl, value: pattern + "/" + flags}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func digitValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+func isLineTerminator(source string, i int) bool {
+	switch source[i] {
+	case '\n', '\r':
+		return true
+	case 0xe2:
+		return strings.HasPrefix(source[i:], "\u2028") || strings.HasPrefix(source[i:], "\u2029")
+	}
+	return false
+}
+
+func isIdentifierStart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r == '$' || r == '_' || (r|0x20 >= 'a' && r|0x20 <= 'z')
+	}
+	return unicode.IsLetter(r) || unicode.Is(unicode.Nl, r) || unicode.Is(unicode.Other_ID_Start, r)
+}
+
+func isIdentifierPart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return isIdentifierStart(r) || (r >= '0' && r <= '9')
+	}
+	return isIdentifierStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) || r == '\u200c' || r == '\u200d'
+}
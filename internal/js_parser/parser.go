@@ -0,0 +1,2088 @@
+// Package js_parser parses JavaScript and TypeScript into an ESTree AST, with the TypeScript
+// nodes of typescript-estree. It is a syntax parser only: it doesn't resolve scopes nor report
+// early errors beyond the grammar.
+package js_parser
+
+import (
+	"strings"
+)
+
+type parser struct {
+	lexer
+	tok     token
+	prevEnd int
+	// inGenerator makes yield a keyword
+	inGenerator bool
+	// noIn disallows the in operator, in the head of a for statement
+	noIn bool
+	// noConditionalType disallows conditional types, in the extends clause of another one
+	noConditionalType bool
+	// jsx parses JSX elements, as in a .tsx file
+	jsx bool
+	// noArrowReturnTypeAt is the start of an arrow function which cannot have a return type, as
+	// `(b): c => c` in `a ? (b) : c => c`, or -1
+	noArrowReturnTypeAt int
+}
+
+type state struct {
+	pos               int
+	tok               token
+	prevEnd           int
+	comments          int
+	inGenerator       bool
+	noIn              bool
+	noConditionalType bool
+}
+
+// Parse parses source[start:end] as a TypeScript module. Offsets in the AST are offsets in
+// source. The comments of the module are in the "comments" of the Program.
+func Parse(source string, start int, end int) (program *Node, err error) {
+	p := newParser(source, start, end)
+	defer p.recover(&err)
+	if strings.HasPrefix(source[start:end], "#!") {
+		// Skip a hashbang, as in an executable script
+		for p.pos < end && !isLineTerminator(source, p.pos) {
+			p.pos++
+		}
+	}
+	p.next()
+	program = &Node{Type: "Program", Start: start, End: end}
+	program.set("body", p.parseStatements(true))
+	program.set("sourceType", "module")
+	program.set("comments", p.comments)
+	return program, nil
+}
+
+// ParseExpression parses source[start:end] as a single expression
+func ParseExpression(source string, start int, end int) (expression *Node, err error) {
+	p := newParser(source, start, end)
+	defer p.recover(&err)
+	p.next()
+	expression = p.parseExpression()
+	if p.tok.kind != tEOF {
+		p.unexpected()
+	}
+	return expression, nil
+}
+
+// ParseJSXExpression is like ParseExpression, for an expression of a .tsx file: it may contain
+// JSX elements, and "<T>value" is an element rather than a type assertion. A range holding only
+// comments, like the empty expressions of JSX, returns a nil expression.
+func ParseJSXExpression(source string, start int, end int) (expression *Node, err error) {
+	p := newParser(source, start, end)
+	p.jsx = true
+	defer p.recover(&err)
+	p.next()
+	if p.tok.kind == tEOF {
+		return nil, nil
+	}
+	expression = p.parseExpression()
+	if p.tok.kind != tEOF {
+		p.unexpected()
+	}
+	return expression, nil
+}
+
+func newParser(source string, start int, end int) *parser {
+	p := &parser{lexer: lexer{source: source, pos: start, end: end, comments: []*Node{}}, noArrowReturnTypeAt: -1}
+	p.tok = token{start: start, end: start}
+	return p
+}
+
+func (p *parser) recover(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(*SyntaxError)
+		if !ok {
+			panic(r)
+		}
+		*err = e
+	}
+}
+
+func (p *parser) parseStatements(topLevel bool) []*Node {
+	body := []*Node{}
+	prologue := true
+	for p.tok.kind != tEOF && !(!topLevel && p.is("}")) {
+		statement := p.parseStatement()
+		if prologue {
+			prologue = p.directive(statement)
+		}
+		body = append(body, statement)
+	}
+	return body
+}
+
+// directive marks a statement of a directive prologue, like "use strict", returning whether it was one
+func (p *parser) directive(statement *Node) bool {
+	if statement.Type != "ExpressionStatement" {
+		return false
+	}
+	expression := statement.Child("expression")
+	if _, ok := expression.Get("value").(string); !ok || expression.Type != "Literal" || expression.Start != statement.Start {
+		return false
+	}
+	statement.set("directive", p.source[expression.Start+1:expression.End-1])
+	return true
+}
+
+func (p *parser) next() {
+	p.prevEnd = p.tok.end
+	p.tok = p.lexer.next()
+}
+
+func (p *parser) save() state {
+	return state{p.pos, p.tok, p.prevEnd, len(p.comments), p.inGenerator, p.noIn, p.noConditionalType}
+}
+
+func (p *parser) restore(s state) {
+	p.pos, p.tok, p.prevEnd = s.pos, s.tok, s.prevEnd
+	p.comments = p.comments[:s.comments]
+	p.inGenerator, p.noIn, p.noConditionalType = s.inGenerator, s.noIn, s.noConditionalType
+}
+
+// try runs fn, returning nil and restoring the state of the parser when it fails
+func (p *parser) try(fn func() *Node) (n *Node) {
+	s := p.save()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*SyntaxError); !ok {
+				panic(r)
+			}
+			p.restore(s)
+			n = nil
+		}
+	}()
+	return fn()
+}
+
+// peek returns the token after the current one
+func (p *parser) peek() token {
+	s := p.save()
+	p.next()
+	t := p.tok
+	p.restore(s)
+	return t
+}
+
+func (p *parser) is(value string) bool {
+	return p.tok.kind == tPunct && p.tok.value == value
+}
+
+func (p *parser) isName(value string) bool {
+	return p.tok.kind == tName && p.tok.value == value && !p.tok.escaped
+}
+
+func (p *parser) eat(value string) bool {
+	if p.is(value) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) eatName(value string) bool {
+	if p.isName(value) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(value string) {
+	if !p.eat(value) {
+		p.expected(value)
+	}
+}
+
+func (p *parser) expectName(value string) {
+	if !p.eatName(value) {
+		p.expected(value)
+	}
+}
+
+func (p *parser) expected(value string) {
+	if p.tok.kind == tEOF {
+		p.fail(p.tok.start, "Expected '%s' but found end of input", value)
+	}
+	p.failRange(p.tok.start, p.tok.end, "Expected '%s' but found '%s'", value, p.source[p.tok.start:p.tok.end])
+}
+
+func (p *parser) unexpected() {
+	if p.tok.kind == tEOF {
+		p.fail(p.tok.start, "Unexpected end of input")
+	}
+	p.failRange(p.tok.start, p.tok.end, "Unexpected token '%s'", p.source[p.tok.start:p.tok.end])
+}
+
+// semicolon consumes the semicolon ending a statement, or inserts one
+func (p *parser) semicolon() {
+	if !p.eat(";") && !p.is("}") && p.tok.kind != tEOF && !p.tok.nl {
+		p.unexpected()
+	}
+}
+
+// make returns a node of type typ from start to the end of the previous token, with fields given as
+// pairs of keys and values
+func (p *parser) make(typ string, start int, fields ...any) *Node {
+	n := &Node{Type: typ, Start: start, End: p.prevEnd, Fields: make([]Field, 0, len(fields)/2)}
+	for i := 0; i < len(fields); i += 2 {
+		n.Fields = append(n.Fields, Field{fields[i].(string), fields[i+1]})
+	}
+	return n
+}
+
+var reservedWords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+}
+
+func (p *parser) isReserved(t token) bool {
+	return t.kind == tName && !t.escaped && (reservedWords[t.value] || (t.value == "yield" && p.inGenerator))
+}
+
+// parseIdentifier parses an identifier, which may be a reserved word when it is a property name
+func (p *parser) parseIdentifier(allowReserved bool) *Node {
+	if p.tok.kind != tName || (!allowReserved && p.isReserved(p.tok)) {
+		p.unexpected()
+	}
+	start, name := p.tok.start, p.tok.value
+	p.next()
+	return p.make("Identifier", start, "name", name)
+}
+
+func (p *parser) parseLiteral() *Node {
+	start, raw := p.tok.start, p.source[p.tok.start:p.tok.end]
+	t := p.tok
+	p.next()
+	switch t.kind {
+	case tNumber:
+		return p.make("Literal", start, "value", t.number, "raw", raw)
+	case tString:
+		return p.make("Literal", start, "value", t.value, "raw", raw)
+	case tBigInt:
+		return p.make("Literal", start, "value", nil, "raw", raw, "bigint", t.value)
+	case tRegExp:
+		i := strings.LastIndexByte(t.value, '/')
+		return p.make("Literal", start, "value", nil, "raw", raw, "regex", Object{{"pattern", t.value[:i]}, {"flags", t.value[i+1:]}})
+	}
+	switch t.value {
+	case "null":
+		return p.make("Literal", start, "value", nil, "raw", raw)
+	default:
+		return p.make("Literal", start, "value", t.value == "true", "raw", raw)
+	}
+}
+
+// Statements
+
+func (p *parser) parseStatement() *Node {
+	start := p.tok.start
+	if p.tok.kind == tPunct {
+		switch p.tok.value {
+		case "{":
+			return p.parseBlock()
+		case ";":
+			p.next()
+			return p.make("EmptyStatement", start)
+		case "@":
+			decorators := p.parseDecorators()
+			if p.isName("export") {
+				// @decorator export class A {}
+				n := p.parseExport(start)
+				if declaration := n.Child("declaration"); declaration != nil && (declaration.Type == "ClassDeclaration" || declaration.Type == "ClassExpression") {
+					declaration.set("decorators", decorators)
+					declaration.Start = start
+				}
+				return n
+			}
+			if p.isName("abstract") {
+				n := p.parseTSDeclaration(start, false)
+				if n == nil {
+					p.unexpected()
+				}
+				n.set("decorators", decorators)
+				return n
+			}
+			return p.named(p.parseClass(start, true, decorators))
+		}
+	}
+	if p.tok.kind == tName && !p.tok.escaped {
+		switch p.tok.value {
+		case "var", "const":
+			if p.isName("const") && p.peek().value == "enum" {
+				p.next()
+				return p.parseEnum(start, true)
+			}
+			return p.parseVarStatement(start, p.tok.value)
+		case "let":
+			if p.startsBinding(p.peek()) {
+				return p.parseVarStatement(start, "let")
+			}
+		case "using", "await":
+			if kind := p.usingKind(); kind != "" {
+				return p.parseVarStatement(start, kind)
+			}
+		case "function":
+			return p.named(p.parseFunction(start, true, false))
+		case "async":
+			if next := p.peek(); next.kind == tName && next.value == "function" && !next.nl {
+				p.next()
+				return p.named(p.parseFunction(start, true, true))
+			}
+		case "class":
+			return p.named(p.parseClass(start, true, nil))
+		case "if":
+			return p.parseIf()
+		case "for":
+			return p.parseFor()
+		case "while":
+			p.next()
+			test := p.parseParenExpression()
+			body := p.parseStatement()
+			return p.make("WhileStatement", start, "test", test, "body", body)
+		case "do":
+			p.next()
+			body := p.parseStatement()
+			p.expectName("while")
+			test := p.parseParenExpression()
+			p.eat(";")
+			return p.make("DoWhileStatement", start, "body", body, "test", test)
+		case "return":
+			p.next()
+			var argument *Node
+			if !p.is(";") && !p.is("}") && p.tok.kind != tEOF && !p.tok.nl {
+				argument = p.parseExpression()
+			}
+			p.semicolon()
+			return p.make("ReturnStatement", start, "argument", argument)
+		case "break", "continue":
+			typ := map[string]string{"break": "BreakStatement", "continue": "ContinueStatement"}[p.tok.value]
+			p.next()
+			var label *Node
+			if p.tok.kind == tName && !p.tok.nl && !p.isReserved(p.tok) {
+				label = p.parseIdentifier(false)
+			}
+			p.semicolon()
+			return p.make(typ, start, "label", label)
+		case "throw":
+			p.next()
+			if p.tok.nl {
+				p.fail(p.prevEnd, "Illegal newline after throw")
+			}
+			argument := p.parseExpression()
+			p.semicolon()
+			return p.make("ThrowStatement", start, "argument", argument)
+		case "try":
+			return p.parseTry()
+		case "switch":
+			return p.parseSwitch()
+		case "debugger":
+			p.next()
+			p.semicolon()
+			return p.make("DebuggerStatement", start)
+		case "with":
+			p.next()
+			object := p.parseParenExpression()
+			body := p.parseStatement()
+			return p.make("WithStatement", start, "object", object, "body", body)
+		case "import":
+			if next := p.peek(); next.value != "(" && next.value != "." {
+				return p.parseImport(start)
+			}
+		case "export":
+			return p.parseExport(start)
+		default:
+			if declaration := p.parseTSDeclaration(start, false); declaration != nil {
+				return declaration
+			}
+		}
+	}
+
+	expression := p.parseExpression()
+	if expression.Type == "Identifier" && expression.Start == start && p.eat(":") {
+		body := p.parseStatement()
+		return p.make("LabeledStatement", start, "label", expression, "body", body)
+	}
+	p.semicolon()
+	return p.make("ExpressionStatement", start, "expression", expression)
+}
+
+// named checks that a declaration has a name, which only the default export may omit
+func (p *parser) named(declaration *Node) *Node {
+	if declaration.Child("id") == nil {
+		p.fail(declaration.Start, "Missing the name of the declaration")
+	}
+	return declaration
+}
+
+// usingKind returns the kind of a using declaration starting at the current token, "using" or
+// "await using", or an empty string when there is none
+func (p *parser) usingKind() string {
+	s := p.save()
+	defer p.restore(s)
+	kind := "using"
+	if p.isName("await") {
+		p.next()
+		if !p.isName("using") || p.tok.nl {
+			return ""
+		}
+		kind = "await using"
+	}
+	if !p.isName("using") {
+		return ""
+	}
+	p.next()
+	if p.tok.kind != tName || p.tok.nl || p.isName("in") || (p.isName("of") && kind == "using" && p.peek().value != "=") {
+		return ""
+	}
+	return kind
+}
+
+// startsBinding reports whether t starts a binding, after let
+func (p *parser) startsBinding(t token) bool {
+	return t.kind == tName || (t.kind == tPunct && (t.value == "[" || t.value == "{"))
+}
+
+func (p *parser) parseBlock() *Node {
+	start := p.tok.start
+	p.expect("{")
+	body := p.parseStatements(false)
+	p.expect("}")
+	return p.make("BlockStatement", start, "body", body)
+}
+
+func (p *parser) parseParenExpression() *Node {
+	p.expect("(")
+	noIn := p.noIn
+	p.noIn = false
+	expression := p.parseExpression()
+	p.noIn = noIn
+	p.expect(")")
+	return expression
+}
+
+func (p *parser) parseIf() *Node {
+	start := p.tok.start
+	p.next()
+	test := p.parseParenExpression()
+	consequent := p.parseStatement()
+	var alternate *Node
+	if p.eatName("else") {
+		alternate = p.parseStatement()
+	}
+	return p.make("IfStatement", start, "test", test, "consequent", consequent, "alternate", alternate)
+}
+
+// parseVarStatement parses a variable declaration of kind "var", "let" or "const"
+func (p *parser) parseVarStatement(start int, kind string) *Node {
+	declaration := p.parseVar(start, kind)
+	p.semicolon()
+	declaration.End = p.prevEnd
+	return declaration
+}
+
+func (p *parser) parseVar(start int, kind string) *Node {
+	for range strings.Fields(kind) {
+		p.next()
+	}
+	declarations := []*Node{}
+	for {
+		declaratorStart := p.tok.start
+		id := p.parseBindingAtom()
+		definite := p.eat("!")
+		if p.is(":") {
+			p.annotate(id, p.parseTypeAnnotation())
+		}
+		var init *Node
+		if p.eat("=") {
+			init = p.parseMaybeAssign()
+		}
+		declarator := p.make("VariableDeclarator", declaratorStart, "id", id, "init", init)
+		if definite {
+			declarator.set("definite", true)
+		}
+		declarations = append(declarations, declarator)
+		if !p.eat(",") {
+			break
+		}
+	}
+	return p.make("VariableDeclaration", start, "declarations", declarations, "kind", kind)
+}
+
+func (p *parser) parseFor() *Node {
+	start := p.tok.start
+	p.next()
+	await := p.eatName("await")
+	p.expect("(")
+	var init *Node
+	if !p.is(";") {
+		initStart := p.tok.start
+		kind := p.usingKind()
+		if kind == "" && (p.isName("var") || p.isName("const") || (p.isName("let") && p.startsBinding(p.peek()))) {
+			kind = p.tok.value
+		}
+		if kind != "" {
+			p.noIn = true
+			init = p.parseVar(initStart, kind)
+			p.noIn = false
+		} else {
+			p.noIn = true
+			init = p.parseExpression()
+			p.noIn = false
+			if p.isName("of") || p.isName("in") {
+				init = p.toPattern(init)
+			}
+		}
+		if p.isName("of") || p.isName("in") {
+			typ := "ForInStatement"
+			if p.isName("of") {
+				typ = "ForOfStatement"
+			}
+			p.next()
+			var right *Node
+			if typ == "ForOfStatement" {
+				right = p.parseMaybeAssign()
+			} else {
+				right = p.parseExpression()
+			}
+			p.expect(")")
+			body := p.parseStatement()
+			if typ == "ForOfStatement" {
+				return p.make(typ, start, "await", await, "left", init, "right", right, "body", body)
+			}
+			return p.make(typ, start, "left", init, "right", right, "body", body)
+		}
+	}
+	p.expect(";")
+	var test, update *Node
+	if !p.is(";") {
+		test = p.parseExpression()
+	}
+	p.expect(";")
+	if !p.is(")") {
+		update = p.parseExpression()
+	}
+	p.expect(")")
+	body := p.parseStatement()
+	return p.make("ForStatement", start, "init", init, "test", test, "update", update, "body", body)
+}
+
+func (p *parser) parseTry() *Node {
+	start := p.tok.start
+	p.next()
+	block := p.parseBlock()
+	var handler, finalizer *Node
+	if p.isName("catch") {
+		catchStart := p.tok.start
+		p.next()
+		var param *Node
+		if p.eat("(") {
+			param = p.parseBindingAtom()
+			if p.is(":") {
+				p.annotate(param, p.parseTypeAnnotation())
+			}
+			p.expect(")")
+		}
+		body := p.parseBlock()
+		handler = p.make("CatchClause", catchStart, "param", param, "body", body)
+	}
+	if p.eatName("finally") {
+		finalizer = p.parseBlock()
+	}
+	if handler == nil && finalizer == nil {
+		p.expected("catch")
+	}
+	return p.make("TryStatement", start, "block", block, "handler", handler, "finalizer", finalizer)
+}
+
+func (p *parser) parseSwitch() *Node {
+	start := p.tok.start
+	p.next()
+	discriminant := p.parseParenExpression()
+	p.expect("{")
+	cases := []*Node{}
+	for !p.eat("}") {
+		caseStart := p.tok.start
+		var test *Node
+		if p.eatName("case") {
+			test = p.parseExpression()
+		} else {
+			p.expectName("default")
+		}
+		p.expect(":")
+		consequent := []*Node{}
+		for !p.is("}") && !p.isName("case") && !p.isName("default") {
+			if p.tok.kind == tEOF {
+				p.unexpected()
+			}
+			consequent = append(consequent, p.parseStatement())
+		}
+		cases = append(cases, p.make("SwitchCase", caseStart, "test", test, "consequent", consequent))
+	}
+	return p.make("SwitchStatement", start, "discriminant", discriminant, "cases", cases)
+}
+
+// Modules
+
+func (p *parser) parseImport(start int) *Node {
+	p.next()
+	kind := "value"
+	if p.isName("type") {
+		// "import type from" imports a default export named type
+		next := p.peek()
+		if next.value == "{" || next.value == "*" || (next.kind == tName && (next.value != "from" || p.peekAfterNext().value == "from")) {
+			p.next()
+			kind = "type"
+		}
+	}
+	specifiers := []*Node{}
+	if p.tok.kind != tString {
+		if p.tok.kind == tName {
+			local := p.parseIdentifier(false)
+			if p.is("=") {
+				return p.parseImportEquals(start, local, kind, false)
+			}
+			specifiers = append(specifiers, p.make("ImportDefaultSpecifier", local.Start, "local", local))
+			if p.eat(",") && !p.is("*") && !p.is("{") {
+				p.unexpected()
+			}
+		}
+		if p.is("*") {
+			specifierStart := p.tok.start
+			p.next()
+			p.expectName("as")
+			local := p.parseIdentifier(false)
+			specifiers = append(specifiers, p.make("ImportNamespaceSpecifier", specifierStart, "local", local))
+		} else if p.eat("{") {
+			for !p.eat("}") {
+				specifierStart := p.tok.start
+				specifierKind := "value"
+				if p.isName("type") {
+					if next := p.peek(); (next.kind == tName && next.value != "as") || next.kind == tString {
+						p.next()
+						specifierKind = "type"
+					}
+				}
+				imported := p.parseModuleExportName()
+				local := imported
+				if p.eatName("as") {
+					local = p.parseIdentifier(false)
+				} else if imported.Type != "Identifier" {
+					p.expected("as")
+				}
+				specifiers = append(specifiers, p.make("ImportSpecifier", specifierStart, "imported", imported, "local", local, "importKind", specifierKind))
+				if !p.is("}") {
+					p.expect(",")
+				}
+			}
+		}
+		p.expectName("from")
+	}
+	if p.tok.kind != tString {
+		p.unexpected()
+	}
+	source := p.parseLiteral()
+	attributes := p.parseImportAttributes()
+	p.semicolon()
+	return p.make("ImportDeclaration", start, "specifiers", specifiers, "source", source, "attributes", attributes, "importKind", kind)
+}
+
+func (p *parser) peekAfterNext() token {
+	s := p.save()
+	p.next()
+	p.next()
+	t := p.tok
+	p.restore(s)
+	return t
+}
+
+// parseImportEquals parses the rest of `import a = require("b")` or `import a = b.c`
+func (p *parser) parseImportEquals(start int, id *Node, kind string, export bool) *Node {
+	p.expect("=")
+	var reference *Node
+	if p.isName("require") && p.peek().value == "(" {
+		referenceStart := p.tok.start
+		p.next()
+		p.next()
+		if p.tok.kind != tString {
+			p.unexpected()
+		}
+		expression := p.parseLiteral()
+		p.expect(")")
+		reference = p.make("TSExternalModuleReference", referenceStart, "expression", expression)
+	} else {
+		reference = p.parseEntityName(false)
+	}
+	p.semicolon()
+	return p.make("TSImportEqualsDeclaration", start, "id", id, "moduleReference", reference, "importKind", kind, "isExport", export)
+}
+
+// parseModuleExportName parses the name of an import or an export, which may be a string
+func (p *parser) parseModuleExportName() *Node {
+	if p.tok.kind == tString {
+		return p.parseLiteral()
+	}
+	return p.parseIdentifier(true)
+}
+
+func (p *parser) parseImportAttributes() []*Node {
+	attributes := []*Node{}
+	if !p.isName("with") && !(p.isName("assert") && !p.tok.nl) {
+		return attributes
+	}
+	p.next()
+	p.expect("{")
+	for !p.eat("}") {
+		start := p.tok.start
+		key := p.parseModuleExportName()
+		p.expect(":")
+		if p.tok.kind != tString {
+			p.unexpected()
+		}
+		value := p.parseLiteral()
+		attributes = append(attributes, p.make("ImportAttribute", start, "key", key, "value", value))
+		if !p.is("}") {
+			p.expect(",")
+		}
+	}
+	return attributes
+}
+
+func (p *parser) parseExport(start int) *Node {
+	p.next()
+	switch {
+	case p.isName("default"):
+		p.next()
+		declarationStart := p.tok.start
+		var declaration *Node
+		switch {
+		case p.isName("function"):
+			declaration = p.parseFunction(declarationStart, true, false)
+		case p.isName("async") && p.peek().value == "function" && !p.peek().nl:
+			p.next()
+			declaration = p.parseFunction(declarationStart, true, true)
+		case p.isName("class"):
+			declaration = p.parseClass(declarationStart, true, nil)
+		case p.is("@"):
+			decorators := p.parseDecorators()
+			declaration = p.parseClass(declarationStart, true, decorators)
+		case p.isName("abstract") && p.peek().value == "class", p.isName("interface") && p.peek().kind == tName:
+			declaration = p.parseTSDeclaration(declarationStart, false)
+		default:
+			declaration = p.parseMaybeAssign()
+			p.semicolon()
+		}
+		return p.make("ExportDefaultDeclaration", start, "declaration", declaration, "exportKind", "value")
+	case p.is("="):
+		p.next()
+		expression := p.parseExpression()
+		p.semicolon()
+		return p.make("TSExportAssignment", start, "expression", expression)
+	case p.isName("as"):
+		p.next()
+		p.expectName("namespace")
+		id := p.parseIdentifier(false)
+		p.semicolon()
+		return p.make("TSNamespaceExportDeclaration", start, "id", id)
+	case p.isName("import"):
+		p.next()
+		kind := "value"
+		if p.isName("type") && p.peek().kind == tName {
+			p.next()
+			kind = "type"
+		}
+		id := p.parseIdentifier(false)
+		return p.parseImportEquals(start, id, kind, true)
+	}
+
+	kind := "value"
+	if p.isName("type") {
+		if next := p.peek(); next.value == "*" || next.value == "{" {
+			p.next()
+			kind = "type"
+		}
+	}
+	if p.eat("*") {
+		var exported *Node
+		if p.eatName("as") {
+			exported = p.parseModuleExportName()
+		}
+		p.expectName("from")
+		if p.tok.kind != tString {
+			p.unexpected()
+		}
+		source := p.parseLiteral()
+		attributes := p.parseImportAttributes()
+		p.semicolon()
+		return p.make("ExportAllDeclaration", start, "exported", exported, "source", source, "attributes", attributes, "exportKind", kind)
+	}
+	if p.eat("{") {
+		specifiers := []*Node{}
+		for !p.eat("}") {
+			specifierStart := p.tok.start
+			specifierKind := "value"
+			if p.isName("type") {
+				if next := p.peek(); (next.kind == tName && next.value != "as") || next.kind == tString {
+					p.next()
+					specifierKind = "type"
+				}
+			}
+			local := p.parseModuleExportName()
+			exported := local
+			if p.eatName("as") {
+				exported = p.parseModuleExportName()
+			}
+			specifiers = append(specifiers, p.make("ExportSpecifier", specifierStart, "local", local, "exported", exported, "exportKind", specifierKind))
+			if !p.is("}") {
+				p.expect(",")
+			}
+		}
+		var source *Node
+		attributes := []*Node{}
+		if p.eatName("from") {
+			if p.tok.kind != tString {
+				p.unexpected()
+			}
+			source = p.parseLiteral()
+			attributes = p.parseImportAttributes()
+		}
+		p.semicolon()
+		return p.make("ExportNamedDeclaration", start, "declaration", nil, "specifiers", specifiers, "source", source, "attributes", attributes, "exportKind", kind)
+	}
+
+	declaration := p.parseStatement()
+	switch declaration.Type {
+	case "VariableDeclaration", "FunctionDeclaration", "ClassDeclaration", "TSDeclareFunction":
+	case "TSInterfaceDeclaration", "TSTypeAliasDeclaration":
+		kind = "type"
+	case "TSEnumDeclaration", "TSModuleDeclaration":
+	default:
+		p.fail(declaration.Start, "Unexpected export")
+	}
+	return p.make("ExportNamedDeclaration", start, "declaration", declaration, "specifiers", []*Node{}, "source", nil, "attributes", []*Node{}, "exportKind", kind)
+}
+
+// Functions and classes
+
+// parseFunction parses a function at the "function" keyword, after the "async" of an async function
+func (p *parser) parseFunction(start int, statement bool, async bool) *Node {
+	p.next()
+	generator := p.eat("*")
+	var id *Node
+	if p.tok.kind == tName && !p.is("(") {
+		id = p.parseIdentifier(false)
+	}
+	typ := "FunctionExpression"
+	if statement {
+		typ = "FunctionDeclaration"
+	}
+	n := &Node{Type: typ, Start: start}
+	n.set("id", id)
+	n.set("expression", false)
+	n.set("generator", generator)
+	n.set("async", async)
+	p.parseFunctionRest(n, generator, statement)
+	return n
+}
+
+// parseFunctionRest parses the type parameters, the parameters, the return type and the body of the
+// function n. A declaration without a body, like an overload, becomes a TSDeclareFunction.
+func (p *parser) parseFunctionRest(n *Node, generator bool, allowNoBody bool) {
+	inGenerator := p.inGenerator
+	p.inGenerator = generator
+	var typeParameters, returnType *Node
+	if p.is("<") {
+		typeParameters = p.parseTypeParameters()
+	}
+	params := p.parseParams()
+	if p.is(":") {
+		returnType = p.parseReturnType()
+	}
+	n.set("params", params)
+	if allowNoBody && !p.is("{") {
+		p.semicolon()
+		if n.Type == "FunctionDeclaration" {
+			n.Type = "TSDeclareFunction"
+		} else {
+			n.Type = "TSEmptyBodyFunctionExpression"
+		}
+		n.set("body", nil)
+	} else {
+		n.set("body", p.parseFunctionBody())
+	}
+	if typeParameters != nil {
+		n.set("typeParameters", typeParameters)
+	}
+	if returnType != nil {
+		n.set("returnType", returnType)
+	}
+	p.inGenerator = inGenerator
+	n.End = p.prevEnd
+}
+
+func (p *parser) parseFunctionBody() *Node {
+	noIn := p.noIn
+	p.noIn = false
+	start := p.tok.start
+	p.expect("{")
+	body := p.parseStatements(false)
+	p.expect("}")
+	p.noIn = noIn
+	return p.make("BlockStatement", start, "body", body)
+}
+
+func (p *parser) parseParams() []*Node {
+	p.expect("(")
+	params := []*Node{}
+	for !p.eat(")") {
+		params = append(params, p.parseParam())
+		if !p.is(")") {
+			p.expect(",")
+		}
+	}
+	return params
+}
+
+var parameterModifiers = map[string]bool{"public": true, "private": true, "protected": true, "readonly": true, "override": true}
+
+func (p *parser) parseParam() *Node {
+	start := p.tok.start
+	decorators := p.parseDecorators()
+	var accessibility string
+	var readonly, override bool
+	for p.tok.kind == tName && parameterModifiers[p.tok.value] {
+		if next := p.peek(); next.kind != tName && next.value != "{" && next.value != "[" {
+			break
+		}
+		switch p.tok.value {
+		case "readonly":
+			readonly = true
+		case "override":
+			override = true
+		default:
+			accessibility = p.tok.value
+		}
+		p.next()
+	}
+	var param *Node
+	if p.is("...") {
+		p.next()
+		argument := p.parseBindingAtom()
+		param = p.make("RestElement", start, "argument", argument)
+		if p.is(":") {
+			p.annotate(param, p.parseTypeAnnotation())
+		}
+	} else {
+		param = p.parseBindingElement(true)
+	}
+	if len(decorators) > 0 {
+		param.set("decorators", decorators)
+	}
+	if accessibility != "" || readonly || override {
+		param = p.make("TSParameterProperty", start, "accessibility", nilIfEmpty(accessibility), "readonly", readonly, "override", override, "parameter", param)
+	}
+	return param
+}
+
+func nilIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// parseBindingElement parses a binding pattern with its default value. Parameters may also be
+// optional, and have a type.
+func (p *parser) parseBindingElement(param bool) *Node {
+	start := p.tok.start
+	var left *Node
+	if param && p.isName("this") {
+		p.next()
+		left = p.make("Identifier", start, "name", "this")
+	} else {
+		left = p.parseBindingAtom()
+	}
+	if param {
+		if p.eat("?") {
+			left.set("optional", true)
+			left.End = p.prevEnd
+		}
+		if p.is(":") {
+			p.annotate(left, p.parseTypeAnnotation())
+		}
+	}
+	if p.eat("=") {
+		right := p.parseMaybeAssign()
+		return p.make("AssignmentPattern", start, "left", left, "right", right)
+	}
+	return left
+}
+
+// annotate adds a type annotation to a binding
+func (p *parser) annotate(n *Node, annotation *Node) {
+	n.set("typeAnnotation", annotation)
+	n.End = annotation.End
+}
+
+func (p *parser) parseBindingAtom() *Node {
+	start := p.tok.start
+	switch {
+	case p.eat("["):
+		elements := []*Node{}
+		for !p.eat("]") {
+			if p.is(",") {
+				p.next()
+				elements = append(elements, nil)
+				continue
+			}
+			if p.is("...") {
+				restStart := p.tok.start
+				p.next()
+				argument := p.parseBindingAtom()
+				elements = append(elements, p.make("RestElement", restStart, "argument", argument))
+			} else {
+				elements = append(elements, p.parseBindingElement(false))
+			}
+			if !p.is("]") {
+				p.expect(",")
+			}
+		}
+		return p.make("ArrayPattern", start, "elements", elements)
+	case p.eat("{"):
+		properties := []*Node{}
+		for !p.eat("}") {
+			propertyStart := p.tok.start
+			if p.eat("...") {
+				argument := p.parseBindingAtom()
+				properties = append(properties, p.make("RestElement", propertyStart, "argument", argument))
+			} else {
+				key, computed := p.parsePropertyKey()
+				var value *Node
+				shorthand := false
+				if p.eat(":") {
+					value = p.parseBindingElement(false)
+				} else {
+					if key.Type != "Identifier" || computed || reservedWords[key.Get("name").(string)] {
+						p.expected(":")
+					}
+					shorthand = true
+					value = key
+					if p.eat("=") {
+						right := p.parseMaybeAssign()
+						value = p.make("AssignmentPattern", propertyStart, "left", key, "right", right)
+					}
+				}
+				properties = append(properties, p.make("Property", propertyStart, "method", false, "shorthand", shorthand, "computed", computed, "key", key, "value", value, "kind", "init"))
+			}
+			if !p.is("}") {
+				p.expect(",")
+			}
+		}
+		return p.make("ObjectPattern", start, "properties", properties)
+	}
+	return p.parseIdentifier(false)
+}
+
+func (p *parser) parseDecorators() []*Node {
+	decorators := []*Node{}
+	for p.is("@") {
+		start := p.tok.start
+		p.next()
+		expressionStart := p.tok.start
+		var expression *Node
+		if p.is("(") {
+			expression = p.parseParenExpression()
+		} else {
+			expression = p.parseIdentifier(true)
+			for p.eat(".") {
+				property := p.parseIdentifier(true)
+				expression = p.make("MemberExpression", expressionStart, "object", expression, "property", property, "computed", false, "optional", false)
+			}
+			var typeArguments *Node
+			if p.is("<") {
+				typeArguments = p.parseTypeArguments()
+			}
+			if p.is("(") {
+				arguments := p.parseArguments()
+				expression = p.make("CallExpression", expressionStart, "callee", expression, "arguments", arguments, "optional", false)
+				if typeArguments != nil {
+					expression.set("typeArguments", typeArguments)
+				}
+			}
+		}
+		decorators = append(decorators, p.make("Decorator", start, "expression", expression))
+	}
+	return decorators
+}
+
+// parseClass parses a class at the "class" keyword. Modifiers like abstract are set by the caller.
+func (p *parser) parseClass(start int, statement bool, decorators []*Node) *Node {
+	p.expectName("class")
+	var id, typeParameters, superClass, superTypeArguments *Node
+	if p.tok.kind == tName && !p.isName("extends") && !p.isName("implements") {
+		id = p.parseIdentifier(false)
+	}
+	if p.is("<") {
+		typeParameters = p.parseTypeParameters()
+	}
+	if p.eatName("extends") {
+		superStart := p.tok.start
+		superClass = p.parseSubscripts(p.parseExprAtom(), superStart, false)
+		if superClass.Type == "TSInstantiationExpression" {
+			superClass, superTypeArguments = superClass.Child("expression"), superClass.Child("typeArguments")
+		} else if p.is("<") {
+			superTypeArguments = p.parseTypeArguments()
+		}
+	}
+	implements := []*Node{}
+	if p.eatName("implements") {
+		for {
+			implements = append(implements, p.parseHeritage("TSClassImplements"))
+			if !p.eat(",") {
+				break
+			}
+		}
+	}
+
+	bodyStart := p.tok.start
+	p.expect("{")
+	members := []*Node{}
+	for !p.eat("}") {
+		if p.eat(";") {
+			continue
+		}
+		members = append(members, p.parseClassMember())
+	}
+	body := p.make("ClassBody", bodyStart, "body", members)
+
+	typ := "ClassExpression"
+	if statement {
+		typ = "ClassDeclaration"
+	}
+	n := p.make(typ, start, "id", id, "superClass", superClass, "body", body)
+	if typeParameters != nil {
+		n.set("typeParameters", typeParameters)
+	}
+	if superTypeArguments != nil {
+		n.set("superTypeArguments", superTypeArguments)
+	}
+	if len(implements) > 0 {
+		n.set("implements", implements)
+	}
+	if len(decorators) > 0 {
+		n.set("decorators", decorators)
+	}
+	return n
+}
+
+var classModifiers = map[string]bool{
+	"static": true, "public": true, "private": true, "protected": true, "readonly": true, "abstract": true,
+	"override": true, "declare": true, "accessor": true, "async": true, "get": true, "set": true,
+}
+
+// isModifier reports whether the current name is a modifier of a member rather than its name
+func (p *parser) isModifier() bool {
+	if p.tok.kind != tName || p.tok.escaped || !classModifiers[p.tok.value] {
+		return false
+	}
+	next := p.peek()
+	if next.nl && p.tok.value != "static" && p.tok.value != "get" && p.tok.value != "set" {
+		return false
+	}
+	switch next.kind {
+	case tName, tString, tNumber, tBigInt, tPrivateName:
+		return true
+	case tPunct:
+		return next.value == "[" || next.value == "*" || (next.value == "{" && p.tok.value == "static")
+	}
+	return false
+}
+
+func (p *parser) parseClassMember() *Node {
+	start := p.tok.start
+	decorators := p.parseDecorators()
+	modifiers := map[string]bool{}
+	accessibility := ""
+	kind := "method"
+	for p.isModifier() {
+		switch value := p.tok.value; value {
+		case "public", "private", "protected":
+			accessibility = value
+		case "get", "set":
+			kind = value
+		default:
+			modifiers[value] = true
+		}
+		p.next()
+	}
+	if modifiers["static"] && p.is("{") {
+		p.next()
+		body := p.parseStatements(false)
+		p.expect("}")
+		return p.make("StaticBlock", start, "body", body)
+	}
+	if p.is("[") && p.isIndexSignature() {
+		signature := p.parseIndexSignature(start, modifiers["readonly"])
+		signature.set("static", modifiers["static"])
+		p.typeMemberSeparator()
+		signature.End = p.prevEnd
+		return signature
+	}
+	generator := p.eat("*")
+	key, computed := p.parsePropertyKey()
+	optional := p.eat("?")
+	definite := !optional && p.eat("!")
+
+	setTS := func(n *Node) {
+		if accessibility != "" {
+			n.set("accessibility", accessibility)
+		}
+		for _, modifier := range []string{"override", "declare", "readonly"} {
+			if modifiers[modifier] {
+				n.set(modifier, true)
+			}
+		}
+		if optional {
+			n.set("optional", true)
+		}
+		if definite {
+			n.set("definite", true)
+		}
+		if len(decorators) > 0 {
+			n.set("decorators", decorators)
+		}
+	}
+
+	if p.is("(") || p.is("<") {
+		if kind == "method" && !computed && !modifiers["static"] && key.Type == "Identifier" && key.Get("name") == "constructor" {
+			kind = "constructor"
+		}
+		valueStart := p.tok.start
+		value := &Node{Type: "FunctionExpression", Start: valueStart}
+		value.set("id", nil)
+		value.set("expression", false)
+		value.set("generator", generator)
+		value.set("async", modifiers["async"])
+		p.parseFunctionRest(value, generator, true)
+		typ := "MethodDefinition"
+		if modifiers["abstract"] {
+			typ = "TSAbstractMethodDefinition"
+		}
+		n := p.make(typ, start, "key", key, "value", value, "kind", kind, "computed", computed, "static", modifiers["static"])
+		setTS(n)
+		return n
+	}
+	if kind != "method" || generator || modifiers["async"] {
+		p.expected("(")
+	}
+
+	var typeAnnotation, value *Node
+	if p.is(":") {
+		typeAnnotation = p.parseTypeAnnotation()
+	}
+	if p.eat("=") {
+		value = p.parseMaybeAssign()
+	}
+	p.semicolon()
+	typ := "PropertyDefinition"
+	if modifiers["accessor"] {
+		typ = "AccessorProperty"
+	}
+	if modifiers["abstract"] {
+		typ = "TSAbstract" + typ
+	}
+	n := p.make(typ, start, "key", key, "value", value, "computed", computed, "static", modifiers["static"])
+	setTS(n)
+	if typeAnnotation != nil {
+		n.set("typeAnnotation", typeAnnotation)
+	}
+	return n
+}
+
+// parsePropertyKey parses the key of a property or of a member, which may be computed
+func (p *parser) parsePropertyKey() (key *Node, computed bool) {
+	switch p.tok.kind {
+	case tString, tNumber, tBigInt:
+		return p.parseLiteral(), false
+	case tName:
+		return p.parseIdentifier(true), false
+	case tPrivateName:
+		start, name := p.tok.start, p.tok.value
+		p.next()
+		return p.make("PrivateIdentifier", start, "name", name), false
+	}
+	p.expect("[")
+	noIn := p.noIn
+	p.noIn = false
+	key = p.parseMaybeAssign()
+	p.noIn = noIn
+	p.expect("]")
+	return key, true
+}
+
+// Expressions
+
+func (p *parser) parseExpression() *Node {
+	start := p.tok.start
+	expression := p.parseMaybeAssign()
+	if !p.is(",") {
+		return expression
+	}
+	expressions := []*Node{expression}
+	for p.eat(",") {
+		expressions = append(expressions, p.parseMaybeAssign())
+	}
+	return p.make("SequenceExpression", start, "expressions", expressions)
+}
+
+var assignmentOperators = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true, "**=": true, "<<=": true,
+	">>=": true, ">>>=": true, "&=": true, "|=": true, "^=": true, "&&=": true, "||=": true, "??=": true,
+}
+
+func (p *parser) parseMaybeAssign() *Node {
+	if p.isName("yield") && p.inGenerator {
+		return p.parseYield()
+	}
+	if arrow := p.parseArrow(); arrow != nil {
+		return arrow
+	}
+	start := p.tok.start
+	left := p.parseConditional()
+	if p.tok.kind == tPunct && assignmentOperators[p.tok.value] {
+		operator := p.tok.value
+		if operator == "=" {
+			left = p.toPattern(left)
+		} else if !isSimpleTarget(left) {
+			p.fail(left.Start, "Invalid assignment target")
+		}
+		p.next()
+		right := p.parseMaybeAssign()
+		return p.make("AssignmentExpression", start, "operator", operator, "left", left, "right", right)
+	}
+	return left
+}
+
+func isSimpleTarget(n *Node) bool {
+	switch n.Type {
+	case "Identifier", "MemberExpression", "TSAsExpression", "TSSatisfiesExpression", "TSNonNullExpression", "TSTypeAssertion":
+		return true
+	}
+	return false
+}
+
+// toPattern converts an expression parsed before an "=" to the pattern it is an assignment to
+func (p *parser) toPattern(n *Node) *Node {
+	switch n.Type {
+	case "ObjectExpression":
+		n.Type = "ObjectPattern"
+		for _, property := range n.Children("properties") {
+			if property.Type == "SpreadElement" {
+				property.Type = "RestElement"
+				property.set("argument", p.toPattern(property.Child("argument")))
+			} else {
+				property.set("value", p.toPattern(property.Child("value")))
+			}
+		}
+	case "ArrayExpression":
+		n.Type = "ArrayPattern"
+		for _, element := range n.Children("elements") {
+			if element == nil {
+				continue
+			}
+			if element.Type == "SpreadElement" {
+				element.Type = "RestElement"
+				element.set("argument", p.toPattern(element.Child("argument")))
+			} else {
+				*element = *p.toPattern(element)
+			}
+		}
+	case "AssignmentExpression":
+		if n.Get("operator") != "=" {
+			p.fail(n.Start, "Invalid assignment target")
+		}
+		n.Type = "AssignmentPattern"
+		n.Fields = []Field{{"left", p.toPattern(n.Child("left"))}, {"right", n.Child("right")}}
+	case "AssignmentPattern", "ObjectPattern", "ArrayPattern", "RestElement":
+	default:
+		if !isSimpleTarget(n) {
+			p.fail(n.Start, "Invalid assignment target")
+		}
+	}
+	return n
+}
+
+func (p *parser) parseYield() *Node {
+	start := p.tok.start
+	p.next()
+	delegate := p.eat("*")
+	var argument *Node
+	if delegate || (!p.tok.nl && p.startsExpression()) {
+		argument = p.parseMaybeAssign()
+	}
+	return p.make("YieldExpression", start, "delegate", delegate, "argument", argument)
+}
+
+// startsExpression reports whether the current token may start an expression
+func (p *parser) startsExpression() bool {
+	switch p.tok.kind {
+	case tEOF:
+		return false
+	case tPunct:
+		switch p.tok.value {
+		case "(", "[", "{", "!", "~", "+", "-", "++", "--", "/", "/=", "<", "@":
+			return true
+		}
+		return false
+	case tName:
+		switch p.tok.value {
+		case "in", "instanceof", "as", "satisfies", "of":
+			return p.tok.escaped
+		}
+	}
+	return true
+}
+
+// parseArrow parses an arrow function, or returns nil when the current expression isn't one
+func (p *parser) parseArrow() *Node {
+	start := p.tok.start
+	async := false
+	if p.isName("async") {
+		next := p.peek()
+		if next.nl {
+			return nil
+		}
+		if next.kind == tName && !p.isReserved(next) {
+			// async x => x
+			if after := p.peekAfterNext(); after.value == "=>" && !after.nl {
+				p.next()
+				param := p.parseIdentifier(false)
+				return p.parseArrowBody(start, true, nil, []*Node{param}, nil)
+			}
+			return nil
+		}
+		if next.value != "(" && next.value != "<" {
+			return nil
+		}
+		async = true
+	} else if p.tok.kind == tName && !p.isReserved(p.tok) {
+		if next := p.peek(); next.value == "=>" && !next.nl {
+			param := p.parseIdentifier(false)
+			return p.parseArrowBody(start, false, nil, []*Node{param}, nil)
+		}
+		return nil
+	} else if !p.is("(") && !p.is("<") {
+		return nil
+	}
+
+	var typeParameters, returnType *Node
+	var params []*Node
+	head := p.try(func() *Node {
+		if async {
+			p.next()
+		}
+		typeParameters = nil
+		if p.is("<") {
+			typeParameters = p.parseTypeParameters()
+			if p.jsx && !p.isJSXTypeParameters(typeParameters) {
+				p.unexpected()
+			}
+		}
+		params = p.parseParams()
+		returnType = nil
+		if p.is(":") && start != p.noArrowReturnTypeAt {
+			returnType = p.parseReturnType()
+		}
+		if !p.is("=>") || p.tok.nl {
+			p.unexpected()
+		}
+		return &Node{}
+	})
+	if head == nil {
+		return nil
+	}
+	return p.parseArrowBody(start, async, typeParameters, params, returnType)
+}
+
+func (p *parser) parseArrowBody(start int, async bool, typeParameters *Node, params []*Node, returnType *Node) *Node {
+	p.expect("=>")
+	inGenerator := p.inGenerator
+	p.inGenerator = false
+	var body *Node
+	expression := !p.is("{")
+	if expression {
+		body = p.parseMaybeAssign()
+	} else {
+		body = p.parseFunctionBody()
+	}
+	p.inGenerator = inGenerator
+	n := p.make("ArrowFunctionExpression", start, "id", nil, "expression", expression, "generator", false, "async", async, "params", params, "body", body)
+	if typeParameters != nil {
+		n.set("typeParameters", typeParameters)
+	}
+	if returnType != nil {
+		n.set("returnType", returnType)
+	}
+	return n
+}
+
+func (p *parser) parseConditional() *Node {
+	start := p.tok.start
+	test := p.parseBinary(p.parseUnary(), start, 0)
+	if !p.eat("?") {
+		return test
+	}
+	noIn := p.noIn
+	p.noIn = false
+	s := p.save()
+	consequent := p.parseMaybeAssign()
+	if !p.is(":") && consequent.Type == "ArrowFunctionExpression" && consequent.Get("returnType") != nil {
+		// The return type of the arrow function was the colon of the conditional, as in
+		// `a ? (b) : c => c`: the consequent is parsed again without it
+		p.restore(s)
+		p.noArrowReturnTypeAt = p.tok.start
+		consequent = p.parseMaybeAssign()
+	}
+	p.noIn = noIn
+	p.expect(":")
+	alternate := p.parseMaybeAssign()
+	return p.make("ConditionalExpression", start, "test", test, "consequent", consequent, "alternate", alternate)
+}
+
+var binaryPrecedence = map[string]int{
+	"??": 1, "||": 2, "&&": 3, "|": 4, "^": 5, "&": 6,
+	"==": 7, "!=": 7, "===": 7, "!==": 7,
+	"<": 8, ">": 8, "<=": 8, ">=": 8, "instanceof": 8, "in": 8, "as": 8, "satisfies": 8,
+	"<<": 9, ">>": 9, ">>>": 9,
+	"+": 10, "-": 10, "*": 11, "/": 11, "%": 11, "**": 12,
+}
+
+// binaryOperator returns the binary operator of the current token, if any
+func (p *parser) binaryOperator() string {
+	switch p.tok.kind {
+	case tPunct:
+		if binaryPrecedence[p.tok.value] > 0 {
+			return p.tok.value
+		}
+	case tName:
+		if p.tok.escaped {
+			return ""
+		}
+		switch p.tok.value {
+		case "instanceof":
+			return p.tok.value
+		case "in":
+			if !p.noIn {
+				return p.tok.value
+			}
+		case "as", "satisfies":
+			if !p.tok.nl {
+				return p.tok.value
+			}
+		}
+	}
+	return ""
+}
+
+// parseBinary parses the binary operators binding tighter than minPrecedence after left
+func (p *parser) parseBinary(left *Node, start int, minPrecedence int) *Node {
+	for {
+		operator := p.binaryOperator()
+		precedence := binaryPrecedence[operator]
+		if operator == "" || precedence <= minPrecedence {
+			return left
+		}
+		p.next()
+		if operator == "as" || operator == "satisfies" {
+			var typeAnnotation *Node
+			if operator == "as" && p.isName("const") {
+				constStart := p.tok.start
+				typeName := p.parseIdentifier(true)
+				typeAnnotation = p.make("TSTypeReference", constStart, "typeName", typeName)
+			} else {
+				typeAnnotation = p.parseType()
+			}
+			typ := map[string]string{"as": "TSAsExpression", "satisfies": "TSSatisfiesExpression"}[operator]
+			left = p.make(typ, start, "expression", left, "typeAnnotation", typeAnnotation)
+			continue
+		}
+		rightStart := p.tok.start
+		var right *Node
+		if operator == "**" {
+			// Exponentiation is right associative
+			right = p.parseBinary(p.parseUnary(), rightStart, precedence-1)
+		} else {
+			right = p.parseBinary(p.parseUnary(), rightStart, precedence)
+		}
+		typ := "BinaryExpression"
+		if operator == "&&" || operator == "||" || operator == "??" {
+			typ = "LogicalExpression"
+		}
+		left = p.make(typ, start, "left", left, "operator", operator, "right", right)
+	}
+}
+
+func (p *parser) parseUnary() *Node {
+	start := p.tok.start
+	switch {
+	case p.tok.kind == tPunct && (p.tok.value == "!" || p.tok.value == "~" || p.tok.value == "+" || p.tok.value == "-"),
+		p.isName("delete") || p.isName("void") || p.isName("typeof"):
+		operator := p.tok.value
+		p.next()
+		argument := p.parseUnary()
+		return p.make("UnaryExpression", start, "operator", operator, "prefix", true, "argument", argument)
+	case p.is("++") || p.is("--"):
+		operator := p.tok.value
+		p.next()
+		argument := p.parseUnary()
+		if !isSimpleTarget(argument) {
+			p.fail(argument.Start, "Invalid update target")
+		}
+		return p.make("UpdateExpression", start, "operator", operator, "prefix", true, "argument", argument)
+	case p.isName("await"):
+		p.next()
+		argument := p.parseUnary()
+		return p.make("AwaitExpression", start, "argument", argument)
+	case p.is("<") && !p.jsx:
+		// <T>value is a type assertion
+		p.next()
+		typeAnnotation := p.parseType()
+		p.expectGreater()
+		expression := p.parseUnary()
+		return p.make("TSTypeAssertion", start, "typeAnnotation", typeAnnotation, "expression", expression)
+	}
+	expression := p.parseSubscripts(p.parseExprAtom(), start, false)
+	for (p.is("++") || p.is("--")) && !p.tok.nl {
+		if !isSimpleTarget(expression) {
+			p.fail(expression.Start, "Invalid update target")
+		}
+		operator := p.tok.value
+		p.next()
+		expression = p.make("UpdateExpression", start, "operator", operator, "prefix", false, "argument", expression)
+	}
+	return expression
+}
+
+// parseSubscripts parses the member accesses, calls and tagged templates following base. With
+// noCalls, as for the callee of new, it stops at calls.
+func (p *parser) parseSubscripts(base *Node, start int, noCalls bool) *Node {
+	chain := false
+	for {
+		switch {
+		case p.is("?."):
+			if noCalls {
+				p.fail(p.tok.start, "Invalid optional chain from new expression")
+			}
+			chain = true
+			p.next()
+			switch {
+			case p.is("("):
+				arguments := p.parseArguments()
+				base = p.make("CallExpression", start, "callee", base, "arguments", arguments, "optional", true)
+			case p.is("<"):
+				typeArguments := p.parseTypeArguments()
+				arguments := p.parseArguments()
+				base = p.make("CallExpression", start, "callee", base, "arguments", arguments, "optional", true, "typeArguments", typeArguments)
+			case p.is("["):
+				base = p.parseComputedMember(base, start, true)
+			default:
+				property := p.parseMemberName()
+				base = p.make("MemberExpression", start, "object", base, "property", property, "computed", false, "optional", true)
+			}
+		case p.is("."):
+			p.next()
+			property := p.parseMemberName()
+			base = p.make("MemberExpression", start, "object", base, "property", property, "computed", false, "optional", false)
+		case p.is("["):
+			base = p.parseComputedMember(base, start, false)
+		case p.is("(") && !noCalls:
+			arguments := p.parseArguments()
+			if base.Type == "Import" {
+				base = p.importExpression(start, arguments)
+				continue
+			}
+			base = p.make("CallExpression", start, "callee", base, "arguments", arguments, "optional", false)
+		case p.tok.kind == tTemplate:
+			if chain {
+				p.fail(p.tok.start, "Invalid tagged template on optional chain")
+			}
+			quasi := p.parseTemplate(true)
+			base = p.make("TaggedTemplateExpression", start, "tag", base, "quasi", quasi)
+		case p.is("!") && !p.tok.nl:
+			p.next()
+			base = p.make("TSNonNullExpression", start, "expression", base)
+		case (p.is("<") || p.is("<<")) && !noCalls:
+			typeArguments := p.try(func() *Node {
+				typeArguments := p.parseTypeArguments()
+				if !p.canFollowTypeArguments() {
+					p.unexpected()
+				}
+				return typeArguments
+			})
+			if typeArguments == nil {
+				return p.endChain(base, start, chain)
+			}
+			switch {
+			case p.is("("):
+				arguments := p.parseArguments()
+				base = p.make("CallExpression", start, "callee", base, "arguments", arguments, "optional", false, "typeArguments", typeArguments)
+			case p.tok.kind == tTemplate:
+				quasi := p.parseTemplate(true)
+				base = p.make("TaggedTemplateExpression", start, "tag", base, "typeArguments", typeArguments, "quasi", quasi)
+			default:
+				base = p.make("TSInstantiationExpression", start, "expression", base, "typeArguments", typeArguments)
+			}
+		default:
+			if base.Type == "Import" {
+				p.expected("(")
+			}
+			return p.endChain(base, start, chain)
+		}
+	}
+}
+
+// endChain wraps an optional chain in a ChainExpression
+func (p *parser) endChain(base *Node, start int, chain bool) *Node {
+	if chain {
+		return p.make("ChainExpression", start, "expression", base)
+	}
+	return base
+}
+
+// canFollowTypeArguments reports whether type arguments in an expression may be followed by the
+// current token, rather than being a comparison, as in TypeScript
+func (p *parser) canFollowTypeArguments() bool {
+	switch {
+	case p.is("(") || p.tok.kind == tTemplate:
+		return true
+	case p.is("<") || p.is(">") || p.is("+") || p.is("-"):
+		return false
+	}
+	return p.tok.nl || p.binaryOperator() != "" || !p.startsExpression()
+}
+
+func (p *parser) parseMemberName() *Node {
+	if p.tok.kind == tPrivateName {
+		start, name := p.tok.start, p.tok.value
+		p.next()
+		return p.make("PrivateIdentifier", start, "name", name)
+	}
+	return p.parseIdentifier(true)
+}
+
+func (p *parser) parseComputedMember(object *Node, start int, optional bool) *Node {
+	p.expect("[")
+	noIn := p.noIn
+	p.noIn = false
+	property := p.parseExpression()
+	p.noIn = noIn
+	p.expect("]")
+	return p.make("MemberExpression", start, "object", object, "property", property, "computed", true, "optional", optional)
+}
+
+func (p *parser) parseArguments() []*Node {
+	p.expect("(")
+	noIn := p.noIn
+	p.noIn = false
+	arguments := []*Node{}
+	for !p.eat(")") {
+		if p.is("...") {
+			start := p.tok.start
+			p.next()
+			argument := p.parseMaybeAssign()
+			arguments = append(arguments, p.make("SpreadElement", start, "argument", argument))
+		} else {
+			arguments = append(arguments, p.parseMaybeAssign())
+		}
+		if !p.is(")") {
+			p.expect(",")
+		}
+	}
+	p.noIn = noIn
+	return arguments
+}
+
+func (p *parser) importExpression(start int, arguments []*Node) *Node {
+	if len(arguments) == 0 || len(arguments) > 2 {
+		p.fail(start, "Invalid number of arguments to import()")
+	}
+	var options *Node
+	if len(arguments) == 2 {
+		options = arguments[1]
+	}
+	return p.make("ImportExpression", start, "source", arguments[0], "options", options)
+}
+
+func (p *parser) parseExprAtom() *Node {
+	start := p.tok.start
+	switch p.tok.kind {
+	case tNumber, tString, tBigInt:
+		return p.parseLiteral()
+	case tTemplate:
+		return p.parseTemplate(false)
+	case tPrivateName:
+		// #x in y
+		name := p.tok.value
+		p.next()
+		if !p.isName("in") {
+			p.fail(start, "Unexpected private name")
+		}
+		return p.make("PrivateIdentifier", start, "name", name)
+	case tPunct:
+		switch p.tok.value {
+		case "(":
+			return p.parseParenExpression()
+		case "[":
+			return p.parseArray()
+		case "{":
+			return p.parseObject()
+		case "/", "/=":
+			p.tok = p.rescanSlash(p.tok)
+			return p.parseLiteral()
+		case "@":
+			decorators := p.parseDecorators()
+			return p.parseClass(start, false, decorators)
+		case "<":
+			if p.jsx {
+				element := p.parseJSXElement()
+				p.next()
+				return element
+			}
+		}
+		p.unexpected()
+	case tName:
+		if p.tok.escaped {
+			return p.parseIdentifier(false)
+		}
+		switch p.tok.value {
+		case "this":
+			p.next()
+			return p.make("ThisExpression", start)
+		case "super":
+			p.next()
+			return p.make("Super", start)
+		case "null", "true", "false":
+			return p.parseLiteral()
+		case "function":
+			return p.parseFunction(start, false, false)
+		case "class":
+			return p.parseClass(start, false, nil)
+		case "new":
+			return p.parseNew()
+		case "import":
+			p.next()
+			if p.eat(".") {
+				meta := p.make("Identifier", start, "name", "import")
+				property := p.parseIdentifier(true)
+				return p.make("MetaProperty", start, "meta", meta, "property", property)
+			}
+			// The call is parsed as a subscript
+			return p.make("Import", start)
+		case "async":
+			if next := p.peek(); next.kind == tName && next.value == "function" && !next.nl {
+				p.next()
+				return p.parseFunction(start, false, true)
+			}
+		}
+		return p.parseIdentifier(false)
+	}
+	p.unexpected()
+	return nil
+}
+
+func (p *parser) parseNew() *Node {
+	start := p.tok.start
+	p.next()
+	if p.eat(".") {
+		meta := p.make("Identifier", start, "name", "new")
+		property := p.parseIdentifier(true)
+		return p.make("MetaProperty", start, "meta", meta, "property", property)
+	}
+	calleeStart := p.tok.start
+	var callee *Node
+	if p.isName("new") {
+		callee = p.parseNew()
+	} else {
+		callee = p.parseSubscripts(p.parseExprAtom(), calleeStart, true)
+	}
+	var typeArguments *Node
+	if p.is("<") {
+		typeArguments = p.try(func() *Node {
+			typeArguments := p.parseTypeArguments()
+			if !p.is("(") {
+				p.unexpected()
+			}
+			return typeArguments
+		})
+	}
+	arguments := []*Node{}
+	if p.is("(") {
+		arguments = p.parseArguments()
+	}
+	n := p.make("NewExpression", start, "callee", callee, "arguments", arguments)
+	if typeArguments != nil {
+		n.set("typeArguments", typeArguments)
+	}
+	return n
+}
+
+func (p *parser) parseTemplate(tagged bool) *Node {
+	start := p.tok.start
+	quasis := []*Node{}
+	expressions := []*Node{}
+	noIn := p.noIn
+	p.noIn = false
+	for {
+		t := p.tok
+		if t.kind != tTemplate {
+			p.unexpected()
+		}
+		if t.invalid && !tagged {
+			p.fail(t.start, "Invalid escape sequence in template")
+		}
+		quasis = append(quasis, p.templateElement(t))
+		p.next()
+		if t.tail {
+			break
+		}
+		expressions = append(expressions, p.parseExpression())
+		if !p.is("}") {
+			p.expected("}")
+		}
+		p.tok = p.rescanTemplate(p.tok)
+	}
+	p.noIn = noIn
+	return p.make("TemplateLiteral", start, "quasis", quasis, "expressions", expressions)
+}
+
+// templateElement returns the TemplateElement of t, which excludes its delimiters
+func (p *parser) templateElement(t token) *Node {
+	start, end := t.start+1, t.end-2
+	if t.tail {
+		end = t.end - 1
+	}
+	raw := strings.ReplaceAll(strings.ReplaceAll(p.source[start:end], "\r\n", "\n"), "\r", "\n")
+	var cooked any = t.value
+	if t.invalid {
+		cooked = nil
+	}
+	return &Node{Type: "TemplateElement", Start: start, End: end, Fields: []Field{
+		{"value", Object{{"raw", raw}, {"cooked", cooked}}},
+		{"tail", t.tail},
+	}}
+}
+
+func (p *parser) parseArray() *Node {
+	start := p.tok.start
+	p.next()
+	noIn := p.noIn
+	p.noIn = false
+	elements := []*Node{}
+	for !p.eat("]") {
+		if p.eat(",") {
+			elements = append(elements, nil)
+			continue
+		}
+		if p.is("...") {
+			spreadStart := p.tok.start
+			p.next()
+			argument := p.parseMaybeAssign()
+			elements = append(elements, p.make("SpreadElement", spreadStart, "argument", argument))
+		} else {
+			elements = append(elements, p.parseMaybeAssign())
+		}
+		if !p.is("]") {
+			p.expect(",")
+		}
+	}
+	p.noIn = noIn
+	return p.make("ArrayExpression", start, "elements", elements)
+}
+
+func (p *parser) parseObject() *Node {
+	start := p.tok.start
+	p.next()
+	noIn := p.noIn
+	p.noIn = false
+	properties := []*Node{}
+	for !p.eat("}") {
+		if p.is("...") {
+			spreadStart := p.tok.start
+			p.next()
+			argument := p.parseMaybeAssign()
+			properties = append(properties, p.make("SpreadElement", spreadStart, "argument", argument))
+		} else {
+			properties = append(properties, p.parseProperty())
+		}
+		if !p.is("}") {
+			p.expect(",")
+		}
+	}
+	p.noIn = noIn
+	return p.make("ObjectExpression", start, "properties", properties)
+}
+
+// isPropertyModifier reports whether the current get, set or async starts a method, rather than
+// being the name of a property
+func (p *parser) isPropertyModifier() bool {
+	next := p.peek()
+	if next.kind == tPunct {
+		switch next.value {
+		case ",", "}", ":", "(", "=", "<", "?":
+			return false
+		}
+	}
+	return !(p.isName("async") && next.nl)
+}
+
+func (p *parser) parseProperty() *Node {
+	start := p.tok.start
+	async, generator := false, false
+	kind := "init"
+	if p.isName("async") && p.isPropertyModifier() {
+		p.next()
+		async = true
+	}
+	if p.eat("*") {
+		generator = true
+	}
+	if !async && !generator && (p.isName("get") || p.isName("set")) && p.isPropertyModifier() {
+		kind = p.tok.value
+		p.next()
+	}
+	key, computed := p.parsePropertyKey()
+	if key.Type == "PrivateIdentifier" {
+		p.fail(key.Start, "Unexpected private name")
+	}
+	if p.is("(") || p.is("<") {
+		value := &Node{Type: "FunctionExpression", Start: p.tok.start}
+		value.set("id", nil)
+		value.set("expression", false)
+		value.set("generator", generator)
+		value.set("async", async)
+		p.parseFunctionRest(value, generator, false)
+		return p.make("Property", start, "method", kind == "init", "shorthand", false, "computed", computed, "key", key, "value", value, "kind", kind)
+	}
+	if async || generator || kind != "init" {
+		p.expected("(")
+	}
+	if p.eat(":") {
+		value := p.parseMaybeAssign()
+		return p.make("Property", start, "method", false, "shorthand", false, "computed", computed, "key", key, "value", value, "kind", "init")
+	}
+	if key.Type != "Identifier" || computed || p.isReserved(token{kind: tName, value: key.Get("name").(string)}) {
+		p.expected(":")
+	}
+	value := key
+	if p.is("=") {
+		// A shorthand with a default, only valid in a pattern
+		p.next()
+		right := p.parseMaybeAssign()
+		value = p.make("AssignmentPattern", start, "left", key, "right", right)
+	}
+	return p.make("Property", start, "method", false, "shorthand", true, "computed", false, "key", key, "value", value, "kind", "init")
+}
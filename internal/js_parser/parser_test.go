@@ -0,0 +1,286 @@
+package js_parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testcase struct {
+	name   string
+	source string
+	// want lists the types of the nodes of the body, depth first
+	want string
+}
+
+func fixturesParse() []testcase {
+	return []testcase{
+		{
+			name:   "imports",
+			source: `import a, { b, type C, d as e, "f-g" as h } from "./x"; import * as ns from "n"; import "side";`,
+			want:   "ImportDeclaration ImportDefaultSpecifier Identifier ImportSpecifier Identifier Identifier ImportSpecifier Identifier Identifier ImportSpecifier Identifier Identifier ImportSpecifier Literal Identifier Literal ImportDeclaration ImportNamespaceSpecifier Identifier Literal ImportDeclaration Literal",
+		},
+		{
+			name:   "type imports",
+			source: `import type Props from "./types"; import type from "./type";`,
+			want:   "ImportDeclaration ImportDefaultSpecifier Identifier Literal ImportDeclaration ImportDefaultSpecifier Identifier Literal",
+		},
+		{
+			name:   "import attributes",
+			source: `import data from "./data.json" with { type: "json" };`,
+			want:   "ImportDeclaration ImportDefaultSpecifier Identifier Literal ImportAttribute Identifier Literal",
+		},
+		{
+			name:   "exports",
+			source: `export const a = 1; export { a as b }; export * as c from "c"; export default a;`,
+			want:   "ExportNamedDeclaration VariableDeclaration VariableDeclarator Identifier Literal ExportNamedDeclaration ExportSpecifier Identifier Identifier ExportAllDeclaration Identifier Literal ExportDefaultDeclaration Identifier",
+		},
+		{
+			name:   "props",
+			source: `interface Props { title: string; count?: number }` + "\n" + `const { title, count = 0 } = Astro.props;`,
+			want:   "TSInterfaceDeclaration Identifier TSInterfaceBody TSPropertySignature Identifier TSTypeAnnotation TSStringKeyword TSPropertySignature Identifier TSTypeAnnotation TSNumberKeyword VariableDeclaration VariableDeclarator ObjectPattern Property Identifier Identifier Property Identifier AssignmentPattern Identifier Literal MemberExpression Identifier Identifier",
+		},
+		{
+			name:   "top-level return",
+			source: `if (!user) return Astro.redirect("/login");`,
+			want:   "IfStatement UnaryExpression Identifier ReturnStatement CallExpression MemberExpression Identifier Identifier Literal",
+		},
+		{
+			name:   "top-level await",
+			source: `const posts = await Astro.glob("../posts/*.md");`,
+			want:   "VariableDeclaration VariableDeclarator Identifier AwaitExpression CallExpression MemberExpression Identifier Identifier Literal",
+		},
+		{
+			name:   "arrow functions",
+			source: `const f = async (a: number, b = 1): Promise<number> => a + b; const g = x => x;`,
+			want:   "VariableDeclaration VariableDeclarator Identifier ArrowFunctionExpression Identifier TSTypeAnnotation TSNumberKeyword AssignmentPattern Identifier Literal BinaryExpression Identifier Identifier TSTypeAnnotation TSTypeReference Identifier TSTypeParameterInstantiation TSNumberKeyword VariableDeclaration VariableDeclarator Identifier ArrowFunctionExpression Identifier Identifier",
+		},
+		{
+			name:   "generic arrow function",
+			source: `const id = <T,>(value: T): T => value;`,
+			want:   "VariableDeclaration VariableDeclarator Identifier ArrowFunctionExpression Identifier TSTypeAnnotation TSTypeReference Identifier Identifier TSTypeParameterDeclaration TSTypeParameter Identifier TSTypeAnnotation TSTypeReference Identifier",
+		},
+		{
+			name:   "parenthesized conditional",
+			source: `a ? (b) : c`,
+			want:   "ExpressionStatement ConditionalExpression Identifier Identifier Identifier",
+		},
+		{
+			name:   "parenthesized conditional with an arrow function",
+			source: `const x = a ? (b) : c => c;`,
+			want:   "VariableDeclaration VariableDeclarator Identifier ConditionalExpression Identifier Identifier ArrowFunctionExpression Identifier Identifier",
+		},
+		{
+			name:   "typed arrow function in a conditional",
+			source: `const x = a ? (b): c => c : d;`,
+			want:   "VariableDeclaration VariableDeclarator Identifier ConditionalExpression Identifier ArrowFunctionExpression Identifier Identifier TSTypeAnnotation TSTypeReference Identifier Identifier",
+		},
+		{
+			name:   "comparison and type arguments",
+			source: `f(a < b, c > d); g<T>(x);`,
+			want:   "ExpressionStatement CallExpression Identifier BinaryExpression Identifier Identifier BinaryExpression Identifier Identifier ExpressionStatement CallExpression Identifier Identifier TSTypeParameterInstantiation TSTypeReference Identifier",
+		},
+		{
+			name:   "nested type arguments",
+			source: `let m: Map<string, Array<number>>= new Map();`,
+			want:   "VariableDeclaration VariableDeclarator Identifier TSTypeAnnotation TSTypeReference Identifier TSTypeParameterInstantiation TSStringKeyword TSTypeReference Identifier TSTypeParameterInstantiation TSNumberKeyword NewExpression Identifier",
+		},
+		{
+			name:   "optional chain",
+			source: `a?.b[c]?.(d)!`,
+			want:   "ExpressionStatement ChainExpression TSNonNullExpression CallExpression MemberExpression MemberExpression Identifier Identifier Identifier Identifier",
+		},
+		{
+			name:   "regular expression and division",
+			source: "const re = /[/]+/g.test(s) ? a / b : c",
+			want:   "VariableDeclaration VariableDeclarator Identifier ConditionalExpression CallExpression MemberExpression Literal Identifier Identifier BinaryExpression Identifier Identifier Identifier",
+		},
+		{
+			name:   "template literal",
+			source: "tag`a${b}c${`d${e}`}`",
+			want:   "ExpressionStatement TaggedTemplateExpression Identifier TemplateLiteral TemplateElement TemplateElement TemplateElement Identifier TemplateLiteral TemplateElement TemplateElement Identifier",
+		},
+		{
+			name:   "conditional type",
+			source: `type A<T> = T extends Array<infer U> ? U : never;`,
+			want:   "TSTypeAliasDeclaration Identifier TSConditionalType TSTypeReference Identifier TSTypeReference Identifier TSTypeParameterInstantiation TSInferType TSTypeParameter Identifier TSTypeReference Identifier TSNeverKeyword TSTypeParameterDeclaration TSTypeParameter Identifier",
+		},
+		{
+			name:   "mapped type",
+			source: `type M = { readonly [K in keyof T]?: T[K] };`,
+			want:   "TSTypeAliasDeclaration Identifier TSMappedType Identifier TSTypeOperator TSTypeReference Identifier TSIndexedAccessType TSTypeReference Identifier TSTypeReference Identifier",
+		},
+		{
+			name:   "class",
+			source: `class A<T> extends B<T> implements C { private x = 1; constructor(public y: string) { super() } get z() { return this.x } }`,
+			want:   "ClassDeclaration Identifier Identifier ClassBody PropertyDefinition Identifier Literal MethodDefinition Identifier FunctionExpression TSParameterProperty Identifier TSTypeAnnotation TSStringKeyword BlockStatement ExpressionStatement CallExpression Super MethodDefinition Identifier FunctionExpression BlockStatement ReturnStatement MemberExpression ThisExpression Identifier TSTypeParameterDeclaration TSTypeParameter Identifier TSTypeParameterInstantiation TSTypeReference Identifier TSClassImplements Identifier",
+		},
+		{
+			name:   "enum and namespace",
+			source: `enum E { A = 1, B } namespace N { export type T = E }`,
+			want:   "TSEnumDeclaration Identifier TSEnumMember Identifier Literal TSEnumMember Identifier TSModuleDeclaration Identifier TSModuleBlock ExportNamedDeclaration TSTypeAliasDeclaration Identifier TSTypeReference Identifier",
+		},
+		{
+			name:   "overloads",
+			source: `function f(a: string): string; function f(a) { return a }`,
+			want:   "TSDeclareFunction Identifier Identifier TSTypeAnnotation TSStringKeyword TSTypeAnnotation TSStringKeyword FunctionDeclaration Identifier Identifier BlockStatement ReturnStatement Identifier",
+		},
+		{
+			name:   "automatic semicolon insertion",
+			source: "let a = b\nlet c = d\n++e",
+			want:   "VariableDeclaration VariableDeclarator Identifier Identifier VariableDeclaration VariableDeclarator Identifier Identifier ExpressionStatement UpdateExpression Identifier",
+		},
+		{
+			name:   "type on its own line",
+			source: "type\nFoo = 1",
+			want:   "ExpressionStatement Identifier ExpressionStatement AssignmentExpression Identifier Literal",
+		},
+		{
+			name:   "statements",
+			source: `for (const [k, v] of o) {} for (let i = 0; i < n; i++) {} for (k in o) {} try {} catch {} finally {} switch (x) { case 1: break; default: }`,
+			want:   "ForOfStatement VariableDeclaration VariableDeclarator ArrayPattern Identifier Identifier Identifier BlockStatement ForStatement VariableDeclaration VariableDeclarator Identifier Literal BinaryExpression Identifier Identifier UpdateExpression Identifier BlockStatement ForInStatement Identifier Identifier BlockStatement TryStatement BlockStatement CatchClause BlockStatement BlockStatement SwitchStatement Identifier SwitchCase Literal BreakStatement SwitchCase",
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range fixturesParse() {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Parse(tt.source, 0, len(tt.source))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			types := []string{}
+			for _, statement := range program.Children("body") {
+				Walk(statement, func(n *Node) bool {
+					types = append(types, n.Type)
+					return true
+				})
+			}
+			if got := strings.Join(types, " "); got != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+			checkRanges(t, program)
+		})
+	}
+}
+
+// checkRanges checks that the range of each node is within the range of its parent
+func checkRanges(t *testing.T, parent *Node) {
+	t.Helper()
+	for _, f := range parent.Fields {
+		children, ok := f.Value.([]*Node)
+		if child, isNode := f.Value.(*Node); isNode {
+			children, ok = []*Node{child}, true
+		}
+		if !ok {
+			continue
+		}
+		for _, child := range children {
+			if child == nil {
+				continue
+			}
+			if child.Start > child.End || child.Start < parent.Start || child.End > parent.End {
+				t.Errorf("%s %d-%d is not within %s %d-%d", child.Type, child.Start, child.End, parent.Type, parent.Start, parent.End)
+			}
+			checkRanges(t, child)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		pos     int
+		message string
+	}{
+		{"const = 1", 6, "Unexpected token '='"},
+		{"let a = (1", 10, "Expected ')' but found end of input"},
+		{"a b", 2, "Unexpected token 'b'"},
+		{"const s = 'abc", 10, "Unterminated string"},
+		{"let x = `a${b", 13, "Expected '}' but found end of input"},
+		{"interface { }", 10, "Unexpected token '{'"},
+		{"f(a,, b)", 4, "Unexpected token ','"},
+		{"1 = 2", 0, "Invalid assignment target"},
+		{"/* never closed", 0, "Unterminated comment"},
+		{"function () {}", 0, "Missing the name of the declaration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Parse(tt.source, 0, len(tt.source))
+			e, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if e.Pos != tt.pos || e.Message != tt.message {
+				t.Errorf("expected %q at %d, got %q at %d", tt.message, tt.pos, e.Message, e.Pos)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	source := "---\n// greeting\nconst a: string = \"héllo\";\n---\n<p>{a}</p>"
+	start, end := 3, strings.LastIndex(source, "---")
+	program, err := Parse(source, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if program.Start != start || program.End != end {
+		t.Errorf("expected the program to span %d-%d, got %d-%d", start, end, program.Start, program.End)
+	}
+	declaration := program.Children("body")[0]
+	if got := source[declaration.Start:declaration.End]; got != `const a: string = "héllo";` {
+		t.Errorf("unexpected declaration range %q", got)
+	}
+
+	var b strings.Builder
+	program.WriteJSON(&b, func(offset int) (int, int) {
+		line := strings.Count(source[:offset], "\n") + 1
+		return line, offset - strings.LastIndex(source[:offset], "\n") - 1
+	})
+	var got struct {
+		Body []struct {
+			Loc struct {
+				Start struct{ Line, Column int }
+			}
+		}
+		Comments []struct {
+			Type  string
+			Value string
+			Start int
+		}
+	}
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b.String())
+	}
+	if got.Body[0].Loc.Start.Line != 3 || got.Body[0].Loc.Start.Column != 0 {
+		t.Errorf("unexpected loc %+v", got.Body[0].Loc)
+	}
+	if len(got.Comments) != 1 || got.Comments[0].Type != "Line" || got.Comments[0].Value != " greeting" || got.Comments[0].Start != 4 {
+		t.Errorf("unexpected comments %+v", got.Comments)
+	}
+}
+
+func TestParseLiterals(t *testing.T) {
+	source := "[1_000, 0x1f, .5e1, 10n, 'a\\u{1F600}\\x41', /a[/]b/gi, `x\\ny`]"
+	expression, err := ParseExpression(source, 0, len(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	expression.WriteJSON(&b, nil)
+	for _, want := range []string{
+		`"value":1000,"raw":"1_000"`,
+		`"value":31,"raw":"0x1f"`,
+		`"value":5,"raw":".5e1"`,
+		`"value":null,"raw":"10n","bigint":"10"`,
+		`"value":"a😀A"`,
+		`"regex":{"pattern":"a[/]b","flags":"gi"}`,
+		`"value":{"raw":"x\\ny","cooked":"x\ny"}`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %s in\n%s", want, b.String())
+		}
+	}
+}
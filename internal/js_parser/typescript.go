@@ -0,0 +1,845 @@
+package js_parser
+
+import "strings"
+
+// TypeScript declarations
+
+var declarationKeywords = map[string]bool{
+	"interface": true, "type": true, "enum": true, "namespace": true, "module": true, "global": true,
+	"abstract": true, "declare": true,
+}
+
+// parseTSDeclaration parses a declaration starting with a contextual keyword, like interface or
+// declare, returning nil when the current name doesn't start one
+func (p *parser) parseTSDeclaration(start int, declare bool) *Node {
+	if p.tok.kind != tName || p.tok.escaped || !declarationKeywords[p.tok.value] {
+		return nil
+	}
+	next := p.peek()
+	if next.nl && p.tok.value != "declare" {
+		// A line break ends the statement, as in `type\nFoo = 1`
+		return nil
+	}
+	var n *Node
+	switch p.tok.value {
+	case "interface":
+		if next.kind != tName {
+			return nil
+		}
+		n = p.parseInterface(start)
+	case "type":
+		if next.kind != tName {
+			return nil
+		}
+		p.next()
+		id := p.parseIdentifier(false)
+		var typeParameters *Node
+		if p.is("<") {
+			typeParameters = p.parseTypeParameters()
+		}
+		p.expect("=")
+		typeAnnotation := p.parseType()
+		p.semicolon()
+		n = p.make("TSTypeAliasDeclaration", start, "id", id, "typeAnnotation", typeAnnotation)
+		if typeParameters != nil {
+			n.set("typeParameters", typeParameters)
+		}
+	case "enum":
+		if next.kind != tName {
+			return nil
+		}
+		n = p.parseEnum(start, false)
+	case "namespace", "module":
+		if next.kind != tName && !(p.tok.value == "module" && next.kind == tString) {
+			return nil
+		}
+		n = p.parseModule(start)
+	case "global":
+		if next.value != "{" {
+			return nil
+		}
+		n = p.parseModule(start)
+	case "abstract":
+		if next.kind != tName || next.value != "class" {
+			return nil
+		}
+		p.next()
+		n = p.parseClass(start, true, nil)
+		n.set("abstract", true)
+	case "declare":
+		if declare || next.nl || next.kind != tName {
+			return nil
+		}
+		p.next()
+		switch {
+		case p.isName("var") || p.isName("let") || p.isName("const"):
+			if p.isName("const") && p.peek().value == "enum" {
+				p.next()
+				n = p.parseEnum(start, true)
+				break
+			}
+			n = p.parseVarStatement(start, p.tok.value)
+		case p.isName("function"):
+			n = p.parseFunction(start, true, false)
+		case p.isName("class"):
+			n = p.parseClass(start, true, nil)
+		case p.isName("async") && p.peek().value == "function":
+			p.next()
+			n = p.parseFunction(start, true, true)
+		default:
+			n = p.parseTSDeclaration(start, true)
+			if n == nil {
+				p.unexpected()
+			}
+		}
+		n.set("declare", true)
+	default:
+		return nil
+	}
+	return n
+}
+
+func (p *parser) parseInterface(start int) *Node {
+	p.next()
+	id := p.parseIdentifier(false)
+	var typeParameters *Node
+	if p.is("<") {
+		typeParameters = p.parseTypeParameters()
+	}
+	extends := []*Node{}
+	if p.eatName("extends") {
+		for {
+			extends = append(extends, p.parseHeritage("TSInterfaceHeritage"))
+			if !p.eat(",") {
+				break
+			}
+		}
+	}
+	bodyStart := p.tok.start
+	members := p.parseTypeMembers()
+	body := p.make("TSInterfaceBody", bodyStart, "body", members)
+	n := p.make("TSInterfaceDeclaration", start, "id", id, "extends", extends, "body", body)
+	if typeParameters != nil {
+		n.set("typeParameters", typeParameters)
+	}
+	return n
+}
+
+// parseHeritage parses a type in an extends clause of an interface or an implements clause of a class
+func (p *parser) parseHeritage(typ string) *Node {
+	start := p.tok.start
+	expression := p.parseEntityName(false)
+	n := p.make(typ, start, "expression", expression)
+	if p.is("<") {
+		n.set("typeArguments", p.parseTypeArguments())
+		n.End = p.prevEnd
+	}
+	return n
+}
+
+// parseEnum parses an enum at the "enum" keyword, after the "const" of a const enum
+func (p *parser) parseEnum(start int, isConst bool) *Node {
+	p.expectName("enum")
+	id := p.parseIdentifier(false)
+	p.expect("{")
+	members := []*Node{}
+	for !p.eat("}") {
+		memberStart := p.tok.start
+		var memberID *Node
+		computed := false
+		switch p.tok.kind {
+		case tString:
+			memberID = p.parseLiteral()
+		case tPunct:
+			memberID, computed = p.parsePropertyKey()
+		default:
+			memberID = p.parseIdentifier(true)
+		}
+		member := p.make("TSEnumMember", memberStart, "id", memberID)
+		if computed {
+			member.set("computed", true)
+		}
+		if p.eat("=") {
+			member.set("initializer", p.parseMaybeAssign())
+			member.End = p.prevEnd
+		}
+		members = append(members, member)
+		if !p.is("}") {
+			p.expect(",")
+		}
+	}
+	n := p.make("TSEnumDeclaration", start, "id", id, "members", members)
+	if isConst {
+		n.set("const", true)
+	}
+	return n
+}
+
+// parseModule parses a namespace, a module or a global augmentation at its keyword
+func (p *parser) parseModule(start int) *Node {
+	kind := p.tok.value
+	p.next()
+	var id *Node
+	switch {
+	case kind == "global":
+		id = p.make("Identifier", start, "name", "global")
+	case p.tok.kind == tString:
+		id = p.parseLiteral()
+	default:
+		id = p.parseEntityName(false)
+	}
+	n := p.make("TSModuleDeclaration", start, "id", id)
+	if p.is("{") {
+		bodyStart := p.tok.start
+		p.next()
+		body := p.parseStatements(false)
+		p.expect("}")
+		n.set("body", p.make("TSModuleBlock", bodyStart, "body", body))
+	} else {
+		p.semicolon()
+	}
+	n.set("kind", kind)
+	n.End = p.prevEnd
+	return n
+}
+
+// Types
+
+// parseTypeAnnotation parses a type annotation at its colon
+func (p *parser) parseTypeAnnotation() *Node {
+	start := p.tok.start
+	p.expect(":")
+	typeAnnotation := p.parseType()
+	return p.make("TSTypeAnnotation", start, "typeAnnotation", typeAnnotation)
+}
+
+// parseReturnType parses a return type at its colon or its arrow, which may be a type predicate
+func (p *parser) parseReturnType() *Node {
+	start := p.tok.start
+	p.next()
+	typeAnnotation := p.parseTypeOrPredicate()
+	return p.make("TSTypeAnnotation", start, "typeAnnotation", typeAnnotation)
+}
+
+func (p *parser) parseTypeOrPredicate() *Node {
+	start := p.tok.start
+	if p.tok.kind != tName || p.tok.escaped {
+		return p.parseType()
+	}
+	next := p.peek()
+	asserts := p.isName("asserts") && next.kind == tName && next.value != "is" && !next.nl
+	if !asserts && !(next.kind == tName && next.value == "is" && !next.nl) {
+		return p.parseType()
+	}
+	if asserts {
+		p.next()
+	}
+	var parameterName *Node
+	if p.isName("this") {
+		p.next()
+		parameterName = p.make("TSThisType", start)
+	} else {
+		parameterName = p.parseIdentifier(false)
+	}
+	var typeAnnotation *Node
+	if p.isName("is") && !p.tok.nl {
+		p.next()
+		typeStart := p.tok.start
+		typ := p.parseType()
+		typeAnnotation = p.make("TSTypeAnnotation", typeStart, "typeAnnotation", typ)
+	}
+	return p.make("TSTypePredicate", start, "asserts", asserts, "parameterName", parameterName, "typeAnnotation", typeAnnotation)
+}
+
+func (p *parser) parseType() *Node {
+	start := p.tok.start
+	if p.is("<") || p.isName("new") || (p.isName("abstract") && p.peek().value == "new") {
+		return p.parseFunctionType(start)
+	}
+	if p.is("(") {
+		if function := p.try(func() *Node { return p.parseFunctionType(start) }); function != nil {
+			return function
+		}
+	}
+	checkType := p.parseUnionType()
+	if p.noConditionalType || !p.isName("extends") || p.tok.nl {
+		return checkType
+	}
+	p.next()
+	p.noConditionalType = true
+	extendsType := p.parseType()
+	p.noConditionalType = false
+	p.expect("?")
+	trueType := p.parseType()
+	p.expect(":")
+	falseType := p.parseType()
+	return p.make("TSConditionalType", start, "checkType", checkType, "extendsType", extendsType, "trueType", trueType, "falseType", falseType)
+}
+
+// parseFunctionType parses a function or a constructor type
+func (p *parser) parseFunctionType(start int) *Node {
+	typ := "TSFunctionType"
+	abstract := p.eatName("abstract")
+	if p.eatName("new") {
+		typ = "TSConstructorType"
+	}
+	var typeParameters *Node
+	if p.is("<") {
+		typeParameters = p.parseTypeParameters()
+	}
+	noConditionalType := p.noConditionalType
+	p.noConditionalType = false
+	params := p.parseParams()
+	if !p.is("=>") {
+		p.expected("=>")
+	}
+	returnType := p.parseReturnType()
+	p.noConditionalType = noConditionalType
+	n := p.make(typ, start, "params", params, "returnType", returnType)
+	if typeParameters != nil {
+		n.set("typeParameters", typeParameters)
+	}
+	if typ == "TSConstructorType" {
+		n.set("abstract", abstract)
+	}
+	return n
+}
+
+func (p *parser) parseUnionType() *Node {
+	return p.parseListType("TSUnionType", "|", p.parseIntersectionType)
+}
+
+func (p *parser) parseIntersectionType() *Node {
+	return p.parseListType("TSIntersectionType", "&", p.parseTypeOperator)
+}
+
+// parseListType parses types separated by operator, which may also lead them
+func (p *parser) parseListType(typ string, operator string, parseMember func() *Node) *Node {
+	start := p.tok.start
+	leading := p.eat(operator)
+	first := parseMember()
+	if !p.is(operator) {
+		if leading {
+			first.Start = start
+		}
+		return first
+	}
+	types := []*Node{first}
+	for p.eat(operator) {
+		types = append(types, parseMember())
+	}
+	return p.make(typ, start, "types", types)
+}
+
+func (p *parser) parseTypeOperator() *Node {
+	start := p.tok.start
+	if p.isName("keyof") || p.isName("unique") || p.isName("readonly") {
+		if next := p.peek(); next.kind == tName || (next.kind == tPunct && (next.value == "[" || next.value == "(" || next.value == "{")) {
+			operator := p.tok.value
+			p.next()
+			typeAnnotation := p.parseTypeOperator()
+			return p.make("TSTypeOperator", start, "operator", operator, "typeAnnotation", typeAnnotation)
+		}
+	}
+	if p.isName("infer") && p.peek().kind == tName {
+		p.next()
+		parameterStart := p.tok.start
+		name := p.parseIdentifier(false)
+		var constraint *Node
+		if p.isName("extends") {
+			// In `T extends [infer U extends string] ? U : never`, the constraint of U may also be
+			// the start of a conditional type, which wins where one is allowed
+			noConditionalType := p.noConditionalType
+			constraint = p.try(func() *Node {
+				p.next()
+				p.noConditionalType = true
+				constraint := p.parseType()
+				if !noConditionalType && p.is("?") {
+					p.unexpected()
+				}
+				return constraint
+			})
+			p.noConditionalType = noConditionalType
+		}
+		typeParameter := p.make("TSTypeParameter", parameterStart, "name", name, "constraint", constraint, "in", false, "out", false, "const", false)
+		return p.make("TSInferType", start, "typeParameter", typeParameter)
+	}
+	return p.parseArrayType()
+}
+
+func (p *parser) parseArrayType() *Node {
+	start := p.tok.start
+	typ := p.parseNonArrayType()
+	for p.is("[") && !p.tok.nl {
+		p.next()
+		if p.eat("]") {
+			typ = p.make("TSArrayType", start, "elementType", typ)
+			continue
+		}
+		noConditionalType := p.noConditionalType
+		p.noConditionalType = false
+		indexType := p.parseType()
+		p.noConditionalType = noConditionalType
+		p.expect("]")
+		typ = p.make("TSIndexedAccessType", start, "objectType", typ, "indexType", indexType)
+	}
+	return typ
+}
+
+var keywordTypes = map[string]string{
+	"any": "TSAnyKeyword", "unknown": "TSUnknownKeyword", "number": "TSNumberKeyword",
+	"bigint": "TSBigIntKeyword", "boolean": "TSBooleanKeyword", "string": "TSStringKeyword",
+	"symbol": "TSSymbolKeyword", "object": "TSObjectKeyword", "never": "TSNeverKeyword",
+	"undefined": "TSUndefinedKeyword", "void": "TSVoidKeyword", "null": "TSNullKeyword",
+	"intrinsic": "TSIntrinsicKeyword",
+}
+
+func (p *parser) parseNonArrayType() *Node {
+	start := p.tok.start
+	// Nested types are parsed as in a new context
+	noConditionalType := p.noConditionalType
+	defer func() { p.noConditionalType = noConditionalType }()
+
+	switch p.tok.kind {
+	case tString, tNumber, tBigInt:
+		literal := p.parseLiteral()
+		return p.make("TSLiteralType", start, "literal", literal)
+	case tTemplate:
+		return p.parseTemplateLiteralType()
+	case tPunct:
+		switch p.tok.value {
+		case "-":
+			p.next()
+			if p.tok.kind != tNumber && p.tok.kind != tBigInt {
+				p.unexpected()
+			}
+			argument := p.parseLiteral()
+			literal := p.make("UnaryExpression", start, "operator", "-", "prefix", true, "argument", argument)
+			return p.make("TSLiteralType", start, "literal", literal)
+		case "(":
+			p.next()
+			p.noConditionalType = false
+			typ := p.parseType()
+			p.expect(")")
+			return typ
+		case "[":
+			return p.parseTupleType()
+		case "{":
+			p.noConditionalType = false
+			if p.isMappedType() {
+				return p.parseMappedType()
+			}
+			members := p.parseTypeMembers()
+			return p.make("TSTypeLiteral", start, "members", members)
+		}
+		p.unexpected()
+	case tName:
+		if p.tok.escaped {
+			break
+		}
+		if keyword, ok := keywordTypes[p.tok.value]; ok && p.peek().value != "." {
+			p.next()
+			return p.make(keyword, start)
+		}
+		switch p.tok.value {
+		case "this":
+			p.next()
+			return p.make("TSThisType", start)
+		case "true", "false":
+			literal := p.parseLiteral()
+			return p.make("TSLiteralType", start, "literal", literal)
+		case "typeof":
+			p.next()
+			var exprName *Node
+			if p.isName("import") {
+				exprName = p.parseImportType()
+			} else {
+				exprName = p.parseEntityName(true)
+			}
+			n := p.make("TSTypeQuery", start, "exprName", exprName)
+			if p.is("<") && !p.tok.nl {
+				n.set("typeArguments", p.parseTypeArguments())
+				n.End = p.prevEnd
+			}
+			return n
+		case "import":
+			return p.parseImportType()
+		}
+	default:
+		p.unexpected()
+	}
+	typeName := p.parseEntityName(false)
+	n := p.make("TSTypeReference", start, "typeName", typeName)
+	if p.is("<") && !p.tok.nl {
+		n.set("typeArguments", p.parseTypeArguments())
+		n.End = p.prevEnd
+	}
+	return n
+}
+
+// parseEntityName parses a qualified name, like a.b.c
+func (p *parser) parseEntityName(allowThis bool) *Node {
+	start := p.tok.start
+	var name *Node
+	if allowThis && p.isName("this") {
+		p.next()
+		name = p.make("ThisExpression", start)
+	} else {
+		name = p.parseIdentifier(true)
+	}
+	for p.is(".") {
+		p.next()
+		right := p.parseIdentifier(true)
+		name = p.make("TSQualifiedName", start, "left", name, "right", right)
+	}
+	return name
+}
+
+// parseImportType parses import("module").Name<T>
+func (p *parser) parseImportType() *Node {
+	start := p.tok.start
+	p.expectName("import")
+	p.expect("(")
+	argumentStart := p.tok.start
+	if p.tok.kind != tString {
+		p.unexpected()
+	}
+	literal := p.parseLiteral()
+	argument := p.make("TSLiteralType", argumentStart, "literal", literal)
+	var options *Node
+	if p.eat(",") && !p.is(")") {
+		options = p.parseMaybeAssign()
+		p.eat(",")
+	}
+	p.expect(")")
+	var qualifier *Node
+	if p.eat(".") {
+		qualifier = p.parseEntityName(false)
+	}
+	n := p.make("TSImportType", start, "argument", argument, "options", options, "qualifier", qualifier)
+	if p.is("<") {
+		n.set("typeArguments", p.parseTypeArguments())
+		n.End = p.prevEnd
+	}
+	return n
+}
+
+func (p *parser) parseTemplateLiteralType() *Node {
+	start := p.tok.start
+	if p.tok.tail {
+		literal := p.parseTemplate(false)
+		return p.make("TSLiteralType", start, "literal", literal)
+	}
+	quasis := []*Node{}
+	types := []*Node{}
+	for {
+		t := p.tok
+		if t.invalid {
+			p.fail(t.start, "Invalid escape sequence in template")
+		}
+		quasis = append(quasis, p.templateElement(t))
+		p.next()
+		if t.tail {
+			break
+		}
+		types = append(types, p.parseType())
+		if !p.is("}") {
+			p.expected("}")
+		}
+		p.tok = p.rescanTemplate(p.tok)
+	}
+	return p.make("TSTemplateLiteralType", start, "quasis", quasis, "types", types)
+}
+
+func (p *parser) parseTupleType() *Node {
+	start := p.tok.start
+	p.next()
+	p.noConditionalType = false
+	elementTypes := []*Node{}
+	for !p.eat("]") {
+		elementStart := p.tok.start
+		rest := p.eat("...")
+		var element *Node
+		if p.tok.kind == tName && p.isTupleLabel() {
+			label := p.parseIdentifier(true)
+			optional := p.eat("?")
+			p.expect(":")
+			elementType := p.parseType()
+			element = p.make("TSNamedTupleMember", elementStart, "label", label, "elementType", elementType, "optional", optional)
+			if rest {
+				element.Start = label.Start
+			}
+		} else {
+			element = p.parseType()
+			if !rest && p.eat("?") {
+				element = p.make("TSOptionalType", elementStart, "typeAnnotation", element)
+			}
+		}
+		if rest {
+			element = p.make("TSRestType", elementStart, "typeAnnotation", element)
+		}
+		elementTypes = append(elementTypes, element)
+		if !p.is("]") {
+			p.expect(",")
+		}
+	}
+	return p.make("TSTupleType", start, "elementTypes", elementTypes)
+}
+
+// isTupleLabel reports whether the current name labels a tuple member, as in [name?: T]
+func (p *parser) isTupleLabel() bool {
+	next := p.peek()
+	if next.kind != tPunct {
+		return false
+	}
+	return next.value == ":" || (next.value == "?" && p.peekAfterNext().value == ":")
+}
+
+// isMappedType reports whether the current "{" starts a mapped type, as in { readonly [K in T]: U }
+func (p *parser) isMappedType() bool {
+	s := p.save()
+	defer p.restore(s)
+	p.next()
+	if p.is("+") || p.is("-") {
+		p.next()
+		if !p.isName("readonly") {
+			return false
+		}
+	}
+	p.eatName("readonly")
+	if !p.eat("[") || p.tok.kind != tName {
+		return false
+	}
+	p.next()
+	return p.isName("in")
+}
+
+func (p *parser) parseMappedType() *Node {
+	start := p.tok.start
+	p.expect("{")
+	var readonly, optional any
+	if p.is("+") || p.is("-") {
+		readonly = p.tok.value
+		p.next()
+		p.expectName("readonly")
+	} else if p.eatName("readonly") {
+		readonly = true
+	}
+	p.expect("[")
+	key := p.parseIdentifier(false)
+	p.expectName("in")
+	constraint := p.parseType()
+	var nameType *Node
+	if p.eatName("as") {
+		nameType = p.parseType()
+	}
+	p.expect("]")
+	if p.is("+") || p.is("-") {
+		optional = p.tok.value
+		p.next()
+		p.expect("?")
+	} else if p.eat("?") {
+		optional = true
+	}
+	var typeAnnotation *Node
+	if p.eat(":") {
+		typeAnnotation = p.parseType()
+	}
+	p.eat(";")
+	p.eat(",")
+	p.expect("}")
+	n := p.make("TSMappedType", start, "key", key, "constraint", constraint, "nameType", nameType, "typeAnnotation", typeAnnotation)
+	if readonly != nil {
+		n.set("readonly", readonly)
+	}
+	if optional != nil {
+		n.set("optional", optional)
+	}
+	return n
+}
+
+// parseTypeMembers parses the members of an interface or a type literal, with their braces
+func (p *parser) parseTypeMembers() []*Node {
+	p.expect("{")
+	members := []*Node{}
+	for !p.eat("}") {
+		members = append(members, p.parseTypeMember())
+	}
+	return members
+}
+
+func (p *parser) parseTypeMember() *Node {
+	start := p.tok.start
+	if p.is("(") || p.is("<") {
+		n := p.parseSignature("TSCallSignatureDeclaration", start)
+		p.typeMemberSeparator()
+		return n
+	}
+	if p.isName("new") {
+		if next := p.peek(); next.value == "(" || next.value == "<" {
+			p.next()
+			n := p.parseSignature("TSConstructSignatureDeclaration", start)
+			p.typeMemberSeparator()
+			return n
+		}
+	}
+	readonly := false
+	if p.isName("readonly") {
+		if next := p.peek(); next.kind != tPunct || next.value == "[" {
+			p.next()
+			readonly = true
+		}
+	}
+	if p.is("[") && p.isIndexSignature() {
+		n := p.parseIndexSignature(start, readonly)
+		n.set("static", false)
+		p.typeMemberSeparator()
+		return n
+	}
+	kind := "method"
+	if p.isName("get") || p.isName("set") {
+		if next := p.peek(); next.kind != tPunct || next.value == "[" {
+			kind = p.tok.value
+			p.next()
+		}
+	}
+	key, computed := p.parsePropertyKey()
+	optional := p.eat("?")
+	if p.is("(") || p.is("<") {
+		n := p.parseSignature("TSMethodSignature", start)
+		fields := []Field{{"key", key}, {"computed", computed}, {"optional", optional}, {"kind", kind}}
+		n.Fields = append(fields, n.Fields...)
+		p.typeMemberSeparator()
+		return n
+	}
+	n := p.make("TSPropertySignature", start, "key", key, "computed", computed, "optional", optional, "readonly", readonly)
+	if p.is(":") {
+		p.annotate(n, p.parseTypeAnnotation())
+	}
+	p.typeMemberSeparator()
+	return n
+}
+
+// parseSignature parses the type parameters, parameters and return type of a signature
+func (p *parser) parseSignature(typ string, start int) *Node {
+	var typeParameters, returnType *Node
+	if p.is("<") {
+		typeParameters = p.parseTypeParameters()
+	}
+	params := p.parseParams()
+	if p.is(":") {
+		returnType = p.parseReturnType()
+	}
+	n := p.make(typ, start, "params", params, "returnType", returnType)
+	if typeParameters != nil {
+		n.set("typeParameters", typeParameters)
+	}
+	return n
+}
+
+// typeMemberSeparator consumes the separator after a member, which may be a line break
+func (p *parser) typeMemberSeparator() {
+	if !p.eat(";") && !p.eat(",") && !p.is("}") && !p.tok.nl {
+		p.unexpected()
+	}
+}
+
+// isIndexSignature reports whether the current "[" starts an index signature, as in [key: string]
+func (p *parser) isIndexSignature() bool {
+	s := p.save()
+	defer p.restore(s)
+	p.next()
+	if p.tok.kind != tName {
+		return false
+	}
+	p.next()
+	return p.is(":") || p.is(",")
+}
+
+func (p *parser) parseIndexSignature(start int, readonly bool) *Node {
+	p.expect("[")
+	parameters := []*Node{}
+	for !p.eat("]") {
+		parameter := p.parseIdentifier(true)
+		p.annotate(parameter, p.parseTypeAnnotation())
+		parameters = append(parameters, parameter)
+		if !p.is("]") {
+			p.expect(",")
+		}
+	}
+	var typeAnnotation *Node
+	if p.is(":") {
+		typeAnnotation = p.parseTypeAnnotation()
+	}
+	return p.make("TSIndexSignature", start, "parameters", parameters, "typeAnnotation", typeAnnotation, "readonly", readonly)
+}
+
+func (p *parser) parseTypeParameters() *Node {
+	start := p.tok.start
+	p.expect("<")
+	params := []*Node{}
+	for !p.isGreater() {
+		paramStart := p.tok.start
+		modifiers := map[string]bool{}
+		for (p.isName("in") || p.isName("out") || p.isName("const")) && p.peek().kind == tName {
+			modifiers[p.tok.value] = true
+			p.next()
+		}
+		name := p.parseIdentifier(false)
+		var constraint, defaultType *Node
+		if p.eatName("extends") {
+			constraint = p.parseType()
+		}
+		if p.eat("=") {
+			defaultType = p.parseType()
+		}
+		params = append(params, p.make("TSTypeParameter", paramStart, "name", name, "constraint", constraint, "default", defaultType,
+			"in", modifiers["in"], "out", modifiers["out"], "const", modifiers["const"]))
+		if !p.eat(",") {
+			break
+		}
+	}
+	p.expectGreater()
+	return p.make("TSTypeParameterDeclaration", start, "params", params)
+}
+
+func (p *parser) parseTypeArguments() *Node {
+	start := p.tok.start
+	if p.is("<<") {
+		// f<<T>(x: T) => T>() starts type arguments with a type parameter list
+		p.pos = p.tok.start + 1
+		p.prevEnd = p.pos
+		p.tok = token{kind: tPunct, value: "<", start: start, end: start + 1}
+	}
+	p.expect("<")
+	noConditionalType := p.noConditionalType
+	p.noConditionalType = false
+	params := []*Node{}
+	for !p.isGreater() {
+		params = append(params, p.parseType())
+		if !p.eat(",") {
+			break
+		}
+	}
+	p.expectGreater()
+	p.noConditionalType = noConditionalType
+	return p.make("TSTypeParameterInstantiation", start, "params", params)
+}
+
+// isGreater reports whether the current token starts with ">", like ">>" closing nested type arguments
+func (p *parser) isGreater() bool {
+	return p.tok.kind == tPunct && strings.HasPrefix(p.tok.value, ">")
+}
+
+// expectGreater consumes a ">" closing type parameters or arguments, splitting a token like ">>"
+func (p *parser) expectGreater() {
+	if p.eat(">") {
+		return
+	}
+	if !p.isGreater() {
+		p.expected(">")
+	}
+	p.pos = p.tok.start + 1
+	p.prevEnd = p.pos
+	p.tok = p.lexer.next()
+}
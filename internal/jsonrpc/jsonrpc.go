@@ -0,0 +1,328 @@
+// Package jsonrpc implements JSON-RPC 2.0 connections, used by the language server
+// and the long-running compiler server.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// Error codes defined by JSON-RPC and the Language Server Protocol
+const (
+	ParseError       = -32700
+	InvalidRequest   = -32600
+	MethodNotFound   = -32601
+	InvalidParams    = -32602
+	InternalError    = -32603
+	RequestCancelled = -32800
+)
+
+// CancelMethod is the notification cancelling an in-flight request
+const CancelMethod = "$/cancelRequest"
+
+// ErrClosed is returned by Call when the connection is closed before a response is received.
+// A Handler returns it from a notification to stop Run.
+var ErrClosed = errors.New("jsonrpc: connection closed")
+
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errorf returns an *Error with the given code
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// A Request is an incoming request or notification
+type Request struct {
+	// ID is nil for notifications
+	ID     json.RawMessage
+	Method string
+	Params json.RawMessage
+}
+
+func (r *Request) IsNotification() bool {
+	return r.ID == nil
+}
+
+// UnmarshalParams decodes the params of the request into v, reporting failures as InvalidParams
+func (r *Request) UnmarshalParams(v any) error {
+	if len(r.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Params, v); err != nil {
+		return Errorf(InvalidParams, "invalid params for %s: %v", r.Method, err)
+	}
+	return nil
+}
+
+// A Handler handles the incoming requests and notifications of a connection.
+//
+// Notifications are handled one at a time, in the order they are received, so they must not
+// wait for the response of a Call. Requests are handled concurrently; their context is cancelled
+// by CancelMethod or when Run stops before the end of the stream. The returned value is sent as
+// the result of a request, and an error which isn't an *Error is sent as an InternalError.
+type Handler func(ctx context.Context, req *Request) (any, error)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type Conn struct {
+	stream  Stream
+	handler Handler
+	// ErrorLog logs the panics of the Handler, which are recovered so that a failing message doesn't
+	// stop the connection. The standard logger is used when nil.
+	ErrorLog *log.Logger
+
+	mu      sync.Mutex
+	seq     int64
+	pending map[string]chan *message
+	running map[string]context.CancelFunc
+	closed  bool
+}
+
+func NewConn(stream Stream, handler Handler) *Conn {
+	return &Conn{
+		stream:  stream,
+		handler: handler,
+		pending: make(map[string]chan *message),
+		running: make(map[string]context.CancelFunc),
+	}
+}
+
+// Run reads and dispatches messages until the stream ends, ctx is cancelled between two messages,
+// or a notification handler returns ErrClosed. It waits for in-flight requests before returning.
+//
+// When the stream ends, the requests in flight run to completion, as their responses can still be
+// written; only the Calls waiting for a response fail with ErrClosed. Otherwise the requests in
+// flight are cancelled.
+func (c *Conn) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+		c.close()
+	}()
+
+	for ctx.Err() == nil {
+		data, err := c.stream.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// No response can arrive anymore, but the requests which don't wait for one can finish
+				c.close()
+				wg.Wait()
+				return nil
+			}
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply(nil, nil, Errorf(ParseError, "invalid message: %v", err))
+			continue
+		}
+
+		switch {
+		case msg.Method == "" && msg.ID != nil:
+			c.deliver(&msg)
+		case msg.Method == "":
+			c.reply(nil, nil, Errorf(InvalidRequest, "missing method"))
+		case msg.ID == nil:
+			if msg.Method == CancelMethod {
+				c.cancel(msg.Params)
+				continue
+			}
+			if _, err := c.handle(ctx, &Request{Method: msg.Method, Params: msg.Params}); errors.Is(err, ErrClosed) {
+				return nil
+			}
+		default:
+			req := &Request{ID: msg.ID, Method: msg.Method, Params: msg.Params}
+			reqCtx, reqCancel := context.WithCancel(ctx)
+			c.mu.Lock()
+			c.running[idKey(req.ID)] = reqCancel
+			c.mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					c.mu.Lock()
+					delete(c.running, idKey(req.ID))
+					c.mu.Unlock()
+					reqCancel()
+				}()
+				result, err := c.handle(reqCtx, req)
+				if err != nil && reqCtx.Err() != nil && errors.Is(err, reqCtx.Err()) {
+					err = Errorf(RequestCancelled, "request %s was cancelled", req.ID)
+				}
+				c.reply(req.ID, result, err)
+			}()
+		}
+	}
+	return ctx.Err()
+}
+
+// handle calls the handler, reporting a panic as an InternalError instead of crashing the server
+func (c *Conn) handle(ctx context.Context, req *Request) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Errorf(InternalError, "%s: %v", req.Method, r)
+			// The error of a notification has no response, so the panic is only known from the log
+			logger := c.ErrorLog
+			if logger == nil {
+				logger = log.Default()
+			}
+			logger.Printf("jsonrpc: panic handling %s: %v\n%s", req.Method, r, debug.Stack())
+		}
+	}()
+	return c.handler(ctx, req)
+}
+
+// Call sends a request and decodes its result into result, which may be nil.
+func (c *Conn) Call(ctx context.Context, method string, params any, result any) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.seq++
+	id := json.RawMessage(strconv.FormatInt(c.seq, 10))
+	ch := make(chan *message, 1)
+	c.pending[idKey(id)] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, idKey(id))
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(&message{ID: id, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.Notify(CancelMethod, map[string]json.RawMessage{"id": id})
+		return ctx.Err()
+	case msg, ok := <-ch:
+		if !ok {
+			return ErrClosed
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	}
+}
+
+// Notify sends a notification
+func (c *Conn) Notify(method string, params any) error {
+	return c.write(&message{Method: method}, params)
+}
+
+func (c *Conn) write(msg *message, params any) error {
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.stream.Write(data)
+}
+
+func (c *Conn) reply(id json.RawMessage, result any, err error) {
+	msg := &message{JSONRPC: "2.0", ID: id}
+	if msg.ID == nil {
+		msg.ID = json.RawMessage("null")
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: InternalError, Message: err.Error()}
+		}
+		msg.Error = rpcErr
+	} else {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			msg.Error = &Error{Code: InternalError, Message: marshalErr.Error()}
+		} else {
+			msg.Result = data
+		}
+	}
+	data, _ := json.Marshal(msg)
+	c.stream.Write(data)
+}
+
+func (c *Conn) deliver(msg *message) {
+	c.mu.Lock()
+	ch, ok := c.pending[idKey(msg.ID)]
+	c.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (c *Conn) cancel(params json.RawMessage) {
+	var p struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return
+	}
+	c.mu.Lock()
+	cancel, ok := c.running[idKey(p.ID)]
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (c *Conn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+// idKey normalizes an ID so that `1` and ` 1` match
+func idKey(id json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, id); err != nil {
+		return string(id)
+	}
+	return buf.String()
+}
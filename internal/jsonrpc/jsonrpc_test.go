@@ -0,0 +1,271 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeaderStream(t *testing.T) {
+	input := "Content-Length: 16\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n{\"method\":\"a\"}\n\ncontent-length:2\r\n\r\n{}"
+	var out bytes.Buffer
+	stream := NewHeaderStream(strings.NewReader(input), &out)
+
+	for _, want := range []string{"{\"method\":\"a\"}\n\n", "{}"} {
+		msg, err := stream.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != want {
+			t.Errorf("expected %q, got %q", want, msg)
+		}
+	}
+	if _, err := stream.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	if err := stream.Write([]byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Content-Length: 8\r\n\r\n{\"id\":1}"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestHeaderStreamErrors(t *testing.T) {
+	for _, input := range []string{
+		"Content-Type: x\r\n\r\n{}",
+		"Content-Length: x\r\n\r\n{}",
+		"invalid\r\n\r\n{}",
+		"Content-Length: 10\r\n\r\n{}",
+	} {
+		if _, err := NewHeaderStream(strings.NewReader(input), io.Discard).Read(); err == nil || err == io.EOF {
+			t.Errorf("expected an error for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestLineStream(t *testing.T) {
+	input := "{\"method\":\"a\"}\r\n\n  \n{\"id\":2,\n\"result\":{}}\n{}"
+	var out bytes.Buffer
+	stream := NewLineStream(strings.NewReader(input), &out)
+
+	for _, want := range []string{`{"method":"a"}`, `{"id":2,`, `"result":{}}`, `{}`} {
+		msg, err := stream.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != want {
+			t.Errorf("expected %q, got %q", want, msg)
+		}
+	}
+	if _, err := stream.Read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	if err := stream.Write([]byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"id\":1}\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+// pipe returns two connected connections, running until the test ends
+func pipe(t *testing.T, serverHandler Handler, clientHandler Handler) (server *Conn, client *Conn) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	server = NewConn(NewHeaderStream(serverIn, serverOut), serverHandler)
+	client = NewConn(NewHeaderStream(clientIn, clientOut), clientHandler)
+	go func() {
+		server.Run(context.Background())
+		serverIn.Close()
+		serverOut.Close()
+	}()
+	go client.Run(context.Background())
+	t.Cleanup(func() { clientOut.Close() })
+	return server, client
+}
+
+func TestConn(t *testing.T) {
+	notified := make(chan string, 1)
+	_, client := pipe(t, func(ctx context.Context, req *Request) (any, error) {
+		switch req.Method {
+		case "add":
+			var params [2]int
+			if err := req.UnmarshalParams(&params); err != nil {
+				return nil, err
+			}
+			return params[0] + params[1], nil
+		case "notify":
+			var params string
+			req.UnmarshalParams(&params)
+			notified <- params
+			return nil, nil
+		case "panic":
+			panic("boom")
+		case "fail":
+			return nil, errors.New("failed")
+		}
+		return nil, Errorf(MethodNotFound, "method not found: %s", req.Method)
+	}, nil)
+
+	var sum int
+	if err := client.Call(context.Background(), "add", []int{1, 2}, &sum); err != nil || sum != 3 {
+		t.Errorf("expected 3, got %d (%v)", sum, err)
+	}
+
+	client.Notify("notify", "hello")
+	select {
+	case params := <-notified:
+		if params != "hello" {
+			t.Errorf("expected hello, got %s", params)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the notification")
+	}
+
+	for method, want := range map[string]int{
+		"add":     InvalidParams,
+		"unknown": MethodNotFound,
+		"panic":   InternalError,
+		"fail":    InternalError,
+	} {
+		err := client.Call(context.Background(), method, "not an array", nil)
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != want {
+			t.Errorf("%s: expected error code %d, got %v", method, want, err)
+		}
+	}
+}
+
+func TestConnNotificationPanic(t *testing.T) {
+	server, client := pipe(t, func(ctx context.Context, req *Request) (any, error) {
+		if req.Method == "panic" {
+			panic("boom")
+		}
+		return "pong", nil
+	}, nil)
+	var logs bytes.Buffer
+	server.ErrorLog = log.New(&logs, "", 0)
+
+	// The connection keeps serving after the notification, which has no response for the error
+	client.Notify("panic", nil)
+	var result string
+	if err := client.Call(context.Background(), "ping", nil, &result); err != nil || result != "pong" {
+		t.Fatalf("expected pong, got %q (%v)", result, err)
+	}
+	if !strings.HasPrefix(logs.String(), "jsonrpc: panic handling panic: boom\n") {
+		t.Errorf("expected the panic to be logged, got %q", logs.String())
+	}
+}
+
+func TestConnCancel(t *testing.T) {
+	started := make(chan struct{})
+	_, client := pipe(t, func(ctx context.Context, req *Request) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if err := client.Call(ctx, "wait", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the call to be cancelled, got %v", err)
+	}
+}
+
+func TestConnCancelRequest(t *testing.T) {
+	started := make(chan struct{})
+	_, client := pipe(t, func(ctx context.Context, req *Request) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, nil)
+
+	// Cancel the first request the server receives, using the ID of the client
+	go func() {
+		<-started
+		client.Notify(CancelMethod, map[string]int{"id": 1})
+	}()
+	err := client.Call(context.Background(), "wait", nil, nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != RequestCancelled {
+		t.Errorf("expected RequestCancelled, got %v", err)
+	}
+}
+
+func TestConnClosed(t *testing.T) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	client := NewConn(NewHeaderStream(clientIn, clientOut), nil)
+	go client.Run(context.Background())
+
+	// Hang up after receiving the request, without responding
+	go func() {
+		NewHeaderStream(serverIn, serverOut).Read()
+		serverOut.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Call(ctx, "ping", nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := client.Call(ctx, "ping", nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed for a call on a closed connection, got %v", err)
+	}
+}
+
+func TestConnEndOfStream(t *testing.T) {
+	// The stream ends right after the requests, which are answered anyway
+	input := `{"jsonrpc":"2.0","id":1,"method":"call"}` + "\n" + `{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"
+	var out bytes.Buffer
+	var conn *Conn
+	called := make(chan error, 1)
+	conn = NewConn(NewLineStream(strings.NewReader(input), &out), func(ctx context.Context, req *Request) (any, error) {
+		switch req.Method {
+		case "call":
+			err := conn.Call(ctx, "callback", nil, nil)
+			called <- err
+			return nil, err
+		case "ping":
+			// The call fails once the stream has ended, which must not cancel this request
+			<-called
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return "pong", nil
+		}
+		return nil, nil
+	})
+	if err := conn.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := map[string]message{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Method == "" {
+			responses[string(msg.ID)] = msg
+		}
+	}
+	if msg := responses["1"]; msg.Error == nil || msg.Error.Message != ErrClosed.Error() {
+		t.Errorf("expected the request waiting for a response to fail with ErrClosed, got %+v", msg)
+	}
+	if msg := responses["2"]; msg.Error != nil || string(msg.Result) != `"pong"` {
+		t.Errorf("expected the request to complete, got %+v", msg)
+	}
+}
@@ -0,0 +1,117 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Stream reads and writes whole JSON-RPC messages. Read is only called from a
+// single goroutine, Write may be called concurrently.
+type Stream interface {
+	Read() ([]byte, error)
+	Write(msg []byte) error
+}
+
+type headerStream struct {
+	r  *bufio.Reader
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewHeaderStream frames messages with a `Content-Length` header, as used by the
+// Language Server Protocol.
+func NewHeaderStream(r io.Reader, w io.Writer) Stream {
+	return &headerStream{r: bufio.NewReader(r), w: w}
+}
+
+func (s *headerStream) Read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line != "" {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("jsonrpc: invalid header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("jsonrpc: invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("jsonrpc: missing Content-Length header")
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(s.r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *headerStream) Write(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(msg)); err != nil {
+		return err
+	}
+	_, err := s.w.Write(msg)
+	return err
+}
+
+type lineStream struct {
+	r  *bufio.Reader
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLineStream frames messages as newline-delimited JSON, one message per line.
+// Empty lines are ignored.
+func NewLineStream(r io.Reader, w io.Writer) Stream {
+	return &lineStream{r: bufio.NewReader(r), w: w}
+}
+
+func (s *lineStream) Read() ([]byte, error) {
+	for {
+		line, err := s.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err == io.EOF {
+			return nil, err
+		}
+	}
+}
+
+func (s *lineStream) Write(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Marshalled JSON never contains a raw newline, so each message stays on its own line
+	if _, err := s.w.Write(msg); err != nil {
+		return err
+	}
+	_, err := s.w.Write([]byte{'\n'})
+	return err
+}
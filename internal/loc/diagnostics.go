@@ -9,6 +9,10 @@ const (
 	ERROR_UNMATCHED_IMPORT            DiagnosticCode = 1003
 	ERROR_UNSUPPORTED_SLOT_ATTRIBUTE  DiagnosticCode = 1004
 	ERROR_UNTERMINATED_STRING         DiagnosticCode = 1005
+	ERROR_CANCELLED                   DiagnosticCode = 1006
+	ERROR_UNTERMINATED_FRONTMATTER    DiagnosticCode = 1007
+	ERROR_UNTERMINATED_EXPRESSION     DiagnosticCode = 1008
+	ERROR_FRONTMATTER_SYNTAX          DiagnosticCode = 1009
 	WARNING                           DiagnosticCode = 2000
 	WARNING_UNTERMINATED_HTML_COMMENT DiagnosticCode = 2001
 	WARNING_UNCLOSED_HTML_TAG         DiagnosticCode = 2002
@@ -20,6 +24,28 @@ const (
 	WARNING_INVALID_SPREAD            DiagnosticCode = 2008
 	WARNING_UNEXPECTED_CHARACTER      DiagnosticCode = 2009
 	WARNING_CANNOT_RERUN              DiagnosticCode = 2010
+	WARNING_UNEXPECTED_BRACE          DiagnosticCode = 2011
+	WARNING_UNCLOSED_ELEMENT          DiagnosticCode = 2012
+	WARNING_UNEXPECTED_END_TAG        DiagnosticCode = 2013
+	WARNING_FOSTER_PARENTED           DiagnosticCode = 2014
+	WARNING_IMPLIED_END_TAG           DiagnosticCode = 2015
+	WARNING_MISNESTED_FORMATTING      DiagnosticCode = 2016
+	WARNING_UNKNOWN_DIRECTIVE         DiagnosticCode = 2017
+	WARNING_MISUSED_DIRECTIVE         DiagnosticCode = 2018
+	WARNING_FOREIGN_CASING            DiagnosticCode = 2019
+	WARNING_UNKNOWN_FOREIGN_ATTRIBUTE DiagnosticCode = 2020
+	WARNING_HTML_IN_FOREIGN_CONTENT   DiagnosticCode = 2021
+	WARNING_UNKNOWN_SLOT              DiagnosticCode = 2022
+	WARNING_A11Y_MISSING_ALT          DiagnosticCode = 2023
+	WARNING_A11Y_MISSING_NAME         DiagnosticCode = 2024
+	WARNING_A11Y_INVALID_ARIA         DiagnosticCode = 2025
+	WARNING_A11Y_INVALID_ROLE         DiagnosticCode = 2026
+	WARNING_A11Y_MISSING_LANG         DiagnosticCode = 2027
+	WARNING_A11Y_DUPLICATE_ID         DiagnosticCode = 2028
+	WARNING_A11Y_MISSING_LABEL        DiagnosticCode = 2029
+	WARNING_NESTED_FORM               DiagnosticCode = 2030
+	WARNING_EXPRESSION_SYNTAX         DiagnosticCode = 2031
 	INFO                              DiagnosticCode = 3000
 	HINT                              DiagnosticCode = 4000
+	HINT_A11Y_POSITIVE_TABINDEX       DiagnosticCode = 4001
 )
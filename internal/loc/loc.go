@@ -20,9 +20,14 @@ type Span struct {
 	Start, End int
 }
 
+// Range returns s as a Range, for diagnostics
+func (s Span) Range() Range {
+	return Range{Loc: Loc{Start: s.Start}, Len: s.End - s.Start}
+}
+
 type TSXRange struct {
-	Start int `js:"start"`
-	End   int `js:"end"`
+	Start int `js:"start" json:"start"`
+	End   int `js:"end" json:"end"`
 }
 
 // A NodeType is the type of a Node.
@@ -36,18 +41,18 @@ const (
 )
 
 type DiagnosticMessage struct {
-	Severity int                 `js:"severity"`
-	Code     int                 `js:"code"`
-	Location *DiagnosticLocation `js:"location"`
-	Hint     string              `js:"hint"`
-	Text     string              `js:"text"`
+	Severity int                 `js:"severity" json:"severity"`
+	Code     int                 `js:"code" json:"code"`
+	Location *DiagnosticLocation `js:"location" json:"location"`
+	Hint     string              `js:"hint" json:"hint,omitempty"`
+	Text     string              `js:"text" json:"text"`
 }
 
 type DiagnosticLocation struct {
-	File   string `js:"file"`
-	Line   int    `js:"line"`
-	Column int    `js:"column"`
-	Length int    `js:"length"`
+	File   string `js:"file" json:"file"`
+	Line   int    `js:"line" json:"line"`
+	Column int    `js:"column" json:"column"`
+	Length int    `js:"length" json:"length"`
 }
 
 type ErrorWithRange struct {
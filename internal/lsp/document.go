@@ -0,0 +1,135 @@
+package lsp
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/withastro/compiler/compiler"
+)
+
+type document struct {
+	uri     string
+	version int
+	text    string
+	// lineStarts holds the byte offset of the start of each line
+	lineStarts []int
+	tsx        compiler.TSXResult
+	// compiled is the source kept parsed between changes. It is handed over to the next version
+	// of the document, and only used by the latest one.
+	compiled *compiler.TSXDocument
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version}
+	d.setText(text)
+	return d
+}
+
+func (d *document) setText(text string) {
+	d.text = text
+	d.lineStarts = []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+}
+
+// applyChange applies an edit sent by textDocument/didChange
+func (d *document) applyChange(change TextDocumentContentChangeEvent) {
+	start, end := 0, len(d.text)
+	if change.Range != nil {
+		start = d.offsetAt(change.Range.Start)
+		end = d.offsetAt(change.Range.End)
+		if end < start {
+			start, end = end, start
+		}
+	}
+	d.setText(d.text[:start] + change.Text + d.text[end:])
+	if d.compiled != nil {
+		// The offsets are clamped to the text, which the compiled source matches
+		d.compiled.Edit(compiler.Edit{Start: start, End: end, Text: change.Text})
+	}
+}
+
+// offsetAt converts a position to a byte offset, clamping positions outside of the document
+func (d *document) offsetAt(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[pos.Line]
+	lineEnd := len(d.text)
+	if pos.Line+1 < len(d.lineStarts) {
+		lineEnd = d.lineStarts[pos.Line+1] - 1
+	}
+	for character := 0; character < pos.Character && offset < lineEnd; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		offset += size
+		character += utf16Len(r)
+	}
+	return offset
+}
+
+// positionAt converts a byte offset to a position
+func (d *document) positionAt(offset int) Position {
+	offset = max(0, min(offset, len(d.text)))
+	line := 0
+	for line+1 < len(d.lineStarts) && d.lineStarts[line+1] <= offset {
+		line++
+	}
+	character := 0
+	for _, r := range d.text[d.lineStarts[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+func (d *document) rangeAt(start int, end int) Range {
+	return Range{Start: d.positionAt(start), End: d.positionAt(end)}
+}
+
+// diagnostics converts the compiler diagnostics of the document
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, msg := range d.tsx.Diagnostics {
+		message := msg.Text
+		if msg.Hint != "" {
+			message += "\n\n" + msg.Hint
+		}
+		diagnostic := Diagnostic{
+			Severity: msg.Severity,
+			Code:     msg.Code,
+			Source:   "astro",
+			Message:  message,
+		}
+		if msg.Location != nil {
+			// Diagnostic locations are 1-based, in UTF-16 columns, with a length in bytes
+			start := d.offsetAt(Position{Line: msg.Location.Line - 1, Character: msg.Location.Column - 1})
+			diagnostic.Range = d.rangeAt(start, start+msg.Location.Length)
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+const tsxScheme = "astro-tsx"
+
+// tsxURI returns the URI of the virtual TSX document generated for uri,
+// e.g. astro-tsx:///src/pages/index.astro.tsx for file:///src/pages/index.astro
+func tsxURI(uri string) string {
+	_, path, ok := strings.Cut(uri, ":")
+	if !ok {
+		path = uri
+	}
+	return tsxScheme + ":" + path + ".tsx"
+}
@@ -0,0 +1,243 @@
+// Package lsp implements a language server for Astro components on top of the compiler:
+// diagnostics, document symbols and the generated TSX as virtual documents.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"sync"
+
+	"github.com/withastro/compiler/compiler"
+	"github.com/withastro/compiler/internal/jsonrpc"
+)
+
+// ErrExitWithoutShutdown is returned by Run when the client sends `exit` before `shutdown`
+var ErrExitWithoutShutdown = errors.New("lsp: exit notification received before shutdown")
+
+type Server struct {
+	conn *jsonrpc.Conn
+
+	mu        sync.Mutex
+	documents map[string]*document
+	// refreshContent is set when the client can be told that a virtual TSX document changed
+	refreshContent bool
+	shutdown       bool
+	exited         bool
+}
+
+func NewServer(stream jsonrpc.Stream) *Server {
+	s := &Server{documents: make(map[string]*document)}
+	s.conn = jsonrpc.NewConn(stream, s.handle)
+	return s
+}
+
+// Run serves requests until the client exits or closes the stream
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.conn.Run(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exited && !s.shutdown {
+		return ErrExitWithoutShutdown
+	}
+	return nil
+}
+
+func (s *Server) handle(ctx context.Context, req *jsonrpc.Request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.refreshContent = params.Capabilities.Workspace.TextDocumentContent != nil
+		s.mu.Unlock()
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync: TextDocumentSyncOptions{
+					OpenClose: true,
+					Change:    TextDocumentSyncIncremental,
+				},
+				DocumentSymbolProvider: true,
+				Workspace: WorkspaceCapabilities{
+					TextDocumentContent: TextDocumentContentOptions{Schemes: []string{tsxScheme}},
+				},
+			},
+			ServerInfo: ServerInfo{Name: "astro-ls"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		return nil, nil
+	case "exit":
+		s.mu.Lock()
+		s.exited = true
+		s.mu.Unlock()
+		return nil, jsonrpc.ErrClosed
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		item := params.TextDocument
+		s.update(newDocument(item.URI, item.Version, item.Text))
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		prev := s.document(params.TextDocument.URI)
+		if prev == nil {
+			return nil, nil
+		}
+		// Documents are never mutated, as requests may be reading the previous version
+		d := newDocument(prev.uri, params.TextDocument.Version, prev.text)
+		d.compiled, prev.compiled = prev.compiled, nil
+		for _, change := range params.ContentChanges {
+			d.applyChange(change)
+		}
+		s.update(d)
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		delete(s.documents, params.TextDocument.URI)
+		s.mu.Unlock()
+		return nil, s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		d := s.document(params.TextDocument.URI)
+		if d == nil {
+			return nil, unknownDocument(params.TextDocument.URI)
+		}
+		return documentSymbols(d), nil
+	case "workspace/textDocumentContent":
+		var params TextDocumentContentParams
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		d := s.tsxDocument(params.URI)
+		if d == nil {
+			return nil, unknownDocument(params.URI)
+		}
+		return TextDocumentContentResult{Text: d.tsx.Code}, nil
+	case "astro/tsx":
+		var params TextDocumentIdentifier
+		if err := req.UnmarshalParams(&params); err != nil {
+			return nil, err
+		}
+		d := s.document(params.URI)
+		if d == nil {
+			d = s.tsxDocument(params.URI)
+		}
+		if d == nil {
+			return nil, unknownDocument(params.URI)
+		}
+		return TSXDocument{
+			URI:        tsxURI(d.uri),
+			SourceURI:  d.uri,
+			Version:    d.version,
+			Code:       d.tsx.Code,
+			Map:        json.RawMessage(d.tsx.Map),
+			MetaRanges: d.tsx.Ranges,
+		}, nil
+	}
+
+	if req.IsNotification() {
+		return nil, nil
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.MethodNotFound, "method not found: %s", req.Method)
+}
+
+// TSXDocument is the result of the `astro/tsx` request, the virtual TSX document generated
+// for an Astro component. It is also served as `workspace/textDocumentContent` for its URI.
+type TSXDocument struct {
+	URI        string             `json:"uri"`
+	SourceURI  string             `json:"sourceUri"`
+	Version    int                `json:"version"`
+	Code       string             `json:"code"`
+	Map        json.RawMessage    `json:"map"`
+	MetaRanges compiler.TSXRanges `json:"metaRanges"`
+}
+
+// update compiles a new version of a document and publishes its diagnostics. Only the part of the
+// source around the changes since the previous version is parsed again.
+func (s *Server) update(d *document) {
+	if d.compiled == nil {
+		d.compiled = compiler.NewTSXDocument(d.text, tsxOptions(d.uri))
+	}
+	d.tsx = d.compiled.ConvertToTSX()
+
+	s.mu.Lock()
+	s.documents[d.uri] = d
+	refreshContent := s.refreshContent
+	s.mu.Unlock()
+
+	s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: d.diagnostics(),
+	})
+	if refreshContent {
+		// Notifications can't wait for a response, as it is read by the same goroutine
+		go s.conn.Call(context.Background(), "workspace/textDocumentContent/refresh", TextDocumentContentRefreshParams{URI: tsxURI(d.uri)}, nil)
+	}
+}
+
+func tsxOptions(uri string) compiler.TSXOptions {
+	return compiler.TSXOptions{
+		Filename:       filenameFromURI(uri),
+		IncludeScripts: true,
+		IncludeStyles:  true,
+		// Documents are usually incomplete while they are being edited
+		Recover: true,
+	}
+}
+
+func (s *Server) document(uri string) *document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.documents[uri]
+}
+
+// tsxDocument returns the document a virtual TSX document URI was generated for
+func (s *Server) tsxDocument(uri string) *document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.documents {
+		if tsxURI(d.uri) == uri {
+			return d
+		}
+	}
+	return nil
+}
+
+func unknownDocument(uri string) error {
+	return jsonrpc.Errorf(jsonrpc.InvalidParams, "unknown document: %s", uri)
+}
+
+func filenameFromURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,324 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/withastro/compiler/compiler"
+	"github.com/withastro/compiler/internal/jsonrpc"
+	"github.com/withastro/compiler/internal/test_utils"
+)
+
+// client is an in-process LSP client connected to a Server
+type client struct {
+	t             *testing.T
+	conn          *jsonrpc.Conn
+	notifications chan *jsonrpc.Request
+	refreshes     chan string
+	done          chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{
+		t:             t,
+		notifications: make(chan *jsonrpc.Request, 16),
+		refreshes:     make(chan string, 16),
+		done:          make(chan error, 1),
+	}
+	server := NewServer(jsonrpc.NewHeaderStream(serverIn, serverOut))
+	go func() {
+		c.done <- server.Run(context.Background())
+		serverIn.Close()
+		serverOut.Close()
+	}()
+
+	c.conn = jsonrpc.NewConn(jsonrpc.NewHeaderStream(clientIn, clientOut), func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		if req.IsNotification() {
+			c.notifications <- req
+			return nil, nil
+		}
+		if req.Method == "workspace/textDocumentContent/refresh" {
+			var params TextDocumentContentRefreshParams
+			json.Unmarshal(req.Params, &params)
+			c.refreshes <- params.URI
+		}
+		return nil, nil
+	})
+	go c.conn.Run(context.Background())
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+func (c *client) call(method string, params any, result any) {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.conn.Call(ctx, method, params, result); err != nil {
+		c.t.Fatalf("%s: %v", method, err)
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.conn.Notify(method, params); err != nil {
+		c.t.Fatalf("%s: %v", method, err)
+	}
+}
+
+func (c *client) publishedDiagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	select {
+	case req := <-c.notifications:
+		if req.Method != "textDocument/publishDiagnostics" {
+			c.t.Fatalf("expected publishDiagnostics, got %s", req.Method)
+		}
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+		return params
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for diagnostics")
+	}
+	return PublishDiagnosticsParams{}
+}
+
+func (c *client) initialize(capabilities string) InitializeResult {
+	c.t.Helper()
+	var result InitializeResult
+	c.call("initialize", json.RawMessage(`{"processId":null,"rootUri":null,"capabilities":`+capabilities+`}`), &result)
+	c.notify("initialized", struct{}{})
+	return result
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	c := newClient(t)
+	result := c.initialize(`{}`)
+	if result.Capabilities.TextDocumentSync.Change != TextDocumentSyncIncremental || !result.Capabilities.DocumentSymbolProvider {
+		t.Errorf("unexpected capabilities: %+v", result.Capabilities)
+	}
+
+	uri := "file:///src/pages/index.astro"
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+		URI:        uri,
+		LanguageID: "astro",
+		Version:    1,
+		Text:       "<h1>Hello 👋</h1>\n<p>😀</p><script async src=\"/analytics.js\"></script>",
+	}})
+
+	published := c.publishedDiagnostics()
+	if published.URI != uri || published.Version != 1 || len(published.Diagnostics) != 1 {
+		t.Fatalf("unexpected diagnostics: %+v", published)
+	}
+	diagnostic := published.Diagnostics[0]
+	// Characters are counted in UTF-16 code units, 😀 is two of them
+	wantRange := Range{Start: Position{Line: 1, Character: 17}, End: Position{Line: 1, Character: 22}}
+	if diff := test_utils.ANSIDiff(wantRange, diagnostic.Range); diff != "" {
+		t.Errorf("range mismatch (-want +got):\n%s", diff)
+	}
+	if diagnostic.Severity != 4 || diagnostic.Source != "astro" || !strings.Contains(diagnostic.Message, "is:inline") {
+		t.Errorf("unexpected diagnostic: %+v", diagnostic)
+	}
+
+	// Adding `is:inline` silences the hint
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{
+			Range: &Range{Start: Position{Line: 1, Character: 17}, End: Position{Line: 1, Character: 17}},
+			Text:  "is:inline ",
+		}},
+	})
+	published = c.publishedDiagnostics()
+	if published.Version != 2 || len(published.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics after the change, got %+v", published)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	published = c.publishedDiagnostics()
+	if published.URI != uri || published.Diagnostics == nil || len(published.Diagnostics) != 0 {
+		t.Errorf("expected diagnostics to be cleared on close, got %+v", published)
+	}
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("expected a clean exit, got %v", err)
+	}
+}
+
+func TestServerIncrementalChanges(t *testing.T) {
+	c := newClient(t)
+	c.initialize(`{}`)
+
+	uri := "file:///src/pages/index.astro"
+	text := "<div>\n\t<p>{unclosed</p>\n</div>\n<main><p>Text</p></main>\n<span>}</span>"
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, Version: 1, Text: text}})
+	c.publishedDiagnostics()
+
+	for version, changes := range [][]TextDocumentContentChangeEvent{
+		{
+			{Range: &Range{Start: Position{Line: 3, Character: 13}, End: Position{Line: 3, Character: 13}}, Text: " <b>more"},
+			{Range: &Range{Start: Position{Line: 1, Character: 4}, End: Position{Line: 1, Character: 5}}, Text: "("},
+		},
+		{{Range: &Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 0}}, Text: "<p>{</p>\n"}},
+		{{Text: "<h1>{title</h1>"}},
+	} {
+		c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: version + 2},
+			ContentChanges: changes,
+		})
+		for _, change := range changes {
+			d := newDocument(uri, 0, text)
+			d.applyChange(change)
+			text = d.text
+		}
+
+		// The diagnostics are the ones of the whole text
+		want := newDocument(uri, version+2, text)
+		want.tsx = compiler.ConvertToTSX(text, tsxOptions(uri))
+		published := c.publishedDiagnostics()
+		if diff := test_utils.ANSIDiff(want.diagnostics(), published.Diagnostics); diff != "" {
+			t.Errorf("diagnostics mismatch for %q (-want +got):\n%s", text, diff)
+		}
+	}
+}
+
+func TestServerDocumentSymbols(t *testing.T) {
+	c := newClient(t)
+	c.initialize(`{}`)
+
+	uri := "file:///src/components/Card.astro"
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+		URI:     uri,
+		Version: 1,
+		Text: strings.Join([]string{
+			"---",
+			"import Layout from '../layouts/Layout.astro';",
+			"import Counter from './Counter.jsx';",
+			"---",
+			`<Layout title="Card">`,
+			`	<div class="card">`,
+			`		<Counter client:visible count={items.filter(i => i > 0).length} />`,
+			`		<slot name="header">Header</slot>`,
+			`		<slot />`,
+			`	</div>`,
+			`</Layout>`,
+			`<script src="/card.js"></script>`,
+			`<style lang="scss">.card { color: red; }</style>`,
+		}, "\n"),
+	}})
+	c.publishedDiagnostics()
+
+	var symbols []DocumentSymbol
+	c.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &symbols)
+
+	want := []DocumentSymbol{
+		{
+			Name:           "Layout",
+			Kind:           SymbolKindClass,
+			Range:          Range{Start: Position{Line: 4, Character: 0}, End: Position{Line: 10, Character: 9}},
+			SelectionRange: Range{Start: Position{Line: 4, Character: 1}, End: Position{Line: 4, Character: 7}},
+			Children: []DocumentSymbol{
+				{
+					Name:           "Counter",
+					Detail:         "client:visible",
+					Kind:           SymbolKindClass,
+					Range:          Range{Start: Position{Line: 6, Character: 2}, End: Position{Line: 6, Character: 68}},
+					SelectionRange: Range{Start: Position{Line: 6, Character: 3}, End: Position{Line: 6, Character: 10}},
+				},
+				{
+					Name:           `slot "header"`,
+					Detail:         "with fallback",
+					Kind:           SymbolKindField,
+					Range:          Range{Start: Position{Line: 7, Character: 2}, End: Position{Line: 7, Character: 35}},
+					SelectionRange: Range{Start: Position{Line: 7, Character: 3}, End: Position{Line: 7, Character: 7}},
+				},
+				{
+					Name:           "slot",
+					Kind:           SymbolKindField,
+					Range:          Range{Start: Position{Line: 8, Character: 2}, End: Position{Line: 8, Character: 10}},
+					SelectionRange: Range{Start: Position{Line: 8, Character: 3}, End: Position{Line: 8, Character: 7}},
+				},
+			},
+		},
+		{
+			Name:           "script",
+			Detail:         `src="/card.js"`,
+			Kind:           SymbolKindModule,
+			Range:          Range{Start: Position{Line: 11, Character: 0}, End: Position{Line: 11, Character: 32}},
+			SelectionRange: Range{Start: Position{Line: 11, Character: 1}, End: Position{Line: 11, Character: 7}},
+		},
+		{
+			Name:           "style",
+			Detail:         `lang="scss"`,
+			Kind:           SymbolKindNamespace,
+			Range:          Range{Start: Position{Line: 12, Character: 0}, End: Position{Line: 12, Character: 48}},
+			SelectionRange: Range{Start: Position{Line: 12, Character: 1}, End: Position{Line: 12, Character: 6}},
+		},
+	}
+	if diff := test_utils.ANSIDiff(want, symbols); diff != "" {
+		t.Errorf("symbols mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServerTSX(t *testing.T) {
+	c := newClient(t)
+	c.initialize(`{"workspace":{"textDocumentContent":{"dynamicRegistration":false}}}`)
+
+	uri := "file:///src/pages/index.astro"
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+		URI:     uri,
+		Version: 1,
+		Text:    "---\nconst name = 'world';\n---\n<h1>Hello {name}</h1>",
+	}})
+	c.publishedDiagnostics()
+
+	virtualURI := "astro-tsx:///src/pages/index.astro.tsx"
+	select {
+	case refreshed := <-c.refreshes:
+		if refreshed != virtualURI {
+			t.Errorf("expected a refresh of %s, got %s", virtualURI, refreshed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a refresh of the virtual document")
+	}
+
+	var tsx TSXDocument
+	c.call("astro/tsx", TextDocumentIdentifier{URI: uri}, &tsx)
+	if tsx.URI != virtualURI || tsx.SourceURI != uri || tsx.Version != 1 {
+		t.Errorf("unexpected virtual document: %+v", tsx)
+	}
+	if !strings.Contains(tsx.Code, "<h1>Hello {name}</h1>") || !json.Valid(tsx.Map) {
+		t.Errorf("unexpected TSX: %s", tsx.Code)
+	}
+	if tsx.MetaRanges.Frontmatter.End <= tsx.MetaRanges.Frontmatter.Start {
+		t.Errorf("expected a frontmatter range, got %+v", tsx.MetaRanges)
+	}
+
+	var content TextDocumentContentResult
+	c.call("workspace/textDocumentContent", TextDocumentContentParams{URI: virtualURI}, &content)
+	if content.Text != tsx.Code {
+		t.Errorf("expected the content of the virtual document to be the TSX, got %s", content.Text)
+	}
+
+	err := c.conn.Call(context.Background(), "workspace/textDocumentContent", TextDocumentContentParams{URI: "astro-tsx:///unknown.astro.tsx"}, nil)
+	if rpcErr, ok := err.(*jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.InvalidParams {
+		t.Errorf("expected an InvalidParams error for an unknown document, got %v", err)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	c.initialize(`{}`)
+	c.notify("exit", nil)
+	if err := <-c.done; err != ErrExitWithoutShutdown {
+		t.Errorf("expected ErrExitWithoutShutdown, got %v", err)
+	}
+}
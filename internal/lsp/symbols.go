@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"strings"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+)
+
+// documentSymbols returns the outline of a document: its components, slots, scripts and styles.
+// Symbols are nested according to the element tree, skipping the elements in between.
+func documentSymbols(d *document) []DocumentSymbol {
+	h := handler.NewHandler(d.text, d.uri)
+	doc, err := astro.ParseWithOptions(strings.NewReader(d.text), astro.ParseOptionWithHandler(h), astro.ParseOptionEnableLiteral(true), astro.ParseOptionEnableRecovery(true))
+	if err != nil {
+		return []DocumentSymbol{}
+	}
+	return childSymbols(d, doc)
+}
+
+func childSymbols(d *document, n *astro.Node) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		symbol, ok := nodeSymbol(d, c)
+		if !ok {
+			symbols = append(symbols, childSymbols(d, c)...)
+			continue
+		}
+		symbol.Children = childSymbols(d, c)
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+func nodeSymbol(d *document, n *astro.Node) (DocumentSymbol, bool) {
+	if n.Type != astro.ElementNode || len(n.Loc) == 0 || n.Fragment {
+		return DocumentSymbol{}, false
+	}
+
+	symbol := DocumentSymbol{Name: n.Data}
+	switch {
+	case n.Component:
+		symbol.Kind = SymbolKindClass
+		symbol.Detail = directives(n)
+	case n.CustomElement:
+		symbol.Kind = SymbolKindClass
+		symbol.Detail = strings.TrimSpace("custom element " + directives(n))
+	case n.Data == "slot":
+		symbol.Kind = SymbolKindField
+		if name := astro.GetAttribute(n, "name"); name != nil {
+			switch name.Type {
+			case astro.QuotedAttribute:
+				symbol.Name += ` "` + name.Val + `"`
+			case astro.ExpressionAttribute:
+				symbol.Name += " {" + name.Val + "}"
+			}
+		}
+		if n.FirstChild != nil {
+			symbol.Detail = "with fallback"
+		}
+	case n.Data == "script":
+		symbol.Kind = SymbolKindModule
+		symbol.Detail = tagDetail(n, "src", "is:inline", "type")
+	case n.Data == "style":
+		symbol.Kind = SymbolKindNamespace
+		symbol.Detail = tagDetail(n, "lang", "is:global", "is:inline", "define:vars")
+	default:
+		return DocumentSymbol{}, false
+	}
+
+	// Loc points after the `<` of the start tag
+	start := n.Loc[0].Start - 1
+	symbol.Range = d.rangeAt(start, elementEnd(d.text, n))
+	symbol.SelectionRange = d.rangeAt(n.Loc[0].Start, n.Loc[0].Start+len(n.Data))
+	return symbol, true
+}
+
+// directives lists the client, server and slot directives of a component
+func directives(n *astro.Node) string {
+	names := []string{}
+	for _, attr := range n.Attr {
+		if strings.HasPrefix(attr.Key, "client:") || strings.HasPrefix(attr.Key, "server:") {
+			names = append(names, attr.Key)
+		}
+		if attr.Key == "slot" && attr.Type == astro.QuotedAttribute {
+			names = append(names, `slot="`+attr.Val+`"`)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func tagDetail(n *astro.Node, keys ...string) string {
+	details := []string{}
+	for _, key := range keys {
+		attr := astro.GetAttribute(n, key)
+		if attr == nil {
+			continue
+		}
+		if attr.Type == astro.QuotedAttribute && attr.Val != "" {
+			details = append(details, key+`="`+attr.Val+`"`)
+		} else {
+			details = append(details, key)
+		}
+	}
+	return strings.Join(details, " ")
+}
+
+// elementEnd returns the offset after the end tag of n, or after its start tag when it has no end tag
+func elementEnd(source string, n *astro.Node) int {
+	if len(n.Loc) > 1 && n.Loc[1].Start > n.Loc[0].Start && n.Loc[1].Start < len(source) {
+		if i := strings.IndexByte(source[n.Loc[1].Start:], '>'); i != -1 {
+			return n.Loc[1].Start + i + 1
+		}
+	}
+	return startTagEnd(source, n.Loc[0].Start)
+}
+
+// startTagEnd returns the offset after the `>` closing the start tag beginning at start,
+// skipping quoted attribute values and expressions
+func startTagEnd(source string, start int) int {
+	var quote byte
+	depth := 0
+	for i := start; i < len(source); i++ {
+		c := source[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth = max(0, depth-1)
+		case c == '>' && depth == 0:
+			return i + 1
+		}
+	}
+	return len(source)
+}
@@ -5,6 +5,7 @@
 package astro
 
 import (
+	"github.com/withastro/compiler/internal/js_parser"
 	"github.com/withastro/compiler/internal/loc"
 	"golang.org/x/net/html/atom"
 )
@@ -70,6 +71,38 @@ type HydratedComponentMetadata struct {
 	ResolvedPath string
 }
 
+// ComponentUsageMetadata is a component rendered in the template, or an unused import of a
+// component, with the import it matches
+type ComponentUsageMetadata struct {
+	ExportName   string
+	LocalName    string
+	Specifier    string
+	ResolvedPath string
+	// Attributes are the names of the attributes passed to the component, like "...props" for a
+	// spread attribute
+	Attributes []string
+	// Slots are the names of the slots filled by the children of the component, "default" for the
+	// children without a slot attribute
+	Slots []string
+	// SlotContents are the children of the component filling a slot, in order
+	SlotContents []*SlotMetadata
+	// Range is the range of the name of the component in its start tag, or of the import statement
+	Range loc.Range
+}
+
+// SlotMetadata is a slot defined by a <slot> element, or a child of a component filling a slot
+type SlotMetadata struct {
+	// Name is the name of the slot, "default" when it isn't named, or the code of the expression
+	// naming a dynamic slot
+	Name string
+	// Dynamic reports whether the slot is named by an expression, like `name={tab}`
+	Dynamic bool
+	// HasFallback reports whether a <slot> element has fallback content
+	HasFallback bool
+	// Range is the range of the name of the element, or of the start of the text filling the slot
+	Range loc.Range
+}
+
 // A Node consists of a NodeType and some Data (tag name for element nodes,
 // content for text) and are part of a tree of Nodes. Element nodes may also
 // have a Namespace and contain a slice of Attributes. Data is unescaped, so
@@ -100,6 +133,9 @@ type Node struct {
 	ClientOnlyComponents     []*HydratedComponentMetadata
 	HydrationDirectives      map[string]bool
 	ServerComponents         []*HydratedComponentMetadata
+	ComponentUsages          []*ComponentUsageMetadata
+	UnusedComponentImports   []*ComponentUsageMetadata
+	Slots                    []*SlotMetadata
 	ContainsHead             bool
 	HeadPropagation          bool
 
@@ -109,6 +145,25 @@ type Node struct {
 	Namespace string
 	Attr      []Attribute
 	Loc       []loc.Loc
+
+	// Span is the range of the node in the source. For an element, it runs from the "<" of its
+	// start tag to the ">" of its end tag, or to the end of its content when the end tag is implied.
+	// It is zero for a node that is not in the source, like an implied element.
+	Span loc.Span
+	// Open and Close are the ranges of the start and end tags of an element, the braces of an
+	// expression or the fences of a frontmatter. Close is zero when it is implied.
+	Open  loc.Span
+	Close loc.Span
+
+	// Program is the ESTree AST of the content of a FrontmatterNode, only parsed with
+	// ParseOptionEnableFrontmatterAST
+	Program *js_parser.Node
+
+	// reparse is the state of the parser around the content of an element,
+	// only recorded with ParseOptionEnableIncremental
+	reparse *reparseState
+	// source is the node as it was parsed, only recorded with ParseOptionEnableTrivia
+	source *sourceState
 }
 
 // InsertBefore inserts newChild as a child of n, immediately before oldChild
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/js_parser"
 	"github.com/withastro/compiler/internal/loc"
 	a "golang.org/x/net/html/atom"
 )
@@ -57,6 +58,24 @@ type parser struct {
 	fragment bool
 	// literal is whether the parser should handle exceptions literally.
 	literal bool
+	// incremental is whether the parser records its state around elements for Reparse.
+	incremental bool
+	// recover is whether the parser recovers from unbalanced braces and tags, see ParseOptionEnableRecovery.
+	recover bool
+	// frontmatterAST is whether the frontmatter is parsed as TypeScript, see ParseOptionEnableFrontmatterAST.
+	frontmatterAST bool
+	// expressionSyntax is whether the expressions are parsed as JavaScript, see ParseOptionEnableExpressionValidation.
+	expressionSyntax bool
+	// trivia is whether the source is kept for PrintToSource, see ParseOptionEnableTrivia.
+	trivia bool
+	// contentModel is whether the parser warns about the markup it restructures, see
+	// ParseOptionEnableContentModelWarnings.
+	contentModel bool
+	// impliedEndTags are the start tags which closed open elements, with contentModel
+	impliedEndTags []impliedEndTag
+	// foreignContent is whether the parser warns about the mistakes in SVG and MathML, see
+	// ParseOptionEnableForeignContentWarnings.
+	foreignContent bool
 	// context is the context element when parsing an HTML fragment
 	// (section 12.4).
 	context *Node
@@ -256,6 +275,7 @@ loop:
 // of open elements if it is an element node.
 func (p *parser) addChild(n *Node) {
 	if p.shouldFosterParent() {
+		p.warnFosterParented(n)
 		p.fosterParent(n)
 	} else {
 		p.top().AppendChild(n)
@@ -320,6 +340,7 @@ func (p *parser) fosterParent(n *Node) {
 	}
 	if prev != nil && prev.Type == TextNode && n.Type == TextNode {
 		prev.Data += n.Data
+		prev.Span = joinSpans(prev.Span, n.Span)
 		return
 	}
 
@@ -333,27 +354,82 @@ func (p *parser) addText(text string) {
 		return
 	}
 
+	span := p.tok.Span
+	if len(text) < len(p.tok.Data) && strings.HasPrefix(p.tok.Data, text) {
+		// The leading whitespace of a text token, added before the rest of it
+		span.End = span.Start + len(text)
+	}
+
 	if p.shouldFosterParent() {
-		p.fosterParent(&Node{
+		n := &Node{
 			Type: TextNode,
 			Data: text,
 			Loc:  p.generateLoc(),
-		})
+			Span: span,
+		}
+		p.warnFosterParented(n)
+		p.fosterParent(n)
 		return
 	}
 
 	t := p.top()
 	if n := t.LastChild; n != nil && n.Type == TextNode {
 		n.Data += text
+		n.Span = joinSpans(n.Span, span)
 		return
 	}
 	p.addChild(&Node{
 		Type: TextNode,
 		Data: text,
 		Loc:  p.generateLoc(),
+		Span: span,
 	})
 }
 
+// setClose records the current end tag or "}" as the Close of the innermost node it closed.
+// open is the stack of open elements before the token.
+func (p *parser) setClose(open nodeStack) {
+	for i := len(open) - 1; i >= 0; i-- {
+		n := open[i]
+		if p.oe.index(n) != -1 || n.Close != (loc.Span{}) {
+			continue
+		}
+		if p.tok.Type == EndExpressionToken && n.Expression || p.tok.Type == EndTagToken && !n.Expression && strings.EqualFold(n.Data, p.tok.Data) {
+			n.Close = p.tok.Span
+			return
+		}
+	}
+}
+
+// finishSpans sets the Span of n and its descendants which have an Open range, ending it with
+// the Close range or, when the end is implied, with the content. It returns the end of the
+// source of n and its descendants.
+func finishSpans(n *Node) int {
+	end := max(n.Open.End, n.Span.End)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		end = max(end, finishSpans(c))
+	}
+	switch {
+	case n.Open == loc.Span{}:
+	case n.Close != loc.Span{}:
+		n.Span = loc.Span{Start: n.Open.Start, End: n.Close.End}
+	default:
+		n.Span = loc.Span{Start: n.Open.Start, End: end}
+	}
+	return max(end, n.Span.End)
+}
+
+// joinSpans returns the range covering a and b, ignoring a zero range
+func joinSpans(a loc.Span, b loc.Span) loc.Span {
+	switch {
+	case a == loc.Span{}:
+		return b
+	case b == loc.Span{}:
+		return a
+	}
+	return loc.Span{Start: min(a.Start, b.Start), End: max(a.End, b.End)}
+}
+
 func (p *parser) addFrontmatter(empty bool) {
 	if p.frontmatterState == FrontmatterInitial {
 		if p.doc.FirstChild != nil {
@@ -374,6 +450,7 @@ func (p *parser) addFrontmatter(empty bool) {
 			p.fm.Attr = append(p.fm.Attr, Attribute{Key: ImplicitNodeMarker, Type: EmptyAttribute})
 		} else {
 			p.frontmatterState = FrontmatterOpen
+			p.fm.Open = p.tok.Span
 			p.oe = append(p.oe, p.fm)
 		}
 	}
@@ -391,6 +468,7 @@ func (p *parser) addExpression() {
 		CustomElement: false,
 		HandledScript: false,
 		Loc:           p.generateLoc(),
+		Open:          p.tok.Span,
 	})
 
 }
@@ -437,6 +515,7 @@ func (p *parser) addElement() {
 		CustomElement: isCustomElement(p.tok.Data),
 		HandledScript: false,
 		Loc:           p.generateLoc(),
+		Open:          p.tok.Span,
 	})
 }
 
@@ -628,7 +707,9 @@ func initialIM(p *parser) bool {
 		p.im = frontmatterIM
 		return false
 	case TextToken:
-		p.tok.Data = strings.TrimLeft(p.tok.Data, whitespace)
+		s := strings.TrimLeft(p.tok.Data, whitespace)
+		p.tok.Span.Start += len(p.tok.Data) - len(s)
+		p.tok.Data = s
 		if len(p.tok.Data) == 0 {
 			// It was all whitespace, so ignore it.
 			return true
@@ -638,10 +719,12 @@ func initialIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case DoctypeToken:
 		n, quirks := parseDoctype(p.tok.Data)
+		n.Span = p.tok.Span
 		p.doc.AppendChild(n)
 		p.quirks = quirks
 		p.im = beforeHTMLIM
@@ -662,7 +745,9 @@ func beforeHTMLIM(p *parser) bool {
 		// Ignore the token.
 		return true
 	case TextToken:
-		p.tok.Data = strings.TrimLeft(p.tok.Data, whitespace)
+		s := strings.TrimLeft(p.tok.Data, whitespace)
+		p.tok.Span.Start += len(p.tok.Data) - len(s)
+		p.tok.Data = s
 		if len(p.tok.Data) == 0 {
 			// It was all whitespace, so ignore it.
 			return true
@@ -717,6 +802,7 @@ func beforeHTMLIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	}
@@ -762,6 +848,7 @@ func beforeHeadIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case DoctypeToken:
@@ -790,6 +877,7 @@ func inHeadIM(p *parser) bool {
 			if s == "" {
 				return true
 			}
+			p.tok.Span.Start += len(p.tok.Data) - len(s)
 			p.tok.Data = s
 			return textIM(p)
 		} else if p.oe.top() != nil && (isComponent(p.oe.top().Data) || isFragment((p.oe.top().Data))) {
@@ -937,6 +1025,7 @@ func inHeadIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case DoctypeToken:
@@ -1021,6 +1110,7 @@ func afterHeadIM(p *parser) bool {
 			if s == "" {
 				return true
 			}
+			p.tok.Span.Start += len(p.tok.Data) - len(s)
 			p.tok.Data = s
 		}
 	case StartTagToken:
@@ -1060,6 +1150,7 @@ func afterHeadIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case DoctypeToken:
@@ -1207,6 +1298,7 @@ func inBodyIM(p *parser) bool {
 		case a.Form:
 			if p.form != nil && !p.oe.contains(a.Template) {
 				// Ignore the token
+				p.warnNestedForm()
 				return true
 			}
 			p.popUntil(buttonScope, a.P)
@@ -1397,6 +1489,7 @@ func inBodyIM(p *parser) bool {
 			}
 		case a.Math, a.Svg:
 			p.reconstructActiveFormattingElements()
+			p.warnForeignStartTag(p.tok.Data)
 			if p.tok.DataAtom == a.Math {
 				adjustAttributeNames(p.tok.Attr, mathMLAttributeAdjustments)
 			} else {
@@ -1518,6 +1611,7 @@ func inBodyIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 	case StartExpressionToken:
 		p.addExpression()
@@ -1599,6 +1693,10 @@ func (p *parser) inBodyEndTagFormatting(tagAtom a.Atom, tagName string) {
 		}
 
 		// Step 9. This step is omitted because it's just a parse error but no need to return.
+		// The elements opened in the formatting element are restructured, warn once they are.
+		if i == 0 && p.contentModel && p.tok.Type == EndTagToken && p.tok.Span != (loc.Span{}) && feIndex > 0 && p.oe.top() != formattingElement {
+			defer p.warnMisnestedFormatting(append([]*Node(nil), p.oe[feIndex:]...), p.oe[feIndex-1])
+		}
 
 		// Steps 10-11. Find the furthest block.
 		var furthestBlock *Node
@@ -1853,6 +1951,9 @@ func inTableIM(p *parser) bool {
 		case a.Form:
 			if p.oe.contains(a.Template) || p.form != nil {
 				// Ignore the token.
+				if p.form != nil && !p.oe.contains(a.Template) {
+					p.warnNestedForm()
+				}
 				return true
 			}
 			p.addElement()
@@ -1896,6 +1997,7 @@ func inTableIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case DoctypeToken:
@@ -1981,6 +2083,7 @@ func inColumnGroupIM(p *parser) bool {
 			if s == "" {
 				return true
 			}
+			p.tok.Span.Start += len(p.tok.Data) - len(s)
 			p.tok.Data = s
 		}
 	case CommentToken:
@@ -1988,6 +2091,7 @@ func inColumnGroupIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case DoctypeToken:
@@ -2099,6 +2203,7 @@ func inTableBodyIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case StartExpressionToken:
@@ -2324,6 +2429,7 @@ func inSelectIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 	case StartExpressionToken:
 		p.addExpression()
@@ -2482,6 +2588,7 @@ func afterBodyIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	}
@@ -2497,6 +2604,7 @@ func inFramesetIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 	case TextToken:
 		// Ignore all text but whitespace.
@@ -2548,6 +2656,7 @@ func afterFramesetIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 	case TextToken:
 		// Ignore all text but whitespace.
@@ -2601,6 +2710,7 @@ func afterAfterBodyIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	case DoctypeToken:
@@ -2618,6 +2728,7 @@ func afterAfterFramesetIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 	case TextToken:
 		// Ignore all text but whitespace.
@@ -2657,6 +2768,10 @@ func frontmatterIM(p *parser) bool {
 		} else {
 			p.frontmatterState = FrontmatterClosed
 			p.fm.Loc = append(p.fm.Loc, p.tok.Loc)
+			if p.tok.Loc.Start > p.tok.Span.Start {
+				// The token of the closing fence includes the newline following it
+				p.fm.Close = loc.Span{Start: p.tok.Span.Start, End: p.tok.Loc.Start}
+			}
 			for range p.oe {
 				// This removes any elements in the Frontmatter from the stack
 				// Note that we can't pop the root <html> element — we need it for ParseFragment
@@ -2727,6 +2842,7 @@ func inLiteralIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 	case EndTagToken:
 		p.addLoc()
@@ -2796,6 +2912,7 @@ func inExpressionIM(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 		return true
 	}
@@ -2808,6 +2925,11 @@ func ignoreTheRemainingTokens(p *parser) bool {
 	return true
 }
 
+// neverExitLiteralIM keeps the parser in `inLiteralIM`, until an expression sets its own exit function
+func neverExitLiteralIM() bool {
+	return false
+}
+
 // Generate a function that will exit `inLiteralIM` when all expressions are closed
 func getExitLiteralFunc(p *parser) func() bool {
 	oe := len(p.oe)
@@ -2832,6 +2954,7 @@ func parseForeignContent(p *parser) bool {
 			Type: CommentNode,
 			Data: p.tok.Data,
 			Loc:  p.generateLoc(),
+			Span: p.tok.Span,
 		})
 	case StartTagToken:
 		if !p.fragment {
@@ -2850,6 +2973,7 @@ func parseForeignContent(p *parser) bool {
 				for i := len(p.oe) - 1; i >= 0; i-- {
 					n := p.oe[i]
 					if n.Namespace == "" || htmlIntegrationPoint(n) || mathMLTextIntegrationPoint(n) {
+						p.warnForeignBreakout(i)
 						p.oe = p.oe[:i+1]
 						break
 					}
@@ -2858,6 +2982,7 @@ func parseForeignContent(p *parser) bool {
 			}
 		}
 		current := p.adjustedCurrentNode()
+		p.warnForeignStartTag(current.Namespace)
 		switch current.Namespace {
 		case "math":
 			adjustAttributeNames(p.tok.Attr, mathMLAttributeAdjustments)
@@ -2970,6 +3095,12 @@ func (p *parser) parseCurrentToken() {
 		p.tok.Type = StartTagToken
 	}
 
+	var open nodeStack
+	startTag := p.tok.Type == StartTagToken
+	if p.tok.Span != (loc.Span{}) && (p.tok.Type == EndTagToken || p.tok.Type == EndExpressionToken || p.contentModel && startTag) {
+		open = append(open, p.oe...)
+	}
+
 	consumed := false
 	for !consumed {
 		if p.inForeignContent() {
@@ -2982,6 +3113,17 @@ func (p *parser) parseCurrentToken() {
 		}
 	}
 
+	if open != nil {
+		if startTag {
+			p.recordImpliedEndTags(open)
+		} else {
+			p.setClose(open)
+		}
+		if p.contentModel && p.tok.Type == EndTagToken {
+			p.warnImpliedEndTag(open)
+		}
+	}
+
 	if p.hasSelfClosingToken {
 		// This is a parse error, but ignore it.
 		p.hasSelfClosingToken = false
@@ -3005,7 +3147,15 @@ func (p *parser) parse() error {
 				return err
 			}
 		}
-		p.parseCurrentToken()
+		top, depth := p.oe.top(), len(p.oe)
+		if p.recover {
+			p.parseCurrentTokenWithRecovery()
+		} else {
+			p.parseCurrentToken()
+		}
+		if p.incremental {
+			p.recordReparseState(top, depth)
+		}
 	}
 	return nil
 }
@@ -3051,6 +3201,77 @@ func ParseOptionEnableLiteral(enable bool) ParseOption {
 	}
 }
 
+// ParseOptionEnableIncremental records the state of the parser around each element,
+// so that the returned tree can be updated with Reparse after an edit.
+func ParseOptionEnableIncremental(enable bool) ParseOption {
+	return func(p *parser) {
+		p.incremental = enable
+	}
+}
+
+// ParseOptionEnableRecovery makes the parser recover from the errors of a component being edited:
+// an unterminated frontmatter or expression, a stray "}" and unbalanced tags are closed at the
+// nearest sensible boundary. Each recovery inserts an ErrorNode in the tree and is reported to
+// the handler, so that the rest of the component is parsed as usual.
+func ParseOptionEnableRecovery(enable bool) ParseOption {
+	return func(p *parser) {
+		p.recover = enable
+		p.tokenizer.recover = enable
+	}
+}
+
+// ParseOptionEnableFrontmatterAST parses the content of the frontmatter as TypeScript, into the
+// Program of the FrontmatterNode. A syntax error is reported to the handler, and leaves Program nil.
+func ParseOptionEnableFrontmatterAST(enable bool) ParseOption {
+	return func(p *parser) {
+		p.frontmatterAST = enable
+	}
+}
+
+// ParseOptionEnableTrivia keeps the source of the document, for PrintToSource to reproduce it
+// byte for byte: the whitespace and quotes in tags, the end tags, the fences of the frontmatter,
+// and the source that is in no node, like the tokens ignored by the parser. Only the nodes that
+// are changed after parsing are printed again.
+func ParseOptionEnableTrivia(enable bool) ParseOption {
+	return func(p *parser) {
+		p.trivia = enable
+	}
+}
+
+// ParseOptionEnableContentModelWarnings warns about the markup restructured by the tree
+// construction rules, with its structure as written and as parsed: the content moved out of a
+// table, the elements closed by a start tag before their own end tag, like the <p> of
+// "<p><div></div></p>", nested <a>, <button> and headings, nested forms, and misnested
+// formatting elements. The rules are not applied with ParseOptionEnableLiteral.
+//
+// An element closed by a start tag without an end tag of its own is not warned about, like the
+// <p> of "<p>a<div>b</div>", as its end tag can be omitted. Neither is the content which the
+// parser leaves as written, like the <div> of "<ul><div></div></ul>".
+func ParseOptionEnableContentModelWarnings(enable bool) ParseOption {
+	return func(p *parser) {
+		p.contentModel = enable
+	}
+}
+
+// ParseOptionEnableForeignContentWarnings warns about the mistakes in SVG and MathML, which the
+// parser leaves as written or silently adjusts: elements and attributes written with the wrong
+// casing, like "viewbox", unknown attributes, like "stroke_width", and HTML elements, which either
+// render nothing or close the <svg> or <math> they are written in. The content of the HTML
+// integration points, like <foreignObject>, is HTML.
+func ParseOptionEnableForeignContentWarnings(enable bool) ParseOption {
+	return func(p *parser) {
+		p.foreignContent = enable
+	}
+}
+
+// ParseOptionEnableExpressionValidation parses the expressions of the template and of the attribute
+// values as JavaScript with JSX, reporting their syntax errors to the handler
+func ParseOptionEnableExpressionValidation(enable bool) ParseOption {
+	return func(p *parser) {
+		p.expressionSyntax = enable
+	}
+}
+
 // ParseWithOptions is like Parse, with options.
 func ParseWithOptions(r io.Reader, opts ...ParseOption) (*Node, error) {
 	p := &parser{
@@ -3062,22 +3283,76 @@ func ParseWithOptions(r io.Reader, opts ...ParseOption) (*Node, error) {
 		framesetOK:       true,
 		im:               initialIM,
 		frontmatterState: FrontmatterInitial,
-		exitLiteralIM:    func() bool { return false },
+		exitLiteralIM:    neverExitLiteralIM,
 	}
 
 	for _, f := range opts {
 		f(p)
 	}
 
-	if err := p.parse(); err != nil {
+	if err := p.parseSource(); err != nil {
 		return nil, err
 	}
 	return p.doc, nil
 }
 
+// parseSource parses the input of p into p.doc. The spans are finished, and the options which need
+// the source as it was before parsing are applied: the trivia, the frontmatter AST and the
+// validation of the expressions.
+func (p *parser) parseSource() error {
+	// The tokenizer unescapes attributes in place
+	var source string
+	if p.trivia || p.expressionSyntax {
+		source = string(p.tokenizer.buf)
+	}
+	if err := p.parse(); err != nil {
+		return err
+	}
+	finishSpans(p.doc)
+	if p.trivia {
+		recordSource(p.doc, source)
+	}
+	if p.frontmatterAST && p.fm != nil {
+		p.parseFrontmatterProgram()
+	}
+	if p.expressionSyntax {
+		p.validateExpressions(p.doc, source)
+	}
+	return nil
+}
+
+// parseFrontmatterProgram parses the source between the fences of the frontmatter into its Program
+func (p *parser) parseFrontmatterProgram() {
+	source := string(p.tokenizer.buf)
+	if isImplicitNode(p.fm) || len(p.fm.Loc) == 0 {
+		return
+	}
+	start, end := p.fm.Loc[0].Start+len("---"), len(source)
+	if len(p.fm.Loc) > 1 {
+		// The closing fence is located after its dashes, unless the tokenizer closed an unterminated frontmatter
+		end = p.fm.Loc[1].Start
+		if strings.HasSuffix(source[:end], "---") && end-len("---") >= start {
+			end -= len("---")
+		}
+	}
+	program, err := js_parser.Parse(source, start, end)
+	if err != nil {
+		if p.handler != nil {
+			e := err.(*js_parser.SyntaxError)
+			p.handler.AppendError(&loc.ErrorWithRange{
+				Code:  loc.ERROR_FRONTMATTER_SYNTAX,
+				Text:  fmt.Sprintf("Invalid frontmatter: %s", e.Message),
+				Range: loc.Range{Loc: loc.Loc{Start: e.Pos}, Len: e.End - e.Pos},
+			})
+		}
+		return
+	}
+	p.fm.Program = program
+}
+
 // ParseFragmentWithOptions parses a fragment of HTML and returns the nodes that were
 // found. If the fragment is the InnerHTML for an existing element, pass that
-// element in context.
+// element in context. No <html>, <head> or <body> element is implied.
 func ParseFragmentWithOptions(r io.Reader, context *Node, opts ...ParseOption) ([]*Node, error) {
 	contextTag := ""
 	if context != nil {
@@ -3100,7 +3375,7 @@ func ParseFragmentWithOptions(r io.Reader, context *Node, opts ...ParseOption) (
 		fragment:         true,
 		context:          context,
 		frontmatterState: FrontmatterInitial,
-		exitLiteralIM:    func() bool { return false },
+		exitLiteralIM:    neverExitLiteralIM,
 	}
 	if context != nil && context.Namespace != "" {
 		p.tokenizer = NewTokenizer(r)
@@ -3136,7 +3411,7 @@ func ParseFragmentWithOptions(r io.Reader, context *Node, opts ...ParseOption) (
 		}
 	}
 
-	if err := p.parse(); err != nil {
+	if err := p.parseSource(); err != nil {
 		return nil, err
 	}
 
@@ -3154,9 +3429,25 @@ func ParseFragmentWithOptions(r io.Reader, context *Node, opts ...ParseOption) (
 	for c := parent.FirstChild; c != nil; {
 		next := c.NextSibling
 		parent.RemoveChild(c)
-		result = append(result, c)
+		result = appendFragmentNode(result, c, root)
 		c = next
 	}
 
 	return result, nil
 }
+
+// appendFragmentNode appends n to the nodes of a fragment. The root and the <html>, <head> and
+// <body> elements implied by the parser are replaced by their children, as there is no document
+// around a fragment.
+func appendFragmentNode(nodes []*Node, n *Node, root *Node) []*Node {
+	if n != root && !(isImplicitNode(n) && (n.DataAtom == a.Html || n.DataAtom == a.Head || n.DataAtom == a.Body)) {
+		return append(nodes, n)
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		n.RemoveChild(c)
+		nodes = appendFragmentNode(nodes, c, root)
+		c = next
+	}
+	return nodes
+}
@@ -1,12 +1,15 @@
 package astro
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/withastro/compiler/internal/handler"
 	"github.com/withastro/compiler/internal/loc"
 	"github.com/withastro/compiler/internal/test_utils"
+	a "golang.org/x/net/html/atom"
 )
 
 type ParserLocTest struct {
@@ -15,36 +18,35 @@ type ParserLocTest struct {
 	expected []int
 }
 
-func TestParserLocation(t *testing.T) {
-	Cases := []ParserLocTest{
-		{
-			"end tag I",
-			`<div id="target"></div>`,
-			[]int{1, 19},
-		},
-		{
-			"end tag II",
-			`<div class="TabBox">
+var parserLocTests = []ParserLocTest{
+	{
+		"end tag I",
+		`<div id="target"></div>`,
+		[]int{1, 19},
+	},
+	{
+		"end tag II",
+		`<div class="TabBox">
 	<div id="target" class="tab-bar">
 		<div id="install-npm" class="active toggle"><h5>npm</h5></div>
 		<div id="install-yarn" class="toggle"><h5>yarn</h5></div>
 	</div>
 </div>`,
-			[]int{23, 184},
-		},
-		{
-			"end tag III",
-			`<span id="target" class:list={["link pixel variant", className]} {style}>
+		[]int{23, 184},
+	},
+	{
+		"end tag III",
+		`<span id="target" class:list={["link pixel variant", className]} {style}>
 	<a {href}>
 		<span><slot /></span>
 	</a>
 </span>
 `,
-			[]int{1, 118},
-		},
-		{
-			"end tag VI",
-			`<HeadingWrapper id="target">
+		[]int{1, 118},
+	},
+	{
+		"end tag VI",
+		`<HeadingWrapper id="target">
 		<h2 class="heading"><UIString key="rightSidebar.community" /></h2>
 		{
 			hideOnLargerScreens && (
@@ -64,11 +66,12 @@ func TestParserLocation(t *testing.T) {
 			)
 		}
 	</HeadingWrapper>`,
-			[]int{1, 492},
-		},
-	}
+		[]int{1, 492},
+	},
+}
 
-	runParserLocTest(t, Cases)
+func TestParserLocation(t *testing.T) {
+	runParserLocTest(t, parserLocTests)
 }
 
 func runParserLocTest(t *testing.T, suite []ParserLocTest) {
@@ -126,3 +129,460 @@ func findTargetNode(doc *Node) *Node {
 	})
 	return target
 }
+
+func TestParserSpan(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// want is the source of each node with a Span, depth first, with its Open and Close
+		want []string
+	}{
+		{
+			"element",
+			`<div class="a">text</div>`,
+			[]string{`<div class="a">text</div>`, `<div class="a">`, `</div>`, `text`},
+		},
+		{
+			"self-closing",
+			`<Component a={1} />`,
+			[]string{`<Component a={1} />`, `<Component a={1} />`},
+		},
+		{
+			"void element",
+			`<p><br>a</p>`,
+			[]string{`<p><br>a</p>`, `<p>`, `</p>`, `<br>`, `<br>`, `a`},
+		},
+		{
+			"implied end tag",
+			"<ul><li>a\n<li>b</ul>",
+			[]string{"<ul><li>a\n<li>b</ul>", `<ul>`, `</ul>`, "<li>a\n", `<li>`, "a\n", `<li>b`, `<li>`, `b`},
+		},
+		{
+			"end tag closing an inner element",
+			`<div><p>a</div>`,
+			[]string{`<div><p>a</div>`, `<div>`, `</div>`, `<p>a`, `<p>`, `a`},
+		},
+		{
+			"expression",
+			`<h1>Hello {name}!</h1>`,
+			[]string{`<h1>Hello {name}!</h1>`, `<h1>`, `</h1>`, `Hello `, `{name}`, `{`, `}`, `name`, `!`},
+		},
+		{
+			"comment and doctype",
+			"<!DOCTYPE html>\n<!-- a -->",
+			[]string{`<!DOCTYPE html>`, `<!-- a -->`},
+		},
+		{
+			"frontmatter",
+			"---\nconst a = 1;\n---\n<div />",
+			[]string{"---\nconst a = 1;\n---", `---`, `---`, "\nconst a = 1;\n", `<div />`, `<div />`},
+		},
+		{
+			"text with entities",
+			`<p>a &amp; b</p>`,
+			[]string{`<p>a &amp; b</p>`, `<p>`, `</p>`, `a &amp; b`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0)
+			walk(doc, func(n *Node) {
+				if n.Span == (loc.Span{}) {
+					return
+				}
+				got = append(got, tt.input[n.Span.Start:n.Span.End])
+				for _, s := range []loc.Span{n.Open, n.Close} {
+					if s != (loc.Span{}) {
+						got = append(got, tt.input[s.Start:s.End])
+					}
+				}
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spans = %q\nExpected = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParserAttributeSpan(t *testing.T) {
+	input := "<div a=\"1\" b='2' c={3} {d} {...e} f g=h i=`j` />"
+	want := [][3]string{
+		{`a="1"`, `a`, `1`},
+		{`b='2'`, `b`, `2`},
+		{`c={3}`, `c`, `3`},
+		{`{d}`, `d`, ``},
+		{`{...e}`, `e`, ``},
+		{`f`, `f`, ``},
+		{`g=h`, `g`, `h`},
+		{"i=`j`", `i`, `j`},
+	}
+	doc, err := ParseWithOptions(strings.NewReader(input), ParseOptionEnableLiteral(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var div *Node
+	walk(doc, func(n *Node) {
+		if n.Data == "div" {
+			div = n
+		}
+	})
+	if div == nil || len(div.Attr) != len(want) {
+		t.Fatalf("expected a div with %d attributes", len(want))
+	}
+	for i, attr := range div.Attr {
+		got := [3]string{input[attr.Span.Start:attr.Span.End], input[attr.KeySpan.Start:attr.KeySpan.End], input[attr.ValSpan.Start:attr.ValSpan.End]}
+		if got != want[i] {
+			t.Errorf("attribute %s: spans = %q, expected %q", attr.Key, got, want[i])
+		}
+	}
+}
+
+func TestParseFragment(t *testing.T) {
+	tests := []struct {
+		name    string
+		context *Node
+		input   string
+		// want is the data of each node, depth first
+		want []string
+	}{
+		{
+			"no context",
+			nil,
+			"---\nconst a = 1;\n---\n<p>a</p>",
+			[]string{"", "\nconst a = 1;\n", "p", "a"},
+		},
+		{
+			"body",
+			&Node{Type: ElementNode, DataAtom: a.Body, Data: "body"},
+			"<title>a</title><p>b</p>",
+			[]string{"title", "a", "p", "b"},
+		},
+		{
+			"tbody",
+			&Node{Type: ElementNode, DataAtom: a.Tbody, Data: "tbody"},
+			"<tr><td>a</td></tr>",
+			[]string{"tr", "td", "a"},
+		},
+		{
+			"html",
+			&Node{Type: ElementNode, DataAtom: a.Html, Data: "html"},
+			"<head></head><p>a</p>",
+			[]string{"head", "p", "a"},
+		},
+		{
+			"svg",
+			&Node{Type: ElementNode, DataAtom: a.Svg, Data: "svg", Namespace: "svg"},
+			"<circle r='1' /><foreignObject><div /></foreignObject>",
+			[]string{"svg:circle", "svg:foreignObject", "div"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler.NewHandler(tt.input, "test.astro")
+			nodes, err := ParseFragmentWithOptions(strings.NewReader(tt.input), tt.context, ParseOptionWithHandler(h), ParseOptionEnableLiteral(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0)
+			for _, n := range nodes {
+				if n.Parent != nil {
+					t.Errorf("%s has a parent", n.Data)
+				}
+				walk(n, func(n *Node) {
+					if n.Namespace != "" {
+						got = append(got, n.Namespace+":"+n.Data)
+					} else {
+						got = append(got, n.Data)
+					}
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nodes = %q\nExpected = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParserContentModelWarnings(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// want is the code, the source and the text of each warning
+		want []string
+	}{
+		{
+			"foster parented element",
+			`<table><div>a</div><tr><td>b</td></tr></table>`,
+			[]string{"2014 `<div` `<div>` is moved before the `<table>` it is written in: written as `<table><div>`, parsed as `<div><table>`"},
+		},
+		{
+			"foster parented text",
+			"<table>\n\ta<tr><td>b</td></tr></table>",
+			[]string{"2014 `\n\ta` Text is moved before the `<table>` it is written in: written as `<table>text`, parsed as `text<table>`"},
+		},
+		{
+			"whitespace in a table",
+			"<table>\n\t<tr><td>b</td></tr>\n</table>",
+			nil,
+		},
+		{
+			"implied end tag",
+			`<p><div>a</div></p>`,
+			[]string{"2015 `<div` `<div>` closes the `<p>` it is written in: written as `<p><div>…</p>`, parsed as `<p></p><div>…`"},
+		},
+		{
+			"nested links",
+			`<a href="/">a<a href="/b">b</a></a>`,
+			[]string{"2015 `<a` `<a>` closes the `<a>` it is written in: written as `<a><a>…`, parsed as `<a></a><a>…`"},
+		},
+		{
+			"nested links without end tags",
+			`<a href="/">a<a href="/b">b`,
+			[]string{"2015 `<a` `<a>` closes the `<a>` it is written in: written as `<a><a>…`, parsed as `<a></a><a>…`"},
+		},
+		{
+			"nested headings",
+			`<h1>a<h2>b</h2></h1><button>c<span><button>d`,
+			[]string{
+				"2015 `<h2` `<h2>` closes the `<h1>` it is written in: written as `<h1><h2>…`, parsed as `<h1></h1><h2>…`",
+				"2015 `<button` `<button>` closes the `<button>` it is written in: written as `<button><span><button>…`, parsed as `<button><span></span></button><button>…`",
+			},
+		},
+		{
+			"nested forms",
+			`<form><form>a</form></form>`,
+			[]string{"2030 `<form` `<form>` is ignored in another `<form>`: written as `<form><form>…</form>`, parsed as `<form>…</form>`"},
+		},
+		{
+			"nested form in a table",
+			`<form><table><tr><td><form>a</form></td></tr></table></form>`,
+			[]string{"2030 `<form` `<form>` is ignored in another `<form>`: written as `<form><form>…</form>`, parsed as `<form>…</form>`"},
+		},
+		{
+			"omitted end tags",
+			`<ul><li>a<li>b</ul><p>c<p>d</p>`,
+			nil,
+		},
+		{
+			// The end tag of the <p> is omitted, which is allowed
+			"block in a paragraph without an end tag",
+			`<p>a<div>b</div>`,
+			nil,
+		},
+		{
+			// The parser leaves the <div> in the <ul>, the content model of which isn't checked
+			"block in a list",
+			`<ul><div>a</div></ul>`,
+			nil,
+		},
+		{
+			"sibling forms",
+			`<form>a</form><form>b</form>`,
+			nil,
+		},
+		{
+			"misnested formatting elements",
+			`<b><i>a</b>b</i>`,
+			[]string{"2016 `</b>` `</b>` closes the `<i>` opened in it: written as `<b><i></b>`, parsed as `<b><i></i></b>`"},
+		},
+		{
+			"formatting element closing a block",
+			`<b><div>a</b>b</div>`,
+			[]string{"2016 `</b>` `</b>` closes the `<div>` opened in it: written as `<b><div></b>`, parsed as `<b></b><div><b></b></div>`"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler.NewHandler(tt.input, "test.astro")
+			_, err := ParseWithOptions(strings.NewReader(tt.input), ParseOptionWithHandler(h), ParseOptionEnableContentModelWarnings(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, w := range h.Warnings() {
+				start := strings.Index(tt.input, strings.Split(tt.input, "\n")[w.Location.Line-1]) + w.Location.Column - 1
+				got = append(got, fmt.Sprintf("%d `%s` %s", w.Code, tt.input[start:start+w.Location.Length], w.Text))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %q\nExpected = %q", got, tt.want)
+			}
+
+			h = handler.NewHandler(tt.input, "test.astro")
+			if _, err := ParseWithOptions(strings.NewReader(tt.input), ParseOptionWithHandler(h)); err != nil {
+				t.Fatal(err)
+			}
+			if len(h.Warnings()) != 0 {
+				t.Errorf("expected no warnings without ParseOptionEnableContentModelWarnings, got %v", h.Warnings())
+			}
+		})
+	}
+}
+
+func TestParserExpressionValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// want is the source and the text of each warning
+		want []string
+	}{
+		{
+			"valid expressions",
+			"<ul>{items.map((item) => <li class={item.kind}>{item.name}</li>)}</ul>\n{ok ? <a /> : <b><i /></b>}{/* comment */}{}",
+			nil,
+		},
+		{
+			"parenthesized conditional with an arrow function",
+			"{a ? (b) : c => c}",
+			nil,
+		},
+		{
+			"consecutive elements",
+			"{list.map((item) => (\n\t<dt>{item.term}</dt>\n\t<dd>{item.text}</dd>\n))}",
+			nil,
+		},
+		{
+			"valid attributes",
+			"<Card {...props} {title} items={[1, 2].map((n) => n * 2)} render={() => <b>bold</b>} href=`/${slug}` />",
+			nil,
+		},
+		{
+			"template expression",
+			"<p>{items.map((item) => item.name))}</p>",
+			[]string{"`)` Invalid expression: Unexpected token ')'"},
+		},
+		{
+			"markup in the wrong place",
+			"<p>{name <b>bold</b>}</p>",
+			[]string{"`<b>` Invalid expression: Unexpected markup '<b>'"},
+		},
+		{
+			"nested expression",
+			"{ok && <p>{a +}</p>}",
+			[]string{"`` Invalid expression: Unexpected end of input"},
+		},
+		{
+			"attribute expression",
+			"<div class={a b} />",
+			[]string{"`b` Invalid expression: Unexpected token 'b'"},
+		},
+		{
+			"spread attribute",
+			"<div {...(props} />",
+			[]string{"`` Invalid expression: Expected ')' but found end of input"},
+		},
+		{
+			"JSX in an attribute",
+			"<Card render={() => <b>bold</i>} />",
+			[]string{"`</i>` Invalid expression: Expected corresponding JSX closing tag for <b>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler.NewHandler(tt.input, "test.astro")
+			_, err := ParseWithOptions(strings.NewReader(tt.input), ParseOptionWithHandler(h), ParseOptionEnableExpressionValidation(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			if errors := h.Errors(); len(errors) != 0 {
+				t.Errorf("unexpected errors %v", errors)
+			}
+			for _, e := range h.Warnings() {
+				if e.Code != int(loc.WARNING_EXPRESSION_SYNTAX) {
+					t.Errorf("unexpected warning %v", e)
+					continue
+				}
+				start := strings.Index(tt.input, strings.Split(tt.input, "\n")[e.Location.Line-1]) + e.Location.Column - 1
+				got = append(got, fmt.Sprintf("`%s` %s", tt.input[start:start+e.Location.Length], e.Text))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %q\nExpected = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParserForeignContentWarnings(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// want is the code, the source and the text of each warning
+		want []string
+	}{
+		{
+			"valid",
+			`<svg viewBox="0 0 24 24" xlink:href="#a" class:list={c} onclick="f()" data-id="1" aria-hidden="true"><linearGradient id="g"><stop offset="0" stop-color="red" /></linearGradient><Icon strokeWidth={2} /><path d={d} stroke-width="2" /></svg>`,
+			nil,
+		},
+		{
+			"attribute casing",
+			`<svg viewbox="0 0 24 24"><path pathlength="1" /></svg><math><mi mathVariant="bold">x</mi></math>`,
+			[]string{
+				"2019 `viewbox` `viewbox` must be written `viewBox` on SVG elements",
+				"2019 `pathlength` `pathlength` must be written `pathLength` on SVG elements",
+				"2019 `mathVariant` `mathVariant` must be written `mathvariant` on MathML elements",
+			},
+		},
+		{
+			"element casing",
+			`<svg><clipPATH id="c" /></svg>`,
+			[]string{"2019 `<clipPATH` `<clipPATH>` must be written `<clipPath>` in SVG"},
+		},
+		{
+			"unknown attributes",
+			`<svg><path stroke_width="2" strokeWidth="2" frobnicate /></svg>`,
+			[]string{
+				"2020 `stroke_width` Unknown attribute `stroke_width` on the SVG element `<path>`",
+				"2020 `strokeWidth` Unknown attribute `strokeWidth` on the SVG element `<path>`",
+				"2020 `frobnicate` Unknown attribute `frobnicate` on the SVG element `<path>`",
+			},
+		},
+		{
+			"HTML element",
+			`<svg><button>a</button></svg>`,
+			[]string{"2021 `<button` `<button>` is not an SVG element"},
+		},
+		{
+			"breakout",
+			`<svg><rect /><div>a</div><circle /></svg><math><mi><b>x</b></mi><span>y</span></math>`,
+			[]string{
+				"2021 `<div` `<div>` closes the `<svg>` it is written in: HTML elements can't be used in SVG",
+				"2021 `<span` `<span>` closes the `<math>` it is written in: HTML elements can't be used in MathML",
+			},
+		},
+		{
+			"integration points",
+			`<svg><foreignObject><div class="a"><p>b</p></div></foreignObject><title><b>c</b></title></svg>`,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler.NewHandler(tt.input, "test.astro")
+			_, err := ParseWithOptions(strings.NewReader(tt.input), ParseOptionWithHandler(h), ParseOptionEnableForeignContentWarnings(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, w := range h.Warnings() {
+				start := w.Location.Column - 1
+				got = append(got, fmt.Sprintf("%d `%s` %s", w.Code, tt.input[start:start+w.Location.Length], w.Text))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %q\nExpected = %q", got, tt.want)
+			}
+
+			h = handler.NewHandler(tt.input, "test.astro")
+			if _, err := ParseWithOptions(strings.NewReader(tt.input), ParseOptionWithHandler(h)); err != nil {
+				t.Fatal(err)
+			}
+			if len(h.Warnings()) != 0 {
+				t.Errorf("expected no warnings without ParseOptionEnableForeignContentWarnings, got %v", h.Warnings())
+			}
+		})
+	}
+}
@@ -2,10 +2,28 @@ package astro
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+
+	"github.com/withastro/compiler/internal/loc"
 )
 
+// PrintToSource prints node as Astro source. When node was parsed with ParseOptionEnableTrivia,
+// the source of the nodes that did not change since is printed as it was written.
 func PrintToSource(buf *strings.Builder, node *Node) {
+	if node.source != nil {
+		p := &sourcePrinter{buf: buf, doc: node.source.doc}
+		if node.Type != DocumentNode {
+			p.pos = sourceStart(node)
+		}
+		p.print(node)
+		if node.Type == DocumentNode {
+			p.gap(len(p.doc.text))
+		}
+		return
+	}
+
 	switch node.Type {
 	case DocumentNode:
 		for c := node.FirstChild; c != nil; c = c.NextSibling {
@@ -19,7 +37,7 @@ func PrintToSource(buf *strings.Builder, node *Node) {
 			}
 			buf.WriteString("---")
 		}
-	case TextNode:
+	case TextNode, ErrorNode:
 		buf.WriteString(node.Data)
 	case ElementNode:
 		isImplicit := false
@@ -44,27 +62,7 @@ func PrintToSource(buf *strings.Builder, node *Node) {
 					buf.WriteString(":")
 				}
 				buf.WriteString(" ")
-				switch attr.Type {
-				case QuotedAttribute:
-					buf.WriteString(attr.Key)
-					buf.WriteString("=")
-					buf.WriteString(`"` + attr.Val + `"`)
-				case EmptyAttribute:
-					buf.WriteString(attr.Key)
-				case ExpressionAttribute:
-					buf.WriteString(attr.Key)
-					buf.WriteString("=")
-					buf.WriteString(`{` + strings.TrimSpace(attr.Val) + `}`)
-				case SpreadAttribute:
-					buf.WriteString(`{...` + strings.TrimSpace(attr.Val) + `}`)
-				case ShorthandAttribute:
-					buf.WriteString(attr.Key)
-					buf.WriteString("=")
-					buf.WriteString(`{` + strings.TrimSpace(attr.Key) + `}`)
-				case TemplateLiteralAttribute:
-					buf.WriteString(attr.Key)
-					buf.WriteString("=`" + strings.TrimSpace(attr.Val) + "`")
-				}
+				printAttributeToSource(buf, attr)
 			}
 			if !node.Expression {
 				buf.WriteString(`>`)
@@ -82,3 +80,288 @@ func PrintToSource(buf *strings.Builder, node *Node) {
 		}
 	}
 }
+
+func printAttributeToSource(buf *strings.Builder, attr Attribute) {
+	switch attr.Type {
+	case QuotedAttribute:
+		buf.WriteString(attr.Key)
+		buf.WriteString("=")
+		buf.WriteString(`"` + attr.Val + `"`)
+	case EmptyAttribute:
+		buf.WriteString(attr.Key)
+	case ExpressionAttribute:
+		buf.WriteString(attr.Key)
+		buf.WriteString("=")
+		buf.WriteString(`{` + strings.TrimSpace(attr.Val) + `}`)
+	case SpreadAttribute:
+		buf.WriteString(`{...` + strings.TrimSpace(attr.Val) + `}`)
+	case ShorthandAttribute:
+		buf.WriteString(attr.Key)
+		buf.WriteString("=")
+		buf.WriteString(`{` + strings.TrimSpace(attr.Key) + `}`)
+	case TemplateLiteralAttribute:
+		buf.WriteString(attr.Key)
+		buf.WriteString("=`" + strings.TrimSpace(attr.Val) + "`")
+	}
+}
+
+// documentSource is the source of a document parsed with ParseOptionEnableTrivia
+type documentSource struct {
+	text string
+	// trivia are the ranges of text that are in no node, in order
+	trivia []loc.Span
+}
+
+// sourceState is a node as it was parsed with ParseOptionEnableTrivia, to find whether it changed
+type sourceState struct {
+	doc       *documentSource
+	data      string
+	namespace string
+	attr      []Attribute
+	children  []*Node
+}
+
+// recordSource records the source of doc and the state of its nodes, for PrintToSource
+func recordSource(doc *Node, text string) {
+	d := &documentSource{text: text}
+	covered := make([]loc.Span, 0)
+	var record func(n *Node)
+	record = func(n *Node) {
+		s := &sourceState{
+			doc:       d,
+			data:      n.Data,
+			namespace: n.Namespace,
+			attr:      slices.Clone(n.Attr),
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			s.children = append(s.children, c)
+			record(c)
+		}
+		n.source = s
+		if n.Open != (loc.Span{}) {
+			covered = append(covered, n.Open, n.Close)
+		} else if n.Type != ElementNode && n.Type != DocumentNode {
+			covered = append(covered, n.Span)
+		}
+	}
+	record(doc)
+
+	sort.Slice(covered, func(i, j int) bool {
+		return covered[i].Start < covered[j].Start
+	})
+	pos := 0
+	for _, s := range covered {
+		if s == (loc.Span{}) {
+			continue
+		}
+		if s.Start > pos {
+			d.trivia = append(d.trivia, loc.Span{Start: pos, End: s.Start})
+		}
+		pos = max(pos, s.End)
+	}
+	if pos < len(text) {
+		d.trivia = append(d.trivia, loc.Span{Start: pos, End: len(text)})
+	}
+}
+
+// sourceStart returns the offset of the first source of n, for the trivia before n not to be
+// printed with it
+func sourceStart(n *Node) int {
+	if n.Span != (loc.Span{}) {
+		return n.Span.Start
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if start := sourceStart(c); start != -1 {
+			return start
+		}
+	}
+	return -1
+}
+
+// changed returns whether n or its descendants changed since they were parsed
+func (s *sourceState) changed(n *Node) bool {
+	if n.Data != s.data || n.Namespace != s.namespace || !slices.Equal(n.Attr, s.attr) {
+		return true
+	}
+	i := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if i == len(s.children) || c != s.children[i] || c.source == nil || c.source.changed(c) {
+			return true
+		}
+		i++
+	}
+	return i != len(s.children)
+}
+
+// sourcePrinter prints the nodes of a document parsed with ParseOptionEnableTrivia. pos is the
+// offset of the source printed so far: the trivia up to a node is printed before it.
+type sourcePrinter struct {
+	buf *strings.Builder
+	doc *documentSource
+	pos int
+}
+
+// gap prints the trivia between the printed source and end
+func (p *sourcePrinter) gap(end int) {
+	for _, t := range p.doc.trivia {
+		if t.End <= p.pos {
+			continue
+		}
+		if t.Start >= end {
+			break
+		}
+		p.buf.WriteString(p.doc.text[max(t.Start, p.pos):min(t.End, end)])
+	}
+	p.pos = max(p.pos, end)
+}
+
+// replace prints text in place of the source of span
+func (p *sourcePrinter) replace(span loc.Span, text string) {
+	p.gap(span.Start)
+	p.buf.WriteString(text)
+	p.pos = max(p.pos, span.End)
+}
+
+// source prints the source of span
+func (p *sourcePrinter) source(span loc.Span) {
+	p.replace(span, p.doc.text[span.Start:span.End])
+}
+
+func (p *sourcePrinter) print(n *Node) {
+	s := n.source
+	if s == nil {
+		// A node added after parsing
+		p.printNew(n)
+		return
+	}
+	if n.Span != (loc.Span{}) && !s.changed(n) {
+		p.source(n.Span)
+		return
+	}
+
+	switch n.Type {
+	case DocumentNode:
+		p.printChildren(n)
+	case TextNode, ErrorNode:
+		p.replace(n.Span, n.Data)
+	case CommentNode:
+		p.replace(n.Span, "<!--"+n.Data+"-->")
+	case DoctypeNode:
+		p.replace(n.Span, "<!DOCTYPE "+n.Data+">")
+	case FrontmatterNode:
+		if n.Open == (loc.Span{}) {
+			p.printNew(n)
+			return
+		}
+		p.source(n.Open)
+		p.printChildren(n)
+		if n.Close != (loc.Span{}) {
+			p.source(n.Close)
+		}
+	case ElementNode:
+		if n.Open == (loc.Span{}) {
+			// An implied element
+			p.printChildren(n)
+			return
+		}
+		if n.Data == s.data && n.Namespace == s.namespace && slices.Equal(n.Attr, s.attr) {
+			p.source(n.Open)
+		} else {
+			p.replace(n.Open, p.startTag(n))
+		}
+		p.printChildren(n)
+		if n.Close != (loc.Span{}) {
+			if n.Data == s.data {
+				p.source(n.Close)
+			} else {
+				p.replace(n.Close, fmt.Sprintf(`</%s>`, n.Data))
+			}
+		}
+	}
+}
+
+func (p *sourcePrinter) printChildren(n *Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		p.print(c)
+	}
+}
+
+// printNew prints a node added after parsing, whose children may have been parsed
+func (p *sourcePrinter) printNew(n *Node) {
+	switch n.Type {
+	case TextNode, ErrorNode:
+		p.buf.WriteString(n.Data)
+	case CommentNode:
+		p.buf.WriteString("<!--" + n.Data + "-->")
+	case FrontmatterNode:
+		if n.FirstChild != nil {
+			p.buf.WriteString("---")
+			p.printChildren(n)
+			p.buf.WriteString("---")
+		}
+	case ElementNode:
+		if isImplicitNode(n) {
+			p.printChildren(n)
+			return
+		}
+		if n.Expression {
+			p.buf.WriteString("{")
+			p.printChildren(n)
+			p.buf.WriteString("}")
+			return
+		}
+		p.buf.WriteString("<" + n.Data)
+		for _, attr := range n.Attr {
+			p.buf.WriteString(" ")
+			printAttributeToSource(p.buf, attr)
+		}
+		p.buf.WriteString(">")
+		p.printChildren(n)
+		p.buf.WriteString(fmt.Sprintf(`</%s>`, n.Data))
+	default:
+		p.printChildren(n)
+	}
+}
+
+// startTag returns the start tag of a changed element, keeping the source of the name and of the
+// attributes that did not change
+func (p *sourcePrinter) startTag(n *Node) string {
+	s, text := n.source, p.doc.text
+	var b strings.Builder
+
+	// The name is read from the source, as the parser may have changed it, like <image> to <img>
+	nameEnd := n.Open.Start + len("<")
+	for nameEnd < n.Open.End && !strings.ContainsRune(" \t\n\f\r/>", rune(text[nameEnd])) {
+		nameEnd++
+	}
+	if n.Data == s.data {
+		b.WriteString(text[n.Open.Start:nameEnd])
+	} else {
+		b.WriteString("<" + n.Data)
+	}
+
+	// The source after the last attribute, like " />"
+	end := nameEnd
+	for _, attr := range s.attr {
+		end = max(end, attr.Span.End)
+	}
+
+	for _, attr := range n.Attr {
+		if attr.Key == ImplicitNodeMarker {
+			continue
+		}
+		if attr.Span != (loc.Span{}) && slices.Contains(s.attr, attr) {
+			// The attribute keeps the whitespace before it
+			start := attr.Span.Start
+			for start > nameEnd && strings.ContainsRune(" \t\n\f\r", rune(text[start-1])) {
+				start--
+			}
+			b.WriteString(text[start:attr.Span.End])
+			continue
+		}
+		b.WriteString(" ")
+		printAttributeToSource(&b, attr)
+	}
+	b.WriteString(text[end:n.Open.End])
+	return b.String()
+}
@@ -0,0 +1,176 @@
+package astro
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/withastro/compiler/internal/handler"
+)
+
+// snapshotInputs returns the inputs of the snapshots of the printer tests
+func snapshotInputs(t *testing.T) map[string]string {
+	files, err := filepath.Glob("printer/__printer_*__/*.snap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no snapshots found")
+	}
+	inputs := make(map[string]string)
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// go-snaps escapes the "---" separating snapshots
+		snapshots := strings.ReplaceAll(string(b), "/-/-/-/", "---")
+		for i := 0; ; i++ {
+			start := strings.Index(snapshots, "## Input\n\n```\n")
+			end := strings.Index(snapshots, "\n```\n\n## Output")
+			if start == -1 || end < start {
+				break
+			}
+			inputs[filepath.Base(file)+"#"+strconv.Itoa(i)] = snapshots[start+len("## Input\n\n```\n") : end]
+			snapshots = snapshots[end+len("\n```\n\n## Output"):]
+		}
+	}
+	return inputs
+}
+
+func parseWithTrivia(t *testing.T, source string, opts ...ParseOption) *Node {
+	opts = append(opts, ParseOptionEnableTrivia(true), ParseOptionWithHandler(handler.NewHandler(source, "test.astro")))
+	doc, err := ParseWithOptions(strings.NewReader(source), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestPrintToSourceRoundTrip(t *testing.T) {
+	modes := map[string][]ParseOption{
+		"default": nil,
+		"literal": {ParseOptionEnableLiteral(true)},
+		"recover": {ParseOptionEnableLiteral(true), ParseOptionEnableRecovery(true)},
+	}
+	for name, source := range snapshotInputs(t) {
+		for mode, opts := range modes {
+			doc := parseWithTrivia(t, source, opts...)
+			var b strings.Builder
+			PrintToSource(&b, doc)
+			if got := b.String(); got != source {
+				t.Errorf("%s (%s): the source changed\n--- got ---\n%s\n--- want ---\n%s", name, mode, got, source)
+			}
+		}
+	}
+}
+
+func TestPrintToSourceTrivia(t *testing.T) {
+	source := "---\nconst a = 1;\n---\n<div  class='a'\n\tid=\"b\" >\n\t<p>text<br/></p>{a}\n<!-- c --></div>\n"
+	find := func(doc *Node, data string) *Node {
+		var found *Node
+		walk(doc, func(n *Node) {
+			if found == nil && n.Data == data {
+				found = n
+			}
+		})
+		return found
+	}
+
+	tests := []struct {
+		name string
+		edit func(doc *Node)
+		want string
+	}{
+		{
+			name: "unchanged",
+			edit: func(doc *Node) {},
+			want: source,
+		},
+		{
+			name: "attribute value",
+			edit: func(doc *Node) {
+				find(doc, "div").Attr[1].Val = "c"
+			},
+			want: "---\nconst a = 1;\n---\n<div  class='a' id=\"c\" >\n\t<p>text<br/></p>{a}\n<!-- c --></div>\n",
+		},
+		{
+			name: "removed attribute",
+			edit: func(doc *Node) {
+				div := find(doc, "div")
+				div.Attr = div.Attr[:1]
+			},
+			want: "---\nconst a = 1;\n---\n<div  class='a' >\n\t<p>text<br/></p>{a}\n<!-- c --></div>\n",
+		},
+		{
+			name: "added attribute",
+			edit: func(doc *Node) {
+				div := find(doc, "div")
+				div.Attr = append(div.Attr, Attribute{Key: "hidden", Type: EmptyAttribute})
+			},
+			want: "---\nconst a = 1;\n---\n<div  class='a'\n\tid=\"b\" hidden >\n\t<p>text<br/></p>{a}\n<!-- c --></div>\n",
+		},
+		{
+			name: "renamed element",
+			edit: func(doc *Node) {
+				find(doc, "p").Data = "span"
+			},
+			want: "---\nconst a = 1;\n---\n<div  class='a'\n\tid=\"b\" >\n\t<span>text<br/></span>{a}\n<!-- c --></div>\n",
+		},
+		{
+			name: "text",
+			edit: func(doc *Node) {
+				find(doc, "p").FirstChild.Data = "changed"
+			},
+			want: "---\nconst a = 1;\n---\n<div  class='a'\n\tid=\"b\" >\n\t<p>changed<br/></p>{a}\n<!-- c --></div>\n",
+		},
+		{
+			name: "removed node",
+			edit: func(doc *Node) {
+				p := find(doc, "p")
+				p.RemoveChild(p.LastChild)
+			},
+			want: "---\nconst a = 1;\n---\n<div  class='a'\n\tid=\"b\" >\n\t<p>text</p>{a}\n<!-- c --></div>\n",
+		},
+		{
+			name: "added node",
+			edit: func(doc *Node) {
+				p := find(doc, "p")
+				p.AppendChild(&Node{Type: ElementNode, Data: "em", Attr: []Attribute{{Key: "x", Val: "y", Type: QuotedAttribute}}})
+			},
+			want: "---\nconst a = 1;\n---\n<div  class='a'\n\tid=\"b\" >\n\t<p>text<br/><em x=\"y\"></em></p>{a}\n<!-- c --></div>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseWithTrivia(t, source, ParseOptionEnableLiteral(true))
+			tt.edit(doc)
+			var b strings.Builder
+			PrintToSource(&b, doc)
+			if got := b.String(); got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintToSourceAfterReparse(t *testing.T) {
+	source := "<div class='a'>\n\t<p>one</p>\n</div>\n<span>two</span>"
+	doc := parseWithTrivia(t, source, ParseOptionEnableIncremental(true))
+	edit := Edit{Start: strings.Index(source, "one"), End: strings.Index(source, "one") + len("one"), Text: "three"}
+
+	doc, element, err := reparse(doc, source, edit, ParseOptionEnableTrivia(true), ParseOptionWithHandler(handler.NewHandler(source, "test.astro")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if element == nil {
+		t.Errorf("expected the paragraph to be parsed again in place")
+	}
+	var b strings.Builder
+	PrintToSource(&b, doc)
+	if want := edit.Apply(source); b.String() != want {
+		t.Errorf("got\n%q\nwant\n%q", b.String(), want)
+	}
+}
@@ -317,6 +317,9 @@ func render1(p *printer, n *Node, opts RenderOptions) {
 	case DoctypeNode:
 		// Doctype doesn't get printed because the Astro runtime always appends it
 		return
+	case ErrorNode:
+		// Markers of a recovered parse are not rendered
+		return
 	case RawNode:
 		p.print(n.Data)
 		return
@@ -464,7 +467,7 @@ func render1(p *printer, n *Node, opts RenderOptions) {
 					p.handler.AppendError(&loc.ErrorWithRange{
 						Code:  loc.ERROR_UNSUPPORTED_SLOT_ATTRIBUTE,
 						Text:  "slot[name] must be a static string",
-						Range: loc.Range{Loc: a.ValLoc, Len: len(a.Val)},
+						Range: a.ValSpan.Range(),
 					})
 				}
 			}
@@ -606,7 +609,7 @@ func render1(p *printer, n *Node, opts RenderOptions) {
 								p.handler.AppendError(&loc.ErrorWithRange{
 									Code:  loc.ERROR_UNSUPPORTED_SLOT_ATTRIBUTE,
 									Text:  "slot[name] must be a static string",
-									Range: loc.Range{Loc: a.ValLoc, Len: len(a.Val)},
+									Range: a.ValSpan.Range(),
 								})
 							}
 						}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	. "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/js_parser"
 	"github.com/withastro/compiler/internal/loc"
 	"github.com/withastro/compiler/internal/sourcemap"
 	"github.com/withastro/compiler/internal/t"
@@ -15,6 +16,30 @@ import (
 type ASTPosition struct {
 	Start ASTPoint `json:"start,omitempty"`
 	End   ASTPoint `json:"end,omitempty"`
+
+	// Open and Close are the positions of the start and end tags of an element, the braces of an
+	// expression or the fences of a frontmatter. Name and Value are those of an attribute.
+	Open  *ASTPosition `json:"open,omitempty"`
+	Close *ASTPosition `json:"close,omitempty"`
+	Name  *ASTPosition `json:"name,omitempty"`
+	Value *ASTPosition `json:"value,omitempty"`
+}
+
+func (p ASTPosition) String() string {
+	str := fmt.Sprintf(`{"start":{"line":%d,"column":%d,"offset":%d}`, p.Start.Line, p.Start.Column, p.Start.Offset)
+	if p.End.Line != 0 {
+		str += fmt.Sprintf(`,"end":{"line":%d,"column":%d,"offset":%d}`, p.End.Line, p.End.Column, p.End.Offset)
+	}
+	for _, child := range []struct {
+		key      string
+		position *ASTPosition
+	}{{"open", p.Open}, {"close", p.Close}, {"name", p.Name}, {"value", p.Value}} {
+		if child.position != nil {
+			str += fmt.Sprintf(`,"%s":%s`, child.key, child.position.String())
+		}
+	}
+	str += "}"
+	return str
 }
 
 type ASTPoint struct {
@@ -35,6 +60,9 @@ type ASTNode struct {
 	// Attributes only
 	Kind string `json:"kind,omitempty"`
 	Raw  string `json:"raw,omitempty"`
+
+	// Frontmatter only, the ESTree JSON of the frontmatter content
+	Program string `json:"program,omitempty"`
 }
 
 func escapeForJSON(value string) string {
@@ -69,6 +97,9 @@ func (n ASTNode) String() string {
 	if n.Raw != "" || n.Type == "attribute" {
 		str += fmt.Sprintf(`,"raw":"%s"`, escapeForJSON(n.Raw))
 	}
+	if n.Program != "" {
+		str += fmt.Sprintf(`,"program":%s`, n.Program)
+	}
 	if len(n.Attributes) > 0 {
 		str += `,"attributes":[`
 		for i, attr := range n.Attributes {
@@ -94,12 +125,7 @@ func (n ASTNode) String() string {
 		str += `,"children":[]`
 	}
 	if n.Position.Start.Line != 0 {
-		str += `,"position":{`
-		str += fmt.Sprintf(`"start":{"line":%d,"column":%d,"offset":%d}`, n.Position.Start.Line, n.Position.Start.Column, n.Position.Start.Offset)
-		if n.Position.End.Line != 0 {
-			str += fmt.Sprintf(`,"end":{"line":%d,"column":%d,"offset":%d}`, n.Position.End.Line, n.Position.End.Column, n.Position.End.Offset)
-		}
-		str += "}"
+		str += `,"position":` + n.Position.String()
 	}
 	str += "}"
 	return str
@@ -131,11 +157,42 @@ func locToPoint(p *printer, loc loc.Loc) ASTPoint {
 	}
 }
 
+func programToJSON(p *printer, program *js_parser.Node, opts t.ParseOptions) string {
+	var position js_parser.Position
+	if opts.Position {
+		position = func(offset int) (int, int) {
+			point := locToPoint(p, loc.Loc{Start: offset})
+			// ESTree columns start at 0
+			return point.Line, point.Column - 1
+		}
+	}
+	b := strings.Builder{}
+	program.WriteJSON(&b, position)
+	return b.String()
+}
+
+// spanToPosition returns the position of span, or nil when it is zero
+func spanToPosition(p *printer, span loc.Span) *ASTPosition {
+	if span == (loc.Span{}) {
+		return nil
+	}
+	return &ASTPosition{
+		Start: locToPoint(p, loc.Loc{Start: span.Start}),
+		End:   locToPoint(p, loc.Loc{Start: span.End}),
+	}
+}
+
 func positionAt(p *printer, n *Node, opts t.ParseOptions) ASTPosition {
 	if !opts.Position {
 		return ASTPosition{}
 	}
 
+	if position := spanToPosition(p, n.Span); position != nil {
+		position.Open = spanToPosition(p, n.Open)
+		position.Close = spanToPosition(p, n.Close)
+		return *position
+	}
+
 	if len(n.Loc) == 1 {
 		s := n.Loc[0]
 		start := locToPoint(p, s)
@@ -179,6 +236,19 @@ func attrPositionAt(p *printer, n *Attribute, opts t.ParseOptions) ASTPosition {
 		return ASTPosition{}
 	}
 
+	if position := spanToPosition(p, n.Span); position != nil {
+		position.Name = spanToPosition(p, n.KeySpan)
+		switch n.Type {
+		case EmptyAttribute, ShorthandAttribute, SpreadAttribute:
+		default:
+			position.Value = &ASTPosition{
+				Start: locToPoint(p, loc.Loc{Start: n.ValSpan.Start}),
+				End:   locToPoint(p, loc.Loc{Start: n.ValSpan.End}),
+			}
+		}
+		return *position
+	}
+
 	k := n.KeyLoc
 	start := locToPoint(p, k)
 
@@ -255,10 +325,13 @@ func renderNode(p *printer, parent *ASTNode, n *Node, opts t.ParseOptions) {
 		}
 	} else {
 		node.Type = n.Type.String()
-		if n.Type == TextNode || n.Type == CommentNode || n.Type == DoctypeNode {
+		if n.Type == TextNode || n.Type == CommentNode || n.Type == DoctypeNode || n.Type == ErrorNode {
 			node.Value = n.Data
 		}
 	}
+	if n.Type == FrontmatterNode && opts.FrontmatterAST && n.Program != nil {
+		node.Program = programToJSON(p, n.Program, opts)
+	}
 	if n.Type == FrontmatterNode && hasChildren {
 		node.Value = n.FirstChild.Data
 	} else {
@@ -60,10 +60,10 @@ func finalizeRanges(content string, ranges TSXRanges) TSXRanges {
 }
 
 type TSXRanges struct {
-	Frontmatter loc.TSXRange      `js:"frontmatter"`
-	Body        loc.TSXRange      `js:"body"`
-	Scripts     []TSXExtractedTag `js:"scripts"`
-	Styles      []TSXExtractedTag `js:"styles"`
+	Frontmatter loc.TSXRange      `js:"frontmatter" json:"frontmatter"`
+	Body        loc.TSXRange      `js:"body" json:"body"`
+	Scripts     []TSXExtractedTag `js:"scripts" json:"scripts"`
+	Styles      []TSXExtractedTag `js:"styles" json:"styles"`
 }
 
 var htmlEvents = map[string]bool{
@@ -214,10 +214,10 @@ func getScriptTypeFromAttrs(attrs []astro.Attribute) string {
 }
 
 type TSXExtractedTag struct {
-	Loc     loc.TSXRange `js:"position"`
-	Type    string       `js:"type"`
-	Content string       `js:"content"`
-	Lang    string       `js:"lang"`
+	Loc     loc.TSXRange `js:"position" json:"position"`
+	Type    string       `js:"type" json:"type"`
+	Content string       `js:"content" json:"content"`
+	Lang    string       `js:"lang" json:"lang,omitempty"`
 }
 
 func isScript(p *astro.Node) bool {
@@ -474,7 +474,7 @@ declare const Astro: Readonly<import('astro').AstroGlobal<%s, typeof %s`, propsI
 		// p.addSourceMapping(n.Loc[0])
 		p.addNilSourceMapping()
 		p.print("{/**")
-		if !unicode.IsSpace(rune(n.Data[0])) {
+		if n.Data == "" || !unicode.IsSpace(rune(n.Data[0])) {
 			// always add a space after the opening comment
 			p.print(" ")
 		}
@@ -506,12 +506,16 @@ declare const Astro: Readonly<import('astro').AstroGlobal<%s, typeof %s`, propsI
 				}
 				continue
 			}
-			if c.PrevSibling == nil || c.PrevSibling.Type == TextNode {
+			// Error nodes of a recovered parse are left out, for the elements around them to be wrapped together
+			if c.Type == ErrorNode {
+				continue
+			}
+			if prev := prevSiblingSkippingErrors(c); prev == nil || prev.Type == TextNode {
 				p.addNilSourceMapping()
 				p.print(`<Fragment>`)
 			}
 			renderTsx(p, c, o)
-			if c.NextSibling == nil || c.NextSibling.Type == TextNode {
+			if next := nextSiblingSkippingErrors(c); next == nil || next.Type == TextNode {
 				p.addNilSourceMapping()
 				p.print(`</Fragment>`)
 			}
@@ -807,3 +811,23 @@ declare const Astro: Readonly<import('astro').AstroGlobal<%s, typeof %s`, propsI
 	p.print(">")
 	p.addSourceMapping(loc.Loc{Start: endLoc + 1})
 }
+
+// prevSiblingSkippingErrors returns the previous sibling of n which is not an ErrorNode
+func prevSiblingSkippingErrors(n *Node) *Node {
+	for c := n.PrevSibling; c != nil; c = c.PrevSibling {
+		if c.Type != ErrorNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// nextSiblingSkippingErrors returns the next sibling of n which is not an ErrorNode
+func nextSiblingSkippingErrors(n *Node) *Node {
+	for c := n.NextSibling; c != nil; c = c.NextSibling {
+		if c.Type != ErrorNode {
+			return c
+		}
+	}
+	return nil
+}
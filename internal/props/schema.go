@@ -0,0 +1,501 @@
+// Package props describes the Props of a component as a JSON Schema, from the declaration of its
+// `Props` interface or type in the frontmatter.
+package props
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/withastro/compiler/internal/js_parser"
+)
+
+// SchemaVersion is the JSON Schema dialect of the schemas
+const SchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema. The types which have no JSON Schema equivalent, like functions or
+// imported types, are described by their TypeScript source in TSType, as json-schema-to-typescript
+// does.
+type Schema struct {
+	Schema      string     `json:"$schema,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Type        string     `json:"type,omitempty"`
+	Const       any        `json:"const,omitempty"`
+	Enum        []any      `json:"enum,omitempty"`
+	Items       *Schema    `json:"items,omitempty"`
+	PrefixItems []*Schema  `json:"prefixItems,omitempty"`
+	Properties  Properties `json:"properties,omitempty"`
+	Required    []string   `json:"required,omitempty"`
+	// AdditionalProperties describes the values of an index signature or of a Record
+	AdditionalProperties *Schema   `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema `json:"anyOf,omitempty"`
+	AllOf                []*Schema `json:"allOf,omitempty"`
+	Default              any       `json:"default,omitempty"`
+	Deprecated           bool      `json:"deprecated,omitempty"`
+	TSType               string    `json:"tsType,omitempty"`
+	// hasConst tells a null Const from no Const
+	hasConst bool
+}
+
+// Property is a named property of an object Schema
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties are the properties of an object Schema, in the order of their declaration
+type Properties []Property
+
+func (p Properties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, property := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := marshal(property.Name)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		schema, err := marshal(property.Schema)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(schema)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	// The alias has the fields of Schema without its methods
+	type schema Schema
+	b, err := marshal((*schema)(s))
+	if err != nil || !s.hasConst || s.Const != nil {
+		return b, err
+	}
+	// A null const is omitted by omitempty
+	return append(append(b[:len(b)-1:len(b)-1], []byte(`,"const":null`)...), '}'), nil
+}
+
+// marshal encodes v as JSON without escaping HTML, for the TypeScript types of TSType like
+// "() => void". The encoder of the schema must not escape HTML either.
+func marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// Extract returns the JSON Schema of the Props of the frontmatter program, given source, the
+// source the program was parsed from. It returns nil when the frontmatter doesn't declare Props.
+func Extract(program *js_parser.Node, source string) *Schema {
+	e := &extractor{source: source, declarations: make(map[string]*js_parser.Node), comments: program.Children("comments"), resolving: make(map[string]bool)}
+	// The statement of Props, which holds its JSDoc when it is exported
+	var statement *js_parser.Node
+	for _, s := range program.Children("body") {
+		declaration := s
+		if s.Type == "ExportNamedDeclaration" && s.Child("declaration") != nil {
+			declaration = s.Child("declaration")
+		}
+		switch declaration.Type {
+		case "TSInterfaceDeclaration", "TSTypeAliasDeclaration", "TSEnumDeclaration":
+			if id := declaration.Child("id"); id != nil {
+				e.declarations[identifierName(id)] = declaration
+				if identifierName(id) == "Props" {
+					statement = s
+				}
+			}
+		}
+	}
+	if statement == nil {
+		return nil
+	}
+	schema := e.declaration(e.declarations["Props"])
+	schema.Schema = SchemaVersion
+	schema.Description, schema.Deprecated = e.jsDoc(statement)
+	e.defaults(program, schema)
+	return schema
+}
+
+type extractor struct {
+	source string
+	// declarations are the interfaces, type aliases and enums of the frontmatter, by name
+	declarations map[string]*js_parser.Node
+	comments     []*js_parser.Node
+	// resolving holds the declarations being inlined, to describe recursive types by name
+	resolving map[string]bool
+}
+
+// declaration returns the schema of an interface, type alias or enum declaration
+func (e *extractor) declaration(n *js_parser.Node) *Schema {
+	name := identifierName(n.Child("id"))
+	e.resolving[name] = true
+	defer delete(e.resolving, name)
+
+	switch n.Type {
+	case "TSInterfaceDeclaration":
+		schema := e.members(n.Child("body").Children("body"))
+		var allOf []*Schema
+		for _, heritage := range n.Children("extends") {
+			parent := e.reference(heritage.Child("expression"), heritage.Child("typeArguments"), e.text(heritage))
+			if parent.Type == "object" && parent.TSType == "" {
+				// A local interface is merged, as TypeScript does
+				schema.Properties = append(parent.Properties, schema.Properties...)
+				schema.Required = append(parent.Required, schema.Required...)
+				continue
+			}
+			allOf = append(allOf, parent)
+		}
+		schema.AllOf = allOf
+		return schema
+	case "TSEnumDeclaration":
+		schema := &Schema{}
+		for _, member := range n.Children("members") {
+			initializer := member.Child("initializer")
+			value, ok := literalValue(initializer)
+			if initializer == nil || !ok {
+				return &Schema{TSType: name}
+			}
+			schema.Enum = append(schema.Enum, value)
+		}
+		return schema
+	}
+	return e.typ(n.Child("typeAnnotation"))
+}
+
+// members returns the object schema of the members of an interface or type literal
+func (e *extractor) members(members []*js_parser.Node) *Schema {
+	schema := &Schema{Type: "object", Properties: Properties{}}
+	for _, member := range members {
+		switch member.Type {
+		case "TSPropertySignature", "TSMethodSignature":
+			name, ok := propertyName(member.Child("key"), member.Get("computed") == true)
+			if !ok {
+				continue
+			}
+			var property *Schema
+			if member.Type == "TSMethodSignature" {
+				property = &Schema{TSType: e.methodType(member)}
+			} else if annotation := member.Child("typeAnnotation"); annotation != nil {
+				property = e.typ(annotation.Child("typeAnnotation"))
+			} else {
+				property = &Schema{}
+			}
+			property.Description, property.Deprecated = e.jsDoc(member)
+			schema.Properties = append(schema.Properties, Property{Name: name, Schema: property})
+			if member.Get("optional") != true {
+				schema.Required = append(schema.Required, name)
+			}
+		case "TSIndexSignature":
+			if annotation := member.Child("typeAnnotation"); annotation != nil {
+				schema.AdditionalProperties = e.typ(annotation.Child("typeAnnotation"))
+			}
+		}
+	}
+	return schema
+}
+
+// methodType returns the type of a method signature as a function type, like "(a: string) => void"
+func (e *extractor) methodType(n *js_parser.Node) string {
+	key := n.Child("key")
+	signature := strings.TrimSpace(e.source[key.End:n.End])
+	signature = strings.TrimPrefix(strings.TrimRight(signature, ";,"), "?")
+	if i := strings.LastIndex(signature, "):"); i != -1 {
+		signature = signature[:i+1] + " =>" + signature[i+2:]
+	} else {
+		signature += " => void"
+	}
+	return signature
+}
+
+// typ returns the schema of a type
+func (e *extractor) typ(n *js_parser.Node) *Schema {
+	if n == nil {
+		return &Schema{}
+	}
+	switch n.Type {
+	case "TSStringKeyword", "TSTemplateLiteralType":
+		return &Schema{Type: "string"}
+	case "TSNumberKeyword":
+		return &Schema{Type: "number"}
+	case "TSBooleanKeyword":
+		return &Schema{Type: "boolean"}
+	case "TSNullKeyword":
+		return &Schema{Type: "null"}
+	case "TSObjectKeyword":
+		return &Schema{Type: "object"}
+	case "TSAnyKeyword", "TSUnknownKeyword":
+		return &Schema{}
+	case "TSLiteralType":
+		if value, ok := literalValue(n.Child("literal")); ok {
+			return &Schema{Type: jsonType(value), Const: value, hasConst: true}
+		}
+	case "TSTypeLiteral":
+		return e.members(n.Children("members"))
+	case "TSArrayType":
+		return &Schema{Type: "array", Items: e.typ(n.Child("elementType"))}
+	case "TSTupleType":
+		schema := &Schema{Type: "array", PrefixItems: []*Schema{}}
+		for _, element := range n.Children("elementTypes") {
+			if element.Type == "TSNamedTupleMember" {
+				element = element.Child("elementType")
+			}
+			if element.Type == "TSOptionalType" || element.Type == "TSRestType" {
+				return &Schema{TSType: e.text(n)}
+			}
+			schema.PrefixItems = append(schema.PrefixItems, e.typ(element))
+		}
+		return schema
+	case "TSTypeOperator":
+		if n.Get("operator") == "readonly" {
+			return e.typ(n.Child("typeAnnotation"))
+		}
+	case "TSUnionType":
+		return e.union(n)
+	case "TSIntersectionType":
+		schema := &Schema{}
+		for _, t := range n.Children("types") {
+			schema.AllOf = append(schema.AllOf, e.typ(t))
+		}
+		return schema
+	case "TSTypeReference":
+		return e.reference(n.Child("typeName"), n.Child("typeArguments"), e.text(n))
+	}
+	return &Schema{TSType: e.text(n)}
+}
+
+// union returns the schema of a union type: an enum for a union of literals, like
+// "'sm' | 'md' | 'lg'", or the schemas of its types. Undefined is left out, as it only makes the
+// property optional.
+func (e *extractor) union(n *js_parser.Node) *Schema {
+	var types []*js_parser.Node
+	for _, t := range n.Children("types") {
+		if t.Type != "TSUndefinedKeyword" && t.Type != "TSVoidKeyword" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 1 {
+		return e.typ(types[0])
+	}
+	schemas := make([]*Schema, 0, len(types))
+	literals := true
+	for _, t := range types {
+		schema := e.typ(t)
+		if t.Type == "TSNullKeyword" {
+			// null is a literal of the enum, like in "'a' | 'b' | null"
+			schema.hasConst = true
+		}
+		schemas = append(schemas, schema)
+		literals = literals && schema.hasConst
+	}
+	if !literals {
+		return &Schema{AnyOf: schemas}
+	}
+	schema := &Schema{Type: schemas[0].Type}
+	for _, s := range schemas {
+		if s.Type != schema.Type {
+			schema.Type = ""
+		}
+		schema.Enum = append(schema.Enum, s.Const)
+	}
+	return schema
+}
+
+// reference returns the schema of a reference to a type: the declarations of the frontmatter are
+// inlined, and arrays and records are described
+func (e *extractor) reference(name *js_parser.Node, typeArguments *js_parser.Node, text string) *Schema {
+	var arguments []*js_parser.Node
+	if typeArguments != nil {
+		arguments = typeArguments.Children("params")
+	}
+	if name.Type == "Identifier" {
+		switch id := identifierName(name); {
+		case (id == "Array" || id == "ReadonlyArray") && len(arguments) == 1:
+			return &Schema{Type: "array", Items: e.typ(arguments[0])}
+		case id == "Record" && len(arguments) == 2:
+			return &Schema{Type: "object", AdditionalProperties: e.typ(arguments[1])}
+		case e.declarations[id] != nil && !e.resolving[id] && len(arguments) == 0:
+			return e.declaration(e.declarations[id])
+		}
+	}
+	return &Schema{TSType: text}
+}
+
+// defaults sets the default values of the properties of schema from the destructuring of
+// Astro.props, like `const { size = "md" } = Astro.props`
+func (e *extractor) defaults(program *js_parser.Node, schema *Schema) {
+	js_parser.Walk(program, func(n *js_parser.Node) bool {
+		if n.Type != "VariableDeclarator" || n.Child("id").Type != "ObjectPattern" || !isAstroProps(n.Child("init")) {
+			return true
+		}
+		for _, property := range n.Child("id").Children("properties") {
+			value := property.Child("value")
+			if property.Type != "Property" || value == nil || value.Type != "AssignmentPattern" {
+				continue
+			}
+			name, ok := propertyName(property.Child("key"), property.Get("computed") == true)
+			if !ok {
+				continue
+			}
+			defaultValue, ok := literalValue(value.Child("right"))
+			if !ok {
+				continue
+			}
+			for _, p := range schema.Properties {
+				if p.Name == name {
+					p.Schema.Default = defaultValue
+				}
+			}
+		}
+		return false
+	})
+}
+
+// isAstroProps reports whether n is `Astro.props`, possibly asserted like `Astro.props as Props`
+func isAstroProps(n *js_parser.Node) bool {
+	for n != nil && (n.Type == "TSAsExpression" || n.Type == "TSSatisfiesExpression" || n.Type == "TSNonNullExpression") {
+		n = n.Child("expression")
+	}
+	if n == nil || n.Type != "MemberExpression" || n.Get("computed") == true {
+		return false
+	}
+	object, property := n.Child("object"), n.Child("property")
+	return object.Type == "Identifier" && identifierName(object) == "Astro" && identifierName(property) == "props"
+}
+
+// jsDoc returns the description of the JSDoc comment right before n, without its tags, and
+// whether it has a @deprecated tag
+func (e *extractor) jsDoc(n *js_parser.Node) (description string, deprecated bool) {
+	var comment *js_parser.Node
+	for _, c := range e.comments {
+		if c.End > n.Start {
+			break
+		}
+		comment = c
+	}
+	if comment == nil || comment.Type != "Block" || strings.TrimSpace(e.source[comment.End:n.Start]) != "" {
+		return "", false
+	}
+	value, _ := comment.Get("value").(string)
+	if !strings.HasPrefix(value, "*") {
+		return "", false
+	}
+	var lines []string
+	inTag := false
+	for _, line := range strings.Split(value[1:], "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		if strings.HasPrefix(line, "@") {
+			inTag = true
+			deprecated = deprecated || line == "@deprecated" || strings.HasPrefix(line, "@deprecated ")
+		}
+		if !inTag {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), deprecated
+}
+
+func (e *extractor) text(n *js_parser.Node) string {
+	return e.source[n.Start:n.End]
+}
+
+func identifierName(n *js_parser.Node) string {
+	name, _ := n.Get("name").(string)
+	return name
+}
+
+// propertyName returns the name of the key of a property, like "size" or "aria-label"
+func propertyName(key *js_parser.Node, computed bool) (string, bool) {
+	switch {
+	case key == nil:
+		return "", false
+	case key.Type == "Identifier" && !computed:
+		return identifierName(key), true
+	case key.Type == "Literal":
+		if name, ok := key.Get("value").(string); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// literalValue returns the JSON value of a literal expression, like "md", -1, ["a"] or { a: 1 }
+func literalValue(n *js_parser.Node) (any, bool) {
+	if n == nil {
+		return nil, false
+	}
+	switch n.Type {
+	case "Literal":
+		if n.Get("bigint") != nil || n.Get("regex") != nil {
+			return nil, false
+		}
+		return n.Get("value"), true
+	case "UnaryExpression":
+		if value, ok := literalValue(n.Child("argument")); ok && n.Get("operator") == "-" {
+			if number, ok := value.(float64); ok {
+				return -number, true
+			}
+		}
+	case "TemplateLiteral":
+		quasis := n.Children("quasis")
+		if len(n.Children("expressions")) == 0 && len(quasis) == 1 {
+			if value, ok := quasis[0].Get("value").(js_parser.Object); ok {
+				for _, f := range value {
+					if f.Key == "cooked" {
+						return f.Value, f.Value != nil
+					}
+				}
+			}
+		}
+	case "ArrayExpression":
+		values := []any{}
+		for _, element := range n.Children("elements") {
+			value, ok := literalValue(element)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, value)
+		}
+		return values, true
+	case "ObjectExpression":
+		values := map[string]any{}
+		for _, property := range n.Children("properties") {
+			if property.Type != "Property" || property.Get("kind") != "init" {
+				return nil, false
+			}
+			name, ok := propertyName(property.Child("key"), property.Get("computed") == true)
+			if !ok {
+				return nil, false
+			}
+			value, ok := literalValue(property.Child("value"))
+			if !ok {
+				return nil, false
+			}
+			values[name] = value
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// jsonType returns the JSON Schema type of a literal value
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package props
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/withastro/compiler/internal/js_parser"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// want is the JSON of the properties of the schema, or of the whole schema when it starts with "$"
+		want string
+	}{
+		{
+			name:   "primitives",
+			source: `interface Props { title: string; count?: number; open: boolean | undefined; data: any; none: null }`,
+			want:   `{"title":{"type":"string"},"count":{"type":"number"},"open":{"type":"boolean"},"data":{},"none":{"type":"null"}}`,
+		},
+		{
+			name:   "literal unions",
+			source: `type Props = { size: 'sm' | 'md' | 'lg'; level: -1 | 0 | 1; mixed: 'a' | 1 | null; only: 'x'; either: string | number }`,
+			want:   `{"size":{"type":"string","enum":["sm","md","lg"]},"level":{"type":"number","enum":[-1,0,1]},"mixed":{"enum":["a",1,null]},"only":{"type":"string","const":"x"},"either":{"anyOf":[{"type":"string"},{"type":"number"}]}}`,
+		},
+		{
+			name:   "null const",
+			source: `interface Props { a: null | undefined; b: 'x' | null }`,
+			want:   `{"a":{"type":"null"},"b":{"enum":["x",null]}}`,
+		},
+		{
+			name:   "arrays and objects",
+			source: "interface Props { tags: string[]; ids: ReadonlyArray<number>; pair: [string, number]; meta: Record<string, boolean>; style: { color?: string; [key: string]: string | undefined }; path: `/${string}` }",
+			want:   `{"tags":{"type":"array","items":{"type":"string"}},"ids":{"type":"array","items":{"type":"number"}},"pair":{"type":"array","prefixItems":[{"type":"string"},{"type":"number"}]},"meta":{"type":"object","additionalProperties":{"type":"boolean"}},"style":{"type":"object","properties":{"color":{"type":"string"}},"additionalProperties":{"type":"string"}},"path":{"type":"string"}}`,
+		},
+		{
+			name:   "functions and imported types",
+			source: `import type { Icon } from './icon'; interface Props { icon: Icon; onClick(event: MouseEvent): void; format?: (value: number) => string }`,
+			want:   `{"icon":{"tsType":"Icon"},"onClick":{"tsType":"(event: MouseEvent) => void"},"format":{"tsType":"(value: number) => string"}}`,
+		},
+		{
+			name:   "local types",
+			source: `type Size = 'sm' | 'lg'; enum Tone { Info = 'info', Danger = 'danger' } interface Item { label: string; children?: Item[] } interface Props { size: Size; tone: Tone; item: Item }`,
+			want:   `{"size":{"type":"string","enum":["sm","lg"]},"tone":{"enum":["info","danger"]},"item":{"type":"object","properties":{"label":{"type":"string"},"children":{"type":"array","items":{"tsType":"Item"}}},"required":["label"]}}`,
+		},
+		{
+			name:   "extends",
+			source: `interface Base { id: string } export interface Props extends Base, Omit<HTMLAttributes<'a'>, 'href'> { href: string }`,
+			want:   `${"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{"id":{"type":"string"},"href":{"type":"string"}},"required":["id","href"],"allOf":[{"tsType":"Omit<HTMLAttributes<'a'>, 'href'>"}]}`,
+		},
+		{
+			name: "jsdoc",
+			source: `/** A call to action */
+export interface Props {
+	/**
+	 * The size of the button
+	 * in pixels
+	 * @default 'md'
+	 */
+	size?: number;
+	/** @deprecated Use size */
+	big?: boolean;
+	// Not JSDoc
+	label: string;
+	/* Not JSDoc either */
+	href: string;
+}`,
+			want: `${"$schema":"https://json-schema.org/draft/2020-12/schema","description":"A call to action","type":"object","properties":{"size":{"description":"The size of the button\nin pixels","type":"number"},"big":{"type":"boolean","deprecated":true},"label":{"type":"string"},"href":{"type":"string"}},"required":["label","href"]}`,
+		},
+		{
+			name: "defaults",
+			source: `interface Props { size?: string; count?: number; tags?: string[]; meta?: object; href?: string; onClick?: () => void }
+const { size = 'md', count: total = -1, tags = ['a', 'b'], meta = { a: 1 }, href = base + '/', onClick = () => {}, ...rest } = Astro.props as Props;`,
+			want: `{"size":{"type":"string","default":"md"},"count":{"type":"number","default":-1},"tags":{"type":"array","items":{"type":"string"},"default":["a","b"]},"meta":{"type":"object","default":{"a":1}},"href":{"type":"string"},"onClick":{"tsType":"() => void"}}`,
+		},
+		{
+			name:   "defaults of other objects",
+			source: `interface Props { size?: string } const { size = 'md' } = other;`,
+			want:   `{"size":{"type":"string"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := js_parser.Parse(tt.source, 0, len(tt.source))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			schema := Extract(program, tt.source)
+			if schema == nil {
+				t.Fatal("expected a schema")
+			}
+			var got []byte
+			want := tt.want
+			if want[0] == '$' {
+				want = want[1:]
+				got, err = marshal(schema)
+			} else {
+				got, err = marshal(schema.Properties)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("expected\n%s\ngot\n%s", want, got)
+			}
+			if !json.Valid(got) {
+				t.Errorf("invalid JSON: %s", got)
+			}
+		})
+	}
+
+	program, _ := js_parser.Parse("interface Other {}", 0, len("interface Other {}"))
+	if schema := Extract(program, "interface Other {}"); schema != nil {
+		t.Errorf("expected no schema without Props, got %+v", schema)
+	}
+}
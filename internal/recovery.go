@@ -0,0 +1,319 @@
+package astro
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/withastro/compiler/internal/loc"
+	a "golang.org/x/net/html/atom"
+)
+
+// parseCurrentTokenWithRecovery is parseCurrentToken for ParseOptionEnableRecovery. Around the token,
+// it closes what the token leaves unbalanced, and marks each recovery with an ErrorNode: the Data of
+// the node is the unexpected source, or empty when something is missing at its location.
+func (p *parser) parseCurrentTokenWithRecovery() {
+	switch p.tok.Type {
+	case FrontmatterFenceToken:
+		// The tokenizer closes an unterminated frontmatter with an empty fence
+		if p.frontmatterState == FrontmatterOpen && p.fm != nil && len(p.tokenizer.Raw()) == 0 {
+			p.fm.AppendChild(p.errorNode("", p.tok.Loc.Start, p.tok.Loc.Start))
+		}
+	case EndTagToken:
+		p.recoverUnclosedExpressions()
+		if p.isUnexpectedEndTag() {
+			// The end tag is dropped, instead of closing unrelated elements
+			p.recoverUnexpectedEndTag(fmt.Sprintf("Unexpected `</%s>`, no matching element is open", p.tok.Data))
+			return
+		}
+		if sameFunc(p.im, inLiteralIM) {
+			p.recoverMisnestedEndTag()
+		}
+	case ErrorToken:
+		p.recoverAtEOF()
+	}
+
+	var open nodeStack
+	if p.tok.Type == EndTagToken {
+		open = append(open, p.oe...)
+	}
+	p.parseCurrentToken()
+
+	switch p.tok.Type {
+	case TextToken:
+		if p.tokenizer.strayBrace {
+			p.recoverStrayBrace()
+		}
+	case EndTagToken:
+		p.recoverUnbalancedEndTag(open)
+	}
+}
+
+func (p *parser) errorNode(data string, start int, end int) *Node {
+	return &Node{
+		Type: ErrorNode,
+		Data: data,
+		Loc:  []loc.Loc{{Start: start}, {Start: end}},
+		Span: loc.Span{Start: start, End: end},
+	}
+}
+
+func (p *parser) appendRecoveryError(err *loc.ErrorWithRange) {
+	if p.handler != nil {
+		p.handler.AppendError(err)
+	}
+}
+
+func (p *parser) appendRecoveryWarning(err *loc.ErrorWithRange) {
+	if p.handler != nil {
+		p.handler.AppendWarning(err)
+	}
+}
+
+// recoverUnclosedExpressions closes the expressions left open before an end tag matching an
+// element opened outside of them, e.g. the "</div>" of "<div>{value</div>". Without recovery,
+// the end tag is ignored and the expression swallows the rest of the component.
+func (p *parser) recoverUnclosedExpressions() {
+	for {
+		expression := -1
+		for i := len(p.oe) - 1; i >= 0 && expression == -1; i-- {
+			if p.oe[i].Expression {
+				expression = i
+			} else if p.oe[i].Type == ElementNode && p.oe[i].Data == p.tok.Data {
+				return
+			}
+		}
+		if expression == -1 || !p.hasOpenElementBelow(expression, p.tok.Data) {
+			return
+		}
+
+		start := p.tokenizer.raw.Start
+		before := fmt.Sprintf("`</%s>`", p.tok.Data)
+		if len(p.oe) > expression+1 {
+			for i := len(p.oe) - 1; i > expression; i-- {
+				p.recoverUnclosed(p.oe[i], before, start)
+				p.afe.remove(p.oe[i])
+			}
+			p.oe = p.oe[:expression+1]
+			p.resetInsertionMode()
+		}
+		n := p.oe[expression]
+		n.AppendChild(p.errorNode("", start, start))
+		p.appendRecoveryError(&loc.ErrorWithRange{
+			Code:  loc.ERROR_UNTERMINATED_EXPRESSION,
+			Text:  fmt.Sprintf("Unterminated expression before %s", before),
+			Hint:  "Add a closing `}` to the expression",
+			Range: loc.Range{Loc: n.Loc[0], Len: 1},
+		})
+
+		// The expression is closed as though a "}" had appeared right before the end tag
+		endTag := p.tok
+		p.tok = Token{Type: EndExpressionToken, Data: "}", Loc: loc.Loc{Start: start}}
+		p.parseCurrentToken()
+		p.tok = endTag
+		p.tokenizer.closeExpression(p.tok.Data)
+	}
+}
+
+// recoverMisnestedEndTag closes the elements left open in the element of the current end tag.
+// Literal mode would otherwise close the innermost element with the end tag, whatever its name.
+func (p *parser) recoverMisnestedEndTag() {
+	for i := len(p.oe) - 1; i >= 0; i-- {
+		n := p.oe[i]
+		if n.Type != ElementNode || n.Expression {
+			return
+		}
+		if !strings.EqualFold(n.Data, p.tok.Data) {
+			continue
+		}
+		start := p.tokenizer.raw.Start
+		before := fmt.Sprintf("`</%s>`", p.tok.Data)
+		for j := len(p.oe) - 1; j > i; j-- {
+			p.recoverUnclosed(p.oe[j], before, start)
+			p.afe.remove(p.oe[j])
+		}
+		p.oe = p.oe[:i+1]
+		return
+	}
+}
+
+// hasOpenElementBelow returns whether an element named data, other than the root elements,
+// is open below the index i of the stack of open elements
+func (p *parser) hasOpenElementBelow(i int, data string) bool {
+	for i--; i >= 0; i-- {
+		n := p.oe[i]
+		if n.Type != ElementNode || n.Expression || n.Data != data {
+			continue
+		}
+		switch n.DataAtom {
+		case a.Html, a.Head, a.Body:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// recoverAtEOF closes the frontmatter, expressions and elements still open at the end of the source
+func (p *parser) recoverAtEOF() {
+	end := len(p.tokenizer.buf)
+	if p.frontmatterState == FrontmatterOpen && p.fm != nil {
+		// The missing fence was reported by the tokenizer, when the frontmatter was opened
+		p.fm.AppendChild(p.errorNode("", end, end))
+	}
+	for i := len(p.oe) - 1; i >= 0; i-- {
+		p.recoverUnclosed(p.oe[i], "", end)
+	}
+}
+
+// recoverUnclosed reports n, an element or expression closed at offset without its own end tag,
+// before is the end tag closing it, if any. Elements with an optional end tag are ignored.
+func (p *parser) recoverUnclosed(n *Node, before string, offset int) {
+	if n.Type != ElementNode || len(n.Loc) == 0 || isImplicitNode(n) {
+		return
+	}
+	text := func(s string) string {
+		if before == "" {
+			return s
+		}
+		return fmt.Sprintf("%s before %s", s, before)
+	}
+	if n.Expression {
+		n.AppendChild(p.errorNode("", offset, offset))
+		p.appendRecoveryError(&loc.ErrorWithRange{
+			Code:  loc.ERROR_UNTERMINATED_EXPRESSION,
+			Text:  text("Unterminated expression"),
+			Hint:  "Add a closing `}` to the expression",
+			Range: loc.Range{Loc: n.Loc[0], Len: 1},
+		})
+		return
+	}
+	if hasOptionalEndTag(n) {
+		return
+	}
+	n.AppendChild(p.errorNode("", offset, offset))
+	p.appendRecoveryWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_UNCLOSED_ELEMENT,
+		Text:  text(fmt.Sprintf("`<%s>` is not closed", n.Data)),
+		Hint:  fmt.Sprintf("Add a closing `</%s>` tag", n.Data),
+		Range: startTagRange(n),
+	})
+}
+
+// startTagRange returns the range of the "<" and the name of the start tag of n. The location of
+// an element is its name, after the "<", except for the shorthand fragment `<>`.
+func startTagRange(n *Node) loc.Range {
+	start := n.Loc[0].Start
+	if n.Data != "" {
+		start -= len("<")
+	}
+	return loc.Range{Loc: loc.Loc{Start: max(start, 0)}, Len: len("<") + len(n.Data)}
+}
+
+// recoverUnbalancedEndTag reports the elements closed by the current end tag other than its own,
+// given open, the stack of open elements before the tag, or the end tag itself if it was ignored.
+func (p *parser) recoverUnbalancedEndTag(open nodeStack) {
+	if p.frontmatterState == FrontmatterOpen {
+		return
+	}
+	common := 0
+	for common < len(open) && common < len(p.oe) && open[common] == p.oe[common] {
+		common++
+	}
+	closed := open[common:]
+
+	// The element of the end tag is the outermost closed element with the same name
+	matched := -1
+	for i, n := range closed {
+		if n.Type == ElementNode && !n.Expression && n.Data == p.tok.Data {
+			matched = i
+			break
+		}
+	}
+	start := p.tokenizer.raw.Start
+	before := fmt.Sprintf("`</%s>`", p.tok.Data)
+	for i := len(closed) - 1; i > matched; i-- {
+		p.recoverUnclosed(closed[i], before, start)
+	}
+
+	if len(closed) == 0 && len(p.oe) == len(open) && !isEndTagWithoutElement(p.tok) {
+		p.recoverUnexpectedEndTag(fmt.Sprintf("Ignored `</%s>`, the open `<%s>` cannot be closed here", p.tok.Data, p.tok.Data))
+	}
+}
+
+// isUnexpectedEndTag returns whether the current end tag matches no open element
+func (p *parser) isUnexpectedEndTag() bool {
+	if p.frontmatterState == FrontmatterOpen || isEndTagWithoutElement(p.tok) {
+		return false
+	}
+	for _, n := range p.oe {
+		if n.Type == ElementNode && !n.Expression && strings.EqualFold(n.Data, p.tok.Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEndTagWithoutElement returns whether the parser expects the end tag t without a matching element
+func isEndTagWithoutElement(t Token) bool {
+	switch t.DataAtom {
+	case a.Html, a.Head, a.Body, a.P, a.Br:
+		return true
+	}
+	return false
+}
+
+// recoverUnexpectedEndTag marks the current end tag, which closes nothing, with an ErrorNode holding the tag
+func (p *parser) recoverUnexpectedEndTag(text string) {
+	start, end := p.tokenizer.raw.Start, p.tokenizer.raw.End
+	p.top().AppendChild(p.errorNode(string(p.tokenizer.buf[start:end]), start, end))
+	p.appendRecoveryWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_UNEXPECTED_END_TAG,
+		Text:  text,
+		Hint:  "Remove the end tag, or add the missing start tag",
+		Range: loc.Range{Loc: loc.Loc{Start: start}, Len: end - start},
+	})
+}
+
+// recoverStrayBrace replaces the "}" of the current text token, which closes no expression,
+// with an ErrorNode
+func (p *parser) recoverStrayBrace() {
+	// The "}" ends the raw text of the token
+	start := max(p.tokenizer.raw.End-len("}"), 0)
+	p.appendRecoveryWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_UNEXPECTED_BRACE,
+		Text:  "Unexpected `}`, no expression is open",
+		Hint:  "Use `{'}'}` to render a closing brace as text",
+		Range: loc.Range{Loc: loc.Loc{Start: start}, Len: len("}")},
+	})
+	n := p.top().LastChild
+	if n == nil || n.Type != TextNode || !strings.HasSuffix(n.Data, "}") {
+		return
+	}
+	n.Data = strings.TrimSuffix(n.Data, "}")
+	n.Parent.InsertBefore(p.errorNode("}", start, start+len("}")), n.NextSibling)
+	if n.Data == "" {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func isImplicitNode(n *Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == ImplicitNodeMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOptionalEndTag returns whether the end tag of n can be omitted in HTML
+func hasOptionalEndTag(n *Node) bool {
+	if n.Namespace != "" {
+		return false
+	}
+	switch n.DataAtom {
+	case a.Html, a.Head, a.Body, a.Li, a.Dt, a.Dd, a.P, a.Rb, a.Rt, a.Rtc, a.Rp, a.Optgroup, a.Option,
+		a.Colgroup, a.Caption, a.Thead, a.Tbody, a.Tfoot, a.Tr, a.Td, a.Th:
+		return true
+	}
+	return false
+}
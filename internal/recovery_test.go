@@ -0,0 +1,169 @@
+package astro
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+type recoveryTest struct {
+	name   string
+	source string
+	// errors lists the ErrorNodes of the tree as "data@start-end"
+	errors []string
+	codes  []loc.DiagnosticCode
+}
+
+func TestParseRecovery(t *testing.T) {
+	tests := []recoveryTest{
+		{
+			name:   "unterminated expression",
+			source: "<div>{value</div>",
+			errors: []string{"@11-11"},
+			codes:  []loc.DiagnosticCode{loc.ERROR_UNTERMINATED_EXPRESSION},
+		},
+		{
+			name:   "unterminated expression at EOF",
+			source: "<div>{value",
+			errors: []string{"@11-11", "@11-11"},
+			codes:  []loc.DiagnosticCode{loc.ERROR_UNTERMINATED_EXPRESSION, loc.WARNING_UNCLOSED_ELEMENT},
+		},
+		{
+			name:   "unterminated frontmatter",
+			source: "---\nconst a = 1;\n<div>x</div>",
+			errors: []string{"@17-17"},
+			codes:  []loc.DiagnosticCode{loc.ERROR_UNTERMINATED_FRONTMATTER},
+		},
+		{
+			name:   "unterminated frontmatter at EOF",
+			source: "---\nconst a = 1;",
+			errors: []string{"@16-16"},
+			codes:  []loc.DiagnosticCode{loc.ERROR_UNTERMINATED_FRONTMATTER},
+		},
+		{
+			name:   "stray brace",
+			source: "<div>a}b</div>",
+			errors: []string{"}@6-7"},
+			codes:  []loc.DiagnosticCode{loc.WARNING_UNEXPECTED_BRACE},
+		},
+		{
+			name:   "stray brace in an unclosed fragment",
+			source: "<>}\n=",
+			errors: []string{"}@2-3", "@5-5"},
+			codes:  []loc.DiagnosticCode{loc.WARNING_UNEXPECTED_BRACE, loc.WARNING_UNCLOSED_ELEMENT},
+		},
+		{
+			name:   "unclosed element",
+			source: "<div><span>x</div>",
+			errors: []string{"@12-12"},
+			codes:  []loc.DiagnosticCode{loc.WARNING_UNCLOSED_ELEMENT},
+		},
+		{
+			name:   "unexpected end tag",
+			source: "<div>x</span></div>",
+			errors: []string{"</span>@6-13"},
+			codes:  []loc.DiagnosticCode{loc.WARNING_UNEXPECTED_END_TAG},
+		},
+		{
+			name:   "unclosed elements at EOF",
+			source: "<main><div>x",
+			errors: []string{"@12-12", "@12-12"},
+			codes:  []loc.DiagnosticCode{loc.WARNING_UNCLOSED_ELEMENT, loc.WARNING_UNCLOSED_ELEMENT},
+		},
+		{
+			name:   "optional end tags",
+			source: "<ul><li>a<li>b</ul>",
+		},
+		{
+			name:   "valid component",
+			source: "---\nconst items = [1, 2];\n---\n<ul>{items.map(i => <li>{i}</li>)}</ul>\n<p>{'}'}</p>",
+		},
+	}
+
+	for _, literal := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				h := handler.NewHandler(tt.source, "")
+				doc, err := ParseWithOptions(strings.NewReader(tt.source), ParseOptionWithHandler(h), ParseOptionEnableLiteral(literal), ParseOptionEnableRecovery(true))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				errors := make([]string, 0)
+				walk(doc, func(n *Node) {
+					if n.Type == ErrorNode {
+						errors = append(errors, fmt.Sprintf("%s@%d-%d", n.Data, n.Loc[0].Start, n.Loc[1].Start))
+					}
+				})
+				if strings.Join(errors, ", ") != strings.Join(tt.errors, ", ") {
+					t.Errorf("literal=%v: expected error nodes %v, got %v", literal, tt.errors, errors)
+				}
+
+				codes := make([]loc.DiagnosticCode, 0)
+				for _, d := range h.Diagnostics() {
+					codes = append(codes, loc.DiagnosticCode(d.Code))
+				}
+				if len(codes) != len(tt.codes) {
+					t.Fatalf("literal=%v: expected diagnostics %v, got %v", literal, tt.codes, codes)
+				}
+				for i := range codes {
+					if codes[i] != tt.codes[i] {
+						t.Errorf("literal=%v: expected diagnostics %v, got %v", literal, tt.codes, codes)
+					}
+				}
+
+				// The error nodes keep the source of the component
+				var b strings.Builder
+				PrintToSource(&b, doc)
+				for _, text := range []string{"}", "</span>"} {
+					if strings.Count(b.String(), text) < strings.Count(tt.source, text) {
+						t.Errorf("literal=%v: expected %q to be printed, got %q", literal, text, b.String())
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParseRecoveryFragmentLocation(t *testing.T) {
+	source := "<>}\n="
+	h := handler.NewHandler(source, "file.astro")
+	_, err := ParseWithOptions(strings.NewReader(source), ParseOptionWithHandler(h), ParseOptionEnableLiteral(true), ParseOptionEnableRecovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := h.Diagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", diagnostics)
+	}
+	brace, fragment := diagnostics[0], diagnostics[1]
+	if brace.Location.Line != 1 || brace.Location.Column != 3 || brace.Location.Length != 1 {
+		t.Errorf("expected the stray brace at 1:3, got %+v", brace.Location)
+	}
+	if fragment.Location.Line != 1 || fragment.Location.Column != 1 || fragment.Location.Length != len("<") {
+		t.Errorf("expected the unclosed `<>` at 1:1, got %+v", fragment.Location)
+	}
+}
+
+func TestParseRecoveryLocation(t *testing.T) {
+	source := "<div>\n\t<span>{value\n</div>"
+	h := handler.NewHandler(source, "file.astro")
+	_, err := ParseWithOptions(strings.NewReader(source), ParseOptionWithHandler(h), ParseOptionEnableLiteral(true), ParseOptionEnableRecovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := h.Diagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", diagnostics)
+	}
+	expression, span := diagnostics[0], diagnostics[1]
+	if expression.Code != int(loc.ERROR_UNTERMINATED_EXPRESSION) || expression.Location.Line != 2 || expression.Location.Column != 8 {
+		t.Errorf("expected the unterminated expression at 2:8, got %+v", expression.Location)
+	}
+	if span.Code != int(loc.WARNING_UNCLOSED_ELEMENT) || span.Location.Line != 2 || span.Location.Column != 2 || span.Location.Length != len("<span") {
+		t.Errorf("expected the unclosed <span> at 2:2, got %+v", span.Location)
+	}
+}
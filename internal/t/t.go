@@ -3,4 +3,6 @@ package t
 type ParseOptions struct {
 	Filename string
 	Position bool
+	// FrontmatterAST emits the Program of the frontmatter
+	FrontmatterAST bool
 }
@@ -142,6 +142,12 @@ type Attribute struct {
 	ValLoc    loc.Loc
 	Tokenizer *Tokenizer
 	Type      AttributeType
+	// Span is the range of the whole attribute in the source, from its name to the closing quote
+	// or brace of its value. KeySpan and ValSpan are the ranges of its name and value, as in
+	// SourceAttribute. They are all zero for an attribute that is not in the source.
+	Span    loc.Span
+	KeySpan loc.Span
+	ValSpan loc.Span
 }
 
 type Expression struct {
@@ -161,6 +167,8 @@ type Token struct {
 	Data     string
 	Attr     []Attribute
 	Loc      loc.Loc
+	// Span is the range of the whole token in the source, zero for a token implied by the parser
+	Span loc.Span
 }
 
 // tagString returns a string representation of a tag Token's Data and Attr.
@@ -295,6 +303,14 @@ type Tokenizer struct {
 	convertNUL bool
 	// allowCDATA is whether CDATA sections are allowed in the current context.
 	allowCDATA bool
+	// recover is whether the tokenizer recovers from an unterminated frontmatter and
+	// flags stray closing braces, see ParseOptionEnableRecovery.
+	recover bool
+	// fmUnclosed is whether the open frontmatter has no closing fence. In recovery mode,
+	// it is closed before the first line starting with a tag instead.
+	fmUnclosed bool
+	// strayBrace is whether the current text token is a "}" closing no expression.
+	strayBrace bool
 
 	handler *handler.Handler
 }
@@ -1642,21 +1658,7 @@ func (z *Tokenizer) trackExpressionElementStack() {
 	if z.tt == StartTagToken {
 		z.expressionElementStack[i] = append(z.expressionElementStack[i], string(z.buf[z.data.Start:z.data.End]))
 	} else if z.tt == EndTagToken {
-		stack := z.expressionElementStack[i]
-		if len(stack) > 0 {
-			for j := 1; j < len(stack)+1; j++ {
-				tok := stack[len(stack)-j]
-				if tok == string(z.buf[z.data.Start:z.data.End]) {
-					// When stack is balanced, reset `openBraceIsExpressionStart`
-					if len(stack) == 1 {
-						z.expressionElementStack[i] = make([]string, 0)
-						z.openBraceIsExpressionStart = false
-					} else {
-						z.expressionElementStack[i] = stack[:len(stack)-1]
-					}
-				}
-			}
-		}
+		z.popExpressionElement(string(z.buf[z.data.Start:z.data.End]))
 	} else if z.tt == SelfClosingTagToken {
 		stack := z.expressionElementStack[i]
 		if len(stack) == 0 {
@@ -1666,12 +1668,90 @@ func (z *Tokenizer) trackExpressionElementStack() {
 	}
 }
 
+// frontmatterIsClosed looks ahead for the fence closing the frontmatter that was just opened
+func (z *Tokenizer) frontmatterIsClosed() bool {
+	ahead := &Tokenizer{
+		buf:     z.buf,
+		fm:      FrontmatterOpen,
+		raw:     loc.Span{Start: z.raw.End, End: z.raw.End},
+		handler: &handler.Handler{},
+	}
+	for {
+		switch ahead.Next() {
+		case FrontmatterFenceToken:
+			return true
+		case ErrorToken:
+			return false
+		}
+	}
+}
+
+// isAtTagLineStart returns whether the "<" that was just read starts a tag or a comment,
+// preceded by nothing but indentation on its line
+func (z *Tokenizer) isAtTagLineStart() bool {
+	i := z.raw.End - 1
+	if i+1 >= len(z.buf) {
+		return false
+	}
+	if c := z.buf[i+1]; !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '!') {
+		return false
+	}
+	for i--; i >= 0; i-- {
+		switch z.buf[i] {
+		case ' ', '\t':
+			continue
+		case '\n':
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// closeExpression closes the innermost open expression, when the parser recovers from a
+// missing "}". name is the end tag closing the expression, if any.
+func (z *Tokenizer) closeExpression(name string) {
+	if len(z.expressionStack) == 0 {
+		return
+	}
+	z.expressionStack = z.expressionStack[:len(z.expressionStack)-1]
+	z.expressionElementStack = z.expressionElementStack[:len(z.expressionElementStack)-1]
+	z.openBraceIsExpressionStart = z.noExpressionTag == ""
+	if name != "" {
+		z.popExpressionElement(name)
+	}
+}
+
+// popExpressionElement tracks the end tag of an element in the innermost open expression
+func (z *Tokenizer) popExpressionElement(name string) {
+	if len(z.expressionStack) == 0 {
+		return
+	}
+	i := len(z.expressionElementStack) - 1
+	stack := z.expressionElementStack[i]
+	if len(stack) > 0 {
+		for j := 1; j < len(stack)+1; j++ {
+			tok := stack[len(stack)-j]
+			if tok == name {
+				// When stack is balanced, reset `openBraceIsExpressionStart`
+				if len(stack) == 1 {
+					z.expressionElementStack[i] = make([]string, 0)
+					z.openBraceIsExpressionStart = false
+				} else {
+					z.expressionElementStack[i] = stack[:len(stack)-1]
+				}
+			}
+		}
+	}
+}
+
 // Next scans the next token and returns its type.
 func (z *Tokenizer) Next() TokenType {
 	z.prevToken = z.Token()
 	z.raw.Start = z.raw.End
 	z.data.Start = z.raw.End
 	z.data.End = z.raw.End
+	z.strayBrace = false
 	defer z.trackExpressionElementStack()
 
 	if z.rawTag != "" {
@@ -1883,17 +1963,38 @@ frontmatter_loop:
 				z.data.End = z.raw.End
 				z.tt = FrontmatterFenceToken
 				z.openBraceIsExpressionStart = false
+				if z.recover && !z.frontmatterIsClosed() {
+					z.fmUnclosed = true
+					if z.handler != nil {
+						z.handler.AppendError(&loc.ErrorWithRange{
+							Code:  loc.ERROR_UNTERMINATED_FRONTMATTER,
+							Text:  "Unterminated frontmatter",
+							Hint:  "Add a closing `---` fence at the end of the frontmatter",
+							Range: loc.Range{Loc: loc.Loc{Start: z.raw.End - len("---")}, Len: len("---")},
+						})
+					}
+				}
 				return z.tt
 			case FrontmatterOpen:
 				if z.raw.Start < z.raw.End-len("---") {
-					z.data.End = z.raw.End - len("---")
+					// The fence is read again as a token of its own
+					z.raw.End -= len("---")
+					z.data.End = z.raw.End
+					z.dashCount = 0
 					z.openBraceIsExpressionStart = false
 					z.tt = TextToken
 					return z.tt
 				}
 				z.fm = FrontmatterClosed
 				z.dashCount = 0
+				// The closing fence is located at its end, and includes the newline following it
+				z.data.Start = z.raw.End
 				z.data.End = z.raw.End
+				if bytes.HasPrefix(z.buf[z.raw.End:], []byte("\r\n")) {
+					z.raw.End += len("\r\n")
+				} else if bytes.HasPrefix(z.buf[z.raw.End:], []byte("\n")) {
+					z.raw.End += len("\n")
+				}
 				z.tt = FrontmatterFenceToken
 				z.openBraceIsExpressionStart = z.noExpressionTag == ""
 				return z.tt
@@ -1904,6 +2005,23 @@ frontmatter_loop:
 			continue frontmatter_loop
 		}
 
+		// Without a closing fence, the frontmatter ends before the first line starting with a tag
+		if c == '<' && z.fmUnclosed && z.isAtTagLineStart() {
+			z.raw.End--
+			z.dashCount = 0
+			z.data.End = z.raw.End
+			if z.raw.Start < z.raw.End {
+				z.tt = TextToken
+				return z.tt
+			}
+			// The fence closing the frontmatter is missing, so the token is empty
+			z.fm = FrontmatterClosed
+			z.fmUnclosed = false
+			z.openBraceIsExpressionStart = z.noExpressionTag == ""
+			z.tt = FrontmatterFenceToken
+			return z.tt
+		}
+
 		// JS Comment or RegExp
 		if c == '/' {
 			z.readCommentOrRegExp([]byte{})
@@ -2066,6 +2184,7 @@ expression_loop:
 			}
 		case '}':
 			if len(z.expressionStack) == 0 {
+				z.strayBrace = z.fm != FrontmatterOpen && z.noExpressionTag == ""
 				z.data.End = z.raw.End
 				z.tt = TextToken
 				return z.tt
@@ -2174,7 +2293,7 @@ func (z *Tokenizer) TagAttr() (key []byte, keyLoc loc.Loc, val []byte, valLoc lo
 // Token returns the current Token. The result's Data and Attr values remain
 // valid after subsequent Next calls.
 func (z *Tokenizer) Token() Token {
-	t := Token{Type: z.tt, Loc: z.Loc()}
+	t := Token{Type: z.tt, Loc: z.Loc(), Span: z.raw}
 
 	switch z.tt {
 	case StartExpressionToken:
@@ -2190,8 +2309,14 @@ func (z *Tokenizer) Token() Token {
 			var keyLoc, valLoc loc.Loc
 			var attrType AttributeType
 			var attrTokenizer *Tokenizer = nil
+			i := z.nAttrReturned
 			key, keyLoc, val, valLoc, attrType, moreAttr = z.TagAttr()
-			t.Attr = append(t.Attr, Attribute{"", atom.String(key), keyLoc, string(val), valLoc, attrTokenizer, attrType})
+			attr := Attribute{Key: atom.String(key), KeyLoc: keyLoc, Val: string(val), ValLoc: valLoc, Tokenizer: attrTokenizer, Type: attrType}
+			if i < len(z.attr) && z.tt != EndTagToken {
+				source := sourceAttribute(z.buf, z.attr[i], attrType)
+				attr.Span, attr.KeySpan, attr.ValSpan = attributeSpan(z.buf, source), source.Key, source.Val
+			}
+			t.Attr = append(t.Attr, attr)
 		}
 		if isFragment(string(name)) || isComponent(string(name)) {
 			t.DataAtom, t.Data = 0, string(name)
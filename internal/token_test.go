@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/withastro/compiler/internal/handler"
 	"github.com/withastro/compiler/internal/test_utils"
 )
 
@@ -26,111 +27,110 @@ type LocTest struct {
 	expected []int
 }
 
-func TestBasic(t *testing.T) {
-	Basic := []TokenTypeTest{
-		{
-			"doctype",
-			`<!DOCTYPE html>`,
-			[]TokenType{DoctypeToken},
-		},
-		{
-			"start tag",
-			`<html>`,
-			[]TokenType{StartTagToken},
-		},
-		{
-			"dot component",
-			`<pkg.Item>`,
-			[]TokenType{StartTagToken},
-		},
-		{
-			"noscript component",
-			`<noscript><Component /></noscript>`,
-			[]TokenType{StartTagToken, SelfClosingTagToken, EndTagToken},
-		},
-		{
-			"end tag",
-			`</html>`,
-			[]TokenType{EndTagToken},
-		},
-		{
-			"unclosed tag",
-			`<components.`,
-			[]TokenType{TextToken},
-		},
-		{
-			"self-closing tag (slash)",
-			`<meta charset="utf-8" />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"self-closing title",
-			`<title set:html={} /><div></div>`,
-			[]TokenType{SelfClosingTagToken, StartTagToken, EndTagToken},
-		},
-		{
-			"self-closing tag (no slash)",
-			`<img width="480" height="320">`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"text",
-			`Hello@`,
-			[]TokenType{TextToken},
-		},
-		{
-			"self-closing script",
-			`<script />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"self-closing script with sibling",
-			`<script /><div></div><div />`,
-			[]TokenType{SelfClosingTagToken, StartTagToken, EndTagToken, SelfClosingTagToken},
-		},
-		{
-			"self-closing style",
-			`<style />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"self-closing style with sibling",
-			`<style /><div></div><div />`,
-			[]TokenType{SelfClosingTagToken, StartTagToken, EndTagToken, SelfClosingTagToken},
-		},
-		{
-			"attribute with quoted template literal",
-			"<a :href=\"`/home`\">Home</a>",
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"No expressions inside math",
-			`<math>{test}</math>`,
-			[]TokenType{StartTagToken, TextToken, TextToken, TextToken, EndTagToken},
-		},
-		{
-			"No expressions inside math (complex)",
-			`<span><math xmlns="http://www.w3.org/1998/Math/MathML"><mo>4</mo><mi /><semantics><annotation encoding="application/x-tex">\sqrt {x}</annotation></semantics></math></span>`,
-			[]TokenType{StartTagToken, StartTagToken, StartTagToken, TextToken, EndTagToken, SelfClosingTagToken, StartTagToken, StartTagToken, TextToken, TextToken, TextToken, TextToken, EndTagToken, EndTagToken, EndTagToken, EndTagToken},
-		},
-		{
-			"Expression attributes allowed inside math",
-			`<math set:html={test} />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"SVG (self-closing)",
-			`<svg><path/></svg>`,
-			[]TokenType{StartTagToken, SelfClosingTagToken, EndTagToken},
-		},
-		{
-			"SVG (left open)",
-			`<svg><path></svg>`, // note: this test isn’t “ideal” it’s just testing current behavior
-			[]TokenType{StartTagToken, StartTagToken, EndTagToken},
-		},
-		{
-			"SVG with style",
-			`<svg><style>
+var basicTests = []TokenTypeTest{
+	{
+		"doctype",
+		`<!DOCTYPE html>`,
+		[]TokenType{DoctypeToken},
+	},
+	{
+		"start tag",
+		`<html>`,
+		[]TokenType{StartTagToken},
+	},
+	{
+		"dot component",
+		`<pkg.Item>`,
+		[]TokenType{StartTagToken},
+	},
+	{
+		"noscript component",
+		`<noscript><Component /></noscript>`,
+		[]TokenType{StartTagToken, SelfClosingTagToken, EndTagToken},
+	},
+	{
+		"end tag",
+		`</html>`,
+		[]TokenType{EndTagToken},
+	},
+	{
+		"unclosed tag",
+		`<components.`,
+		[]TokenType{TextToken},
+	},
+	{
+		"self-closing tag (slash)",
+		`<meta charset="utf-8" />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"self-closing title",
+		`<title set:html={} /><div></div>`,
+		[]TokenType{SelfClosingTagToken, StartTagToken, EndTagToken},
+	},
+	{
+		"self-closing tag (no slash)",
+		`<img width="480" height="320">`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"text",
+		`Hello@`,
+		[]TokenType{TextToken},
+	},
+	{
+		"self-closing script",
+		`<script />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"self-closing script with sibling",
+		`<script /><div></div><div />`,
+		[]TokenType{SelfClosingTagToken, StartTagToken, EndTagToken, SelfClosingTagToken},
+	},
+	{
+		"self-closing style",
+		`<style />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"self-closing style with sibling",
+		`<style /><div></div><div />`,
+		[]TokenType{SelfClosingTagToken, StartTagToken, EndTagToken, SelfClosingTagToken},
+	},
+	{
+		"attribute with quoted template literal",
+		"<a :href=\"`/home`\">Home</a>",
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"No expressions inside math",
+		`<math>{test}</math>`,
+		[]TokenType{StartTagToken, TextToken, TextToken, TextToken, EndTagToken},
+	},
+	{
+		"No expressions inside math (complex)",
+		`<span><math xmlns="http://www.w3.org/1998/Math/MathML"><mo>4</mo><mi /><semantics><annotation encoding="application/x-tex">\sqrt {x}</annotation></semantics></math></span>`,
+		[]TokenType{StartTagToken, StartTagToken, StartTagToken, TextToken, EndTagToken, SelfClosingTagToken, StartTagToken, StartTagToken, TextToken, TextToken, TextToken, TextToken, EndTagToken, EndTagToken, EndTagToken, EndTagToken},
+	},
+	{
+		"Expression attributes allowed inside math",
+		`<math set:html={test} />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"SVG (self-closing)",
+		`<svg><path/></svg>`,
+		[]TokenType{StartTagToken, SelfClosingTagToken, EndTagToken},
+	},
+	{
+		"SVG (left open)",
+		`<svg><path></svg>`, // note: this test isn’t “ideal” it’s just testing current behavior
+		[]TokenType{StartTagToken, StartTagToken, EndTagToken},
+	},
+	{
+		"SVG with style",
+		`<svg><style>
 				#fire {
 					fill: orange;
 					stroke: purple;
@@ -139,77 +139,77 @@ func TestBasic(t *testing.T) {
 					fill: black;
 				}
 		</style><path id="#fire" d="M0,0 M340,29"></path><path class="wordmark" d="M0,0 M340,29"></path></svg>`,
-			[]TokenType{StartTagToken, StartTagToken, TextToken, EndTagToken, StartTagToken, EndTagToken, StartTagToken, EndTagToken, EndTagToken},
-		},
-		{
-			"form element with expression follwed by another form",
-			`<form>{data.formLabelA}</form><form><button></button></form>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, StartTagToken, StartTagToken, EndTagToken, EndTagToken},
-		},
-		{
-			"text",
-			"test",
-			[]TokenType{TextToken},
-		},
-		{
-			"comment",
-			`<!-- comment -->`,
-			[]TokenType{CommentToken},
-		},
-		{
-			"top-level expression",
-			`{ value }`,
-			[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
-		},
-		{
-			"expression inside element",
-			`<div>{ value }</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with solidus inside element",
-			`<div>{ 16 / 4 }</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with strings inside element",
-			`<div>{ "string" + 16 / 4 + "}" }</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression inside component",
-			`<Component>{items.map(item => <div>{item}</div>)}</Component>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression inside component with quoted attr",
-			`<Component a="b">{items.map(item => <div>{item}</div>)}</Component>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression inside component with expression attr",
-			`<Component data={data}>{items.map(item => <div>{item}</div>)}</Component>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression inside component with named expression attr",
-			`<Component named={data}>{items.map(item => <div>{item}</div>)}</Component>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with multiple returns",
-			`<div>{() => {
+		[]TokenType{StartTagToken, StartTagToken, TextToken, EndTagToken, StartTagToken, EndTagToken, StartTagToken, EndTagToken, EndTagToken},
+	},
+	{
+		"form element with expression follwed by another form",
+		`<form>{data.formLabelA}</form><form><button></button></form>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, StartTagToken, StartTagToken, EndTagToken, EndTagToken},
+	},
+	{
+		"text",
+		"test",
+		[]TokenType{TextToken},
+	},
+	{
+		"comment",
+		`<!-- comment -->`,
+		[]TokenType{CommentToken},
+	},
+	{
+		"top-level expression",
+		`{ value }`,
+		[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
+	},
+	{
+		"expression inside element",
+		`<div>{ value }</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with solidus inside element",
+		`<div>{ 16 / 4 }</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with strings inside element",
+		`<div>{ "string" + 16 / 4 + "}" }</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression inside component",
+		`<Component>{items.map(item => <div>{item}</div>)}</Component>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression inside component with quoted attr",
+		`<Component a="b">{items.map(item => <div>{item}</div>)}</Component>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression inside component with expression attr",
+		`<Component data={data}>{items.map(item => <div>{item}</div>)}</Component>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression inside component with named expression attr",
+		`<Component named={data}>{items.map(item => <div>{item}</div>)}</Component>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with multiple returns",
+		`<div>{() => {
 			let generate = (input) => {
 				let a = () => { return; };
 				let b = () => { return; };
 				let c = () => { return; };
 			};
 		}}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with multiple elements",
-			`<div>{() => {
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with multiple elements",
+		`<div>{() => {
 				if (value > 0.25) {
 					return <span>Default</span>
 				} else if (value > 0.5) {
@@ -219,11 +219,11 @@ func TestBasic(t *testing.T) {
 				}
 				return <span>Yet Other</span>
 			}}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with multiple elements returning self closing tags",
-			`<div>{()=>{
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with multiple elements returning self closing tags",
+		`<div>{()=>{
 				if (true) {
 					return <hr />;
 				};
@@ -231,11 +231,11 @@ func TestBasic(t *testing.T) {
 					return <img />;
 				}
 			}}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression returning a mix of self-closing tags and elements",
-			`<div>{() => {
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression returning a mix of self-closing tags and elements",
+		`<div>{() => {
 				if (value > 0.25) {
 					return <br />
 				} else if (value > 0.5) {
@@ -245,16 +245,16 @@ func TestBasic(t *testing.T) {
 				}
 				return <div>Yaaay</div>
 			}}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with switch returning a mix of self-closing tags and elements",
-			`<div>{items.map(({ type, ...data }) => { switch (type) { case 'card': { return (<Card {...data} />);}case 'paragraph': { return (<p>{data.body}</p>);}}})}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with < operators",
-			`<div>{() => {
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with switch returning a mix of self-closing tags and elements",
+		`<div>{items.map(({ type, ...data }) => { switch (type) { case 'card': { return (<Card {...data} />);}case 'paragraph': { return (<p>{data.body}</p>);}}})}</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with < operators",
+		`<div>{() => {
 				if (value < 0.25) {
 					return <span>Default</span>
 				} else if (value <0.5) {
@@ -264,297 +264,297 @@ func TestBasic(t *testing.T) {
 				}
 				return <span>Yet Other</span>
 			}}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
 
-		{
-			"attribute expression with quoted braces",
-			`<div value={"{"} />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"attribute expression with solidus",
-			`<div value={100 / 2} />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"attribute expression with solidus inside template literal",
-			"<div value={attr ? `a/b` : \"c\"} />",
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"complex attribute expression",
-			"<div value={`${attr ? `a/b ${`c ${`d ${cool}`}`}` : \"d\"} awesome`} />",
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"attribute expression with solidus no spaces",
-			`<div value={(100/2)} />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"attribute expression with quote",
-			`<div value={/* hello */} />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"JSX-style comment inside element",
-			`<div {/* hello */} a=b />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"quotes within textContent",
-			`<p>can't</p>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"apostrophe within title",
-			`<title>Astro's</title>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"quotes within title",
-			`<title>My Astro "Website"</title>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"textarea inside expression",
-			`
+	{
+		"attribute expression with quoted braces",
+		`<div value={"{"} />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"attribute expression with solidus",
+		`<div value={100 / 2} />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"attribute expression with solidus inside template literal",
+		"<div value={attr ? `a/b` : \"c\"} />",
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"complex attribute expression",
+		"<div value={`${attr ? `a/b ${`c ${`d ${cool}`}`}` : \"d\"} awesome`} />",
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"attribute expression with solidus no spaces",
+		`<div value={(100/2)} />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"attribute expression with quote",
+		`<div value={/* hello */} />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"JSX-style comment inside element",
+		`<div {/* hello */} a=b />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"quotes within textContent",
+		`<p>can't</p>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"apostrophe within title",
+		`<title>Astro's</title>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"quotes within title",
+		`<title>My Astro "Website"</title>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"textarea inside expression",
+		`
 						{bool && <textarea>It was a dark and stormy night...</textarea>}
 						{bool && <input>}
 					`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken, TextToken, StartExpressionToken, TextToken, SelfClosingTagToken, EndExpressionToken, TextToken},
-		},
-		{
-			"text containing a /",
-			"<span>next/router</span>",
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"iframe allows attributes",
-			"<iframe src=\"https://google.com\"></iframe>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"is:raw allows children to be parsed as Text",
-			"<span is:raw>function foo() { }</span>",
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"is:raw treats all children as raw text",
-			"<Fragment is:raw><ul></ue></Fragment>",
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"is:raw treats all children as raw text",
-			"<Fragment is:raw><ul></ue></Fragment>",
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"is:raw allows other attributes",
-			"<span data-raw={true} is:raw><%= Hi =%></span>",
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"Doesn't throw on other data attributes",
-			"<span data-foo></span>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"Doesn't work if attr is named data",
-			"<span data>{Hello}</span>",
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"Supports <style> inside of <svg>",
-			`<svg><style><div>:root { color: red; }</style></svg>`,
-			[]TokenType{StartTagToken, StartTagToken, TextToken, EndTagToken, EndTagToken},
-		},
-		{
-			"multiple scoped :global",
-			`<style>:global(test-2) {}</style><style>test-1{}</style>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken, StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"multiple styles",
-			`<style global>a {}</style><style>b {}</style><style>c {}</style>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken, StartTagToken, TextToken, EndTagToken, StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"element with single quote",
-			`<div>Don't panic</div>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"fragment",
-			`<>foo</>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"fragment shorthand",
-			`<h1>A{cond && <>item <span>{text}</span></>}</h1>`,
-			[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, StartTagToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, EndTagToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"fragment",
-			`<Fragment>foo</Fragment>`,
-			[]TokenType{StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"fragment shorthand in nested expression",
-			`<div>{x.map((x) => (<>{x ? "truthy" : "falsy"}</>))}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"select with expression",
-			`<select>{[1, 2, 3].map(num => <option>{num}</option>)}</select>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"select with expression",
-			`<select>{[1, 2, 3].map(num => <option>{num}</option>)}</select><div>Hello</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken, StartTagToken, TextToken, EndTagToken},
-		},
-		{
-			"single open brace",
-			"<main id={`{`}></main>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"single close brace",
-			"<main id={`}`}></main>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"extra close brace",
-			"<main id={`${}}`}></main>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"Empty expression",
-			"({})",
-			[]TokenType{TextToken, StartExpressionToken, EndExpressionToken, TextToken},
-		},
-		{
-			"expression after text",
-			`<h1>A{cond && <span>Test {text}</span>}</h1>`,
-			[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression surrounded by text",
-			`<h1>A{cond && <span>Test {text} Cool</span>}</h1>`,
-			[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, EndTagToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"switch statement",
-			`<div>{() => { switch(value) { case 'a': return <A></A>; case 'b': return <B />; case 'c': return <C></C> }}}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, EndTagToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, StartTagToken, EndTagToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"switch statement with expression",
-			`<div>{() => { switch(value) { case 'a': return <A>{value}</A>; case 'b': return <B />; case 'c': return <C>{value.map(i => <span>{i}</span>)}</C> }}}</div>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"attribute expression with unmatched quotes",
-			"<h1 set:text={`Houston we've got a problem`}></h1>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"attribute expression with unmatched quotes",
-			"<h1 set:html={`Oh \"no...`}></h1>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"attribute expression with unmatched quotes inside matched quotes",
-			"<h1 set:html={\"hello y'all\"}></h1>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"attribute expression with unmatched quotes inside matched quotes II",
-			"<h1 set:html={'\"Did Nate handle this case, too?\", Fred pondered...'}></h1>",
-			[]TokenType{StartTagToken, EndTagToken},
-		},
-		{
-			"typescript generic",
-			`<ul>{items.map((item: Item<Checkbox>)) => <li>{item.checked}</li>)}</ul>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"typescript generic II",
-			`<ul>{items.map((item: Item<Checkbox>)) => <Checkbox>{item.checked}</Checkbox>)}</ul>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"incomplete tag",
-			`<MyAstroComponent`,
-			[]TokenType{TextToken},
-		},
-		{
-			"incomplete tag II",
-			`<MyAstroComponent` + "\n",
-			[]TokenType{TextToken},
-		},
-		{
-			"incomplete tag III",
-			`<div></div><MyAstroComponent` + "\n",
-			[]TokenType{StartTagToken, EndTagToken, TextToken},
-		},
-	}
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken, TextToken, StartExpressionToken, TextToken, SelfClosingTagToken, EndExpressionToken, TextToken},
+	},
+	{
+		"text containing a /",
+		"<span>next/router</span>",
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"iframe allows attributes",
+		"<iframe src=\"https://google.com\"></iframe>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"is:raw allows children to be parsed as Text",
+		"<span is:raw>function foo() { }</span>",
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"is:raw treats all children as raw text",
+		"<Fragment is:raw><ul></ue></Fragment>",
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"is:raw treats all children as raw text",
+		"<Fragment is:raw><ul></ue></Fragment>",
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"is:raw allows other attributes",
+		"<span data-raw={true} is:raw><%= Hi =%></span>",
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"Doesn't throw on other data attributes",
+		"<span data-foo></span>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"Doesn't work if attr is named data",
+		"<span data>{Hello}</span>",
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"Supports <style> inside of <svg>",
+		`<svg><style><div>:root { color: red; }</style></svg>`,
+		[]TokenType{StartTagToken, StartTagToken, TextToken, EndTagToken, EndTagToken},
+	},
+	{
+		"multiple scoped :global",
+		`<style>:global(test-2) {}</style><style>test-1{}</style>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken, StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"multiple styles",
+		`<style global>a {}</style><style>b {}</style><style>c {}</style>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken, StartTagToken, TextToken, EndTagToken, StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"element with single quote",
+		`<div>Don't panic</div>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"fragment",
+		`<>foo</>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"fragment shorthand",
+		`<h1>A{cond && <>item <span>{text}</span></>}</h1>`,
+		[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, StartTagToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, EndTagToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"fragment",
+		`<Fragment>foo</Fragment>`,
+		[]TokenType{StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"fragment shorthand in nested expression",
+		`<div>{x.map((x) => (<>{x ? "truthy" : "falsy"}</>))}</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"select with expression",
+		`<select>{[1, 2, 3].map(num => <option>{num}</option>)}</select>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"select with expression",
+		`<select>{[1, 2, 3].map(num => <option>{num}</option>)}</select><div>Hello</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken, StartTagToken, TextToken, EndTagToken},
+	},
+	{
+		"single open brace",
+		"<main id={`{`}></main>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"single close brace",
+		"<main id={`}`}></main>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"extra close brace",
+		"<main id={`${}}`}></main>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"Empty expression",
+		"({})",
+		[]TokenType{TextToken, StartExpressionToken, EndExpressionToken, TextToken},
+	},
+	{
+		"expression after text",
+		`<h1>A{cond && <span>Test {text}</span>}</h1>`,
+		[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression surrounded by text",
+		`<h1>A{cond && <span>Test {text} Cool</span>}</h1>`,
+		[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, EndTagToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"switch statement",
+		`<div>{() => { switch(value) { case 'a': return <A></A>; case 'b': return <B />; case 'c': return <C></C> }}}</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, EndTagToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, StartTagToken, EndTagToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"switch statement with expression",
+		`<div>{() => { switch(value) { case 'a': return <A>{value}</A>; case 'b': return <B />; case 'c': return <C>{value.map(i => <span>{i}</span>)}</C> }}}</div>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, SelfClosingTagToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"attribute expression with unmatched quotes",
+		"<h1 set:text={`Houston we've got a problem`}></h1>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"attribute expression with unmatched quotes",
+		"<h1 set:html={`Oh \"no...`}></h1>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"attribute expression with unmatched quotes inside matched quotes",
+		"<h1 set:html={\"hello y'all\"}></h1>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"attribute expression with unmatched quotes inside matched quotes II",
+		"<h1 set:html={'\"Did Nate handle this case, too?\", Fred pondered...'}></h1>",
+		[]TokenType{StartTagToken, EndTagToken},
+	},
+	{
+		"typescript generic",
+		`<ul>{items.map((item: Item<Checkbox>)) => <li>{item.checked}</li>)}</ul>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"typescript generic II",
+		`<ul>{items.map((item: Item<Checkbox>)) => <Checkbox>{item.checked}</Checkbox>)}</ul>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"incomplete tag",
+		`<MyAstroComponent`,
+		[]TokenType{TextToken},
+	},
+	{
+		"incomplete tag II",
+		`<MyAstroComponent` + "\n",
+		[]TokenType{TextToken},
+	},
+	{
+		"incomplete tag III",
+		`<div></div><MyAstroComponent` + "\n",
+		[]TokenType{StartTagToken, EndTagToken, TextToken},
+	},
+}
 
-	runTokenTypeTest(t, Basic)
+func TestBasic(t *testing.T) {
+	runTokenTypeTest(t, basicTests)
 }
 
-func TestFrontmatter(t *testing.T) {
-	Frontmatter := []TokenTypeTest{
-		{
-			"simple token",
-			`---`,
-			[]TokenType{FrontmatterFenceToken},
-		},
-		{
-			"basic case",
-			`
+var frontmatterTests = []TokenTypeTest{
+	{
+		"simple token",
+		`---`,
+		[]TokenType{FrontmatterFenceToken},
+	},
+	{
+		"basic case",
+		`
 			---
 			const a = 0;
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"ignores leading whitespace",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"ignores leading whitespace",
+		`
 
 			---
 			const a = 0;
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"allows leading comments",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"allows leading comments",
+		`
 			<!-- Why? Who knows! -->
 			---
 			const a = 0;
 			---
 			`,
-			[]TokenType{CommentToken, FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"treated as text after element",
-			`
+		[]TokenType{CommentToken, FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"treated as text after element",
+		`
 			<div />
 
 			---
 			const a = 0;
 			---
 			`,
-			[]TokenType{SelfClosingTagToken, TextToken},
-		},
-		{
-			"treated as text after closed",
-			`
+		[]TokenType{SelfClosingTagToken, TextToken},
+	},
+	{
+		"treated as text after closed",
+		`
 			---
 			const a = 0;
 			---
@@ -562,41 +562,41 @@ func TestFrontmatter(t *testing.T) {
 			---
 			</div>
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken},
-		},
-		{
-			"does not tokenize elements inside",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken},
+	},
+	{
+		"does not tokenize elements inside",
+		`
 			---
 			const a = <div />;
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"no elements or expressions in frontmatter",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"no elements or expressions in frontmatter",
+		`
 			---
 			const contents = "foo";
 			const a = <div>{contents}</div>;
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"brackets within frontmatter treated as text",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"brackets within frontmatter treated as text",
+		`
 			---
 			const someProps = {
 				count: 0,
 			}
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"frontmatter tags and brackets all treated as text",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"frontmatter tags and brackets all treated as text",
+		`
 			---
 			const contents = "foo";
 			const a = <ul>{contents}</ul>
@@ -605,399 +605,432 @@ func TestFrontmatter(t *testing.T) {
 			}
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"less than isn’t a tag",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"less than isn’t a tag",
+		`
 			---
 			const a = 2;
 			const div = 4
 			const isBigger = a < div;
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"less than attr",
-			`<div aria-hidden={count < 1} />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"greater than attr",
-			`<div aria-hidden={count > 1} />`,
-			[]TokenType{SelfClosingTagToken},
-		},
-		{
-			"greater than attr inside expression",
-			`{values.map(value => <div aria-hidden={count > 1} />)}`,
-			[]TokenType{StartExpressionToken, TextToken, SelfClosingTagToken, TextToken, EndExpressionToken},
-		},
-		{
-			"single-line comments",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"less than attr",
+		`<div aria-hidden={count < 1} />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"greater than attr",
+		`<div aria-hidden={count > 1} />`,
+		[]TokenType{SelfClosingTagToken},
+	},
+	{
+		"greater than attr inside expression",
+		`{values.map(value => <div aria-hidden={count > 1} />)}`,
+		[]TokenType{StartExpressionToken, TextToken, SelfClosingTagToken, TextToken, EndExpressionToken},
+	},
+	{
+		"single-line comments",
+		`
 			---
 			// --- <div>
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"multi-line comments",
-			`
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"multi-line comments",
+		`
 			---
 			/* --- <div> */
 			---
 			`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken},
-		},
-		{
-			"RegExp",
-			`---
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken},
+	},
+	{
+		"RegExp",
+		`---
 const RegExp = /---< > > { }; import thing from "thing"; /
 ---
 			{html}`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken},
-		},
-		{
-			"RegExp with Escape",
-			`---
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, FrontmatterFenceToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken},
+	},
+	{
+		"RegExp with Escape",
+		`---
 export async function getStaticPaths() {
   const pattern = /\.md$/g;
 }
 ---
 <div />`,
-			[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken, SelfClosingTagToken},
-		},
-		{
-			"textarea",
-			`<textarea>{html}</textarea>`,
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		// {
-		// 	"less than with no space isn’t a tag",
-		// 	`
-		// 	---
-		// 	const a = 2;
-		// 	const div = 4
-		// 	const isBigger = a <div
-		// 	---
-		// 	`,
-		// 	[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
-		// },
-	}
+		[]TokenType{FrontmatterFenceToken, TextToken, TextToken, TextToken, TextToken, TextToken, TextToken, FrontmatterFenceToken, SelfClosingTagToken},
+	},
+	{
+		"textarea",
+		`<textarea>{html}</textarea>`,
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	// {
+	// 	"less than with no space isn’t a tag",
+	// 	`
+	// 	---
+	// 	const a = 2;
+	// 	const div = 4
+	// 	const isBigger = a <div
+	// 	---
+	// 	`,
+	// 	[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken},
+	// },
+	{
+		"element right after the closing fence",
+		`
+			---
+			const a = 0;
+			---<div></div>
+			`,
+		[]TokenType{FrontmatterFenceToken, TextToken, FrontmatterFenceToken, StartTagToken, EndTagToken, TextToken},
+	},
+}
 
-	runTokenTypeTest(t, Frontmatter)
+func TestFrontmatter(t *testing.T) {
+	runTokenTypeTest(t, frontmatterTests)
 }
 
-func TestExpressions(t *testing.T) {
-	Expressions := []TokenTypeTest{
-		{
-			"simple expression",
-			`{value}`,
-			[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
-		},
-		{
-			"object expression",
-			`{{ value }}`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"tag expression",
-			`{<div />}`,
-			[]TokenType{StartExpressionToken, SelfClosingTagToken, EndExpressionToken},
-		},
-		{
-			"string expression",
-			`{"<div {attr} />"}`,
-			[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
-		},
-		{
-			"function expression",
-			`{() => {
+var expressionTests = []TokenTypeTest{
+	{
+		"simple expression",
+		`{value}`,
+		[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
+	},
+	{
+		"object expression",
+		`{{ value }}`,
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"tag expression",
+		`{<div />}`,
+		[]TokenType{StartExpressionToken, SelfClosingTagToken, EndExpressionToken},
+	},
+	{
+		"string expression",
+		`{"<div {attr} />"}`,
+		[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
+	},
+	{
+		"function expression",
+		`{() => {
 				return value
 			}}`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"nested one level",
-			`{() => {
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"nested one level",
+		`{() => {
 				return <div>{value}</div>
 			}}`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"nested one level with self-closing tag before expression",
-			`{() => {
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"nested one level with self-closing tag before expression",
+		`{() => {
 				return <div><div />{value}</div>
 			}}`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, SelfClosingTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"nested two levels",
-			`{() => {
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, SelfClosingTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"nested two levels",
+		`{() => {
 				return <div>{() => {
 					return value
 				}}</div>
 			}}`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"nested two levels with tag",
-			`{() => {
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"nested two levels with tag",
+		`{() => {
 				return <div>{() => {
 					return <div>{value}</div>
 				}}</div>
 			}}`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"expression map",
-			`<div>
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken, EndTagToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"expression map",
+		`<div>
 			  {items.map((item) => (
 		      // < > < }
 		      <div>{item}</div>
 		    ))}
 		  </div>`,
-			[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, TextToken, EndTagToken},
-		},
-		{
-			"left bracket within string",
-			`{"{"}`,
-			[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
-		},
-		{
-			"right bracket within string",
-			`{'}'}`,
-			[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
-		},
-		{
-			"expression within string",
-			`{'{() => <Component />}'}`,
-			[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
-		},
-		{
-			"expression within single-line comment",
-			`{ // < > < }
+		[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken, TextToken, EndExpressionToken, TextToken, EndTagToken},
+	},
+	{
+		"left bracket within string",
+		`{"{"}`,
+		[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
+	},
+	{
+		"right bracket within string",
+		`{'}'}`,
+		[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
+	},
+	{
+		"expression within string",
+		`{'{() => <Component />}'}`,
+		[]TokenType{StartExpressionToken, TextToken, EndExpressionToken},
+	},
+	{
+		"expression within single-line comment",
+		`{ // < > < }
 		    'text'
 		  }`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"expression within multi-line comment",
-			`{/* < > < } */ 'text'}`,
-			[]TokenType{StartExpressionToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"expression with nested strings",
-			"{`${`${`${foo}`}`}`}",
-			[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken},
-		},
-		{
-			"element with multiple expressions",
-			"<div>Hello {first} {last}</div>",
-			[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"ternary render",
-			"{false ? <div>#f</div> : <div>#t</div>}",
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-		{
-			"title",
-			"<title>test {expr} test</title>",
-			[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, EndTagToken},
-		},
-		{
-			"String interpolation inside an expression within a title",
-			"<title>{content.title && `${title} 🚀 ${title}`}</title>",
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"Nested use of string templates inside expressions",
-			"<div>{`${a} inner${a > 1 ? 's' : ''}.`}</div>",
-			[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
-		},
-		{
-			"expression with single quote",
-			`{true && <div>Don't panic</div>}`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-		{
-			"expression with double quote",
-			`{true && <div>Don't panic</div>}`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-		{
-			"expression with literal quote",
-			`{true && <div>Don` + "`" + `t panic</div>}`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-		{
-			"ternary expression with single quote",
-			`{true ? <div>Don't panic</div> : <div>Do' panic</div>}`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-		{
-			"single quote after expression",
-			`{true && <div>{value} Don't panic</div>}`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-		{
-			"single quote after self-closing",
-			`{true && <div><span /> Don't panic</div>}`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, SelfClosingTagToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-		{
-			"single quote after end tag",
-			`{true && <div><span></span> Don't panic</div>}`,
-			[]TokenType{StartExpressionToken, TextToken, StartTagToken, StartTagToken, EndTagToken, TextToken, EndTagToken, EndExpressionToken},
-		},
-	}
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"expression within multi-line comment",
+		`{/* < > < } */ 'text'}`,
+		[]TokenType{StartExpressionToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"expression with nested strings",
+		"{`${`${`${foo}`}`}`}",
+		[]TokenType{StartExpressionToken, TextToken, TextToken, TextToken, TextToken, TextToken, EndExpressionToken},
+	},
+	{
+		"element with multiple expressions",
+		"<div>Hello {first} {last}</div>",
+		[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"ternary render",
+		"{false ? <div>#f</div> : <div>#t</div>}",
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+	{
+		"title",
+		"<title>test {expr} test</title>",
+		[]TokenType{StartTagToken, TextToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, EndTagToken},
+	},
+	{
+		"String interpolation inside an expression within a title",
+		"<title>{content.title && `${title} 🚀 ${title}`}</title>",
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"Nested use of string templates inside expressions",
+		"<div>{`${a} inner${a > 1 ? 's' : ''}.`}</div>",
+		[]TokenType{StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, EndTagToken},
+	},
+	{
+		"expression with single quote",
+		`{true && <div>Don't panic</div>}`,
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+	{
+		"expression with double quote",
+		`{true && <div>Don't panic</div>}`,
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+	{
+		"expression with literal quote",
+		`{true && <div>Don` + "`" + `t panic</div>}`,
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+	{
+		"ternary expression with single quote",
+		`{true ? <div>Don't panic</div> : <div>Do' panic</div>}`,
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, TextToken, EndTagToken, TextToken, StartTagToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+	{
+		"single quote after expression",
+		`{true && <div>{value} Don't panic</div>}`,
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, StartExpressionToken, TextToken, EndExpressionToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+	{
+		"single quote after self-closing",
+		`{true && <div><span /> Don't panic</div>}`,
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, SelfClosingTagToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+	{
+		"single quote after end tag",
+		`{true && <div><span></span> Don't panic</div>}`,
+		[]TokenType{StartExpressionToken, TextToken, StartTagToken, StartTagToken, EndTagToken, TextToken, EndTagToken, EndExpressionToken},
+	},
+}
 
-	runTokenTypeTest(t, Expressions)
+func TestExpressions(t *testing.T) {
+	runTokenTypeTest(t, expressionTests)
 }
 
-func TestAttributes(t *testing.T) {
-	Attributes := []AttributeTest{
-		{
-			"double quoted",
-			`<div a="value" />`,
-			[]AttributeType{QuotedAttribute},
-		},
-		{
-			"single quoted",
-			`<div a='value' />`,
-			[]AttributeType{QuotedAttribute},
-		},
-		{
-			"not quoted",
-			`<div a=value />`,
-			[]AttributeType{QuotedAttribute},
-		},
-		{
-			"expression",
-			`<div a={value} />`,
-			[]AttributeType{ExpressionAttribute},
-		},
-		{
-			"expression with apostrophe",
-			`<div a="fred's" />`,
-			[]AttributeType{QuotedAttribute},
-		},
-		{
-			"expression with template literal",
-			"<div a=\"`value`\" />",
-			[]AttributeType{QuotedAttribute},
-		},
-		{
-			"expression with template literal interpolation",
-			"<div a=\"`${value}`\" />",
-			[]AttributeType{QuotedAttribute},
-		},
-		{
-			"shorthand",
-			`<div {value} />`,
-			[]AttributeType{ShorthandAttribute},
-		},
-		{
-			"less than expression",
-			`<div a={a < b} />`,
-			[]AttributeType{ExpressionAttribute},
-		},
-		{
-			"greater than expression",
-			`<div a={a > b} />`,
-			[]AttributeType{ExpressionAttribute},
-		},
-		{
-			"spread",
-			`<div {...value} />`,
-			[]AttributeType{SpreadAttribute},
-		},
-		{
-			"template literal",
-			"<div a=`value` />",
-			[]AttributeType{TemplateLiteralAttribute},
-		},
-		{
-			"all",
-			"<div a='value' a={value} {value} {...value} a=`value` />",
-			[]AttributeType{QuotedAttribute, ExpressionAttribute, ShorthandAttribute, SpreadAttribute, TemplateLiteralAttribute},
-		},
-		{
-			"multiple quoted",
-			`<div a="value" b='value' c=value/>`,
-			[]AttributeType{QuotedAttribute, QuotedAttribute, QuotedAttribute},
-		},
-		{
-			"expression with quoted braces",
-			`<div value={ "{" } />`,
-			[]AttributeType{ExpressionAttribute},
-		},
-		{
-			"attribute expression with solidus inside template literal",
-			"<div value={attr ? `a/b` : \"c\"} />",
-			[]AttributeType{ExpressionAttribute},
-		},
-		{
-			"attribute expression with solidus inside template literal with trailing text",
-			"<div value={`${attr ? `a/b` : \"c\"} awesome`} />",
-			[]AttributeType{ExpressionAttribute},
-		},
-		{
-			"iframe allows attributes",
-			"<iframe src=\"https://google.com\"></iframe>",
-			[]AttributeType{QuotedAttribute},
-		},
-		{
-			"shorthand attribute with comment",
-			"<div {/* a comment */ value} />",
-			[]AttributeType{ShorthandAttribute},
-		},
-		{
-			"expression with comment",
-			"<div a={/* a comment */ value} />",
-			[]AttributeType{ExpressionAttribute},
-		},
-	}
+var attributeTests = []AttributeTest{
+	{
+		"double quoted",
+		`<div a="value" />`,
+		[]AttributeType{QuotedAttribute},
+	},
+	{
+		"single quoted",
+		`<div a='value' />`,
+		[]AttributeType{QuotedAttribute},
+	},
+	{
+		"not quoted",
+		`<div a=value />`,
+		[]AttributeType{QuotedAttribute},
+	},
+	{
+		"expression",
+		`<div a={value} />`,
+		[]AttributeType{ExpressionAttribute},
+	},
+	{
+		"expression with apostrophe",
+		`<div a="fred's" />`,
+		[]AttributeType{QuotedAttribute},
+	},
+	{
+		"expression with template literal",
+		"<div a=\"`value`\" />",
+		[]AttributeType{QuotedAttribute},
+	},
+	{
+		"expression with template literal interpolation",
+		"<div a=\"`${value}`\" />",
+		[]AttributeType{QuotedAttribute},
+	},
+	{
+		"shorthand",
+		`<div {value} />`,
+		[]AttributeType{ShorthandAttribute},
+	},
+	{
+		"less than expression",
+		`<div a={a < b} />`,
+		[]AttributeType{ExpressionAttribute},
+	},
+	{
+		"greater than expression",
+		`<div a={a > b} />`,
+		[]AttributeType{ExpressionAttribute},
+	},
+	{
+		"spread",
+		`<div {...value} />`,
+		[]AttributeType{SpreadAttribute},
+	},
+	{
+		"template literal",
+		"<div a=`value` />",
+		[]AttributeType{TemplateLiteralAttribute},
+	},
+	{
+		"all",
+		"<div a='value' a={value} {value} {...value} a=`value` />",
+		[]AttributeType{QuotedAttribute, ExpressionAttribute, ShorthandAttribute, SpreadAttribute, TemplateLiteralAttribute},
+	},
+	{
+		"multiple quoted",
+		`<div a="value" b='value' c=value/>`,
+		[]AttributeType{QuotedAttribute, QuotedAttribute, QuotedAttribute},
+	},
+	{
+		"expression with quoted braces",
+		`<div value={ "{" } />`,
+		[]AttributeType{ExpressionAttribute},
+	},
+	{
+		"attribute expression with solidus inside template literal",
+		"<div value={attr ? `a/b` : \"c\"} />",
+		[]AttributeType{ExpressionAttribute},
+	},
+	{
+		"attribute expression with solidus inside template literal with trailing text",
+		"<div value={`${attr ? `a/b` : \"c\"} awesome`} />",
+		[]AttributeType{ExpressionAttribute},
+	},
+	{
+		"iframe allows attributes",
+		"<iframe src=\"https://google.com\"></iframe>",
+		[]AttributeType{QuotedAttribute},
+	},
+	{
+		"shorthand attribute with comment",
+		"<div {/* a comment */ value} />",
+		[]AttributeType{ShorthandAttribute},
+	},
+	{
+		"expression with comment",
+		"<div a={/* a comment */ value} />",
+		[]AttributeType{ExpressionAttribute},
+	},
+}
 
-	runAttributeTypeTest(t, Attributes)
+func TestAttributes(t *testing.T) {
+	runAttributeTypeTest(t, attributeTests)
 }
 
-func TestLoc(t *testing.T) {
-	Locs := []LocTest{
-		{
-			"doctype",
-			`<!DOCTYPE html>`,
-			[]int{0, 11},
-		},
-		{
-			"frontmatter",
-			`---
+var locTests = []LocTest{
+	{
+		"doctype",
+		`<!DOCTYPE html>`,
+		[]int{0, 11},
+	},
+	{
+		"frontmatter",
+		`---
 doesNotExist
 ---
 `,
-			[]int{0, 1, 4},
-		},
-		{
-			"expression",
-			`<div>{console.log(hey)}</div>`,
-			[]int{0, 2, 6, 7, 23, 26},
-		},
-		{
-			"expression II",
-			`{"hello" + hey}`,
-			[]int{0, 1, 2, 9, 15},
-		},
-		{
-			"element I",
-			`<div></div>`,
-			[]int{0, 2, 8},
-		},
-	}
+		[]int{0, 1, 4, 21},
+	},
+	{
+		"expression",
+		`<div>{console.log(hey)}</div>`,
+		[]int{0, 2, 6, 7, 23, 26},
+	},
+	{
+		"expression II",
+		`{"hello" + hey}`,
+		[]int{0, 1, 2, 9, 15},
+	},
+	{
+		"element I",
+		`<div></div>`,
+		[]int{0, 2, 8},
+	},
+}
+
+func TestLoc(t *testing.T) {
+	runTokenLocTest(t, locTests)
+}
 
-	runTokenLocTest(t, Locs)
+func TestTokenize(t *testing.T) {
+	for _, source := range []string{
+		"---\nconst a = 0;\n---\n<div class=\"a\" {b}>{c.map(d => <p>{d}</p>)}</div>",
+		"---\nconst a = 0;\n---<div></div>",
+		"---\r\nconst a = 0;\r\n---\r\n<div />",
+		"<script>const a = '<b>';</script><style>a {}</style>",
+		"<div>{value",
+	} {
+		var raw strings.Builder
+		end := 0
+		for _, token := range Tokenize([]byte(source), handler.NewHandler(source, "")) {
+			if token.Raw.Start != end {
+				t.Errorf("%q: expected a token at %d, got %d", source, end, token.Raw.Start)
+			}
+			end = token.Raw.End
+			raw.WriteString(source[token.Raw.Start:token.Raw.End])
+		}
+		if raw.String() != source {
+			t.Errorf("expected the tokens to cover %q, got %q", source, raw.String())
+		}
+	}
 }
 
 func runTokenTypeTest(t *testing.T, suite []TokenTypeTest) {
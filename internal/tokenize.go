@@ -0,0 +1,96 @@
+package astro
+
+import (
+	"bytes"
+
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+// SourceToken is a token of Tokenize, with the ranges of its source
+type SourceToken struct {
+	Type TokenType
+	// Data is the name of a tag, as in Token
+	Data string
+	// Raw is the range of the whole token
+	Raw  loc.Span
+	Attr []SourceAttribute
+}
+
+// SourceAttribute is an attribute of a tag of Tokenize. Key is the range of the name, without
+// the braces of a shorthand attribute nor the "..." of a spread attribute. Val is the range of the
+// value, without quotes nor braces; it is empty for empty, shorthand and spread attributes.
+type SourceAttribute struct {
+	Type AttributeType
+	Key  loc.Span
+	Val  loc.Span
+	// Quote is the character quoting the value: '"', '\'' or '`', or zero when the value is not quoted
+	Quote byte
+}
+
+// Tokenize splits an Astro component into tokens, reporting problems to h. Unlike the parser,
+// it leaves the source as written: no tag is implied, and every byte of the source is covered
+// by exactly one token, in order.
+func Tokenize(source []byte, h *handler.Handler) []SourceToken {
+	z := NewTokenizer(bytes.NewReader(source))
+	z.handler = h
+	tokens := make([]SourceToken, 0)
+	for {
+		tt := z.Next()
+		if tt == ErrorToken {
+			return tokens
+		}
+		t := SourceToken{Type: tt, Raw: z.raw}
+		switch tt {
+		case StartTagToken, SelfClosingTagToken, EndTagToken:
+			t.Data = z.Token().Data
+			for i, x := range z.attr {
+				t.Attr = append(t.Attr, sourceAttribute(source, x, z.attrTypes[i]))
+			}
+		}
+		tokens = append(tokens, t)
+	}
+}
+
+func sourceAttribute(source []byte, x [2]loc.Span, attrType AttributeType) SourceAttribute {
+	attr := SourceAttribute{Type: attrType, Key: x[0], Val: x[1]}
+	switch attrType {
+	case EmptyAttribute, ShorthandAttribute, SpreadAttribute:
+		attr.Val = loc.Span{Start: x[0].End, End: x[0].End}
+	case QuotedAttribute, TemplateLiteralAttribute:
+		if start := x[1].Start; start > 0 {
+			switch c := source[start-1]; c {
+			case '"', '\'', '`':
+				attr.Quote = c
+			}
+		}
+	}
+	return attr
+}
+
+// attributeSpan returns the range of the whole attribute attr, including the quotes or braces
+// around its value, and the braces of a shorthand or spread attribute
+func attributeSpan(source []byte, attr SourceAttribute) loc.Span {
+	span := loc.Span{Start: attr.Key.Start, End: attr.Val.End}
+	switch attr.Type {
+	case EmptyAttribute:
+		span.End = attr.Key.End
+	case QuotedAttribute, TemplateLiteralAttribute:
+		if attr.Quote != 0 && span.End < len(source) && source[span.End] == attr.Quote {
+			span.End++
+		}
+	case ExpressionAttribute:
+		if i := bytes.IndexByte(source[span.End:], '}'); i != -1 {
+			span.End += i + 1
+		}
+	case ShorthandAttribute, SpreadAttribute:
+		if i := bytes.LastIndexByte(source[:span.Start], '{'); i != -1 {
+			span.Start = i
+		}
+		span.End = attr.Key.End
+		if i := bytes.IndexByte(source[span.End:], '}'); i != -1 {
+			span.End += i + 1
+		}
+	}
+	return span
+}
@@ -0,0 +1,493 @@
+package transform
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/helpers"
+	"github.com/withastro/compiler/internal/loc"
+	a "golang.org/x/net/html/atom"
+)
+
+// accessibility checks the elements of a document for TransformOptions.AccessibilityWarnings.
+// Only the HTML elements are checked, and the attributes written as expressions or spread are
+// skipped, as their values are only known at runtime.
+type accessibility struct {
+	// ids maps the static ids of the document to the range of their first use
+	ids map[string]loc.Range
+	// labelled are the ids referenced by the `for` attribute of a <label>. anyLabelled is set when
+	// a `for` attribute is an expression, which may reference any id.
+	labelled    map[string]bool
+	anyLabelled bool
+	// controls are the form controls which aren't labelled by their own attributes or an
+	// enclosing <label>, checked against the labels of the whole document
+	controls []*astro.Node
+}
+
+func newAccessibility() *accessibility {
+	return &accessibility{ids: make(map[string]loc.Range), labelled: make(map[string]bool)}
+}
+
+// enter checks the element n, and records its id and its labels
+func (l *accessibility) enter(n *astro.Node, h *handler.Handler) {
+	if n.Type != astro.ElementNode || n.Expression || n.Fragment || n.Component || len(n.Loc) == 0 {
+		return
+	}
+	for _, attr := range n.Attr {
+		switch {
+		case attr.Key == "role":
+			checkRole(attr, h)
+		case attr.Key == "tabindex":
+			checkTabindex(attr, h)
+		case attr.Key == "id":
+			l.checkID(n, attr, h)
+		case strings.HasPrefix(attr.Key, "aria-"):
+			checkAriaAttribute(attr, h)
+		}
+	}
+	if n.CustomElement || n.Namespace != "" {
+		return
+	}
+	if n.DataAtom == a.Label {
+		if attr := GetAttr(n, "for"); attr != nil {
+			if isStaticAttribute(attr) {
+				l.labelled[strings.TrimSpace(attr.Val)] = true
+			} else {
+				l.anyLabelled = true
+			}
+		}
+	}
+	if hasSpreadAttribute(n) {
+		// The spread attributes may provide any of the attributes checked below
+		return
+	}
+	switch n.DataAtom {
+	case a.Html:
+		if attr := GetAttr(n, "lang"); !IsImplicitNode(n) && (attr == nil || isStaticAttribute(attr) && strings.TrimSpace(attr.Val) == "") {
+			h.AppendWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_A11Y_MISSING_LANG,
+				Text:  "`<html>` has no `lang` attribute",
+				Hint:  "Set the language of the page, like `lang=\"en\"`, for screen readers to pronounce it correctly.",
+				Range: nameRange(n),
+			})
+		}
+	case a.Img:
+		if !HasAttr(n, "alt") {
+			warnMissingAlt(n, h)
+		}
+	case a.A:
+		if HasAttr(n, "href") && !hasAccessibleName(n) {
+			warnMissingName(n, h)
+		}
+	case a.Button:
+		if !hasAccessibleName(n) {
+			warnMissingName(n, h)
+		}
+	case a.Input:
+		switch inputType(n) {
+		case "", "hidden", "submit", "reset":
+		case "image":
+			if !HasAttr(n, "alt") {
+				warnMissingAlt(n, h)
+			}
+		case "button":
+			if !HasAttr(n, "value") && !hasNameAttribute(n) {
+				warnMissingName(n, h)
+			}
+		default:
+			l.recordControl(n)
+		}
+	case a.Select, a.Textarea:
+		l.recordControl(n)
+	}
+}
+
+// leave reports the form controls of the document without a label
+func (l *accessibility) leave(h *handler.Handler) {
+	for _, n := range l.controls {
+		if attr := GetAttr(n, "id"); attr != nil {
+			if !isStaticAttribute(attr) || l.anyLabelled || l.labelled[strings.TrimSpace(attr.Val)] {
+				continue
+			}
+		}
+		h.AppendWarning(&loc.ErrorWithRange{
+			Code:  loc.WARNING_A11Y_MISSING_LABEL,
+			Text:  fmt.Sprintf("`<%s>` has no label", n.Data),
+			Hint:  "Wrap it in a `<label>`, reference its `id` with `<label for>`, or add an `aria-label` attribute.",
+			Range: nameRange(n),
+		})
+	}
+}
+
+// recordControl records the form control n unless it is labelled by its own attributes or by an
+// ancestor. A control in a component may be labelled by the component, and isn't checked.
+func (l *accessibility) recordControl(n *astro.Node) {
+	if hasNameAttribute(n) {
+		return
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Component || p.Type == astro.ElementNode && p.DataAtom == a.Label {
+			return
+		}
+	}
+	l.controls = append(l.controls, n)
+}
+
+// checkID warns about the static id of n when an element already uses it. The elements rendered
+// by an expression are skipped, as they may be rendered conditionally.
+func (l *accessibility) checkID(n *astro.Node, attr astro.Attribute, h *handler.Handler) {
+	id := strings.TrimSpace(attr.Val)
+	if attr.Type != astro.QuotedAttribute || id == "" {
+		return
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Expression {
+			return
+		}
+	}
+	r := valueRange(attr)
+	first, ok := l.ids[id]
+	if !ok {
+		l.ids[id] = r
+		return
+	}
+	h.AppendWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_A11Y_DUPLICATE_ID,
+		Text:  fmt.Sprintf("Duplicate id `%s`", id),
+		Hint:  fmt.Sprintf("The id is first used on line %d. Ids must be unique in a page, for labels and ARIA attributes to reference a single element.", h.Location(first).Line),
+		Range: r,
+	})
+}
+
+func warnMissingAlt(n *astro.Node, h *handler.Handler) {
+	h.AppendWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_A11Y_MISSING_ALT,
+		Text:  fmt.Sprintf("`<%s>` has no `alt` attribute", n.Data),
+		Hint:  "Describe the image in `alt`, or use `alt=\"\"` when it is decorative.",
+		Range: nameRange(n),
+	})
+}
+
+func warnMissingName(n *astro.Node, h *handler.Handler) {
+	h.AppendWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_A11Y_MISSING_NAME,
+		Text:  fmt.Sprintf("`<%s>` has no accessible name", n.Data),
+		Hint:  "Add text content, or an `aria-label` or `aria-labelledby` attribute.",
+		Range: nameRange(n),
+	})
+}
+
+func checkTabindex(attr astro.Attribute, h *handler.Handler) {
+	if attr.Type != astro.QuotedAttribute {
+		return
+	}
+	if tabindex, err := strconv.Atoi(strings.TrimSpace(attr.Val)); err == nil && tabindex > 0 {
+		h.AppendHint(&loc.ErrorWithRange{
+			Code:  loc.HINT_A11Y_POSITIVE_TABINDEX,
+			Text:  "A positive `tabindex` changes the keyboard navigation order of the page",
+			Hint:  "Use `tabindex=\"0\"` to make the element focusable in the order of the document.",
+			Range: valueRange(attr),
+		})
+	}
+}
+
+func checkRole(attr astro.Attribute, h *handler.Handler) {
+	if attr.Type != astro.QuotedAttribute {
+		return
+	}
+	// A role may list fallback roles, like `role="switch checkbox"`
+	offset := 0
+	for _, role := range strings.Fields(attr.Val) {
+		i := offset + strings.Index(attr.Val[offset:], role)
+		offset = i + len(role)
+		r := loc.Range{Loc: loc.Loc{Start: attr.ValLoc.Start + i}, Len: len(role)}
+		lower := strings.ToLower(role)
+		switch {
+		case ariaRoles[lower]:
+		case ariaAbstractRoles[lower]:
+			h.AppendWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_A11Y_INVALID_ROLE,
+				Text:  fmt.Sprintf("`%s` is an abstract ARIA role, which can't be used in markup", role),
+				Range: r,
+			})
+		default:
+			h.AppendWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_A11Y_INVALID_ROLE,
+				Text:  fmt.Sprintf("Unknown ARIA role `%s`", role),
+				Hint:  suggestName(lower, ariaRoles),
+				Range: r,
+			})
+		}
+	}
+}
+
+func checkAriaAttribute(attr astro.Attribute, h *handler.Handler) {
+	key := strings.ToLower(attr.Key)
+	spec, ok := ariaAttributes[key]
+	if !ok {
+		h.AppendWarning(&loc.ErrorWithRange{
+			Code:  loc.WARNING_A11Y_INVALID_ARIA,
+			Text:  fmt.Sprintf("Unknown ARIA attribute `%s`", attr.Key),
+			Hint:  suggestName(key, ariaAttributes),
+			Range: loc.Range{Loc: attr.KeyLoc, Len: len(attr.Key)},
+		})
+		return
+	}
+	if !isStaticAttribute(&attr) || spec.valid(attr.Val) {
+		return
+	}
+	r := valueRange(attr)
+	if attr.Type == astro.EmptyAttribute {
+		r = loc.Range{Loc: attr.KeyLoc, Len: len(attr.Key)}
+	}
+	h.AppendWarning(&loc.ErrorWithRange{
+		Code:  loc.WARNING_A11Y_INVALID_ARIA,
+		Text:  fmt.Sprintf("Invalid value `%s` for `%s`", attr.Val, attr.Key),
+		Hint:  spec.expected(),
+		Range: r,
+	})
+}
+
+// suggestName returns a hint with the name of names closest to the unknown name, when it looks
+// like a typo
+func suggestName[T any](name string, names map[string]T) string {
+	best, bestDistance := "", 3
+	for n := range names {
+		if d := helpers.EditDistance(name, n); d < bestDistance || d == bestDistance && best != "" && n < best {
+			best, bestDistance = n, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("Did you mean `%s`?", best)
+}
+
+// hasAccessibleName reports whether the element n has a name for assistive technologies: from its
+// attributes, or from its content. Content which is only known at runtime, like an expression or a
+// component, is assumed to provide a name.
+func hasAccessibleName(n *astro.Node) bool {
+	return hasNameAttribute(n) || HasSetDirective(n) || hasAccessibleContent(n)
+}
+
+func hasAccessibleContent(n *astro.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case astro.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				return true
+			}
+		case astro.ElementNode:
+			if attr := GetAttr(c, "aria-hidden"); attr != nil && attr.Type == astro.QuotedAttribute && strings.TrimSpace(attr.Val) == "true" {
+				continue
+			}
+			if c.Expression || c.Component || c.CustomElement || c.DataAtom == a.Slot || hasSpreadAttribute(c) || hasAccessibleName(c) {
+				return true
+			}
+			if c.DataAtom == a.Img || c.DataAtom == a.Area {
+				if attr := GetAttr(c, "alt"); attr != nil && (!isStaticAttribute(attr) || strings.TrimSpace(attr.Val) != "") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasNameAttribute reports whether the element n is named by an attribute, like `aria-label`
+func hasNameAttribute(n *astro.Node) bool {
+	for _, key := range []string{"aria-label", "aria-labelledby", "title"} {
+		if attr := GetAttr(n, key); attr != nil && (!isStaticAttribute(attr) || strings.TrimSpace(attr.Val) != "") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSpreadAttribute(n *astro.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Type == astro.SpreadAttribute {
+			return true
+		}
+	}
+	return false
+}
+
+// isStaticAttribute reports whether the value of attr is known at build time
+func isStaticAttribute(attr *astro.Attribute) bool {
+	return attr.Type == astro.QuotedAttribute || attr.Type == astro.EmptyAttribute
+}
+
+// inputType returns the type of the <input> n, or "" when it is an expression
+func inputType(n *astro.Node) string {
+	attr := GetAttr(n, "type")
+	if attr == nil {
+		return "text"
+	}
+	if !isStaticAttribute(attr) {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(attr.Val))
+}
+
+// valueRange returns the range of the value of the quoted attribute attr
+func valueRange(attr astro.Attribute) loc.Range {
+	return loc.Range{Loc: attr.ValLoc, Len: len(attr.Val)}
+}
+
+// ariaValue is the kind of value of an ARIA attribute
+type ariaValue int
+
+const (
+	// ariaString is any string, like the ids of aria-labelledby
+	ariaString ariaValue = iota
+	ariaInteger
+	ariaNumber
+	// ariaToken is one of the values of the attribute, and ariaTokens a list of them
+	ariaToken
+	ariaTokens
+)
+
+type ariaAttribute struct {
+	kind   ariaValue
+	tokens []string
+}
+
+func (attr ariaAttribute) valid(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch attr.kind {
+	case ariaInteger:
+		_, err := strconv.Atoi(value)
+		return err == nil
+	case ariaNumber:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case ariaToken:
+		return slices.Contains(attr.tokens, value)
+	case ariaTokens:
+		tokens := strings.Fields(value)
+		for _, token := range tokens {
+			if !slices.Contains(attr.tokens, token) {
+				return false
+			}
+		}
+		return len(tokens) > 0
+	}
+	return true
+}
+
+func (attr ariaAttribute) expected() string {
+	switch attr.kind {
+	case ariaInteger:
+		return "Expected an integer."
+	case ariaNumber:
+		return "Expected a number."
+	case ariaTokens:
+		return fmt.Sprintf("Expected a list of `%s`.", strings.Join(attr.tokens, "`, `"))
+	}
+	return fmt.Sprintf("Expected `%s` or `%s`.", strings.Join(attr.tokens[:len(attr.tokens)-1], "`, `"), attr.tokens[len(attr.tokens)-1])
+}
+
+var (
+	ariaBoolean      = ariaAttribute{kind: ariaToken, tokens: []string{"false", "true"}}
+	ariaOptionalBool = ariaAttribute{kind: ariaToken, tokens: []string{"false", "true", "undefined"}}
+	ariaTristate     = ariaAttribute{kind: ariaToken, tokens: []string{"false", "mixed", "true", "undefined"}}
+)
+
+// ariaAttributes are the states and properties of WAI-ARIA 1.2
+var ariaAttributes = map[string]ariaAttribute{
+	"aria-activedescendant":       {},
+	"aria-atomic":                 ariaBoolean,
+	"aria-autocomplete":           {kind: ariaToken, tokens: []string{"both", "inline", "list", "none"}},
+	"aria-braillelabel":           {},
+	"aria-brailleroledescription": {},
+	"aria-busy":                   ariaBoolean,
+	"aria-checked":                ariaTristate,
+	"aria-colcount":               {kind: ariaInteger},
+	"aria-colindex":               {kind: ariaInteger},
+	"aria-colindextext":           {},
+	"aria-colspan":                {kind: ariaInteger},
+	"aria-controls":               {},
+	"aria-current":                {kind: ariaToken, tokens: []string{"date", "false", "location", "page", "step", "time", "true"}},
+	"aria-describedby":            {},
+	"aria-description":            {},
+	"aria-details":                {},
+	"aria-disabled":               ariaBoolean,
+	"aria-dropeffect":             {kind: ariaTokens, tokens: []string{"copy", "execute", "link", "move", "none", "popup"}},
+	"aria-errormessage":           {},
+	"aria-expanded":               ariaOptionalBool,
+	"aria-flowto":                 {},
+	"aria-grabbed":                ariaOptionalBool,
+	"aria-haspopup":               {kind: ariaToken, tokens: []string{"dialog", "false", "grid", "listbox", "menu", "tree", "true"}},
+	"aria-hidden":                 ariaOptionalBool,
+	"aria-invalid":                {kind: ariaToken, tokens: []string{"false", "grammar", "spelling", "true"}},
+	"aria-keyshortcuts":           {},
+	"aria-label":                  {},
+	"aria-labelledby":             {},
+	"aria-level":                  {kind: ariaInteger},
+	"aria-live":                   {kind: ariaToken, tokens: []string{"assertive", "off", "polite"}},
+	"aria-modal":                  ariaBoolean,
+	"aria-multiline":              ariaBoolean,
+	"aria-multiselectable":        ariaBoolean,
+	"aria-orientation":            {kind: ariaToken, tokens: []string{"horizontal", "undefined", "vertical"}},
+	"aria-owns":                   {},
+	"aria-placeholder":            {},
+	"aria-posinset":               {kind: ariaInteger},
+	"aria-pressed":                ariaTristate,
+	"aria-readonly":               ariaBoolean,
+	"aria-relevant":               {kind: ariaTokens, tokens: []string{"additions", "all", "removals", "text"}},
+	"aria-required":               ariaBoolean,
+	"aria-roledescription":        {},
+	"aria-rowcount":               {kind: ariaInteger},
+	"aria-rowindex":               {kind: ariaInteger},
+	"aria-rowindextext":           {},
+	"aria-rowspan":                {kind: ariaInteger},
+	"aria-selected":               ariaOptionalBool,
+	"aria-setsize":                {kind: ariaInteger},
+	"aria-sort":                   {kind: ariaToken, tokens: []string{"ascending", "descending", "none", "other"}},
+	"aria-valuemax":               {kind: ariaNumber},
+	"aria-valuemin":               {kind: ariaNumber},
+	"aria-valuenow":               {kind: ariaNumber},
+	"aria-valuetext":              {},
+}
+
+// ariaRoles are the concrete roles of WAI-ARIA 1.2, of the Graphics module and of the Digital
+// Publishing module
+var ariaRoles = roleSet(
+	"alert", "alertdialog", "application", "article", "banner", "blockquote", "button", "caption",
+	"cell", "checkbox", "code", "columnheader", "combobox", "complementary", "contentinfo",
+	"definition", "deletion", "dialog", "directory", "document", "emphasis", "feed", "figure", "form",
+	"generic", "grid", "gridcell", "group", "heading", "img", "insertion", "link", "list", "listbox",
+	"listitem", "log", "main", "mark", "marquee", "math", "menu", "menubar", "menuitem",
+	"menuitemcheckbox", "menuitemradio", "meter", "navigation", "none", "note", "option", "paragraph",
+	"presentation", "progressbar", "radio", "radiogroup", "region", "row", "rowgroup", "rowheader",
+	"scrollbar", "search", "searchbox", "separator", "slider", "spinbutton", "status", "strong",
+	"subscript", "superscript", "switch", "tab", "table", "tablist", "tabpanel", "term", "textbox",
+	"time", "timer", "toolbar", "tooltip", "tree", "treegrid", "treeitem",
+	"graphics-document", "graphics-object", "graphics-symbol",
+	"doc-abstract", "doc-acknowledgments", "doc-afterword", "doc-appendix", "doc-backlink",
+	"doc-biblioentry", "doc-bibliography", "doc-biblioref", "doc-chapter", "doc-colophon",
+	"doc-conclusion", "doc-cover", "doc-credit", "doc-credits", "doc-dedication", "doc-endnote",
+	"doc-endnotes", "doc-epigraph", "doc-epilogue", "doc-errata", "doc-example", "doc-footnote",
+	"doc-foreword", "doc-glossary", "doc-glossref", "doc-index", "doc-introduction", "doc-noteref",
+	"doc-notice", "doc-pagebreak", "doc-pagefooter", "doc-pageheader", "doc-pagelist", "doc-part",
+	"doc-preface", "doc-prologue", "doc-pullquote", "doc-qna", "doc-subtitle", "doc-tip", "doc-toc",
+)
+
+// ariaAbstractRoles are the roles which only structure the ontology of WAI-ARIA
+var ariaAbstractRoles = roleSet(
+	"command", "composite", "input", "landmark", "range", "roletype", "section", "sectionhead",
+	"select", "structure", "widget", "window",
+)
+
+func roleSet(roles ...string) map[string]bool {
+	m := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		m[role] = true
+	}
+	return m
+}
@@ -0,0 +1,158 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+func TestAccessibilityWarnings(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		diagnostics []string
+	}{
+		{
+			name:        "img",
+			source:      `<img src="a.png" /><img src="b.png" alt="" /><img {...props} /><img alt={alt} />`,
+			diagnostics: []string{"2023 1:2:3 `<img>` has no `alt` attribute"},
+		},
+		{
+			name:        "input image",
+			source:      `<input type="image" src="go.png" /><input type="image" alt="Go" /><input type={type} />`,
+			diagnostics: []string{"2023 1:2:5 `<input>` has no `alt` attribute"},
+		},
+		{
+			name: "accessible names",
+			source: `<a href="/">Home</a>
+<a href="/">  </a>
+<a name="top"></a>
+<button><svg aria-hidden="true" /></button>
+<button aria-label="Close"><svg aria-hidden="true" /></button>
+<button><img src="x.png" alt="Close" /></button>
+<button>{label}</button>
+<button><Icon /></button>
+<button set:text={label} />
+<input type="button" />
+<input type="button" value="Go" />`,
+			diagnostics: []string{
+				"2024 2:2:1 `<a>` has no accessible name",
+				"2024 4:2:6 `<button>` has no accessible name",
+				"2024 10:2:5 `<input>` has no accessible name",
+			},
+		},
+		{
+			name:   "aria attributes",
+			source: `<div aria-labeledby="t" aria-hidden="yes" aria-level="two" aria-live="polite" aria-relevant="additions text" aria-busy aria-checked={checked} />`,
+			diagnostics: []string{
+				"2025 1:6:14 Unknown ARIA attribute `aria-labeledby` (Did you mean `aria-labelledby`?)",
+				"2025 1:38:3 Invalid value `yes` for `aria-hidden` (Expected `false`, `true` or `undefined`.)",
+				"2025 1:55:3 Invalid value `two` for `aria-level` (Expected an integer.)",
+				"2025 1:110:9 Invalid value `` for `aria-busy` (Expected `false` or `true`.)",
+			},
+		},
+		{
+			name:   "roles",
+			source: `<div role="buton" /><div role="switch checkbox" /><div role="widget" /><div role={role} /><div role="doc-toc" />`,
+			diagnostics: []string{
+				"2026 1:12:5 Unknown ARIA role `buton` (Did you mean `button`?)",
+				"2026 1:62:6 `widget` is an abstract ARIA role, which can't be used in markup",
+			},
+		},
+		{
+			name:        "tabindex",
+			source:      `<div tabindex="3" /><div tabindex="0" /><div tabindex="-1" /><div tabindex={i} />`,
+			diagnostics: []string{"4001 1:16:1 A positive `tabindex` changes the keyboard navigation order of the page"},
+		},
+		{
+			name:        "lang",
+			source:      "<html><head></head><body></body></html>",
+			diagnostics: []string{"2027 1:2:4 `<html>` has no `lang` attribute"},
+		},
+		{
+			name:        "lang set",
+			source:      `<html lang="en"><head></head><body><p>Implicit</p></body></html>`,
+			diagnostics: []string{},
+		},
+		{
+			name: "duplicate ids",
+			source: `<h1 id="title">A</h1>
+<h2 id="title">B</h2>
+<p id={id} /><p id={id} />
+{show ? <p id="note" /> : <p id="note" />}
+<svg><g id="title" /></svg>`,
+			diagnostics: []string{
+				"2028 2:9:5 Duplicate id `title` (The id is first used on line 1. Ids must be unique in a page, for labels and ARIA attributes to reference a single element.)",
+				"2028 5:13:5 Duplicate id `title` (The id is first used on line 1. Ids must be unique in a page, for labels and ARIA attributes to reference a single element.)",
+			},
+		},
+		{
+			name: "labels",
+			source: `<input name="q" />
+<label>Name <input name="name" /></label>
+<label for="email">Email</label><input id="email" />
+<input id="phone" />
+<textarea aria-label="Message"></textarea>
+<select name="size"></select>
+<input type="hidden" name="token" />
+<input type="submit" />
+<Field><input name="city" /></Field>`,
+			diagnostics: []string{
+				"2029 1:2:5 `<input>` has no label",
+				"2029 4:2:5 `<input>` has no label",
+				"2029 6:2:6 `<select>` has no label",
+			},
+		},
+		{
+			name:        "dynamic label",
+			source:      `<label for={id}>Name</label><input id="name" /><input name="other" />`,
+			diagnostics: []string{"2029 1:49:5 `<input>` has no label"},
+		},
+		{
+			name:        "components and custom elements",
+			source:      `<Image src={src} /><Button /><my-button aria-bogus="x"></my-button><Fragment><img src="a.png" /></Fragment>`,
+			diagnostics: []string{"2025 1:41:10 Unknown ARIA attribute `aria-bogus`", "2023 1:79:3 `<img>` has no `alt` attribute"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := astro.Parse(strings.NewReader(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := handler.NewHandler(tt.source, "<stdin>")
+			Transform(doc, TransformOptions{Filename: "<stdin>", AccessibilityWarnings: true}, h)
+
+			diagnostics := []string{}
+			for _, d := range h.Diagnostics() {
+				text := fmt.Sprintf("%d %d:%d:%d %s", d.Code, d.Location.Line, d.Location.Column, d.Location.Length, d.Text)
+				switch loc.DiagnosticCode(d.Code) {
+				case loc.WARNING_A11Y_INVALID_ARIA, loc.WARNING_A11Y_INVALID_ROLE, loc.WARNING_A11Y_DUPLICATE_ID:
+					// The hints of the other rules don't depend on the source
+					if d.Hint != "" {
+						text += fmt.Sprintf(" (%s)", d.Hint)
+					}
+				}
+				diagnostics = append(diagnostics, text)
+			}
+			if strings.Join(diagnostics, "\n") != strings.Join(tt.diagnostics, "\n") {
+				t.Errorf("unexpected diagnostics:\n%s\nwant:\n%s", strings.Join(diagnostics, "\n"), strings.Join(tt.diagnostics, "\n"))
+			}
+		})
+	}
+
+	source := `<img src="a.png" /><div role="buton" />`
+	doc, err := astro.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewHandler(source, "<stdin>")
+	Transform(doc, TransformOptions{Filename: "<stdin>"}, h)
+	if diagnostics := h.Diagnostics(); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics without AccessibilityWarnings, got %v", diagnostics)
+	}
+}
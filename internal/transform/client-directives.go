@@ -0,0 +1,244 @@
+package transform
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/withastro/compiler/internal/js_parser"
+)
+
+// valueError is an error of the part of the value of a directive from the byte offset start to
+// end, returned by Directive.Validate and Directive.ValidateExpression to narrow the warning
+type valueError struct {
+	start   int
+	end     int
+	message string
+	hint    string
+}
+
+func (e *valueError) Error() string {
+	return e.message
+}
+
+// clientOptions declares the options object of a client directive, like `client:idle={{ timeout: 500 }}`
+type clientOptions struct {
+	// types maps the name of each option to the type of its value, "string" or "number"
+	types   map[string]string
+	example string
+}
+
+var clientIdleOptions = clientOptions{types: map[string]string{"timeout": "number"}, example: "{{ timeout: 500 }}"}
+var clientVisibleOptions = clientOptions{types: map[string]string{"rootMargin": "string"}, example: `{{ rootMargin: "200px" }}`}
+
+// validate rejects a string value, which the directive ignores
+func (o clientOptions) validate(value string) error {
+	return &valueError{end: len(value), message: fmt.Sprintf("expected an object of options, like `%s`", o.example)}
+}
+
+// validateExpression checks the options of an object literal: their names, and the types of their
+// literal values. Other expressions, like a variable, are accepted.
+func (o clientOptions) validateExpression(expression string) error {
+	expr, err := js_parser.ParseExpression(expression, 0, len(expression))
+	if err != nil || expr == nil {
+		// The syntax errors are reported with TransformOptions.ValidateExpressions
+		return nil
+	}
+	switch expr.Type {
+	case "ObjectExpression":
+	case "Literal", "TemplateLiteral", "ArrayExpression", "ArrowFunctionExpression", "FunctionExpression":
+		return &valueError{start: expr.Start, end: expr.End, message: fmt.Sprintf("expected an object of options, like `%s`", o.example)}
+	default:
+		return nil
+	}
+	for _, property := range expr.Children("properties") {
+		key := property.Child("key")
+		if property.Type != "Property" || property.Get("computed") == true || key == nil {
+			continue
+		}
+		name, ok := propertyName(key)
+		if !ok {
+			continue
+		}
+		optionType, ok := o.types[name]
+		if !ok {
+			err := &valueError{start: key.Start, end: key.End, message: fmt.Sprintf("unknown option `%s`", name)}
+			if hint := suggestName(name, o.types); hint != "" {
+				err.hint = hint
+			} else {
+				err.hint = fmt.Sprintf("The options are `%s`.", strings.Join(sortedKeys(o.types), "`, `"))
+			}
+			return err
+		}
+		value := property.Child("value")
+		if valueType := literalType(value); valueType != "" && valueType != optionType {
+			return &valueError{start: value.Start, end: value.End, message: fmt.Sprintf("`%s` must be a %s", name, optionType)}
+		}
+	}
+	return nil
+}
+
+// propertyName returns the name of the key of a property, like `timeout` or `"timeout"`
+func propertyName(key *js_parser.Node) (string, bool) {
+	switch key.Type {
+	case "Identifier":
+		name, ok := key.Get("name").(string)
+		return name, ok
+	case "Literal":
+		name, ok := key.Get("value").(string)
+		return name, ok
+	}
+	return "", false
+}
+
+// literalType returns the type of the literal n, like "number" for `500` or `-1`, or "" when n
+// isn't a literal
+func literalType(n *js_parser.Node) string {
+	if n == nil {
+		return ""
+	}
+	switch n.Type {
+	case "Literal":
+		switch n.Get("value").(type) {
+		case string:
+			return "string"
+		case float64:
+			return "number"
+		case bool:
+			return "boolean"
+		case nil:
+			return "null"
+		}
+	case "TemplateLiteral":
+		return "string"
+	case "UnaryExpression":
+		if op := n.Get("operator"); (op == "-" || op == "+") && literalType(n.Child("argument")) == "number" {
+			return "number"
+		}
+	case "ObjectExpression":
+		return "object"
+	case "ArrayExpression":
+		return "array"
+	}
+	return ""
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// validateStringLiteral returns a validation of the expression value of a directive which checks a
+// string literal, like `{"(max-width: 600px)"}`, with validate
+func validateStringLiteral(validate func(value string) error) func(expression string) error {
+	return func(expression string) error {
+		expr, err := js_parser.ParseExpression(expression, 0, len(expression))
+		if err != nil || expr == nil || expr.Type != "Literal" {
+			return nil
+		}
+		value, ok := expr.Get("value").(string)
+		if !ok {
+			return &valueError{start: expr.Start, end: expr.End, message: "expected a string"}
+		}
+		err = validate(value)
+		if valueErr, ok := err.(*valueError); ok {
+			if expression[expr.Start+1:expr.End-1] == value {
+				valueErr.start += expr.Start + 1
+				valueErr.end += expr.Start + 1
+			} else {
+				// The offsets in the string don't match the source of the literal, which has escapes
+				valueErr.start, valueErr.end = expr.Start, expr.End
+			}
+		}
+		return err
+	}
+}
+
+// mediaQueryWords are the media types and the keywords which are written outside of the
+// parentheses of the media features, like `screen and (max-width: 600px)`
+var mediaQueryWords = map[string]bool{
+	"all": true, "and": true, "not": true, "only": true, "or": true, "print": true, "screen": true,
+}
+
+// validateMediaQuery checks the value of `client:media`, given to matchMedia: a media query with
+// balanced parentheses, whose features are in parentheses
+func validateMediaQuery(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return &valueError{end: len(value), message: "expected a media query, like `(max-width: 600px)`"}
+	}
+	isSeparator := func(c byte) bool {
+		return strings.IndexByte(" \t\r\n\f,()", c) != -1
+	}
+	depth, open, word := 0, 0, -1
+	for i := 0; i <= len(value); i++ {
+		c := byte(' ')
+		if i < len(value) {
+			c = value[i]
+		}
+		if word != -1 && isSeparator(c) {
+			if !mediaQueryWords[strings.ToLower(value[word:i])] {
+				return &valueError{
+					start:   word,
+					end:     i,
+					message: fmt.Sprintf("`%s` is not a media type or keyword", value[word:i]),
+					hint:    "Media features are written in parentheses, like `(max-width: 600px)`.",
+				}
+			}
+			word = -1
+		}
+		switch {
+		case c == '(':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return &valueError{start: i, end: i + 1, message: "unexpected `)`"}
+			}
+			depth--
+			if depth == 0 && strings.TrimSpace(value[open+1:i]) == "" {
+				return &valueError{start: open, end: i + 1, message: "empty media feature"}
+			}
+		case depth == 0 && word == -1 && !isSeparator(c):
+			word = i
+		}
+	}
+	if depth > 0 {
+		return &valueError{start: open, end: len(value), message: "unclosed `(`"}
+	}
+	return nil
+}
+
+// clientOnlyRenderers are the renderers of the official framework integrations, which `client:only`
+// names without their "@astrojs/" scope
+var clientOnlyRenderers = map[string]bool{
+	"lit": true, "preact": true, "react": true, "solid": true, "solid-js": true, "svelte": true, "vue": true,
+}
+
+// validateRenderer checks the value of `client:only`, the renderer of the component. Other
+// integrations may register renderers of any name, so only a near miss of an official renderer,
+// like "reakt", is reported.
+func validateRenderer(value string) error {
+	name := strings.TrimSpace(value)
+	start := len(value) - len(strings.TrimLeftFunc(value, unicode.IsSpace))
+	if short, ok := strings.CutPrefix(name, "@astrojs/"); ok {
+		name = short
+		start += len("@astrojs/")
+	} else if name == "" || strings.Contains(name, "/") {
+		return nil
+	}
+	if clientOnlyRenderers[name] {
+		return nil
+	}
+	hint := suggestName(name, clientOnlyRenderers)
+	if hint == "" {
+		return nil
+	}
+	return &valueError{start: start, end: start + len(name), message: fmt.Sprintf("unknown renderer `%s`", name), hint: hint}
+}
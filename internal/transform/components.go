@@ -0,0 +1,171 @@
+package transform
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/js_scanner"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+// componentGraph links the components of the template to the imports of the frontmatter, for
+// doc.ComponentUsages and doc.UnusedComponentImports
+type componentGraph struct {
+	imports []js_scanner.ImportStatement
+	// rendered holds the bindings rendered as a tag, by index of statement and of import
+	rendered map[[2]int]bool
+	// resolved caches ResolveIdForMatch by specifier
+	resolved map[string]string
+	// expressions holds the code of the template, which may reference a component without
+	// rendering it, like `<Card as={Link} />`
+	expressions strings.Builder
+	// loaded caches the slots of the components loaded with LoadComponent, by path
+	loaded map[string]childSlots
+}
+
+func newComponentGraph(doc *astro.Node) *componentGraph {
+	g := &componentGraph{rendered: make(map[[2]int]bool), resolved: make(map[string]string), loaded: make(map[string]childSlots)}
+	if doc.FirstChild != nil {
+		eachImportStatement(doc, func(stmt js_scanner.ImportStatement) bool {
+			g.imports = append(g.imports, stmt)
+			return true
+		})
+	}
+	return g
+}
+
+func (g *componentGraph) resolve(specifier string, opts *TransformOptions) string {
+	if resolved, ok := g.resolved[specifier]; ok {
+		return resolved
+	}
+	resolved := ResolveIdForMatch(specifier, opts)
+	g.resolved[specifier] = resolved
+	return resolved
+}
+
+// recordComponentUsage records the component n in doc.ComponentUsages, with the attributes passed
+// to it as written, so it must run before AddComponentProps
+func recordComponentUsage(doc *astro.Node, n *astro.Node, opts *TransformOptions, g *componentGraph) {
+	if n.Type != astro.ElementNode {
+		return
+	}
+	for _, attr := range n.Attr {
+		switch attr.Type {
+		case astro.ExpressionAttribute, astro.TemplateLiteralAttribute:
+			g.expressions.WriteString(attr.Val + "\n")
+		case astro.SpreadAttribute, astro.ShorthandAttribute:
+			g.expressions.WriteString(attr.Key + "\n")
+		}
+	}
+	if n.Expression {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == astro.TextNode {
+				g.expressions.WriteString(c.Data + "\n")
+			}
+		}
+	}
+	if !n.Component {
+		return
+	}
+
+	usage := &astro.ComponentUsageMetadata{
+		LocalName:    n.Data,
+		Attributes:   []string{},
+		SlotContents: slotContents(n),
+	}
+	usage.Slots = slotNames(usage.SlotContents)
+	if len(n.Loc) > 0 {
+		usage.Range = loc.Range{Loc: n.Loc[0], Len: len(n.Data)}
+	}
+	for _, attr := range n.Attr {
+		if attr.Type == astro.SpreadAttribute {
+			usage.Attributes = append(usage.Attributes, "..."+attr.Key)
+		} else {
+			usage.Attributes = append(usage.Attributes, attr.Key)
+		}
+	}
+match:
+	for i, stmt := range g.imports {
+		if stmt.IsType {
+			continue
+		}
+		for j, imported := range stmt.Imports {
+			if imported.IsType {
+				continue
+			}
+			if exportName, ok := js_scanner.ExtractComponentExportName(n.Data, imported); ok {
+				g.rendered[[2]int{i, j}] = true
+				usage.ExportName = exportName
+				usage.Specifier = stmt.Specifier
+				usage.ResolvedPath = g.resolve(stmt.Specifier, opts)
+				break match
+			}
+		}
+	}
+	doc.ComponentUsages = append(doc.ComponentUsages, usage)
+}
+
+// recordUnusedComponentImports records in doc.UnusedComponentImports the imports of components
+// which are neither rendered nor referenced in the frontmatter or in the expressions of the template
+func recordUnusedComponentImports(doc *astro.Node, opts *TransformOptions, g *componentGraph) {
+	if len(g.imports) == 0 {
+		return
+	}
+	frontmatter := doc.FirstChild.FirstChild
+	// The code of the frontmatter, without the import statements
+	code := []byte(frontmatter.Data)
+	for _, stmt := range g.imports {
+		for i := stmt.Span.Start; i < stmt.Span.End && i < len(code); i++ {
+			code[i] = ' '
+		}
+	}
+	code = append(code, g.expressions.String()...)
+
+	for i, stmt := range g.imports {
+		if stmt.IsType {
+			continue
+		}
+		for j, imported := range stmt.Imports {
+			if imported.IsType || g.rendered[[2]int{i, j}] || !isComponentName(imported.LocalName) || containsIdentifier(string(code), imported.LocalName) {
+				continue
+			}
+			unused := &astro.ComponentUsageMetadata{
+				ExportName:   imported.ExportName,
+				LocalName:    imported.LocalName,
+				Specifier:    stmt.Specifier,
+				ResolvedPath: g.resolve(stmt.Specifier, opts),
+			}
+			if len(frontmatter.Loc) > 0 {
+				unused.Range = loc.Range{Loc: loc.Loc{Start: frontmatter.Loc[0].Start + stmt.Span.Start}, Len: stmt.Span.End - stmt.Span.Start}
+			}
+			doc.UnusedComponentImports = append(doc.UnusedComponentImports, unused)
+		}
+	}
+}
+
+// isComponentName reports whether the binding name can be rendered as a component, like "Card"
+func isComponentName(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// containsIdentifier reports whether code contains name as a whole identifier
+func containsIdentifier(code string, name string) bool {
+	isIdentifierPart := func(b byte) bool {
+		return b == '_' || b == '$' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b >= utf8.RuneSelf
+	}
+	for i := 0; i <= len(code)-len(name); {
+		j := strings.Index(code[i:], name)
+		if j == -1 {
+			return false
+		}
+		start, end := i+j, i+j+len(name)
+		if (start == 0 || !isIdentifierPart(code[start-1])) && (end == len(code) || !isIdentifierPart(code[end])) {
+			return true
+		}
+		i = start + 1
+	}
+	return false
+}
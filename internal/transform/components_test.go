@@ -0,0 +1,107 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+)
+
+func TestRecordComponents(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// usages and unused are the components as "LocalName ExportName Specifier [attributes] [slots]"
+		usages []string
+		unused []string
+	}{
+		{
+			name: "imports",
+			source: `---
+import Card from './Card.astro';
+import { Button as Btn } from '../ui';
+import * as UI from 'ui';
+---
+<Card title="a" {...rest} {name} client:load /><Btn /><UI.Tab.Item />`,
+			usages: []string{
+				"Card default ./Card.astro [title ...rest name client:load] []",
+				"Btn Button ../ui [] []",
+				"UI.Tab.Item Tab.Item ui [] []",
+			},
+		},
+		{
+			name:   "not imported",
+			source: `<Astro.self depth={1} />`,
+			usages: []string{"Astro.self   [depth] []"},
+		},
+		{
+			name: "slots",
+			source: `---
+import Card from './Card.astro';
+---
+<Card>
+	<h2 slot="title">Title</h2>
+	text
+	{show && <p slot="note" />}
+	<p slot={dynamic} />
+	<Fragment slot="title">again</Fragment>
+</Card>
+<Card>{title}</Card>
+<Card>
+</Card>`,
+			usages: []string{
+				"Card default ./Card.astro [] [title default note]",
+				"Card default ./Card.astro [] [default]",
+				"Card default ./Card.astro [] []",
+			},
+		},
+		{
+			name: "unused imports",
+			source: `---
+import Card from './Card.astro';
+import Dead from './Dead.astro';
+import Passed from './Passed.astro';
+import Aliased from './Aliased.astro';
+import type { Props } from './types';
+import { helper } from './utils';
+const Tag = Aliased;
+---
+<Card as={Passed} />`,
+			usages: []string{"Card default ./Card.astro [as] []"},
+			unused: []string{"Dead default ./Dead.astro"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := astro.Parse(strings.NewReader(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := handler.NewHandler(tt.source, "<stdin>")
+			Transform(doc, TransformOptions{Filename: "<stdin>"}, h)
+
+			var usages []string
+			for _, c := range doc.ComponentUsages {
+				usages = append(usages, fmt.Sprintf("%s %s %s %v %v", c.LocalName, c.ExportName, c.Specifier, c.Attributes, c.Slots))
+				if name := tt.source[c.Range.Loc.Start : c.Range.Loc.Start+c.Range.Len]; name != c.LocalName {
+					t.Errorf("expected the range of %s, got %q", c.LocalName, name)
+				}
+			}
+			if strings.Join(usages, "\n") != strings.Join(tt.usages, "\n") {
+				t.Errorf("unexpected usages:\n%s\nwant:\n%s", strings.Join(usages, "\n"), strings.Join(tt.usages, "\n"))
+			}
+			var unused []string
+			for _, c := range doc.UnusedComponentImports {
+				unused = append(unused, fmt.Sprintf("%s %s %s", c.LocalName, c.ExportName, c.Specifier))
+				if stmt := tt.source[c.Range.Loc.Start : c.Range.Loc.Start+c.Range.Len]; !strings.HasPrefix(stmt, "import "+c.LocalName) {
+					t.Errorf("expected the range of the import of %s, got %q", c.LocalName, stmt)
+				}
+			}
+			if strings.Join(unused, "\n") != strings.Join(tt.unused, "\n") {
+				t.Errorf("unexpected unused imports:\n%s\nwant:\n%s", strings.Join(unused, "\n"), strings.Join(tt.unused, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/helpers"
+	"github.com/withastro/compiler/internal/loc"
+	a "golang.org/x/net/html/atom"
+)
+
+// Kinds of elements for Directive.Elements, besides the name of an element like "script"
+const (
+	DirectiveOnComponent     = "component"
+	DirectiveOnCustomElement = "custom-element"
+	DirectiveOnFragment      = "fragment"
+	// DirectiveOnElement is any element which isn't a component, a custom element or a fragment
+	DirectiveOnElement = "element"
+)
+
+// A Directive declares an attribute with a special meaning, like `client:load`, for the validation
+// of the directives, see TransformOptions.Directives
+type Directive struct {
+	// Name is the name of the attribute, like "client:hover". A name ending with ":*", like "data:*",
+	// declares every attribute with that prefix.
+	Name string
+	// Elements lists the kinds of elements the directive is allowed on, see DirectiveOnComponent,
+	// or the names of elements like "script". The directive is allowed on any element when empty.
+	Elements []string
+	// RequiresValue reports the directive when it is written without a value, like `<div set:html>`
+	RequiresValue bool
+	// Validate validates the value of the directive when it is a string, like "(max-width: 600px)"
+	// for `client:media="(max-width: 600px)"`. The error is reported as a warning on the value.
+	Validate func(value string) error
+	// ValidateExpression validates the value of the directive when it is an expression, like
+	// "{ timeout: 500 }" for `client:idle={{ timeout: 500 }}`. Expressions which can't be evaluated
+	// statically are expected to be accepted.
+	ValidateExpression func(expression string) error
+}
+
+var hydratable = []string{DirectiveOnComponent, DirectiveOnCustomElement}
+
+// BuiltinDirectives are the directives handled by the compiler and the Astro runtime
+var BuiltinDirectives = []Directive{
+	{Name: "client:load", Elements: hydratable},
+	{Name: "client:idle", Elements: hydratable, Validate: clientIdleOptions.validate, ValidateExpression: clientIdleOptions.validateExpression},
+	{Name: "client:visible", Elements: hydratable, Validate: clientVisibleOptions.validate, ValidateExpression: clientVisibleOptions.validateExpression},
+	{Name: "client:media", Elements: hydratable, RequiresValue: true, Validate: validateMediaQuery, ValidateExpression: validateStringLiteral(validateMediaQuery)},
+	{Name: "client:only", Elements: hydratable, Validate: validateRenderer, ValidateExpression: validateStringLiteral(validateRenderer)},
+	{Name: "server:defer", Elements: []string{DirectiveOnComponent}},
+	{Name: "set:html", RequiresValue: true},
+	{Name: "set:text", RequiresValue: true},
+	{Name: "is:raw"},
+	{Name: "is:inline", Elements: []string{"script", "style", "slot"}},
+	{Name: "is:global", Elements: []string{"style"}},
+	{Name: "define:vars", Elements: []string{"script", "style"}, RequiresValue: true},
+	{Name: "class:list", RequiresValue: true},
+	{Name: TRANSITION_NAME, RequiresValue: true},
+	{Name: TRANSITION_ANIMATE, RequiresValue: true},
+	{Name: TRANSITION_PERSIST},
+	{Name: TRANSITION_PERSIST_PROPS, RequiresValue: true},
+}
+
+// directiveRegistry resolves the directives of an attribute name: the directives of
+// TransformOptions.Directives take precedence over the built-in ones with the same name
+type directiveRegistry struct {
+	names    map[string]*Directive
+	prefixes []*Directive
+	// namespaces are the prefixes, like "client:", of the attributes which are validated
+	namespaces map[string]bool
+	// reportUnknown reports the attributes of a namespace which aren't declared
+	reportUnknown bool
+}
+
+// newDirectiveRegistry returns the registry of the built-in directives and the custom ones. When
+// custom is nil, only the built-in client directives are validated, as they are always handled by
+// the compiler, and the unknown ones aren't reported, as they may be declared by integrations.
+func newDirectiveRegistry(custom []Directive) *directiveRegistry {
+	r := &directiveRegistry{names: make(map[string]*Directive), namespaces: make(map[string]bool), reportUnknown: custom != nil}
+	for _, directives := range [][]Directive{BuiltinDirectives, custom} {
+		for i := range directives {
+			d := &directives[i]
+			if custom == nil && !strings.HasPrefix(d.Name, "client:") {
+				continue
+			}
+			if prefix, ok := strings.CutSuffix(d.Name, "*"); ok && strings.HasSuffix(prefix, ":") {
+				r.prefixes = append(r.prefixes, d)
+				r.namespaces[prefix] = true
+				continue
+			}
+			r.names[d.Name] = d
+			if ns, _, ok := strings.Cut(d.Name, ":"); ok {
+				r.namespaces[ns+":"] = true
+			}
+		}
+	}
+	// The most specific prefix wins
+	sort.SliceStable(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i].Name) > len(r.prefixes[j].Name)
+	})
+	return r
+}
+
+// lookup returns the directive of the attribute key, and whether key is in the namespace of a
+// directive at all
+func (r *directiveRegistry) lookup(key string) (d *Directive, isDirective bool) {
+	ns, _, ok := strings.Cut(key, ":")
+	if !ok || !r.namespaces[ns+":"] {
+		return nil, false
+	}
+	if d, ok := r.names[key]; ok {
+		return d, true
+	}
+	for _, d := range r.prefixes {
+		if strings.HasPrefix(key, strings.TrimSuffix(d.Name, "*")) {
+			return d, true
+		}
+	}
+	return nil, true
+}
+
+// validateDirectives warns about the attributes of n in the namespace of a directive, like
+// `client:hover`, which aren't declared in the registry, are used on the wrong kind of element,
+// lack a required value or have an invalid one
+func validateDirectives(n *astro.Node, r *directiveRegistry, h *handler.Handler) {
+	if n.Type != astro.ElementNode || n.Expression {
+		return
+	}
+	for _, attr := range n.Attr {
+		if attr.Type == astro.SpreadAttribute || attr.Type == astro.ShorthandAttribute {
+			continue
+		}
+		if n.DataAtom == a.Script && strings.HasPrefix(attr.Key, "client:") {
+			// Reported by ExtractScript
+			continue
+		}
+		d, isDirective := r.lookup(attr.Key)
+		if !isDirective || d == nil && !r.reportUnknown {
+			continue
+		}
+		keyRange := loc.Range{Loc: attr.KeyLoc, Len: len(attr.Key)}
+		if d == nil {
+			h.AppendWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_UNKNOWN_DIRECTIVE,
+				Text:  fmt.Sprintf("Unknown directive `%s`", attr.Key),
+				Hint:  r.suggest(attr.Key),
+				Range: keyRange,
+			})
+			continue
+		}
+		if !directiveAllowedOn(d, n) {
+			h.AppendWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_MISUSED_DIRECTIVE,
+				Text:  fmt.Sprintf("`%s` is not allowed on `<%s>`", attr.Key, n.Data),
+				Hint:  fmt.Sprintf("`%s` can only be used on %s", attr.Key, describeElementKinds(d.Elements)),
+				Range: keyRange,
+			})
+			continue
+		}
+		switch {
+		case attr.Type == astro.EmptyAttribute && d.RequiresValue:
+			h.AppendWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_MISUSED_DIRECTIVE,
+				Text:  fmt.Sprintf("`%s` requires a value", attr.Key),
+				Hint:  fmt.Sprintf("Add a value, like `%s={value}`", attr.Key),
+				Range: keyRange,
+			})
+		case attr.Type == astro.QuotedAttribute && d.Validate != nil:
+			warnInvalidDirectiveValue(attr, d.Validate(attr.Val), h)
+		case attr.Type == astro.ExpressionAttribute && d.ValidateExpression != nil:
+			warnInvalidDirectiveValue(attr, d.ValidateExpression(attr.Val), h)
+		}
+	}
+}
+
+// warnInvalidDirectiveValue reports err, returned by the validation of the value of attr, on the
+// value or on the part of it given by a *valueError
+func warnInvalidDirectiveValue(attr astro.Attribute, err error, h *handler.Handler) {
+	if err == nil {
+		return
+	}
+	warning := &loc.ErrorWithRange{
+		Code:  loc.WARNING_MISUSED_DIRECTIVE,
+		Text:  fmt.Sprintf("Invalid value for `%s`: %s", attr.Key, err.Error()),
+		Range: loc.Range{Loc: attr.ValLoc, Len: len(attr.Val)},
+	}
+	var valueErr *valueError
+	if errors.As(err, &valueErr) {
+		warning.Hint = valueErr.hint
+		warning.Range = loc.Range{Loc: loc.Loc{Start: attr.ValLoc.Start + valueErr.start}, Len: valueErr.end - valueErr.start}
+	}
+	h.AppendWarning(warning)
+}
+
+// directiveElementKind returns the kind of n for Directive.Elements
+func directiveElementKind(n *astro.Node) string {
+	switch {
+	case n.Fragment:
+		return DirectiveOnFragment
+	case n.Component:
+		return DirectiveOnComponent
+	case n.CustomElement:
+		return DirectiveOnCustomElement
+	}
+	return DirectiveOnElement
+}
+
+func directiveAllowedOn(d *Directive, n *astro.Node) bool {
+	if len(d.Elements) == 0 {
+		return true
+	}
+	kind := directiveElementKind(n)
+	for _, e := range d.Elements {
+		if e == kind || kind == DirectiveOnElement && e == n.Data {
+			return true
+		}
+	}
+	return false
+}
+
+func describeElementKinds(elements []string) string {
+	names := make([]string, 0, len(elements))
+	for _, e := range elements {
+		switch e {
+		case DirectiveOnComponent:
+			names = append(names, "components")
+		case DirectiveOnCustomElement:
+			names = append(names, "custom elements")
+		case DirectiveOnFragment:
+			names = append(names, "`<Fragment>`")
+		case DirectiveOnElement:
+			names = append(names, "HTML elements")
+		default:
+			names = append(names, fmt.Sprintf("`<%s>`", e))
+		}
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// suggest returns a hint for the unknown directive key: the closest directive of its namespace
+// when it looks like a typo, or the directives of the namespace otherwise
+func (r *directiveRegistry) suggest(key string) string {
+	ns, _, _ := strings.Cut(key, ":")
+	var known []string
+	for name := range r.names {
+		if strings.HasPrefix(name, ns+":") {
+			known = append(known, name)
+		}
+	}
+	sort.Strings(known)
+	best, bestDistance := "", 3
+	for _, name := range known {
+		if d := helpers.EditDistance(key, name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("Did you mean `%s`?", best)
+	}
+	if len(known) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("The `%s:` directives are `%s`. Declare custom directives in the `directives` option.", ns, strings.Join(known, "`, `"))
+}
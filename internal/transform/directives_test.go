@@ -0,0 +1,150 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+func TestValidateDirectives(t *testing.T) {
+	custom := []Directive{
+		{Name: "client:hover", Elements: []string{DirectiveOnComponent}},
+		{Name: "data:*", Elements: []string{DirectiveOnElement}},
+		{Name: "data:json", Elements: []string{"script"}, RequiresValue: true, Validate: func(value string) error {
+			if !strings.HasPrefix(value, "{") {
+				return errors.New("expected a JSON object")
+			}
+			return nil
+		}},
+	}
+	tests := []struct {
+		name       string
+		source     string
+		directives []Directive
+		want       []string
+	}{
+		{
+			name:       "builtin",
+			source:     `<Counter client:load /><script is:inline define:vars={{ a }}></script><div class:list={["a"]} set:html={html} transition:persist />`,
+			directives: custom,
+		},
+		{
+			name:   "disabled",
+			source: `<div client:hover />`,
+		},
+		{
+			name:       "unknown typo",
+			source:     `<Counter client:laod />`,
+			directives: []Directive{},
+			want:       []string{"2017 10:11 Unknown directive `client:laod` (Did you mean `client:load`?)"},
+		},
+		{
+			name:       "unknown",
+			source:     `<div is:cool />`,
+			directives: []Directive{},
+			want:       []string{"2017 6:7 Unknown directive `is:cool` (The `is:` directives are `is:global`, `is:inline`, `is:raw`. Declare custom directives in the `directives` option.)"},
+		},
+		{
+			name:       "custom",
+			source:     `<Counter client:hover /><div data:foo="bar" />`,
+			directives: custom,
+		},
+		{
+			name:       "wrong element",
+			source:     `<div client:load /><my-element client:hover /><Counter data:foo />`,
+			directives: custom,
+			want: []string{
+				"2018 6:11 `client:load` is not allowed on `<div>` (`client:load` can only be used on components and custom elements)",
+				"2018 32:12 `client:hover` is not allowed on `<my-element>` (`client:hover` can only be used on components)",
+				"2018 56:8 `data:foo` is not allowed on `<Counter>` (`data:foo` can only be used on HTML elements)",
+			},
+		},
+		{
+			name:       "missing value",
+			source:     `<div set:html /><Counter client:media />`,
+			directives: []Directive{},
+			want: []string{
+				"2018 6:8 `set:html` requires a value (Add a value, like `set:html={value}`)",
+				"2018 26:12 `client:media` requires a value (Add a value, like `client:media={value}`)",
+			},
+		},
+		{
+			name:   "client directives by default",
+			source: `<div client:load /><Counter client:laod /><script client:load></script>`,
+			want: []string{
+				"2018 6:11 `client:load` is not allowed on `<div>` (`client:load` can only be used on components and custom elements)",
+			},
+		},
+		{
+			name:   "client:media",
+			source: `<A client:media="(max-width: 600px)" /><A client:media="screen and (min-width: 40em), print" /><A client:media="max-width: 600px" /><A client:media="(max-width: 600px" /><A client:media={"()"} /><A client:media={query} /><A client:media={600} />`,
+			want: []string{
+				"2018 113:10 Invalid value for `client:media`: `max-width:` is not a media type or keyword (Media features are written in parentheses, like `(max-width: 600px)`.)",
+				"2018 150:17 Invalid value for `client:media`: unclosed `(`",
+				"2018 189:2 Invalid value for `client:media`: empty media feature",
+				"2018 239:3 Invalid value for `client:media`: expected a string",
+			},
+		},
+		{
+			name:   "client:only",
+			source: `<A client:only="react" /><A client:only="@astrojs/vue" /><A client:only="@scope/renderer" /><A client:only /><A client:only="reakt" /><A client:only={"qwik"} />`,
+			want: []string{
+				"2018 126:5 Invalid value for `client:only`: unknown renderer `reakt` (Did you mean `react`?)",
+			},
+		},
+		{
+			name:   "client:only range",
+			source: `<A client:only="@astrojs/astrojs" /><A client:only="  reakt " /><A client:only=" @astrojs/vu" />`,
+			want: []string{
+				"2018 55:5 Invalid value for `client:only`: unknown renderer `reakt` (Did you mean `react`?)",
+				"2018 91:2 Invalid value for `client:only`: unknown renderer `vu` (Did you mean `vue`?)",
+			},
+		},
+		{
+			name:   "client options",
+			source: `<A client:idle={{ timeout: 500 }} /><A client:visible={{ rootMargin: "200px" }} /><A client:idle={options} /><A client:idle={{ timout: 500 }} /><A client:visible={{ rootMargin: 200 }} /><A client:idle="500" /><A client:idle={500} />`,
+			want: []string{
+				"2018 128:6 Invalid value for `client:idle`: unknown option `timout` (Did you mean `timeout`?)",
+				"2018 178:3 Invalid value for `client:visible`: `rootMargin` must be a string",
+				"2018 203:3 Invalid value for `client:idle`: expected an object of options, like `{{ timeout: 500 }}`",
+				"2018 226:3 Invalid value for `client:idle`: expected an object of options, like `{{ timeout: 500 }}`",
+			},
+		},
+		{
+			name:       "invalid value",
+			source:     `<script data:json="[1]"></script><script data:json={value}></script>`,
+			directives: custom,
+			want:       []string{"2018 20:3 Invalid value for `data:json`: expected a JSON object"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := astro.Parse(strings.NewReader(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := handler.NewHandler(tt.source, "/src/pages/index.astro")
+			Transform(doc, TransformOptions{Directives: tt.directives}, h)
+
+			var got []string
+			for _, w := range h.Warnings() {
+				if w.Code != int(loc.WARNING_UNKNOWN_DIRECTIVE) && w.Code != int(loc.WARNING_MISUSED_DIRECTIVE) {
+					continue
+				}
+				s := fmt.Sprintf("%d %d:%d %s", w.Code, w.Location.Column, w.Location.Length, w.Text)
+				if w.Hint != "" {
+					s += " (" + w.Hint + ")"
+				}
+				got = append(got, s)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("unexpected warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+)
+
+// Names of the built-in passes of Transform, in the order they run
+const (
+	PassWarnRerunOnExternalESMs     = "warn-rerun-on-external-esms"
+	PassWarnMisplacedReload         = "warn-misplaced-reload"
+	PassHintImplicitInlineDirective = "hint-implicit-inline-directive"
+	PassValidateDirectives          = "validate-directives"
+	PassExtractScript               = "extract-script"
+	PassRecordComponents            = "record-components"
+	PassLintAccessibility           = "lint-accessibility"
+	PassAddComponentProps           = "add-component-props"
+	PassScopeElement                = "scope-element"
+	PassTransitions                 = "transitions"
+	PassDefineVars                  = "define-vars"
+	PassMergeClassList              = "merge-class-list"
+	PassContainsHead                = "contains-head"
+	PassAnnotateSourceFile          = "annotate-source-file"
+)
+
+// PassContext is shared by the passes of a single Transform
+type PassContext struct {
+	Doc     *astro.Node
+	Options *TransformOptions
+	Handler *handler.Handler
+}
+
+// A Pass visits every node of the document during Transform. Enter is called on a node before
+// its children and Leave after them; either may be nil. For each node, the passes are entered
+// and left in the order resolved by Before and After.
+//
+// Passes run after the styles are hoisted and before the scripts are removed from the tree.
+type Pass struct {
+	// Name identifies the pass for the ordering constraints of other passes. It may be empty.
+	Name  string
+	Enter func(ctx *PassContext, n *astro.Node)
+	Leave func(ctx *PassContext, n *astro.Node)
+	// Before and After list the names of the passes this pass must run before or after.
+	// A pass without constraints runs after the built-in passes, in the order it was registered.
+	Before []string
+	After  []string
+}
+
+// orderPasses sorts the built-in passes and the custom ones so that every constraint is satisfied.
+// The built-in passes keep their relative order, and passes keep the given order unless a constraint
+// moves them. It fails on duplicate or unknown names, and on cycles.
+func orderPasses(builtin []Pass, custom []Pass) ([]Pass, error) {
+	passes := append(builtin[:len(builtin):len(builtin)], custom...)
+	index := make(map[string]int)
+	for i, p := range passes {
+		if p.Name == "" {
+			continue
+		}
+		if _, ok := index[p.Name]; ok {
+			return nil, fmt.Errorf("transform: duplicate pass %q", p.Name)
+		}
+		index[p.Name] = i
+	}
+
+	// deps[i] holds the passes which must run before passes[i]
+	deps := make([][]int, len(passes))
+	for i := 1; i < len(builtin); i++ {
+		deps[i] = append(deps[i], i-1)
+	}
+	for i := len(builtin); i < len(passes) && len(builtin) > 0; i++ {
+		if len(passes[i].Before) == 0 && len(passes[i].After) == 0 {
+			deps[i] = append(deps[i], len(builtin)-1)
+		}
+	}
+	for i, p := range passes {
+		for _, name := range p.After {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("transform: pass %q runs after unknown pass %q", p.Name, name)
+			}
+			deps[i] = append(deps[i], j)
+		}
+		for _, name := range p.Before {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("transform: pass %q runs before unknown pass %q", p.Name, name)
+			}
+			deps[j] = append(deps[j], i)
+		}
+	}
+
+	ordered := make([]Pass, 0, len(passes))
+	done := make([]bool, len(passes))
+	for len(ordered) < len(passes) {
+		next := -1
+		for i := range passes {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, j := range deps[i] {
+				if !done[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			names := make([]string, 0)
+			for i, p := range passes {
+				if !done[i] {
+					names = append(names, fmt.Sprintf("%q", p.Name))
+				}
+			}
+			return nil, fmt.Errorf("transform: cycle in the order of the passes %s", strings.Join(names, ", "))
+		}
+		done[next] = true
+		ordered = append(ordered, passes[next])
+	}
+	return ordered, nil
+}
+
+// runPasses walks doc once, calling the visitors of every pass on each node
+func runPasses(ctx *PassContext, passes []Pass) {
+	var f func(*astro.Node)
+	f = func(n *astro.Node) {
+		for _, p := range passes {
+			if p.Enter != nil {
+				p.Enter(ctx, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+		for _, p := range passes {
+			if p.Leave != nil {
+				p.Leave(ctx, n)
+			}
+		}
+	}
+	f(ctx.Doc)
+}
@@ -0,0 +1,162 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+func TestOrderPasses(t *testing.T) {
+	builtin := []Pass{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	tests := []struct {
+		name   string
+		custom []Pass
+		want   string
+		err    string
+	}{
+		{
+			name:   "after the built-in passes",
+			custom: []Pass{{Name: "x"}, {}, {Name: "y"}},
+			want:   "a b c x  y",
+		},
+		{
+			name:   "before",
+			custom: []Pass{{Name: "x", Before: []string{"b"}}},
+			want:   "a x b c",
+		},
+		{
+			name:   "after",
+			custom: []Pass{{Name: "x", After: []string{"a"}}, {Name: "y", Before: []string{"a"}}},
+			want:   "y a b c x",
+		},
+		{
+			name:   "between custom passes",
+			custom: []Pass{{Name: "x", After: []string{"y"}}, {Name: "y", Before: []string{"c"}}},
+			want:   "a b y c x",
+		},
+		{
+			name:   "duplicate",
+			custom: []Pass{{Name: "b"}},
+			err:    `transform: duplicate pass "b"`,
+		},
+		{
+			name:   "unknown",
+			custom: []Pass{{Name: "x", After: []string{"z"}}},
+			err:    `transform: pass "x" runs after unknown pass "z"`,
+		},
+		{
+			name:   "cycle",
+			custom: []Pass{{Name: "x", After: []string{"c"}, Before: []string{"b"}}},
+			err:    `transform: cycle in the order of the passes "b", "c", "x"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passes, err := orderPasses(builtin, tt.custom)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, len(passes))
+			for i, p := range passes {
+				names[i] = p.Name
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("want: %q\n got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTransformPasses(t *testing.T) {
+	source := `<style>div { color: red; }</style><div class="a" x:upper>Hello<span>world</span></div>`
+	doc, err := astro.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewHandler(source, "/src/pages/index.astro")
+
+	var visits []string
+	visit := func(prefix string) func(*PassContext, *astro.Node) {
+		return func(ctx *PassContext, n *astro.Node) {
+			if n.Type == astro.ElementNode && (n.Data == "div" || n.Data == "span") {
+				visits = append(visits, fmt.Sprintf("%s %s class=%q", prefix, n.Data, GetQuotedAttr(n, "class")))
+			}
+		}
+	}
+	ExtractStyles(doc)
+	Transform(doc, TransformOptions{
+		Scope: "xxxxxx",
+		Passes: []Pass{
+			{Name: "after", Enter: visit("after"), Leave: visit("leave")},
+			{Name: "before", Enter: visit("before"), Before: []string{PassScopeElement}},
+			{
+				Name: "upper",
+				Enter: func(ctx *PassContext, n *astro.Node) {
+					if attr := GetAttr(n, "x:upper"); attr != nil {
+						n.RemoveAttribute("x:upper")
+						ctx.Handler.AppendWarning(&loc.ErrorWithRange{
+							Code:  loc.WARNING,
+							Text:  "x:upper is deprecated",
+							Range: loc.Range{Loc: attr.KeyLoc, Len: len(attr.Key)},
+						})
+						for c := n.FirstChild; c != nil; c = c.NextSibling {
+							if c.Type == astro.TextNode {
+								c.Data = strings.ToUpper(c.Data)
+							}
+						}
+					}
+				},
+			},
+		},
+	}, h)
+
+	want := []string{
+		`before div class="a"`,
+		`after div class="a astro-xxxxxx"`,
+		`before span class=""`,
+		`after span class="astro-xxxxxx"`,
+		`leave span class="astro-xxxxxx"`,
+		`leave div class="a astro-xxxxxx"`,
+	}
+	if got := strings.Join(visits, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected visits:\n%s", got)
+	}
+
+	var b strings.Builder
+	astro.PrintToSource(&b, doc)
+	if got := b.String(); !strings.Contains(got, `<div class="a astro-xxxxxx">HELLO<span class="astro-xxxxxx">world</span></div>`) {
+		t.Errorf("expected the custom directive to be applied, got %s", got)
+	}
+	if warnings := h.Warnings(); len(warnings) != 1 || warnings[0].Text != "x:upper is deprecated" {
+		t.Errorf("expected a warning from the custom pass, got %v", warnings)
+	}
+}
+
+func TestTransformPassesError(t *testing.T) {
+	source := `<div class="a" />`
+	doc, err := astro.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewHandler(source, "/src/pages/index.astro")
+	called := false
+	Transform(doc, TransformOptions{Passes: []Pass{
+		{Name: "custom", After: []string{"unknown"}, Enter: func(*PassContext, *astro.Node) { called = true }},
+	}}, h)
+	if called {
+		t.Error("expected the custom pass to be skipped")
+	}
+	if errors := h.Errors(); len(errors) != 1 || !strings.Contains(errors[0].Text, `unknown pass "unknown"`) {
+		t.Errorf("expected an error about the order of the passes, got %v", errors)
+	}
+}
@@ -0,0 +1,110 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	astro "github.com/withastro/compiler/internal"
+)
+
+// StylePreprocessor preprocesses the content of a <style> tag, as documented by
+// compiler.StylePreprocessor.
+type StylePreprocessor interface {
+	PreprocessStyle(content string, attrs map[string]*string) (PreprocessorResult, error)
+}
+
+type PreprocessorResult struct {
+	Code string
+	// Map is an optional JSON source map, from Code to the content of the style
+	Map string
+}
+
+// StylePreprocessorFunc is a function implementing StylePreprocessor
+type StylePreprocessorFunc func(content string, attrs map[string]*string) (PreprocessorResult, error)
+
+func (f StylePreprocessorFunc) PreprocessStyle(content string, attrs map[string]*string) (PreprocessorResult, error) {
+	return f(content, attrs)
+}
+
+// PreprocessStyles runs the preprocessor of opts on every hoisted style concurrently. It returns
+// the preprocessing errors in the authored order of the styles, and the source map returned for
+// each style of doc.Styles, if any.
+//
+// When ctx is done before every style is preprocessed, PreprocessStyles stops waiting and returns
+// the cause of ctx. The pending calls are abandoned and doc must not be used anymore.
+func PreprocessStyles(ctx context.Context, doc *astro.Node, opts TransformOptions) (styleErrors []error, maps []string, err error) {
+	errs := make([]error, len(doc.Styles))
+	maps = make([]string, len(doc.Styles))
+	if opts.PreprocessStyle == nil {
+		return nil, maps, nil
+	}
+
+	var wg sync.WaitGroup
+	for i, style := range doc.Styles {
+		if style.FirstChild == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, style *astro.Node) {
+			defer wg.Done()
+			result, err := preprocessStyle(opts.PreprocessStyle, style)
+			// If an error is returned, override the style's CSS so the compiler doesn't hang
+			// and return a styleError. The caller will use this to know that style processing failed.
+			if err != nil {
+				style.FirstChild.Data = ""
+				errs[i] = err
+				return
+			}
+			if result.Code == "" {
+				return
+			}
+			style.FirstChild.Data = result.Code
+			maps[i] = result.Map
+		}(i, style)
+	}
+	// Wait for all the style goroutines to finish, unless ctx is done first
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, nil, context.Cause(ctx)
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			styleErrors = append(styleErrors, err)
+		}
+	}
+	return styleErrors, maps, nil
+}
+
+// preprocessStyle calls p for style, turning a panic into the error of the style. Raised in the
+// goroutine of the style, it would otherwise crash the whole process.
+func preprocessStyle(p StylePreprocessor, style *astro.Node) (result PreprocessorResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("style preprocessor panicked: %v", r)
+		}
+	}()
+	return p.PreprocessStyle(style.FirstChild.Data, GetStyleAttrs(style))
+}
+
+// GetStyleAttrs returns the static attributes of a style, as passed to a StylePreprocessor
+func GetStyleAttrs(n *astro.Node) map[string]*string {
+	attrs := make(map[string]*string)
+	for _, attr := range n.Attr {
+		switch attr.Type {
+		case astro.QuotedAttribute:
+			value := attr.Val
+			attrs[attr.Key] = &value
+		case astro.EmptyAttribute:
+			attrs[attr.Key] = nil
+		}
+	}
+	return attrs
+}
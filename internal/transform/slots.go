@@ -0,0 +1,208 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+	a "golang.org/x/net/html/atom"
+)
+
+// recordSlot records the <slot> element n in doc.Slots
+func recordSlot(doc *astro.Node, n *astro.Node) {
+	if isSlotElement(n) {
+		doc.Slots = append(doc.Slots, definedSlot(n))
+	}
+}
+
+// isSlotElement reports whether n is rendered as a slot, unlike a `<slot is:inline>`
+func isSlotElement(n *astro.Node) bool {
+	return n.Type == astro.ElementNode && n.DataAtom == a.Slot && !HasInlineDirective(n)
+}
+
+// definedSlot returns the slot defined by the <slot> element n. A slot named by an expression is
+// an error of the printer, but is still defined.
+func definedSlot(n *astro.Node) *astro.SlotMetadata {
+	slot := &astro.SlotMetadata{Name: "default", Range: nameRange(n)}
+	if attr := GetAttr(n, "name"); attr != nil {
+		switch attr.Type {
+		case astro.QuotedAttribute:
+			slot.Name = attr.Val
+		case astro.ExpressionAttribute, astro.TemplateLiteralAttribute:
+			slot.Name = attr.Val
+			slot.Dynamic = true
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != astro.CommentNode && (c.Type != astro.TextNode || strings.TrimSpace(c.Data) != "") {
+			slot.HasFallback = true
+		}
+	}
+	return slot
+}
+
+// slotContents returns the children of the component n filling a slot, in order
+func slotContents(n *astro.Node) []*astro.SlotMetadata {
+	contents := []*astro.SlotMetadata{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == astro.TextNode && strings.TrimSpace(c.Data) != "":
+			contents = append(contents, &astro.SlotMetadata{Name: "default", Range: textRange(c)})
+		case c.Type == astro.ElementNode && c.Expression:
+			// The elements of an expression fill their own slot, like `{show && <p slot="note" />}`
+			hasElement := false
+			for gc := c.FirstChild; gc != nil; gc = gc.NextSibling {
+				if gc.Type == astro.ElementNode {
+					hasElement = true
+					contents = append(contents, slotContent(gc))
+				}
+			}
+			if !hasElement {
+				contents = append(contents, &astro.SlotMetadata{Name: "default", Range: nameRange(c)})
+			}
+		case c.Type == astro.ElementNode:
+			contents = append(contents, slotContent(c))
+		}
+	}
+	return contents
+}
+
+// slotContent returns the slot filled by the element c
+func slotContent(c *astro.Node) *astro.SlotMetadata {
+	content := &astro.SlotMetadata{Name: "default", Range: nameRange(c)}
+	if attr := GetAttr(c, "slot"); attr != nil {
+		content.Name = attr.Val
+		content.Dynamic = attr.Type != astro.QuotedAttribute
+	}
+	return content
+}
+
+// slotNames returns the names of the static slots of contents, without duplicates
+func slotNames(contents []*astro.SlotMetadata) []string {
+	names := []string{}
+	for _, content := range contents {
+		if !content.Dynamic && !slices.Contains(names, content.Name) {
+			names = append(names, content.Name)
+		}
+	}
+	return names
+}
+
+// nameRange returns the range of the name of the element n, or of the brace opening an expression
+func nameRange(n *astro.Node) loc.Range {
+	if len(n.Loc) == 0 {
+		return loc.Range{}
+	}
+	if n.Expression {
+		return loc.Range{Loc: n.Loc[0], Len: 1}
+	}
+	return loc.Range{Loc: n.Loc[0], Len: len(n.Data)}
+}
+
+// textRange returns the range of the first line of the text n, without its leading whitespace
+func textRange(n *astro.Node) loc.Range {
+	if len(n.Loc) == 0 {
+		return loc.Range{}
+	}
+	text := strings.TrimLeft(n.Data, " \t\r\n\f")
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return loc.Range{Loc: loc.Loc{Start: n.Loc[0].Start + len(n.Data) - len(text)}, Len: len(strings.TrimSpace(line))}
+}
+
+// childSlots are the slots defined by a component loaded with TransformOptions.LoadComponent
+type childSlots struct {
+	names []string
+	// known is false when the slots of the component can't be listed, like when a slot is rendered
+	// with `Astro.slots.render(name)`
+	known bool
+}
+
+// astroSlotsCall matches the calls of Astro.slots with a string literal, like `Astro.slots.has('icon')`
+var astroSlotsCall = regexp.MustCompile(`Astro\.slots\??\.(?:render|has)\(\s*(?:'([^'\\]*)'|"([^"\\]*)")`)
+
+// loadChildSlots returns the slots defined by the source of a component: its <slot> elements, and
+// the slots it renders with Astro.slots
+func loadChildSlots(source string) childSlots {
+	doc, err := astro.ParseWithOptions(strings.NewReader(source))
+	if err != nil {
+		return childSlots{}
+	}
+	slots := childSlots{known: true}
+	walk(doc, func(n *astro.Node) {
+		if !isSlotElement(n) {
+			return
+		}
+		slot := definedSlot(n)
+		if slot.Dynamic {
+			slots.known = false
+		} else if !slices.Contains(slots.names, slot.Name) {
+			slots.names = append(slots.names, slot.Name)
+		}
+	})
+	calls := astroSlotsCall.FindAllStringSubmatch(source, -1)
+	if len(calls) != strings.Count(source, "Astro.slots") {
+		// Astro.slots is used in another way, like `Astro.slots.render(name)`
+		slots.known = false
+	}
+	for _, call := range calls {
+		if name := call[1] + call[2]; !slices.Contains(slots.names, name) {
+			slots.names = append(slots.names, name)
+		}
+	}
+	return slots
+}
+
+// childSlots returns the slots defined by the .astro component at path, loaded once per transform
+func (g *componentGraph) childSlots(path string, opts *TransformOptions) childSlots {
+	if slots, ok := g.loaded[path]; ok {
+		return slots
+	}
+	var slots childSlots
+	if source, ok := opts.LoadComponent(path); ok {
+		slots = loadChildSlots(source)
+	}
+	g.loaded[path] = slots
+	return slots
+}
+
+// warnUnknownSlots warns about the content passed to a slot which the .astro component it is passed
+// to doesn't define, as Astro drops it. The components are loaded with TransformOptions.LoadComponent.
+func warnUnknownSlots(doc *astro.Node, opts *TransformOptions, h *handler.Handler, g *componentGraph) {
+	if opts.LoadComponent == nil {
+		return
+	}
+	for _, usage := range doc.ComponentUsages {
+		if usage.ResolvedPath == "" || !strings.HasSuffix(usage.Specifier, ".astro") {
+			continue
+		}
+		slots := g.childSlots(usage.ResolvedPath, opts)
+		if !slots.known {
+			continue
+		}
+		warned := []string{}
+		for _, content := range usage.SlotContents {
+			if content.Dynamic || slices.Contains(slots.names, content.Name) || slices.Contains(warned, content.Name) {
+				continue
+			}
+			warned = append(warned, content.Name)
+			text := fmt.Sprintf("`<%s>` has no slot named `%s`, so this content is not rendered", usage.LocalName, content.Name)
+			if content.Name == "default" {
+				text = fmt.Sprintf("`<%s>` has no default slot, so this content is not rendered", usage.LocalName)
+			}
+			hint := fmt.Sprintf("`%s` defines no slot.", usage.Specifier)
+			if len(slots.names) > 0 {
+				hint = fmt.Sprintf("`%s` defines the slots `%s`.", usage.Specifier, strings.Join(slots.names, "`, `"))
+			}
+			h.AppendWarning(&loc.ErrorWithRange{
+				Code:  loc.WARNING_UNKNOWN_SLOT,
+				Text:  text,
+				Hint:  hint,
+				Range: content.Range,
+			})
+		}
+	}
+}
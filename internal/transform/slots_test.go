@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	astro "github.com/withastro/compiler/internal"
+	"github.com/withastro/compiler/internal/handler"
+	"github.com/withastro/compiler/internal/loc"
+)
+
+func TestRecordSlots(t *testing.T) {
+	source := `---
+import Card from './Card.astro';
+---
+<slot name="title"><h1>Untitled</h1></slot>
+<slot>
+	<!-- empty -->
+</slot>
+<slot name={name} />
+<slot is:inline name="shadow" />
+<Card>
+	Hello
+	<h2 slot="title">Title</h2>
+	{show && <p slot="note" />}
+	{text}
+	<p slot={tab} />
+</Card>`
+	doc, err := astro.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewHandler(source, "<stdin>")
+	Transform(doc, TransformOptions{Filename: "<stdin>"}, h)
+
+	// text returns the source of the range r
+	text := func(r loc.Range) string {
+		return source[r.Loc.Start : r.Loc.Start+r.Len]
+	}
+	var slots []string
+	for _, s := range doc.Slots {
+		slots = append(slots, fmt.Sprintf("%s %v %v %s", s.Name, s.Dynamic, s.HasFallback, text(s.Range)))
+	}
+	want := []string{"title false true slot", "default false false slot", "name true false slot"}
+	if strings.Join(slots, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected slots:\n%s\nwant:\n%s", strings.Join(slots, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(doc.ComponentUsages) != 1 {
+		t.Fatalf("expected a component, got %d", len(doc.ComponentUsages))
+	}
+	var contents []string
+	for _, c := range doc.ComponentUsages[0].SlotContents {
+		contents = append(contents, fmt.Sprintf("%s %v %s", c.Name, c.Dynamic, text(c.Range)))
+	}
+	want = []string{"default false Hello", "title false h2", "note false p", "default false {", "tab true p"}
+	if strings.Join(contents, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected slot contents:\n%s\nwant:\n%s", strings.Join(contents, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWarnUnknownSlots(t *testing.T) {
+	components := map[string]string{
+		"/src/Card.astro":    `<div><slot name="title" /><slot /></div>`,
+		"/src/Empty.astro":   `<div />`,
+		"/src/Render.astro":  "---\nconst html = await Astro.slots.render('footer');\n---\n<slot />",
+		"/src/Dynamic.astro": "---\nconst { name } = Astro.props;\n---\n{Astro.slots.render(name)}",
+	}
+	tests := []struct {
+		name     string
+		source   string
+		warnings []string
+	}{
+		{
+			name:     "defined slots",
+			source:   `<Card><h2 slot="title" />Content</Card>`,
+			warnings: []string{},
+		},
+		{
+			name:   "unknown slot",
+			source: `<Card><p slot="footer" /><p slot="footer" /></Card>`,
+			warnings: []string{
+				"9:8:1 `<Card>` has no slot named `footer`, so this content is not rendered (`./Card.astro` defines the slots `title`, `default`.)",
+			},
+		},
+		{
+			name:   "default slot",
+			source: "<Empty>\n\tContent\n</Empty>",
+			warnings: []string{
+				"10:2:7 `<Empty>` has no default slot, so this content is not rendered (`./Empty.astro` defines no slot.)",
+			},
+		},
+		{
+			name:     "Astro.slots",
+			source:   `<Render><p slot="footer" />Content</Render>`,
+			warnings: []string{},
+		},
+		{
+			name:     "dynamic slots",
+			source:   `<Dynamic><p slot="anything" /></Dynamic><Card><p slot={name} /></Card>`,
+			warnings: []string{},
+		},
+		{
+			name:     "not loaded",
+			source:   `<Missing><p slot="footer" /></Missing><Counter><p slot="footer" /></Counter>`,
+			warnings: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := `---
+import Card from './Card.astro';
+import Empty from './Empty.astro';
+import Render from './Render.astro';
+import Dynamic from './Dynamic.astro';
+import Missing from './Missing.astro';
+import Counter from './Counter.jsx';
+---
+` + tt.source
+			doc, err := astro.Parse(strings.NewReader(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := handler.NewHandler(source, "/src/index.astro")
+			loaded := 0
+			Transform(doc, TransformOptions{Filename: "/src/index.astro", LoadComponent: func(path string) (string, bool) {
+				loaded++
+				component, ok := components[path]
+				return component, ok
+			}}, h)
+
+			warnings := []string{}
+			for _, w := range h.Warnings() {
+				if w.Code == int(loc.WARNING_UNKNOWN_SLOT) {
+					warnings = append(warnings, fmt.Sprintf("%d:%d:%d %s (%s)", w.Location.Line, w.Location.Column, w.Location.Length, w.Text, w.Hint))
+				}
+			}
+			if strings.Join(warnings, "\n") != strings.Join(tt.warnings, "\n") {
+				t.Errorf("unexpected warnings:\n%s\nwant:\n%s", strings.Join(warnings, "\n"), strings.Join(tt.warnings, "\n"))
+			}
+			if loaded > 2 {
+				t.Errorf("expected each component to be loaded once, got %d loads", loaded)
+			}
+		})
+	}
+}
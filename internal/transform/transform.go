@@ -31,45 +31,113 @@ type TransformOptions struct {
 	ResultScopedSlot        bool
 	TransitionsAnimationURL string
 	ResolvePath             func(string) string
-	PreprocessStyle         interface{}
+	PreprocessStyle         StylePreprocessor
 	AnnotateSourceFile      bool
 	RenderScript            bool
+	// AccessibilityWarnings reports the accessibility mistakes of the template, see accessibility
+	AccessibilityWarnings bool
+	// LoadComponent returns the source of the .astro component at a resolved path, to warn about
+	// the content passed to the slots it doesn't define
+	LoadComponent func(path string) (source string, ok bool)
+	// Directives declares the directives of integrations, like `client:hover`. When not nil, the
+	// attributes in the namespace of a directive, like `client:`, are validated against these and
+	// BuiltinDirectives: unknown directives and misused ones are reported as warnings. When nil, only
+	// the misused built-in client directives are reported.
+	Directives []Directive
+	// Passes are run by Transform along with the built-in passes, see Pass
+	Passes []Pass
 }
 
 func Transform(doc *astro.Node, opts TransformOptions, h *handler.Handler) *astro.Node {
 	shouldScope := len(doc.Styles) > 0 && ScopeStyle(doc.Styles, opts)
 	definedVars := GetDefineVars(doc.Styles)
 	didAddDefinedVars := false
+	directives := newDirectiveRegistry(opts.Directives)
+	components := newComponentGraph(doc)
+	var a11y *accessibility
+	if opts.AccessibilityWarnings {
+		a11y = newAccessibility()
+	}
 	i := 0
-	walk(doc, func(n *astro.Node) {
-		i++
-		WarnAboutRerunOnExternalESMs(n, h)
-		WarnAboutMisplacedReload(n, h)
-		HintAboutImplicitInlineDirective(n, h)
-		ExtractScript(doc, n, &opts, h)
-		AddComponentProps(doc, n, &opts)
-		if shouldScope {
-			ScopeElement(n, opts)
-		}
-		if HasAttr(n, TRANSITION_ANIMATE) || HasAttr(n, TRANSITION_NAME) || HasAttr(n, TRANSITION_PERSIST) {
-			doc.Transition = true
-			doc.HeadPropagation = true
-			getOrCreateTransitionScope(n, &opts, i)
-		}
-		if len(definedVars) > 0 {
-			didAdd := AddDefineVars(n, definedVars)
-			if !didAddDefinedVars {
-				didAddDefinedVars = didAdd
+	builtin := []Pass{
+		{Name: PassWarnRerunOnExternalESMs, Enter: func(ctx *PassContext, n *astro.Node) {
+			WarnAboutRerunOnExternalESMs(n, ctx.Handler)
+		}},
+		{Name: PassWarnMisplacedReload, Enter: func(ctx *PassContext, n *astro.Node) {
+			WarnAboutMisplacedReload(n, ctx.Handler)
+		}},
+		{Name: PassHintImplicitInlineDirective, Enter: func(ctx *PassContext, n *astro.Node) {
+			HintAboutImplicitInlineDirective(n, ctx.Handler)
+		}},
+		{Name: PassValidateDirectives, Enter: func(ctx *PassContext, n *astro.Node) {
+			validateDirectives(n, directives, ctx.Handler)
+		}},
+		{Name: PassExtractScript, Enter: func(ctx *PassContext, n *astro.Node) {
+			ExtractScript(ctx.Doc, n, ctx.Options, ctx.Handler)
+		}},
+		{Name: PassRecordComponents, Enter: func(ctx *PassContext, n *astro.Node) {
+			recordComponentUsage(ctx.Doc, n, ctx.Options, components)
+			recordSlot(ctx.Doc, n)
+		}, Leave: func(ctx *PassContext, n *astro.Node) {
+			if n == ctx.Doc {
+				recordUnusedComponentImports(ctx.Doc, ctx.Options, components)
+				warnUnknownSlots(ctx.Doc, ctx.Options, ctx.Handler, components)
 			}
-		}
-		mergeClassList(doc, n, &opts)
-		if n.DataAtom == a.Head && !IsImplicitNode(n) {
-			doc.ContainsHead = true
-		}
-		if opts.AnnotateSourceFile {
-			AnnotateElement(n, opts)
-		}
-	})
+		}},
+		{Name: PassLintAccessibility, Enter: func(ctx *PassContext, n *astro.Node) {
+			if a11y != nil {
+				a11y.enter(n, ctx.Handler)
+			}
+		}, Leave: func(ctx *PassContext, n *astro.Node) {
+			if a11y != nil && n == ctx.Doc {
+				a11y.leave(ctx.Handler)
+			}
+		}},
+		{Name: PassAddComponentProps, Enter: func(ctx *PassContext, n *astro.Node) {
+			AddComponentProps(ctx.Doc, n, ctx.Options)
+		}},
+		{Name: PassScopeElement, Enter: func(ctx *PassContext, n *astro.Node) {
+			if shouldScope {
+				ScopeElement(n, *ctx.Options)
+			}
+		}},
+		{Name: PassTransitions, Enter: func(ctx *PassContext, n *astro.Node) {
+			i++
+			if HasAttr(n, TRANSITION_ANIMATE) || HasAttr(n, TRANSITION_NAME) || HasAttr(n, TRANSITION_PERSIST) {
+				ctx.Doc.Transition = true
+				ctx.Doc.HeadPropagation = true
+				getOrCreateTransitionScope(n, ctx.Options, i)
+			}
+		}},
+		{Name: PassDefineVars, Enter: func(ctx *PassContext, n *astro.Node) {
+			if len(definedVars) > 0 {
+				didAdd := AddDefineVars(n, definedVars)
+				if !didAddDefinedVars {
+					didAddDefinedVars = didAdd
+				}
+			}
+		}},
+		{Name: PassMergeClassList, Enter: func(ctx *PassContext, n *astro.Node) {
+			mergeClassList(ctx.Doc, n, ctx.Options)
+		}},
+		{Name: PassContainsHead, Enter: func(ctx *PassContext, n *astro.Node) {
+			if n.DataAtom == a.Head && !IsImplicitNode(n) {
+				ctx.Doc.ContainsHead = true
+			}
+		}},
+		{Name: PassAnnotateSourceFile, Enter: func(ctx *PassContext, n *astro.Node) {
+			if ctx.Options.AnnotateSourceFile {
+				AnnotateElement(n, *ctx.Options)
+			}
+		}},
+	}
+	passes, err := orderPasses(builtin, opts.Passes)
+	if err != nil {
+		// The custom passes are skipped, rather than running them in the wrong order
+		h.AppendError(err)
+		passes = builtin
+	}
+	runPasses(&PassContext{Doc: doc, Options: &opts, Handler: h}, passes)
 	if len(definedVars) > 0 && !didAddDefinedVars {
 		for _, style := range doc.Styles {
 			for _, a := range style.Attr {
@@ -1,58 +1,74 @@
 package wasm_utils
 
 import (
+	"context"
+	"fmt"
 	"runtime/debug"
 	"strings"
 	"syscall/js"
 
 	"github.com/norunners/vert"
-	astro "github.com/withastro/compiler/internal"
-	"github.com/withastro/compiler/internal/handler"
 )
 
 // See https://stackoverflow.com/questions/68426700/how-to-wait-a-js-async-function-from-golang-wasm
 func Await(awaitable js.Value) ([]js.Value, []js.Value) {
-	then := make(chan []js.Value)
+	result, reason, _ := AwaitContext(context.Background(), awaitable)
+	return result, reason
+}
+
+// AwaitContext is like Await, but stops waiting when ctx is done and returns the cause of ctx.
+// awaitable may also be a plain value, which is awaited like with `await`.
+func AwaitContext(ctx context.Context, awaitable js.Value) ([]js.Value, []js.Value, error) {
+	// Buffered, as the promise may settle after ctx is done and nobody is receiving anymore
+	then := make(chan []js.Value, 1)
 	thenFunc := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		then <- args
 		return nil
 	})
-	// defers are called LIFO!
-	// This will `close` before `Release()`
-	defer thenFunc.Release()
-	defer close(then)
-
-	catch := make(chan []js.Value)
+	catch := make(chan []js.Value, 1)
 	catchFunc := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		catch <- args
 		return nil
 	})
-	// defers are called LIFO!
-	// This will `close` before `Release()`
-	defer catchFunc.Release()
-	defer close(catch)
+	release := func() {
+		thenFunc.Release()
+		catchFunc.Release()
+	}
 
-	awaitable.Call("then", thenFunc).Call("catch", catchFunc)
+	js.Global().Get("Promise").Call("resolve", awaitable).Call("then", thenFunc).Call("catch", catchFunc)
 
 	select {
 	case result := <-then:
-		return result, nil
+		release()
+		return result, nil, nil
 	case err := <-catch:
-		return nil, err
+		release()
+		return nil, err, nil
+	case <-ctx.Done():
+		// The callbacks can only be released once the promise settles, if ever
+		go func() {
+			select {
+			case <-then:
+			case <-catch:
+			}
+			release()
+		}()
+		return nil, nil, context.Cause(ctx)
 	}
 }
 
-func GetAttrs(n *astro.Node) js.Value {
-	attrs := js.Global().Get("Object").New()
-	for _, attr := range n.Attr {
-		switch attr.Type {
-		case astro.QuotedAttribute:
-			attrs.Set(attr.Key, attr.Val)
-		case astro.EmptyAttribute:
-			attrs.Set(attr.Key, true)
+// GetAttrs converts the attributes of a style to a JS object.
+// Attributes without a value are set to `true`.
+func GetAttrs(attrs map[string]*string) js.Value {
+	obj := js.Global().Get("Object").New()
+	for key, value := range attrs {
+		if value == nil {
+			obj.Set(key, true)
+		} else {
+			obj.Set(key, *value)
 		}
 	}
-	return attrs
+	return obj
 }
 
 type JSError struct {
@@ -64,7 +80,20 @@ func (err *JSError) Value() js.Value {
 	return vert.ValueOf(err).Value
 }
 
-func ErrorToJSError(h *handler.Handler, err error) js.Value {
+// RecoveredError converts a value returned by recover() to an error
+func RecoveredError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// ErrorToJSErrorInstance converts err to an instance of Error, which can be thrown by JS code
+func ErrorToJSErrorInstance(err error) js.Value {
+	return js.Global().Get("Error").New(strings.TrimSpace(err.Error()))
+}
+
+func ErrorToJSError(err error) js.Value {
 	stack := string(debug.Stack())
 	message := strings.TrimSpace(err.Error())
 	jsError := JSError{
@@ -5,17 +5,37 @@ export const transform: typeof types.transform = (input, options) => {
 	return ensureServiceIsRunning().transform(input, options);
 };
 
+export const transformBatch: typeof types.transformBatch = (files, options) => {
+	return ensureServiceIsRunning().transformBatch(files, options);
+};
+
 export const parse: typeof types.parse = (input, options) => {
 	return ensureServiceIsRunning().parse(input, options);
 };
 
+export const parseFragment: typeof types.parseFragment = (input, options) => {
+	return ensureServiceIsRunning().parseFragment(input, options);
+};
+
+export const tokenize: typeof types.tokenize = (input, options) => {
+	return ensureServiceIsRunning().tokenize(input, options);
+};
+
+export const extractProps: typeof types.extractProps = (input, options) => {
+	return ensureServiceIsRunning().extractProps(input, options);
+};
+
 export const convertToTSX: typeof types.convertToTSX = (input, options) => {
 	return ensureServiceIsRunning().convertToTSX(input, options);
 };
 
 interface Service {
 	transform: typeof types.transform;
+	transformBatch: typeof types.transformBatch;
 	parse: typeof types.parse;
+	parseFragment: typeof types.parseFragment;
+	tokenize: typeof types.tokenize;
+	extractProps: typeof types.extractProps;
 	convertToTSX: typeof types.convertToTSX;
 }
 
@@ -81,6 +101,15 @@ const startRunningService = async (wasmURL: string): Promise<Service> => {
 	return {
 		transform: (input, options) =>
 			new Promise((resolve) => resolve(service.transform(input, options || {}))),
+		transformBatch: (files, options) =>
+			new Promise((resolve) =>
+				resolve(
+					service.transformBatch(
+						files.map((file) => ({ ...file, options: file.options || {} })),
+						options || {}
+					)
+				)
+			),
 		convertToTSX: (input, options) =>
 			new Promise((resolve) => resolve(service.convertToTSX(input, options || {}))).then(
 				(result: any) => ({
@@ -92,5 +121,15 @@ const startRunningService = async (wasmURL: string): Promise<Service> => {
 			new Promise((resolve) => resolve(service.parse(input, options || {}))).then(
 				(result: any) => ({ ...result, ast: JSON.parse(result.ast) })
 			),
+		parseFragment: (input, options) =>
+			new Promise((resolve) => resolve(service.parseFragment(input, options || {}))).then(
+				(result: any) => ({ ...result, ast: JSON.parse(result.ast) })
+			),
+		tokenize: (input, options) =>
+			new Promise((resolve) => resolve(service.tokenize(input, options || {}))),
+		extractProps: (input, options) =>
+			new Promise((resolve) => resolve(service.extractProps(input, options || {}))).then(
+				(result: any) => ({ ...result, schema: result.schema ? JSON.parse(result.schema) : null })
+			),
 	};
 };
@@ -1,8 +1,20 @@
 export type {
 	HoistedScript,
+	ParseFragmentOptions,
 	ParseOptions,
 	ParseResult,
 	PreprocessorResult,
+	PropsOptions,
+	PropsResult,
+	PropsSchema,
+	Token,
+	TokenAttribute,
+	TokenizeOptions,
+	TokenizeResult,
+	TokenRange,
+	TransformBatchInput,
+	TransformBatchOptions,
+	TransformBatchResult,
 	TransformOptions,
 	TransformResult,
 } from '../shared/types.js';
@@ -15,10 +27,26 @@ export const transform: typeof types.transform = async (input, options) => {
 	return getService().then((service) => service.transform(input, options));
 };
 
+export const transformBatch: typeof types.transformBatch = async (files, options) => {
+	return getService().then((service) => service.transformBatch(files, options));
+};
+
 export const parse: typeof types.parse = async (input, options) => {
 	return getService().then((service) => service.parse(input, options));
 };
 
+export const parseFragment: typeof types.parseFragment = async (input, options) => {
+	return getService().then((service) => service.parseFragment(input, options));
+};
+
+export const tokenize: typeof types.tokenize = async (input, options) => {
+	return getService().then((service) => service.tokenize(input, options));
+};
+
+export const extractProps: typeof types.extractProps = async (input, options) => {
+	return getService().then((service) => service.extractProps(input, options));
+};
+
 export const convertToTSX: typeof types.convertToTSX = async (input, options) => {
 	return getService().then((service) => service.convertToTSX(input, options));
 };
@@ -32,7 +60,11 @@ export const compile = async (template: string): Promise<string> => {
 
 interface Service {
 	transform: typeof types.transform;
+	transformBatch: typeof types.transformBatch;
 	parse: typeof types.parse;
+	parseFragment: typeof types.parseFragment;
+	tokenize: typeof types.tokenize;
+	extractProps: typeof types.extractProps;
 	convertToTSX: typeof types.convertToTSX;
 }
 
@@ -89,6 +121,21 @@ const startRunningService = async (): Promise<Service> => {
 					throw err;
 				}
 			}),
+		transformBatch: (files, options) =>
+			new Promise((resolve) => {
+				try {
+					resolve(
+						_service.transformBatch(
+							files.map((file) => ({ ...file, options: file.options || {} })),
+							options || {}
+						)
+					);
+				} catch (err) {
+					// Recreate the service next time on panic
+					longLivedService = void 0;
+					throw err;
+				}
+			}),
 		parse: (input, options) =>
 			new Promise((resolve) => resolve(_service.parse(input, options || {})))
 				.catch((error) => {
@@ -96,6 +143,30 @@ const startRunningService = async (): Promise<Service> => {
 					throw error;
 				})
 				.then((result: any) => ({ ...result, ast: JSON.parse(result.ast) })),
+		parseFragment: (input, options) =>
+			new Promise((resolve) => resolve(_service.parseFragment(input, options || {})))
+				.catch((error) => {
+					longLivedService = void 0;
+					throw error;
+				})
+				.then((result: any) => ({ ...result, ast: JSON.parse(result.ast) })),
+		tokenize: (input, options) =>
+			new Promise<types.TokenizeResult>((resolve) =>
+				resolve(_service.tokenize(input, options || {}))
+			).catch((error) => {
+				longLivedService = void 0;
+				throw error;
+			}),
+		extractProps: (input, options) =>
+			new Promise((resolve) => resolve(_service.extractProps(input, options || {})))
+				.catch((error) => {
+					longLivedService = void 0;
+					throw error;
+				})
+				.then((result: any) => ({
+					...result,
+					schema: result.schema ? JSON.parse(result.schema) : null,
+				})),
 		convertToTSX: (input, options) => {
 			return new Promise((resolve) => resolve(_service.convertToTSX(input, options || {})))
 				.catch((error) => {
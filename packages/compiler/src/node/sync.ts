@@ -10,6 +10,9 @@ type UnwrappedPromise<T> = T extends (...params: any) => Promise<infer Return>
 interface Service {
 	transform: UnwrappedPromise<typeof types.transform>;
 	parse: UnwrappedPromise<typeof types.parse>;
+	parseFragment: UnwrappedPromise<typeof types.parseFragment>;
+	tokenize: UnwrappedPromise<typeof types.tokenize>;
+	extractProps: UnwrappedPromise<typeof types.extractProps>;
 	convertToTSX: UnwrappedPromise<typeof types.convertToTSX>;
 }
 
@@ -22,6 +25,10 @@ function getService(): Service {
 
 let longLivedService: Service | undefined;
 
+/**
+ * Transforms a component synchronously. `resolvePath`, `preprocessStyle` and `loadComponent` must not
+ * return a Promise, and the `signal` and `timeout` options are ignored.
+ */
 export const transform = ((input, options) =>
 	getService().transform(input, options)) satisfies Service['transform'];
 
@@ -29,6 +36,18 @@ export const parse = ((input, options) => {
 	return getService().parse(input, options);
 }) satisfies Service['parse'];
 
+export const parseFragment = ((input, options) => {
+	return getService().parseFragment(input, options);
+}) satisfies Service['parseFragment'];
+
+export const tokenize = ((input, options) => {
+	return getService().tokenize(input, options);
+}) satisfies Service['tokenize'];
+
+export const extractProps = ((input, options) => {
+	return getService().extractProps(input, options);
+}) satisfies Service['extractProps'];
+
 export const convertToTSX = ((input, options) => {
 	return getService().convertToTSX(input, options);
 }) satisfies Service['convertToTSX'];
@@ -43,13 +62,19 @@ export function startRunningService(): Service {
 	const _service: any = (globalThis as any)['@astrojs/compiler'];
 	return {
 		transform: (input, options) => {
+			let result: any;
 			try {
-				return _service.transform(input, options || {});
+				result = _service.transformSync(input, options || {});
 			} catch (err) {
 				// Recreate the service next time on panic
 				longLivedService = void 0;
 				throw err;
 			}
+			// Errors of the transform itself are returned, as Go can't throw them
+			if (result instanceof Error) {
+				throw result;
+			}
+			return result;
 		},
 		parse: (input, options) => {
 			try {
@@ -60,6 +85,32 @@ export function startRunningService(): Service {
 				throw err;
 			}
 		},
+		parseFragment: (input, options) => {
+			try {
+				const result = _service.parseFragment(input, options || {});
+				return { ...result, ast: JSON.parse(result.ast) };
+			} catch (err) {
+				longLivedService = void 0;
+				throw err;
+			}
+		},
+		tokenize: (input, options) => {
+			try {
+				return _service.tokenize(input, options || {});
+			} catch (err) {
+				longLivedService = void 0;
+				throw err;
+			}
+		},
+		extractProps: (input, options) => {
+			try {
+				const result = _service.extractProps(input, options || {});
+				return { ...result, schema: result.schema ? JSON.parse(result.schema) : null };
+			} catch (err) {
+				longLivedService = void 0;
+				throw err;
+			}
+		},
 		convertToTSX: (input, options) => {
 			try {
 				const result = _service.convertToTSX(input, options || {});
@@ -5,7 +5,7 @@ export type ParentNode =
 	| CustomElementNode
 	| FragmentNode
 	| ExpressionNode;
-export type LiteralNode = TextNode | DoctypeNode | CommentNode | FrontmatterNode;
+export type LiteralNode = TextNode | DoctypeNode | CommentNode | FrontmatterNode | ErrorNode;
 
 export type Node =
 	| RootNode
@@ -17,11 +17,20 @@ export type Node =
 	| TextNode
 	| FrontmatterNode
 	| DoctypeNode
-	| CommentNode;
+	| CommentNode
+	| ErrorNode;
 
 export interface Position {
 	start: Point;
 	end?: Point;
+	/** The start tag of an element, the opening brace of an expression or the opening fence of a frontmatter */
+	open?: Position;
+	/** The end tag, closing brace or closing fence, unless it is implied */
+	close?: Position;
+	/** The name of an attribute */
+	name?: Position;
+	/** The value of an attribute, without its quotes or braces */
+	value?: Position;
 }
 export interface Point {
 	/** 1-based line number */
@@ -97,8 +106,45 @@ export interface CommentNode extends ValueNode {
 
 export interface FrontmatterNode extends ValueNode {
 	type: 'frontmatter';
+	/** The ESTree AST of the frontmatter, with the `frontmatterAST` option */
+	program?: ESTreeProgram;
+}
+
+/**
+ * A node of an ESTree AST, with the TypeScript nodes of `@typescript-eslint/typescript-estree`.
+ * `start` and `end` are byte offsets in the component, and `loc` is only set with `position`.
+ */
+export interface ESTreeNode {
+	type: string;
+	start: number;
+	end: number;
+	loc?: {
+		start: { line: number; column: number };
+		end: { line: number; column: number };
+	};
+	[key: string]: unknown;
+}
+
+export interface ESTreeComment extends ESTreeNode {
+	type: 'Line' | 'Block';
+	value: string;
+}
+
+export interface ESTreeProgram extends ESTreeNode {
+	type: 'Program';
+	sourceType: 'module';
+	body: ESTreeNode[];
+	comments: ESTreeComment[];
 }
 
 export interface ExpressionNode extends ParentLikeNode {
 	type: 'expression';
 }
+
+/**
+ * Marks where the parser recovered from an error, with the `recover` option. The value is the
+ * unexpected source, like a stray `}` or end tag, or empty when something is missing there.
+ */
+export interface ErrorNode extends ValueNode {
+	type: 'error';
+}
@@ -4,6 +4,11 @@ export enum DiagnosticCode {
 	ERROR_FRAGMENT_SHORTHAND_ATTRS = 1002,
 	ERROR_UNMATCHED_IMPORT = 1003,
 	ERROR_UNSUPPORTED_SLOT_ATTRIBUTE = 1004,
+	ERROR_UNTERMINATED_STRING = 1005,
+	ERROR_CANCELLED = 1006,
+	ERROR_UNTERMINATED_FRONTMATTER = 1007,
+	ERROR_UNTERMINATED_EXPRESSION = 1008,
+	ERROR_FRONTMATTER_SYNTAX = 1009,
 	WARNING = 2000,
 	WARNING_UNTERMINATED_HTML_COMMENT = 2001,
 	WARNING_UNCLOSED_HTML_TAG = 2002,
@@ -11,6 +16,32 @@ export enum DiagnosticCode {
 	WARNING_IGNORED_DIRECTIVE = 2004,
 	WARNING_UNSUPPORTED_EXPRESSION = 2005,
 	WARNING_SET_WITH_CHILDREN = 2006,
+	WARNING_CANNOT_DEFINE_VARS = 2007,
+	WARNING_INVALID_SPREAD = 2008,
+	WARNING_UNEXPECTED_CHARACTER = 2009,
+	WARNING_CANNOT_RERUN = 2010,
+	WARNING_UNEXPECTED_BRACE = 2011,
+	WARNING_UNCLOSED_ELEMENT = 2012,
+	WARNING_UNEXPECTED_END_TAG = 2013,
+	WARNING_FOSTER_PARENTED = 2014,
+	WARNING_IMPLIED_END_TAG = 2015,
+	WARNING_MISNESTED_FORMATTING = 2016,
+	WARNING_UNKNOWN_DIRECTIVE = 2017,
+	WARNING_MISUSED_DIRECTIVE = 2018,
+	WARNING_FOREIGN_CASING = 2019,
+	WARNING_UNKNOWN_FOREIGN_ATTRIBUTE = 2020,
+	WARNING_HTML_IN_FOREIGN_CONTENT = 2021,
+	WARNING_UNKNOWN_SLOT = 2022,
+	WARNING_A11Y_MISSING_ALT = 2023,
+	WARNING_A11Y_MISSING_NAME = 2024,
+	WARNING_A11Y_INVALID_ARIA = 2025,
+	WARNING_A11Y_INVALID_ROLE = 2026,
+	WARNING_A11Y_MISSING_LANG = 2027,
+	WARNING_A11Y_DUPLICATE_ID = 2028,
+	WARNING_A11Y_MISSING_LABEL = 2029,
+	WARNING_NESTED_FORM = 2030,
+	WARNING_EXPRESSION_SYNTAX = 2031,
 	INFO = 3000,
 	HINT = 4000,
+	HINT_A11Y_POSITIVE_TABINDEX = 4001,
 }
@@ -4,7 +4,8 @@ export type * from './ast.js';
 
 export interface PreprocessorResult {
 	code: string;
-	map?: string;
+	/** A source map from `code` to the original content of the style, as a JSON string or an object */
+	map?: string | object;
 }
 
 export interface PreprocessorError {
@@ -13,6 +14,29 @@ export interface PreprocessorError {
 
 export interface ParseOptions {
 	position?: boolean;
+	/**
+	 * Recover from the errors of an incomplete component, e.g. while it is being edited: an
+	 * unterminated frontmatter or expression, a stray `}` and unbalanced tags are closed at the
+	 * nearest boundary. Each recovery adds an `error` node to the AST and a diagnostic.
+	 */
+	recover?: boolean;
+	/**
+	 * Parse the frontmatter as TypeScript, and add its ESTree AST to the `program` of the
+	 * frontmatter node. A syntax error is reported as a diagnostic and leaves `program` unset.
+	 */
+	frontmatterAST?: boolean;
+	/**
+	 * Parse the expressions of the template and of the attribute values as JavaScript with JSX, and report
+	 * their syntax errors as warnings, located in the source of the component.
+	 */
+	validateExpressions?: boolean;
+}
+
+export interface ParseFragmentOptions extends ParseOptions {
+	/**
+	 * The name of the element the fragment is parsed in, e.g. `tbody` or `svg`. Defaults to `body`.
+	 */
+	context?: string;
 }
 
 export enum DiagnosticSeverity {
@@ -39,6 +63,20 @@ export interface DiagnosticLocation {
 	length: number;
 }
 
+export interface DirectiveDefinition {
+	/** The name of the directive, e.g. `client:hover`. A name ending with `:*`, e.g. `data:*`, declares every directive with that prefix. */
+	name: string;
+	/**
+	 * The kinds of elements the directive is allowed on, or the names of elements like `script`.
+	 * `element` is any element which isn't a component, a custom element or a fragment. Defaults to any element.
+	 */
+	elements?: ('component' | 'custom-element' | 'fragment' | 'element' | (string & {}))[];
+	/** Reports the directive when it is written without a value, e.g. `<div set:html>` */
+	requiresValue?: boolean;
+	/** Validates a string value of the directive, returning an error message for an invalid value */
+	validate?: (value: string) => Promise<string | null | undefined> | string | null | undefined;
+}
+
 export interface TransformOptions {
 	internalURL?: string;
 	filename?: string;
@@ -54,6 +92,12 @@ export interface TransformOptions {
 	as?: 'document' | 'fragment';
 	transitionsAnimationURL?: string;
 	resolvePath?: (specifier: string) => Promise<string> | string;
+	/**
+	 * Returns the source of the `.astro` component at `path`, a specifier resolved like with `resolvePath`,
+	 * or `null` when it can't be loaded. When set, the content passed to a slot which the `.astro` component
+	 * doesn't define, and which is never rendered, is reported as a warning.
+	 */
+	loadComponent?: (path: string) => Promise<string | null> | string | null;
 	preprocessStyle?: (
 		content: string,
 		attrs: Record<string, string>
@@ -65,6 +109,47 @@ export interface TransformOptions {
 	 * @experimental
 	 */
 	renderScript?: boolean;
+	/**
+	 * Warn about the markup restructured by the HTML parsing rules, e.g. a `<div>` closing the `<p>`
+	 * it is written in, or a `<div>` moved out of a `<table>`. The warnings show the structure as
+	 * written and as rendered. An element whose end tag is omitted, like the `<p>` of
+	 * `<p>a<div>b</div>`, and the content which isn't restructured, like a `<div>` in a `<ul>`,
+	 * are not warned about.
+	 */
+	contentModelWarnings?: boolean;
+	/**
+	 * Warn about the mistakes in inline SVG and MathML: elements and attributes written with the wrong
+	 * casing (e.g. `viewbox`), unknown attributes (e.g. `stroke_width`), and HTML elements outside of a
+	 * `<foreignObject>`.
+	 */
+	foreignContentWarnings?: boolean;
+	/**
+	 * Parse the expressions of the template and of the attribute values as JavaScript with JSX, and report
+	 * their syntax errors as warnings, located in the source of the component.
+	 */
+	validateExpressions?: boolean;
+	/**
+	 * Report the accessibility mistakes of the template: images without `alt`, links, buttons and form controls
+	 * without an accessible name or label, unknown or invalid ARIA attributes and roles, positive `tabindex` values,
+	 * an `<html>` without `lang` and duplicate ids. Each rule has its own `DiagnosticCode`, and the attributes
+	 * written as expressions or spread are not checked.
+	 */
+	accessibilityWarnings?: boolean;
+	/**
+	 * Declares the custom directives of integrations, e.g. `client:hover` or `data:*`. When set, the attributes
+	 * in the namespace of a directive are validated against these and the built-in directives: unknown directives,
+	 * directives on the wrong kind of element and missing or invalid values are reported as warnings. When unset,
+	 * only the built-in client directives are validated, e.g. a `client:media` without a media query or a `client:load`
+	 * on an HTML element.
+	 */
+	directives?: DirectiveDefinition[];
+	/**
+	 * Aborts the transform, including the pending `preprocessStyle`, `resolvePath` and `loadComponent` calls.
+	 * An aborted transform rejects with an `AbortError`, whose `diagnostic` has the code `DiagnosticCode.ERROR_CANCELLED`.
+	 */
+	signal?: AbortSignal;
+	/** Aborts the transform after this many milliseconds, like `signal` */
+	timeout?: number;
 }
 
 export type ConvertToTSXOptions = Pick<
@@ -79,6 +164,8 @@ export type ConvertToTSXOptions = Pick<
 	 * Styles will be wrapped in a template literal to be compatible with JSX's spec
 	 */
 	includeStyles?: boolean;
+	/** Recover from the errors of an incomplete component, as with `parse` */
+	recover?: boolean;
 };
 
 export type HoistedScript = { type: string } & (
@@ -100,6 +187,51 @@ export interface HydratedComponent {
 	resolvedPath: string;
 }
 
+export interface ComponentUsage {
+	exportName: string;
+	localName: string;
+	specifier: string;
+	resolvedPath: string;
+	/** The names of the attributes passed to the component, e.g. `...props` for a spread attribute */
+	attributes: string[];
+	/** The names of the slots filled by the children of the component, `default` for the children without a `slot` attribute */
+	slots: string[];
+	/** The children of the component filling a slot, in order */
+	slotContents: SlotContent[];
+	location: DiagnosticLocation;
+}
+
+/** A slot defined by a `<slot>` element of the template */
+export interface Slot {
+	/** The name of the slot, `default` when it isn't named, or the code of the expression naming a dynamic slot */
+	name: string;
+	dynamic: boolean;
+	/** Whether the slot has fallback content, rendered when no content is passed to it */
+	hasFallback: boolean;
+	location: DiagnosticLocation;
+}
+
+/** A child of a component filling a slot */
+export interface SlotContent {
+	/**
+	 * The name of the slot, `default` for a child without a `slot` attribute, or the code of the expression
+	 * naming a dynamic slot, e.g. `slot={tab}`
+	 */
+	name: string;
+	dynamic: boolean;
+	/** The location of the name of the element, or of the start of the text */
+	location: DiagnosticLocation;
+}
+
+export interface ComponentImport {
+	exportName: string;
+	localName: string;
+	specifier: string;
+	resolvedPath: string;
+	/** The location of the import statement */
+	location: DiagnosticLocation;
+}
+
 export interface TransformResult {
 	code: string;
 	map: string;
@@ -107,14 +239,68 @@ export interface TransformResult {
 	styleError: string[];
 	diagnostics: DiagnosticMessage[];
 	css: string[];
+	/**
+	 * The source map returned by `preprocessStyle` for each entry of `css`, or an empty string.
+	 * It maps the preprocessed style, before scoping, to its original content.
+	 */
+	cssMaps: string[];
 	scripts: HoistedScript[];
 	hydratedComponents: HydratedComponent[];
 	clientOnlyComponents: HydratedComponent[];
 	serverComponents: HydratedComponent[];
+	/**
+	 * The components rendered in the template, in order, with the imports they match. The components which
+	 * aren't imported, e.g. `Astro.self`, have no specifier.
+	 */
+	components: ComponentUsage[];
+	/**
+	 * The imports of components which are neither rendered nor referenced in the frontmatter or the
+	 * expressions of the template.
+	 */
+	unusedImports: ComponentImport[];
+	/** The slots defined by the `<slot>` elements of the template, in order */
+	slots: Slot[];
 	containsHead: boolean;
 	propagation: boolean;
 }
 
+export interface TransformBatchInput {
+	source: string;
+	options?: TransformOptions;
+}
+
+export interface TransformBatchOptions {
+	/** Maximum number of files compiled at the same time */
+	concurrency?: number;
+	/**
+	 * Resolves the specifier of a hydrated or server component imported by `importer`.
+	 * When set, it replaces the `resolvePath` option of every file in the batch.
+	 * Results are memoized for the whole batch, per specifier and directory of the importer.
+	 */
+	resolvePath?: (specifier: string, importer: string) => Promise<string> | string;
+	/**
+	 * Aborts the batch. The files which aren't compiled by then fail with an `ERROR_CANCELLED` diagnostic.
+	 * The `signal` and `timeout` options of the files are ignored.
+	 */
+	signal?: AbortSignal;
+	/** Aborts the batch after this many milliseconds, like `signal` */
+	timeout?: number;
+}
+
+export interface TransformBatchFileResult {
+	filename: string;
+	result: TransformResult;
+	/** Set when the file could not be compiled at all. It is also reported in `result.diagnostics`. */
+	error?: string;
+}
+
+export interface TransformBatchResult {
+	/** The result of each file, in the order they were passed to `transformBatch` */
+	files: TransformBatchFileResult[];
+	/** The diagnostics of every file, in the same order */
+	diagnostics: DiagnosticMessage[];
+}
+
 export interface SourceMap {
 	file: string;
 	mappings: string;
@@ -172,6 +358,102 @@ export interface TSXResult {
 export interface ParseResult {
 	ast: RootNode;
 	diagnostics: DiagnosticMessage[];
+	/** The slots defined by the `<slot>` elements of the template, in order */
+	slots: Slot[];
+	/** The components rendered in the template, with the slots they fill, as in `TransformResult` */
+	components: ComponentUsage[];
+}
+
+export interface TokenizeOptions {
+	filename?: string;
+}
+
+/**
+ * A range of the source. `start` and `end` are offsets in bytes of UTF-8, `utf16Start` and
+ * `utf16End` are offsets in UTF-16 code units, which index the source as a JS string.
+ */
+export interface TokenRange {
+	start: number;
+	end: number;
+	utf16Start: number;
+	utf16End: number;
+}
+
+export interface TokenAttribute {
+	kind: 'quoted' | 'empty' | 'expression' | 'spread' | 'shorthand' | 'template-literal';
+	name: string;
+	/** Excludes the braces of a shorthand attribute and the `...` of a spread attribute */
+	nameRange: TokenRange;
+	value: string;
+	/** Excludes quotes and braces. It is empty, at the end of the name, for the attributes without a value. */
+	valueRange: TokenRange;
+	/** The quote around the value, or an empty string when the value isn't quoted */
+	quote: '"' | "'" | '`' | '';
+}
+
+/**
+ * A token of a component, as written in the source. The content of the frontmatter,
+ * of expressions, scripts and styles is `text`.
+ */
+export interface Token {
+	type:
+		| 'text'
+		| 'start-tag'
+		| 'end-tag'
+		| 'self-closing-tag'
+		| 'comment'
+		| 'doctype'
+		| 'frontmatter-fence'
+		| 'start-expression'
+		| 'end-expression';
+	raw: string;
+	range: TokenRange;
+	/** The name of a tag, or an empty string */
+	name: string;
+	/** The attributes of a tag, or an empty array */
+	attributes: TokenAttribute[];
+}
+
+export interface TokenizeResult {
+	/** The tokens cover the whole source, in order */
+	tokens: Token[];
+	diagnostics: DiagnosticMessage[];
+}
+
+export interface PropsOptions {
+	filename?: string;
+}
+
+/**
+ * A JSON Schema (draft 2020-12) of the Props of a component, or of one of its properties.
+ * The types which have no JSON Schema equivalent, like functions or imported types, are
+ * described by their TypeScript source in `tsType`.
+ */
+export interface PropsSchema {
+	$schema?: string;
+	/** The JSDoc comment of the declaration, without its tags */
+	description?: string;
+	type?: 'string' | 'number' | 'boolean' | 'null' | 'object' | 'array';
+	const?: unknown;
+	enum?: unknown[];
+	items?: PropsSchema;
+	prefixItems?: PropsSchema[];
+	/** The properties, in the order of their declaration */
+	properties?: Record<string, PropsSchema>;
+	required?: string[];
+	additionalProperties?: PropsSchema;
+	anyOf?: PropsSchema[];
+	allOf?: PropsSchema[];
+	/** The default value given by the destructuring of `Astro.props` */
+	default?: unknown;
+	deprecated?: boolean;
+	tsType?: string;
+}
+
+export interface PropsResult {
+	/** The schema of the Props, or null when the component doesn't declare Props */
+	schema: PropsSchema | null;
+	diagnostics: DiagnosticMessage[];
 }
 
 // This function transforms a single JavaScript file. It can be used to minify
@@ -186,8 +468,33 @@ export declare function transform(
 	options?: TransformOptions
 ): Promise<TransformResult>;
 
+// This function transforms many components at once. Files are compiled
+// concurrently, and component paths are only resolved once for the whole batch.
+export declare function transformBatch(
+	files: TransformBatchInput[],
+	options?: TransformBatchOptions
+): Promise<TransformBatchResult>;
+
 export declare function parse(input: string, options?: ParseOptions): Promise<ParseResult>;
 
+// This function parses a fragment of a component, e.g. a snippet or an editor selection, as the
+// content of the "context" element. Unlike "parse", no <html>, <head> or <body> is implied.
+export declare function parseFragment(
+	input: string,
+	options?: ParseFragmentOptions
+): Promise<ParseResult>;
+
+// This function splits a component into tokens, e.g. for syntax highlighting.
+// Unlike "parse", it keeps the source as written: no tag is implied or closed.
+export declare function tokenize(
+	input: string,
+	options?: TokenizeOptions
+): Promise<TokenizeResult>;
+
+// This function describes the "Props" interface or type of a component as a JSON Schema, with the
+// JSDoc descriptions of the properties and their defaults in the destructuring of "Astro.props".
+export declare function extractProps(input: string, options?: PropsOptions): Promise<PropsResult>;
+
 export declare function convertToTSX(
 	input: string,
 	options?: ConvertToTSXOptions
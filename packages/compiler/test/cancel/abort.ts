@@ -0,0 +1,52 @@
+import { transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+import { DiagnosticCode } from '../../dist/shared/diagnostics.js';
+
+const FIXTURE = `
+<style lang="scss">
+	h1 { color: red; }
+</style>
+<h1>Hello</h1>
+`;
+
+const never = () => new Promise<never>(() => {});
+
+test('rejects when the signal is aborted', async () => {
+	const controller = new AbortController();
+	const result = transform(FIXTURE, {
+		filename: '/src/pages/index.astro',
+		signal: controller.signal,
+		preprocessStyle: never,
+	});
+	controller.abort();
+
+	const error: any = await result.then(
+		() => assert.unreachable('expected the transform to be aborted'),
+		(err) => err
+	);
+	assert.equal(error.name, 'AbortError');
+	assert.match(error.message, 'transform of /src/pages/index.astro was cancelled');
+	assert.equal(error.diagnostic.code, DiagnosticCode.ERROR_CANCELLED);
+});
+
+test('rejects after the timeout', async () => {
+	const error: any = await transform(FIXTURE, {
+		filename: '/src/pages/index.astro',
+		timeout: 10,
+		preprocessStyle: never,
+	}).catch((err) => err);
+	assert.equal(error.message, 'transform of /src/pages/index.astro was cancelled: timed out after 10ms');
+});
+
+test('rejects when the signal is already aborted', async () => {
+	const error: any = await transform(FIXTURE, { signal: AbortSignal.abort() }).catch((err) => err);
+	assert.equal(error.name, 'AbortError');
+});
+
+test('completes before the timeout', async () => {
+	const result = await transform(FIXTURE, { timeout: 10_000 });
+	assert.equal(result.css.length, 1);
+});
+
+test.run();
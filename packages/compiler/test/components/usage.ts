@@ -0,0 +1,52 @@
+import { transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `---
+import Card from './Card.astro';
+import { Button } from '../ui';
+import Dead from './Dead.astro';
+---
+
+<Card title="Hello" {...rest}>
+	<h2 slot="title">Title</h2>
+	<Button client:load>Click</Button>
+</Card>
+`;
+
+let result: Awaited<ReturnType<typeof transform>>;
+test.before(async () => {
+	result = await transform(FIXTURE, {
+		filename: '/src/pages/index.astro',
+	});
+});
+
+test('records every component usage', () => {
+	assert.equal(
+		result.components.map((c) => [c.localName, c.exportName, c.specifier, c.resolvedPath]),
+		[
+			['Card', 'default', './Card.astro', '/src/pages/Card.astro'],
+			['Button', 'Button', '../ui', '/src/ui'],
+		]
+	);
+});
+
+test('records the attributes and slots of a usage', () => {
+	assert.equal(result.components[0].attributes, ['title', '...rest']);
+	assert.equal(result.components[0].slots, ['title', 'default']);
+	assert.equal(result.components[1].attributes, ['client:load']);
+});
+
+test('records the location of a usage', () => {
+	assert.equal(result.components[0].location.line, 7);
+	assert.equal(result.components[0].location.column, 2);
+});
+
+test('records the unused imports', () => {
+	assert.equal(
+		result.unusedImports.map((i) => [i.localName, i.specifier, i.location.line]),
+		[['Dead', './Dead.astro', 4]]
+	);
+});
+
+test.run();
@@ -0,0 +1,43 @@
+import { transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+import { DiagnosticCode } from '../../dist/shared/diagnostics.js';
+
+const FIXTURE = `<html>
+  <body>
+    <img src="logo.png" />
+    <button><svg aria-hidden="true" /></button>
+    <div role="buton" aria-hiden="true" tabindex="2" id="main"></div>
+    <main id="main"><input name="q" /></main>
+    <img {...props} /><div role={role} />
+  </body>
+</html>`;
+
+test('reports the accessibility mistakes of the template', async () => {
+	const result = await transform(FIXTURE, { accessibilityWarnings: true });
+
+	assert.equal(
+		result.diagnostics.map((diagnostic) => [diagnostic.code, diagnostic.location.line]),
+		[
+			[DiagnosticCode.WARNING_A11Y_MISSING_LANG, 1],
+			[DiagnosticCode.WARNING_A11Y_MISSING_ALT, 3],
+			[DiagnosticCode.WARNING_A11Y_MISSING_NAME, 4],
+			[DiagnosticCode.WARNING_A11Y_INVALID_ROLE, 5],
+			[DiagnosticCode.WARNING_A11Y_INVALID_ARIA, 5],
+			[DiagnosticCode.WARNING_A11Y_DUPLICATE_ID, 6],
+			[DiagnosticCode.WARNING_A11Y_MISSING_LABEL, 6],
+			[DiagnosticCode.HINT_A11Y_POSITIVE_TABINDEX, 5],
+		]
+	);
+	assert.equal(result.diagnostics[3].hint, 'Did you mean `button`?');
+	assert.equal(result.diagnostics[4].hint, 'Did you mean `aria-hidden`?');
+	assert.equal(result.diagnostics[7].severity, 4);
+});
+
+test('no warnings by default', async () => {
+	const result = await transform(FIXTURE);
+
+	assert.equal(result.diagnostics, []);
+});
+
+test.run();
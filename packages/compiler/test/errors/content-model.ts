@@ -0,0 +1,31 @@
+import { transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `<table>
+  <div>not a row</div>
+  <tr><td>cell</td></tr>
+</table>
+<p><div>block</div></p>`;
+
+test('warns about the restructured markup', async () => {
+	const result = await transform(FIXTURE, { contentModelWarnings: true });
+
+	assert.equal(
+		result.diagnostics.map((diagnostic) => [diagnostic.code, diagnostic.location.line]),
+		[
+			[2014, 2],
+			[2015, 5],
+		]
+	);
+	assert.match(result.diagnostics[0].text, 'written as `<table><div>`, parsed as `<div><table>`');
+	assert.match(result.diagnostics[1].text, 'written as `<p><div>…</p>`, parsed as `<p></p><div>…`');
+});
+
+test('no warnings by default', async () => {
+	const result = await transform(FIXTURE);
+
+	assert.equal(result.diagnostics, []);
+});
+
+test.run();
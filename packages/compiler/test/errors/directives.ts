@@ -0,0 +1,71 @@
+import { transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `---
+import Counter from '../components/Counter.jsx';
+---
+<Counter client:hover />
+<Counter client:laod />
+<div client:load data:theme="blue" />
+<img data:src="" />`;
+
+const directives = [
+	{ name: 'client:hover', elements: ['component'] },
+	{
+		name: 'data:*',
+		elements: ['element'],
+		validate: (value: string) => (value === '' ? 'expected a non-empty value' : undefined),
+	},
+];
+
+test('validates the directives', async () => {
+	const result = await transform(FIXTURE, { directives });
+
+	assert.equal(
+		result.diagnostics.map((diagnostic) => [diagnostic.code, diagnostic.location.line, diagnostic.location.column]),
+		[
+			[2017, 5, 10],
+			[2018, 6, 6],
+			[2018, 7, 16],
+		]
+	);
+	assert.equal(result.diagnostics[0].hint, 'Did you mean `client:load`?');
+	assert.equal(result.diagnostics[1].hint, '`client:load` can only be used on components and custom elements');
+	assert.equal(result.diagnostics[2].text, 'Invalid value for `data:src`: expected a non-empty value');
+});
+
+test('async validation', async () => {
+	const result = await transform('<div data:theme="red" />', {
+		directives: [{ name: 'data:theme', validate: async (value) => (value === 'red' ? 'red is not a theme' : null) }],
+	});
+
+	assert.equal(result.diagnostics.map((diagnostic) => diagnostic.text), ['Invalid value for `data:theme`: red is not a theme']);
+});
+
+test('validates the built-in client directives by default', async () => {
+	const result = await transform(FIXTURE);
+
+	assert.equal(
+		result.diagnostics.map((diagnostic) => [diagnostic.code, diagnostic.location.line, diagnostic.location.column]),
+		[[2018, 6, 6]]
+	);
+});
+
+test('validates the values of the client directives', async () => {
+	const result = await transform(
+		`<A client:media="max-width: 600px" /><A client:only="reakt" /><A client:idle={{ timout: 500 }} /><A client:visible={{ rootMargin: "200px" }} />`
+	);
+
+	assert.equal(
+		result.diagnostics.map((diagnostic) => [diagnostic.text, diagnostic.location.column, diagnostic.location.length]),
+		[
+			['Invalid value for `client:media`: `max-width:` is not a media type or keyword', 18, 10],
+			['Invalid value for `client:only`: unknown renderer `reakt`', 54, 5],
+			['Invalid value for `client:idle`: unknown option `timout`', 81, 6],
+		]
+	);
+	assert.equal(result.diagnostics[1].hint, 'Did you mean `react`?');
+});
+
+test.run();
@@ -0,0 +1,37 @@
+import { parse, transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `---
+const items = [];
+---
+<ul>
+	{items.map((item) => <li>{item.name}</li>))}
+</ul>
+<Card render={() => <b>ok</b>} class={a b} />`;
+
+test('reports the syntax errors of the expressions', async () => {
+	const result = await transform(FIXTURE, { validateExpressions: true });
+
+	assert.equal(
+		result.diagnostics.map((diagnostic) => [diagnostic.code, diagnostic.text, diagnostic.location.line, diagnostic.location.column]),
+		[
+			[2031, "Invalid expression: Unexpected token ')'", 5, 44],
+			[2031, "Invalid expression: Unexpected token 'b'", 7, 41],
+		]
+	);
+});
+
+test('parse reports the syntax errors of the expressions', async () => {
+	const result = await parse(FIXTURE, { validateExpressions: true });
+
+	assert.equal(result.diagnostics.length, 2);
+});
+
+test('expressions are not validated by default', async () => {
+	const result = await transform(FIXTURE);
+
+	assert.equal(result.diagnostics, []);
+});
+
+test.run();
@@ -0,0 +1,32 @@
+import { transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `<svg viewbox="0 0 24 24">
+  <path stroke_width="2" d="M0 0" />
+  <div>not SVG</div>
+</svg>`;
+
+test('warns about the mistakes in SVG', async () => {
+	const result = await transform(FIXTURE, { foreignContentWarnings: true });
+
+	assert.equal(
+		result.diagnostics.map((diagnostic) => [diagnostic.code, diagnostic.location.line]),
+		[
+			[2019, 1],
+			[2020, 2],
+			[2021, 3],
+		]
+	);
+	assert.match(result.diagnostics[0].text, '`viewbox` must be written `viewBox`');
+	assert.equal(result.diagnostics[1].hint, 'Did you mean `stroke-width`?');
+	assert.match(result.diagnostics[2].text, '`<div>` closes the `<svg>` it is written in');
+});
+
+test('no warnings by default', async () => {
+	const result = await transform(FIXTURE);
+
+	assert.equal(result.diagnostics, []);
+});
+
+test.run();
@@ -0,0 +1,48 @@
+import { parse } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+import type { FrontmatterNode } from '../../types.js';
+import { DiagnosticCode } from '../../dist/shared/diagnostics.js';
+
+const FIXTURE = `---
+import Card from './Card.astro';
+interface Props { title: string }
+const { title } = Astro.props;
+---
+<Card title={title} />`;
+
+test('frontmatter program', async () => {
+	const { ast, diagnostics } = await parse(FIXTURE, { frontmatterAST: true });
+
+	const frontmatter = ast.children[0] as FrontmatterNode;
+	assert.equal(diagnostics, []);
+	assert.equal(frontmatter.program?.type, 'Program');
+	assert.equal(
+		frontmatter.program?.body.map((node) => node.type),
+		['ImportDeclaration', 'TSInterfaceDeclaration', 'VariableDeclaration']
+	);
+});
+
+test('frontmatter program offsets and locations', async () => {
+	const { ast } = await parse(FIXTURE, { frontmatterAST: true });
+
+	const [, declaration] = (ast.children[0] as FrontmatterNode).program!.body;
+	assert.equal(FIXTURE.slice(declaration.start, declaration.end), 'interface Props { title: string }');
+	assert.equal(declaration.loc, { start: { line: 3, column: 0 }, end: { line: 3, column: 33 } });
+});
+
+test('no frontmatter program without the option', async () => {
+	const { ast } = await parse(FIXTURE);
+
+	assert.equal((ast.children[0] as FrontmatterNode).program, undefined);
+});
+
+test('frontmatter syntax error', async () => {
+	const { ast, diagnostics } = await parse('---\nconst = 1;\n---\n', { frontmatterAST: true });
+
+	assert.equal((ast.children[0] as FrontmatterNode).program, undefined);
+	assert.equal(diagnostics.length, 1);
+	assert.equal(diagnostics[0].code, DiagnosticCode.ERROR_FRONTMATTER_SYNTAX);
+});
+
+test.run();
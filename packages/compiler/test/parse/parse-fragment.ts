@@ -0,0 +1,35 @@
+import { parseFragment } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+import type { ElementNode } from '../../types.js';
+
+test('no implied html, head or body', async () => {
+	const { ast, diagnostics } = await parseFragment('<title>Hello</title><p>World</p>');
+
+	assert.equal(diagnostics, []);
+	assert.equal(
+		ast.children.map((node) => (node as ElementNode).name),
+		['title', 'p']
+	);
+});
+
+test('table context', async () => {
+	const { ast } = await parseFragment('<tr><td>Hello</td></tr>', { context: 'tbody' });
+
+	const [tr] = ast.children as ElementNode[];
+	assert.equal(tr.name, 'tr');
+	assert.equal((tr.children[0] as ElementNode).name, 'td');
+});
+
+test('svg context', async () => {
+	const { ast } = await parseFragment('<circle r="1" /><foreignObject><div /></foreignObject>', {
+		context: 'svg',
+	});
+
+	assert.equal(
+		ast.children.map((node) => (node as ElementNode).name),
+		['circle', 'foreignObject']
+	);
+});
+
+test.run();
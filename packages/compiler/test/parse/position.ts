@@ -75,7 +75,7 @@ test('include correct start and end position for self-closing tag', async () =>
 	);
 	assert.equal(
 		li.position.end,
-		{ line: 3, column: 6, offset: 31 },
+		{ line: 3, column: 7, offset: 32 },
 		'Expected serialized output to contain an end position'
 	);
 });
@@ -103,6 +103,31 @@ test('include correct start and end position for normal closing tag', async () =
 	);
 });
 
+test('include tag positions', async () => {
+	const input = `<div><p>one</p><p>two</div>`;
+	const { ast } = await parse(input);
+
+	const div = ast.children[0] as ElementNode;
+	const [first, second] = div.children as ElementNode[];
+	assert.equal(div.position?.open?.end.offset, 5);
+	assert.equal(first.position?.close?.start.offset, 11);
+	assert.equal(first.position?.end?.offset, 15);
+	assert.equal(second.position?.end?.offset, 21, 'Expected an unclosed element to end with its content');
+	assert.is(second.position?.close, undefined);
+});
+
+test('include attribute positions', async () => {
+	const input = `<div class="a" {...rest}></div>`;
+	const { ast } = await parse(input);
+
+	const [quoted, spread] = (ast.children[0] as ElementNode).attributes;
+	assert.equal(input.slice(quoted.position?.start.offset, quoted.position?.end?.offset), 'class="a"');
+	assert.equal(quoted.position?.name?.end.offset, 10);
+	assert.equal(quoted.position?.value?.start.offset, 12);
+	assert.equal(input.slice(spread.position?.start.offset, spread.position?.end?.offset), '{...rest}');
+	assert.is(spread.position?.value, undefined);
+});
+
 test('include start and end position if frontmatter is only thing in file (#802)', async () => {
 	const input = `---
 ---`;
@@ -0,0 +1,47 @@
+import { parse } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+import type { ElementNode, ErrorNode } from '../../types.js';
+import { DiagnosticCode } from '../../dist/shared/diagnostics.js';
+
+test('unterminated expression is closed before the end tag', async () => {
+	const input = `<div>{value</div>
+<p>after</p>`;
+	const { ast, diagnostics } = await parse(input, { recover: true });
+
+	const div = ast.children[0] as ElementNode;
+	const expression = div.children[0] as ElementNode;
+	const error = expression.children.at(-1) as ErrorNode;
+	assert.equal(error.type, 'error', 'Expected an error node at the end of the expression');
+	assert.equal(error.value, '');
+	assert.equal(
+		(ast.children.at(-1) as ElementNode).name,
+		'p',
+		'Expected the rest of the component to be parsed'
+	);
+	assert.equal(diagnostics.length, 1);
+	assert.equal(diagnostics[0].code, DiagnosticCode.ERROR_UNTERMINATED_EXPRESSION);
+});
+
+test('stray brace is an error node', async () => {
+	const { ast, diagnostics } = await parse('<div>a}b</div>', { recover: true });
+
+	const div = ast.children[0] as ElementNode;
+	assert.equal(
+		div.children.map((child) => child.type),
+		['text', 'error', 'text']
+	);
+	assert.equal(diagnostics[0].code, DiagnosticCode.WARNING_UNEXPECTED_BRACE);
+});
+
+test('no error nodes without recover', async () => {
+	const { ast } = await parse('<div>a}b</div>');
+
+	const div = ast.children[0] as ElementNode;
+	assert.equal(
+		div.children.map((child) => child.type),
+		['text']
+	);
+});
+
+test.run();
@@ -0,0 +1,67 @@
+import { extractProps } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `---
+import type { HTMLAttributes } from 'astro/types';
+
+/** A call to action */
+interface Props extends HTMLAttributes<'a'> {
+	/** The size of the button */
+	size?: 'sm' | 'md' | 'lg';
+	/** @deprecated Use size */
+	big?: boolean;
+	label: string;
+	onClick?: (event: MouseEvent) => void;
+}
+
+const { size = 'md', ...attrs } = Astro.props;
+---
+<a {...attrs} class={size}>{Astro.props.label}</a>`;
+
+test('schema', async () => {
+	const { schema, diagnostics } = await extractProps(FIXTURE);
+
+	assert.equal(diagnostics, []);
+	assert.equal(schema, {
+		$schema: 'https://json-schema.org/draft/2020-12/schema',
+		description: 'A call to action',
+		type: 'object',
+		properties: {
+			size: {
+				description: 'The size of the button',
+				type: 'string',
+				enum: ['sm', 'md', 'lg'],
+				default: 'md',
+			},
+			big: { type: 'boolean', deprecated: true },
+			label: { type: 'string' },
+			onClick: { tsType: '(event: MouseEvent) => void' },
+		},
+		required: ['label'],
+		allOf: [{ tsType: "HTMLAttributes<'a'>" }],
+	});
+});
+
+test('properties are in the order of their declaration', async () => {
+	const { schema } = await extractProps(FIXTURE);
+
+	assert.equal(Object.keys(schema?.properties ?? {}), ['size', 'big', 'label', 'onClick']);
+});
+
+test('no Props', async () => {
+	const { schema, diagnostics } = await extractProps('---\nconst a = 1;\n---\n<p>{a}</p>');
+
+	assert.equal(schema, null);
+	assert.equal(diagnostics, []);
+});
+
+test('invalid frontmatter', async () => {
+	const { schema, diagnostics } = await extractProps('---\ninterface Props { a: }\n---');
+
+	assert.equal(schema, null);
+	assert.equal(diagnostics.length, 1);
+	assert.ok(diagnostics[0].text.startsWith('Invalid frontmatter'));
+});
+
+test.run();
@@ -0,0 +1,68 @@
+import { parse, transform } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+import { DiagnosticCode } from '../../dist/shared/diagnostics.js';
+
+const FIXTURE = `---
+import Card from './Card.astro';
+---
+<slot name="header"><h1>Untitled</h1></slot>
+<slot />
+<Card>
+	<h2 slot="title">Title</h2>
+	<p slot="footer">Footer</p>
+</Card>
+`;
+
+const COMPONENTS: Record<string, string> = {
+	'/src/Card.astro': '<div><slot name="title" /><slot /></div>',
+};
+
+test('records the slots defined by the template', async () => {
+	const { slots } = await transform(FIXTURE, { filename: '/src/index.astro' });
+
+	assert.equal(
+		slots.map((slot) => [slot.name, slot.hasFallback, slot.location.line]),
+		[
+			['header', true, 4],
+			['default', false, 5],
+		]
+	);
+});
+
+test('records the slots filled by a component', async () => {
+	const { components } = await parse(FIXTURE);
+
+	assert.equal(
+		components[0].slotContents.map((content) => [content.name, content.location.line]),
+		[
+			['title', 7],
+			['footer', 8],
+		]
+	);
+});
+
+test('warns about the content of an unknown slot', async () => {
+	const loaded: string[] = [];
+	const { diagnostics } = await transform(FIXTURE, {
+		filename: '/src/index.astro',
+		loadComponent: (path) => {
+			loaded.push(path);
+			return COMPONENTS[path] ?? null;
+		},
+	});
+
+	assert.equal(loaded, ['/src/Card.astro']);
+	assert.equal(diagnostics.length, 1);
+	assert.equal(diagnostics[0].code, DiagnosticCode.WARNING_UNKNOWN_SLOT);
+	assert.equal(diagnostics[0].text, '`<Card>` has no slot named `footer`, so this content is not rendered');
+	assert.equal(diagnostics[0].location.line, 8);
+});
+
+test('does not warn without loadComponent', async () => {
+	const { diagnostics } = await transform(FIXTURE, { filename: '/src/index.astro' });
+
+	assert.equal(diagnostics, []);
+});
+
+test.run();
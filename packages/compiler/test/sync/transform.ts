@@ -0,0 +1,42 @@
+import { transform } from '@astrojs/compiler/sync';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `
+---
+import Counter from '../components/Counter.jsx';
+---
+<style lang="scss">$color: red; h1 { color: $color; }</style>
+<h1>Hello</h1>
+<Counter client:load />
+`;
+
+test('returns the result directly', () => {
+	const result = transform(FIXTURE, { filename: '/src/pages/index.astro' });
+	assert.not.instance(result, Promise);
+	assert.match(result.code, '<h1');
+	assert.equal(result.hydratedComponents[0].resolvedPath, '/src/components/Counter.jsx');
+});
+
+test('calls synchronous callbacks', () => {
+	const result = transform(FIXTURE, {
+		filename: '/src/pages/index.astro',
+		resolvePath: (specifier) => `/resolved/${specifier}`,
+		preprocessStyle: () => ({ code: 'h1 { color: red; }' }),
+	});
+	assert.equal(result.hydratedComponents[0].resolvedPath, '/resolved/../components/Counter.jsx');
+	assert.match(result.css[0], 'color:red');
+});
+
+test('throws on async callbacks', () => {
+	assert.throws(
+		() =>
+			transform(FIXTURE, {
+				filename: '/src/pages/index.astro',
+				preprocessStyle: async () => ({ code: 'h1 { color: red; }' }),
+			}),
+		/preprocessStyle returned a Promise/
+	);
+});
+
+test.run();
@@ -0,0 +1,41 @@
+import { tokenize } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `---
+const title = 'Hello';
+---
+<h1 class="title" {title} data-x={1}>🚀 {title}</h1>`;
+
+test('tokens cover the source', async () => {
+	const { tokens, diagnostics } = await tokenize(FIXTURE);
+
+	assert.equal(diagnostics, []);
+	assert.equal(tokens.map((token) => token.raw).join(''), FIXTURE);
+	for (const token of tokens) {
+		assert.equal(FIXTURE.slice(token.range.utf16Start, token.range.utf16End), token.raw);
+	}
+});
+
+test('attributes', async () => {
+	const { tokens } = await tokenize(FIXTURE);
+
+	const h1 = tokens.find((token) => token.type === 'start-tag');
+	assert.ok(h1);
+	assert.equal(h1.name, 'h1');
+	assert.equal(
+		h1.attributes.map((attr) => [attr.kind, attr.name, attr.value, attr.quote]),
+		[
+			['quoted', 'class', 'title', '"'],
+			['shorthand', 'title', '', ''],
+			['expression', 'data-x', '1', ''],
+		]
+	);
+	const [className] = h1.attributes;
+	assert.equal(
+		FIXTURE.slice(className.valueRange.utf16Start, className.valueRange.utf16End),
+		'title'
+	);
+});
+
+test.run();
@@ -0,0 +1,50 @@
+import { type TransformBatchResult, transformBatch } from '@astrojs/compiler';
+import { test } from 'uvu';
+import * as assert from 'uvu/assert';
+
+const FIXTURE = `
+---
+import Counter from '../components/Counter.jsx';
+---
+<Counter client:load />
+`;
+
+const resolved: string[] = [];
+let result: TransformBatchResult;
+test.before(async () => {
+	result = await transformBatch(
+		[
+			{ source: FIXTURE, options: { filename: '/src/pages/one.astro' } },
+			{ source: FIXTURE, options: { filename: '/src/pages/two.astro' } },
+			{ source: '<h1>Hello</h1>', options: { filename: '/src/pages/three.astro' } },
+		],
+		{
+			concurrency: 2,
+			resolvePath: async (specifier, importer) => {
+				resolved.push(importer);
+				return `/resolved/${specifier.replace('../', '')}`;
+			},
+		}
+	);
+});
+
+test('returns a result per file, in order', () => {
+	assert.equal(
+		result.files.map((file) => file.filename),
+		['/src/pages/one.astro', '/src/pages/two.astro', '/src/pages/three.astro']
+	);
+	assert.match(result.files[2].result.code, '<h1>Hello</h1>');
+});
+
+test('resolves shared imports once', () => {
+	assert.equal(resolved.length, 1);
+	for (const file of result.files.slice(0, 2)) {
+		assert.equal(file.result.hydratedComponents[0].resolvedPath, '/resolved/components/Counter.jsx');
+	}
+});
+
+test('aggregates diagnostics', () => {
+	assert.equal(result.diagnostics, []);
+});
+
+test.run();